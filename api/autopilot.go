@@ -1,8 +1,10 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/siad/build"
@@ -17,10 +19,23 @@ const (
 	DefaultAutopilotID = "autopilot"
 )
 
+const (
+	// The autopilot subsystems that can be paused and resumed at runtime.
+	AutopilotSubsystemAccountRefills = "accountrefills"
+	AutopilotSubsystemContractor     = "contractor"
+	AutopilotSubsystemMigrator       = "migrator"
+	AutopilotSubsystemPruning        = "pruning"
+	AutopilotSubsystemScanner        = "scanner"
+)
+
 var (
 	// ErrAutopilotNotFound is returned when an autopilot can't be found.
 	ErrAutopilotNotFound = errors.New("couldn't find autopilot")
 
+	// ErrUnknownAutopilotSubsystem is returned when trying to pause or resume
+	// a subsystem that doesn't exist.
+	ErrUnknownAutopilotSubsystem = errors.New("unknown autopilot subsystem")
+
 	// ErrMaxDowntimeHoursTooHigh is returned if the autopilot config is updated
 	// with a value that exceeds the maximum of 99 years.
 	ErrMaxDowntimeHoursTooHigh = errors.New("MaxDowntimeHours is too high, exceeds max value of 99 years")
@@ -36,11 +51,52 @@ type (
 
 	// AutopilotConfig contains all autopilot configuration.
 	AutopilotConfig struct {
-		Contracts ContractsConfig `json:"contracts"`
-		Hosts     HostsConfig     `json:"hosts"`
+		Contracts   ContractsConfig          `json:"contracts"`
+		Hosts       HostsConfig              `json:"hosts"`
+		AutoGouging *AutoGougingConfig       `json:"autoGouging,omitempty"`
+		Migrations  *MigrationsConfig        `json:"migrations,omitempty"`
+		Schedule    *AutopilotSchedule       `json:"schedule,omitempty"`
+		Wallet      *WalletMaintenanceConfig `json:"wallet,omitempty"`
+	}
+
+	// WalletMaintenanceConfig configures the wallet maintenance of the
+	// autopilot. The wallet is split into 'Outputs' outputs of 'OutputValue'
+	// each, if no value is set the allowance is divided by the number of
+	// missing outputs. Once there are more than 'MaxSmallOutputs' outputs with
+	// a value below the 'ConsolidationThreshold', up to
+	// 'MaxConsolidationInputs' of them are merged into a single output as long
+	// as the recommended fee doesn't exceed 'MaxConsolidationFee'. Zero values
+	// use the defaults.
+	WalletMaintenanceConfig struct {
+		Outputs     uint64         `json:"outputs"`
+		OutputValue types.Currency `json:"outputValue"`
+
+		DisableConsolidation   bool           `json:"disableConsolidation"`
+		ConsolidationThreshold types.Currency `json:"consolidationThreshold"`
+		MaxSmallOutputs        uint64         `json:"maxSmallOutputs"`
+		MaxConsolidationInputs uint64         `json:"maxConsolidationInputs"`
+		MaxConsolidationFee    types.Currency `json:"maxConsolidationFee"`
+	}
+
+	// AutoGougingConfig configures the autopilot to derive the price limits
+	// of the gouging settings from the prices of the hosts on the network.
+	// Every limit is set to the given percentile of the hosts' prices but
+	// never exceeds the corresponding ceiling.
+	AutoGougingConfig struct {
+		Enabled    bool    `json:"enabled"`
+		Percentile float64 `json:"percentile"`
+
+		MaxRPCPrice      types.Currency `json:"maxRPCPrice"`
+		MaxContractPrice types.Currency `json:"maxContractPrice"`
+		MaxDownloadPrice types.Currency `json:"maxDownloadPrice"`
+		MaxUploadPrice   types.Currency `json:"maxUploadPrice"`
+		MaxStoragePrice  types.Currency `json:"maxStoragePrice"`
 	}
 
 	// ContractsConfig contains all contract settings used in the autopilot.
+	// If StaggerWindow is set, the end heights of contracts are spread across
+	// that many blocks so the contracts don't all enter the renew window at
+	// the same time.
 	ContractsConfig struct {
 		Set         string         `json:"set"`
 		Amount      uint64         `json:"amount"`
@@ -51,18 +107,69 @@ type (
 		Upload      uint64         `json:"upload"`
 		Storage     uint64         `json:"storage"`
 		Prune       bool           `json:"prune"`
+
+		StaggerWindow uint64 `json:"staggerWindow,omitempty"`
 	}
 
 	// HostsConfig contains all hosts settings used in the autopilot.
 	HostsConfig struct {
 		AllowRedundantIPs     bool                        `json:"allowRedundantIPs"`
+		MaxASNPct             float64                     `json:"maxASNPct,omitempty"`
+		MaxCountryPct         float64                     `json:"maxCountryPct,omitempty"`
 		MaxDowntimeHours      uint64                      `json:"maxDowntimeHours"`
 		MinProtocolVersion    string                      `json:"minProtocolVersion"`
 		MinRecentScanFailures uint64                      `json:"minRecentScanFailures"`
 		ScoreOverrides        map[types.PublicKey]float64 `json:"scoreOverrides"`
+		ScoreWeights          *HostScoreWeights           `json:"scoreWeights,omitempty"`
+	}
+
+	// HostScoreWeights contains the weights of the components that make up a
+	// host's score. Every component is raised to the power of its weight
+	// before the components are multiplied, a weight of 1 leaves the
+	// component untouched while a weight of 0 ignores it entirely. Weights
+	// that are omitted when decoding default to 1.
+	HostScoreWeights struct {
+		Age              float64 `json:"age"`
+		Collateral       float64 `json:"collateral"`
+		Interactions     float64 `json:"interactions"`
+		Prices           float64 `json:"prices"`
+		StorageRemaining float64 `json:"storageRemaining"`
+		Uptime           float64 `json:"uptime"`
+		Version          float64 `json:"version"`
 	}
 )
 
+// DefaultHostScoreWeights are the weights used if the config doesn't specify
+// any, every component contributes equally to the score.
+var DefaultHostScoreWeights = HostScoreWeights{
+	Age:              1,
+	Collateral:       1,
+	Interactions:     1,
+	Prices:           1,
+	StorageRemaining: 1,
+	Uptime:           1,
+	Version:          1,
+}
+
+const (
+	// DefaultWalletOutputs is the default number of outputs the wallet is
+	// split into.
+	DefaultWalletOutputs = 10
+
+	// DefaultMaxSmallOutputs is the default number of small outputs the
+	// wallet tolerates before they are consolidated.
+	DefaultMaxSmallOutputs = 20
+
+	// DefaultMaxConsolidationInputs is the default number of outputs that
+	// are merged by a single consolidation transaction.
+	DefaultMaxConsolidationInputs = 100
+)
+
+// DefaultMaxConsolidationFee is the default fee per byte above which the
+// wallet's outputs aren't consolidated, it matches the fee recommended by a
+// transaction pool that isn't congested.
+var DefaultMaxConsolidationFee = types.Siacoins(3).Div64(100_000)
+
 // EndHeight of a contract formed using the AutopilotConfig given the current
 // period.
 func (ap *Autopilot) EndHeight() uint64 {
@@ -94,10 +201,23 @@ type (
 		ScanningLastStart  TimeRFC3339 `json:"scanningLastStart"`
 		UptimeMS           DurationMS  `json:"uptimeMs"`
 
+		Paused      AutopilotPausedSubsystems `json:"paused"`
+		RenewalPlan *RenewalPlan              `json:"renewalPlan,omitempty"`
+
 		StartTime TimeRFC3339 `json:"startTime"`
 		BuildState
 	}
 
+	// AutopilotPausedSubsystems contains the subsystems of the autopilot that
+	// are paused, it is persisted in the bus so pauses survive restarts.
+	AutopilotPausedSubsystems struct {
+		AccountRefills bool `json:"accountRefills"`
+		Contractor     bool `json:"contractor"`
+		Migrator       bool `json:"migrator"`
+		Pruning        bool `json:"pruning"`
+		Scanner        bool `json:"scanner"`
+	}
+
 	ConfigEvaluationRequest struct {
 		AutopilotConfig    AutopilotConfig    `json:"autopilotConfig"`
 		GougingSettings    GougingSettings    `json:"gougingSettings"`
@@ -125,6 +245,133 @@ type (
 			NotScanned            uint64 `json:"notScanned"`
 		} `json:"unusable"`
 		Recommendation *ConfigRecommendation `json:"recommendation,omitempty"`
+		HostSelection  *ConfigHostSelection  `json:"hostSelection,omitempty"`
+	}
+
+	// ConfigHostSelection compares the hosts selected using the evaluated
+	// config to the ones selected using the current config. The selected
+	// hosts are the usable hosts with the highest scores.
+	ConfigHostSelection struct {
+		Selected []types.PublicKey `json:"selected"`
+		Added    []types.PublicKey `json:"added"`
+		Removed  []types.PublicKey `json:"removed"`
+	}
+)
+
+type (
+	// ContractMaintenancePlan describes what the next contract maintenance
+	// would do given the current state. It's the response type for the GET
+	// /maintenance/plan endpoint.
+	ContractMaintenancePlan struct {
+		ContractSet string `json:"contractSet"`
+		SkipReason  string `json:"skipReason,omitempty"`
+
+		// Remaining is the amount of the allowance that hasn't been spent in
+		// the current period, EstimatedCost is the amount the maintenance
+		// would spend out of it.
+		Remaining     types.Currency `json:"remaining"`
+		EstimatedCost types.Currency `json:"estimatedCost"`
+
+		Form    []PlannedFormation `json:"form"`
+		Renew   []PlannedRenewal   `json:"renew"`
+		Refresh []PlannedRenewal   `json:"refresh"`
+		Drop    []PlannedRemoval   `json:"drop"`
+	}
+
+	// SpendingForecast projects the autopilot's spending for the current and
+	// the next period. It's the response type for the GET /forecast
+	// endpoint.
+	SpendingForecast struct {
+		BlockHeight uint64 `json:"blockHeight"`
+		PeriodStart uint64 `json:"periodStart"`
+		PeriodEnd   uint64 `json:"periodEnd"`
+
+		// Allowance is the configured allowance, Allocated is the amount of
+		// the allowance that was used to fund contracts in the current period.
+		Allowance types.Currency `json:"allowance"`
+		Allocated types.Currency `json:"allocated"`
+
+		// Spent contains the spending recorded in the current period,
+		// Projected the spending we expect by the end of the period if
+		// spending continues at the current rate and NextPeriod the spending
+		// we expect in the next period given the configured estimates and
+		// the prices of the hosts in the contract set.
+		Spent      SpendingBreakdown `json:"spent"`
+		Projected  SpendingBreakdown `json:"projected"`
+		NextPeriod SpendingBreakdown `json:"nextPeriod"`
+
+		// AllowanceExhaustedHeight is the height at which the allowance runs
+		// out if spending continues at the current rate, it's omitted if the
+		// allowance lasts until the end of the period.
+		AllowanceExhaustedHeight *uint64 `json:"allowanceExhaustedHeight,omitempty"`
+
+		// WalletBalance is the wallet's spendable balance, UpcomingRenewals
+		// is the amount needed to renew the contracts that expire before
+		// the end of the next period and WalletShortfall is the amount the
+		// wallet lacks to cover them.
+		WalletBalance    types.Currency `json:"walletBalance"`
+		UpcomingRenewals types.Currency `json:"upcomingRenewals"`
+		WalletShortfall  types.Currency `json:"walletShortfall"`
+	}
+
+	// SpendingBreakdown breaks down spending per category, Contracts contains
+	// the fees paid to form, renew and refresh contracts.
+	SpendingBreakdown struct {
+		ContractSpending
+		Contracts types.Currency `json:"contracts"`
+		Total     types.Currency `json:"total"`
+	}
+
+	// RenewalPlan describes when the contracts that are due or coming up for
+	// renewal are renewed. If the wallet can't afford all of those renewals
+	// at once, a portion of the upcoming renewals is renewed early.
+	RenewalPlan struct {
+		BlockHeight   uint64         `json:"blockHeight"`
+		StaggerWindow uint64         `json:"staggerWindow"`
+		WalletBalance types.Currency `json:"walletBalance"`
+		EstimatedCost types.Currency `json:"estimatedCost"`
+
+		Renewals []ScheduledRenewal `json:"renewals"`
+	}
+
+	// ScheduledRenewal describes the renewal of a single contract.
+	// RenewHeight is the height at which the contract enters the renew
+	// window, TargetEndHeight is the end height of the renewed contract.
+	ScheduledRenewal struct {
+		ContractID      types.FileContractID `json:"contractID"`
+		HostKey         types.PublicKey      `json:"hostKey"`
+		EndHeight       uint64               `json:"endHeight"`
+		RenewHeight     uint64               `json:"renewHeight"`
+		TargetEndHeight uint64               `json:"targetEndHeight"`
+		EstimatedCost   types.Currency       `json:"estimatedCost"`
+		Due             bool                 `json:"due"`
+		Early           bool                 `json:"early"`
+	}
+
+	// PlannedFormation describes a contract the maintenance would form.
+	PlannedFormation struct {
+		HostKey       types.PublicKey `json:"hostKey"`
+		Score         float64         `json:"score"`
+		EstimatedCost types.Currency  `json:"estimatedCost"`
+	}
+
+	// PlannedRenewal describes a contract the maintenance would renew or
+	// refresh.
+	PlannedRenewal struct {
+		ContractID    types.FileContractID `json:"contractID"`
+		HostKey       types.PublicKey      `json:"hostKey"`
+		Reason        string               `json:"reason"`
+		EstimatedCost types.Currency       `json:"estimatedCost"`
+	}
+
+	// PlannedRemoval describes a contract the maintenance would remove from
+	// the contract set, archived contracts are removed from the set and
+	// archived.
+	PlannedRemoval struct {
+		ContractID types.FileContractID `json:"contractID"`
+		HostKey    types.PublicKey      `json:"hostKey"`
+		Reason     string               `json:"reason"`
+		Archive    bool                 `json:"archive"`
 	}
 )
 
@@ -133,6 +380,152 @@ func (c AutopilotConfig) Validate() error {
 		return ErrMaxDowntimeHoursTooHigh
 	} else if c.Hosts.MinProtocolVersion != "" && !build.IsVersion(c.Hosts.MinProtocolVersion) {
 		return fmt.Errorf("invalid min protocol version '%s'", c.Hosts.MinProtocolVersion)
+	} else if !(c.Hosts.MaxASNPct >= 0 && c.Hosts.MaxASNPct <= 1) {
+		return fmt.Errorf("invalid max ASN percentage %v, must be between 0 and 1", c.Hosts.MaxASNPct)
+	} else if !(c.Hosts.MaxCountryPct >= 0 && c.Hosts.MaxCountryPct <= 1) {
+		return fmt.Errorf("invalid max country percentage %v, must be between 0 and 1", c.Hosts.MaxCountryPct)
+	} else if c.Hosts.ScoreWeights != nil {
+		if err := c.Hosts.ScoreWeights.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Contracts.StaggerWindow > 0 && c.Contracts.StaggerWindow >= c.Contracts.Period {
+		return fmt.Errorf("invalid stagger window %v, must be smaller than the period %v", c.Contracts.StaggerWindow, c.Contracts.Period)
+	}
+	if c.AutoGouging != nil {
+		if err := c.AutoGouging.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Migrations != nil {
+		if err := c.Migrations.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Wallet != nil && c.Wallet.MaxConsolidationInputs == 1 {
+		return errors.New("invalid max consolidation inputs, must be at least 2")
+	}
+	if c.Schedule != nil {
+		return c.Schedule.Validate()
+	}
+	return nil
+}
+
+// WalletMaintenance returns the wallet maintenance config with the defaults
+// applied.
+func (c AutopilotConfig) WalletMaintenance() WalletMaintenanceConfig {
+	var wc WalletMaintenanceConfig
+	if c.Wallet != nil {
+		wc = *c.Wallet
+	}
+	if wc.Outputs == 0 {
+		wc.Outputs = DefaultWalletOutputs
+	}
+	if wc.ConsolidationThreshold.IsZero() {
+		value := wc.OutputValue
+		if value.IsZero() {
+			value = c.Contracts.Allowance.Div64(wc.Outputs)
+		}
+		wc.ConsolidationThreshold = value.Div64(10)
+	}
+	if wc.MaxSmallOutputs == 0 {
+		wc.MaxSmallOutputs = DefaultMaxSmallOutputs
+	}
+	if wc.MaxConsolidationInputs == 0 {
+		wc.MaxConsolidationInputs = DefaultMaxConsolidationInputs
+	}
+	if wc.MaxConsolidationFee.IsZero() {
+		wc.MaxConsolidationFee = DefaultMaxConsolidationFee
+	}
+	return wc
+}
+
+// IsPaused returns whether the given subsystem is paused.
+func (p AutopilotPausedSubsystems) IsPaused(subsystem string) bool {
+	switch subsystem {
+	case AutopilotSubsystemAccountRefills:
+		return p.AccountRefills
+	case AutopilotSubsystemContractor:
+		return p.Contractor
+	case AutopilotSubsystemMigrator:
+		return p.Migrator
+	case AutopilotSubsystemPruning:
+		return p.Pruning
+	case AutopilotSubsystemScanner:
+		return p.Scanner
+	default:
+		return false
+	}
+}
+
+// SetPaused pauses or resumes the given subsystem.
+func (p *AutopilotPausedSubsystems) SetPaused(subsystem string, paused bool) error {
+	switch subsystem {
+	case AutopilotSubsystemAccountRefills:
+		p.AccountRefills = paused
+	case AutopilotSubsystemContractor:
+		p.Contractor = paused
+	case AutopilotSubsystemMigrator:
+		p.Migrator = paused
+	case AutopilotSubsystemPruning:
+		p.Pruning = paused
+	case AutopilotSubsystemScanner:
+		p.Scanner = paused
+	default:
+		return fmt.Errorf("%w: '%s'", ErrUnknownAutopilotSubsystem, subsystem)
+	}
+	return nil
+}
+
+// Validate returns an error if automatic gouging settings are enabled without
+// a valid percentile or without a ceiling for every price.
+func (c AutoGougingConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	} else if !(c.Percentile > 0 && c.Percentile <= 100) {
+		return fmt.Errorf("invalid auto gouging percentile %v, must be between 0 and 100", c.Percentile)
+	} else if c.MaxRPCPrice.IsZero() || c.MaxContractPrice.IsZero() || c.MaxDownloadPrice.IsZero() || c.MaxUploadPrice.IsZero() || c.MaxStoragePrice.IsZero() {
+		return errors.New("auto gouging requires a ceiling for every price")
+	}
+	return nil
+}
+
+// Weights returns the configured score weights or the default weights if
+// none were configured.
+func (hc HostsConfig) Weights() HostScoreWeights {
+	if hc.ScoreWeights == nil {
+		return DefaultHostScoreWeights
+	}
+	return *hc.ScoreWeights
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Weights that are omitted default
+// to 1 so they don't ignore the component.
+func (w *HostScoreWeights) UnmarshalJSON(data []byte) error {
+	type weights HostScoreWeights
+	decoded := weights(DefaultHostScoreWeights)
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*w = HostScoreWeights(decoded)
+	return nil
+}
+
+// Validate returns an error if any of the weights is negative or not a
+// finite number.
+func (w HostScoreWeights) Validate() error {
+	for name, weight := range map[string]float64{
+		"Age":              w.Age,
+		"Collateral":       w.Collateral,
+		"Interactions":     w.Interactions,
+		"Prices":           w.Prices,
+		"StorageRemaining": w.StorageRemaining,
+		"Uptime":           w.Uptime,
+		"Version":          w.Version,
+	} {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return fmt.Errorf("invalid score weight for %s: %v, must be a non-negative number", name, weight)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,14 @@
+package api
+
+import "errors"
+
+// ErrBackupNotSupported is returned when the database doesn't support online
+// backups.
+var ErrBackupNotSupported = errors.New("online backups are not supported by this database")
+
+// BackupRequest is the request type for the /backup endpoint.
+type BackupRequest struct {
+	// Password is used to encrypt the backup, if it's empty the backup isn't
+	// encrypted.
+	Password string `json:"password,omitempty"`
+}
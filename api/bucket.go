@@ -2,6 +2,9 @@ package api
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 )
 
 const (
@@ -20,6 +23,10 @@ var (
 	// ErrBucketNotFound is returned when an bucket can't be retrieved from the
 	// database.
 	ErrBucketNotFound = errors.New("bucket not found")
+
+	// ErrInvalidBucketPolicy is returned when a bucket policy is not
+	// considered valid.
+	ErrInvalidBucketPolicy = errors.New("invalid bucket policy")
 )
 
 type (
@@ -30,7 +37,33 @@ type (
 	}
 
 	BucketPolicy struct {
-		PublicReadAccess bool `json:"publicReadAccess"`
+		PublicReadAccess bool           `json:"publicReadAccess"`
+		Website          *BucketWebsite `json:"website,omitempty"`
+	}
+
+	// BucketWebsite configures how a bucket is served as a static website,
+	// it requires the bucket to allow public read access.
+	BucketWebsite struct {
+		IndexDocument string               `json:"indexDocument"`
+		ErrorDocument string               `json:"errorDocument,omitempty"`
+		RedirectRules []BucketRedirectRule `json:"redirectRules,omitempty"`
+	}
+
+	// BucketRedirectRule redirects website requests that match the rule's
+	// conditions. A rule without an error code condition is applied before
+	// the object is looked up, a rule with an error code condition is only
+	// applied if serving the object failed with that code.
+	BucketRedirectRule struct {
+		// conditions
+		KeyPrefixEquals             string `json:"keyPrefixEquals,omitempty"`
+		HTTPErrorCodeReturnedEquals int    `json:"httpErrorCodeReturnedEquals,omitempty"`
+
+		// redirect
+		HostName             string `json:"hostName,omitempty"`
+		Protocol             string `json:"protocol,omitempty"`
+		ReplaceKeyPrefixWith string `json:"replaceKeyPrefixWith,omitempty"`
+		ReplaceKeyWith       string `json:"replaceKeyWith,omitempty"`
+		HTTPRedirectCode     int    `json:"httpRedirectCode,omitempty"`
 	}
 
 	CreateBucketOptions struct {
@@ -48,3 +81,70 @@ type (
 		Policy BucketPolicy `json:"policy"`
 	}
 )
+
+// Validate returns an error if the bucket policy is not considered valid.
+func (bp BucketPolicy) Validate() error {
+	if bp.Website == nil {
+		return nil
+	} else if !bp.PublicReadAccess {
+		return fmt.Errorf("%w: website hosting requires public read access", ErrInvalidBucketPolicy)
+	}
+	return bp.Website.Validate()
+}
+
+// Validate returns an error if the website configuration is not considered
+// valid.
+func (bw BucketWebsite) Validate() error {
+	if bw.IndexDocument == "" {
+		return fmt.Errorf("%w: IndexDocument can't be empty", ErrInvalidBucketPolicy)
+	} else if strings.Contains(bw.IndexDocument, "/") {
+		return fmt.Errorf("%w: IndexDocument can't contain a slash", ErrInvalidBucketPolicy)
+	} else if strings.HasSuffix(bw.ErrorDocument, "/") {
+		return fmt.Errorf("%w: ErrorDocument must be an object, not a directory", ErrInvalidBucketPolicy)
+	}
+	for i, rule := range bw.RedirectRules {
+		if rule.ReplaceKeyWith != "" && rule.ReplaceKeyPrefixWith != "" {
+			return fmt.Errorf("%w: redirect rule %d can't set both ReplaceKeyWith and ReplaceKeyPrefixWith", ErrInvalidBucketPolicy, i)
+		} else if rule.HTTPRedirectCode != 0 && (rule.HTTPRedirectCode < 300 || rule.HTTPRedirectCode > 399) {
+			return fmt.Errorf("%w: redirect rule %d has invalid HTTPRedirectCode %d", ErrInvalidBucketPolicy, i, rule.HTTPRedirectCode)
+		} else if rule.HTTPErrorCodeReturnedEquals != 0 && (rule.HTTPErrorCodeReturnedEquals < 400 || rule.HTTPErrorCodeReturnedEquals > 599) {
+			return fmt.Errorf("%w: redirect rule %d has invalid HTTPErrorCodeReturnedEquals %d", ErrInvalidBucketPolicy, i, rule.HTTPErrorCodeReturnedEquals)
+		} else if rule.Protocol != "" && rule.Protocol != "http" && rule.Protocol != "https" {
+			return fmt.Errorf("%w: redirect rule %d has invalid Protocol '%s'", ErrInvalidBucketPolicy, i, rule.Protocol)
+		}
+	}
+	return nil
+}
+
+// RedirectRule returns the first redirect rule that applies to a request for
+// the given key. The code is the status code that serving the request
+// resulted in, 0 if the object wasn't looked up yet.
+func (bw BucketWebsite) RedirectRule(key string, code int) (BucketRedirectRule, bool) {
+	for _, rule := range bw.RedirectRules {
+		if rule.HTTPErrorCodeReturnedEquals == code && strings.HasPrefix(key, rule.KeyPrefixEquals) {
+			return rule, true
+		}
+	}
+	return BucketRedirectRule{}, false
+}
+
+// RedirectCode returns the status code used when redirecting, it defaults to
+// 301 Moved Permanently.
+func (r BucketRedirectRule) RedirectCode() int {
+	if r.HTTPRedirectCode == 0 {
+		return http.StatusMovedPermanently
+	}
+	return r.HTTPRedirectCode
+}
+
+// RedirectKey returns the key the given key is redirected to.
+func (r BucketRedirectRule) RedirectKey(key string) string {
+	switch {
+	case r.ReplaceKeyWith != "":
+		return r.ReplaceKeyWith
+	case r.ReplaceKeyPrefixWith != "":
+		return r.ReplaceKeyPrefixWith + strings.TrimPrefix(key, r.KeyPrefixEquals)
+	default:
+		return key
+	}
+}
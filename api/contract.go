@@ -194,6 +194,16 @@ func (x ContractSpending) Add(y ContractSpending) (z ContractSpending) {
 	return
 }
 
+// InSet returns whether the contract is part of the given contract set.
+func (c ContractMetadata) InSet(set string) bool {
+	for _, s := range c.ContractSets {
+		if s == set {
+			return true
+		}
+	}
+	return false
+}
+
 // EndHeight returns the height at which the host is no longer obligated to
 // store contract data.
 func (c Contract) EndHeight() uint64 { return c.WindowStart }
@@ -0,0 +1,33 @@
+package api
+
+// FsckRequest is the request type for the /fsck endpoint.
+type FsckRequest struct {
+	// Repair causes repairable issues to be fixed, otherwise they are only
+	// reported.
+	Repair bool `json:"repair"`
+}
+
+// FsckResponse is the response type for the /fsck endpoint.
+type FsckResponse struct {
+	Repair bool        `json:"repair"`
+	Issues []FsckIssue `json:"issues"`
+}
+
+// FsckIssue describes the inconsistencies found by a single check of the
+// metadata consistency checker.
+type FsckIssue struct {
+	// Check is the name of the check that found the issue.
+	Check       string `json:"check"`
+	Description string `json:"description"`
+
+	// Count is the number of affected rows or files, Examples contains up to
+	// FsckMaxExamples of them.
+	Count    int      `json:"count"`
+	Examples []string `json:"examples,omitempty"`
+
+	Repairable bool `json:"repairable"`
+	Repaired   bool `json:"repaired"`
+}
+
+// FsckMaxExamples is the maximum number of examples reported per issue.
+const FsckMaxExamples = 10
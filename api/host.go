@@ -3,6 +3,7 @@ package api
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/url"
 	"strings"
 	"time"
@@ -72,8 +73,17 @@ type (
 	// HostResponse is the response type for the GET
 	// /api/autopilot/host/:hostkey endpoint.
 	HostResponse struct {
-		Host   Host        `json:"host"`
-		Checks *HostChecks `json:"checks,omitempty"`
+		Host     Host          `json:"host"`
+		Checks   *HostChecks   `json:"checks,omitempty"`
+		Location *HostLocation `json:"location,omitempty"`
+	}
+
+	// HostLocation describes where a host is located according to the
+	// autopilot's GeoIP/ASN database.
+	HostLocation struct {
+		Country       string `json:"country"`
+		ASN           uint32 `json:"asn"`
+		ASDescription string `json:"asDescription,omitempty"`
 	}
 
 	HostChecks struct {
@@ -81,6 +91,7 @@ type (
 		GougingBreakdown HostGougingBreakdown `json:"gougingBreakdown"`
 		Score            float64              `json:"score"`
 		ScoreBreakdown   HostScoreBreakdown   `json:"scoreBreakdown"`
+		ScoreWeights     *HostScoreWeights    `json:"scoreWeights,omitempty"`
 		Usable           bool                 `json:"usable"`
 		UnusableReasons  []string             `json:"unusableReasons,omitempty"`
 	}
@@ -289,6 +300,21 @@ func (sb HostScoreBreakdown) Score() float64 {
 	return sb.Age * sb.Collateral * sb.Interactions * sb.StorageRemaining * sb.Uptime * sb.Version * sb.Prices
 }
 
+// Weighted returns the breakdown with every component raised to the power of
+// its weight, the result contains each component's weighted contribution to
+// the score.
+func (sb HostScoreBreakdown) Weighted(w HostScoreWeights) HostScoreBreakdown {
+	return HostScoreBreakdown{
+		Age:              math.Pow(sb.Age, w.Age),
+		Collateral:       math.Pow(sb.Collateral, w.Collateral),
+		Interactions:     math.Pow(sb.Interactions, w.Interactions),
+		StorageRemaining: math.Pow(sb.StorageRemaining, w.StorageRemaining),
+		Uptime:           math.Pow(sb.Uptime, w.Uptime),
+		Version:          math.Pow(sb.Version, w.Version),
+		Prices:           math.Pow(sb.Prices, w.Prices),
+	}
+}
+
 func (ub HostUsabilityBreakdown) IsUsable() bool {
 	return !ub.Blocked && !ub.Offline && !ub.LowScore && !ub.RedundantIP && !ub.Gouging && !ub.NotAcceptingContracts && !ub.NotAnnounced && !ub.NotCompletingScan
 }
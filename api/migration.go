@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/object"
+)
+
+var (
+	// ErrMigrationNotFound is returned when a slab is neither queued for
+	// migration nor being migrated.
+	ErrMigrationNotFound = errors.New("migration not found")
+)
+
+type (
+	// MigrationsConfig contains the migration settings used by the autopilot.
+	// Slabs are migrated in order of their priority, a slab's priority is the
+	// highest weight of the buckets that contain objects referencing the slab.
+	// Buckets without a weight have a weight of 1.
+	//
+	// The budget limits the amount spent on migrations per period, once it is
+	// exhausted only slabs with a health below the schedule's critical
+	// migration health, or 0.25 if it's not set, are migrated. A zero budget
+	// disables the limit.
+	MigrationsConfig struct {
+		BucketPriorities map[string]float64 `json:"bucketPriorities,omitempty"`
+		Budget           types.Currency     `json:"budget"`
+	}
+
+	// MigrationSpending is the amount spent on migrations in a period.
+	MigrationSpending struct {
+		Period uint64         `json:"period"`
+		Spent  types.Currency `json:"spent"`
+	}
+
+	// MigrationBudgetResponse is the response type for the /migrations/budget
+	// endpoint.
+	MigrationBudgetResponse struct {
+		MigrationSpending
+		Budget    types.Currency `json:"budget"`
+		Remaining types.Currency `json:"remaining"`
+		Exhausted bool           `json:"exhausted"`
+	}
+
+	// MigrationJob describes a slab that is queued for migration or is being
+	// migrated.
+	MigrationJob struct {
+		Key      object.EncryptionKey `json:"key"`
+		Health   float64              `json:"health"`
+		Priority float64              `json:"priority"`
+		Manual   bool                 `json:"manual"`
+		QueuedAt TimeRFC3339          `json:"queuedAt"`
+
+		// in-flight migrations only
+		StartedAt TimeRFC3339 `json:"startedAt,omitempty"`
+		Worker    string      `json:"worker,omitempty"`
+
+		// Objects contains the names of the objects referencing the slab
+		// grouped by bucket, it is only set if the objects were looked up.
+		Objects map[string][]string `json:"objects,omitempty"`
+	}
+
+	// MigrationOutcome is the result of a migration.
+	MigrationOutcome struct {
+		Key              object.EncryptionKey `json:"key"`
+		Health           float64              `json:"health"`
+		Manual           bool                 `json:"manual"`
+		Worker           string               `json:"worker"`
+		StartedAt        TimeRFC3339          `json:"startedAt"`
+		Duration         DurationMS           `json:"duration"`
+		ShardsMigrated   int                  `json:"shardsMigrated"`
+		SurchargeApplied bool                 `json:"surchargeApplied"`
+		Cost             types.Currency       `json:"cost"`
+		Error            string               `json:"error,omitempty"`
+	}
+
+	// MigrationQueueResponse is the response type for the
+	// /migrations/queue endpoint.
+	MigrationQueueResponse struct {
+		Queued      []MigrationJob `json:"queued"`
+		InFlight    []MigrationJob `json:"inFlight"`
+		TotalQueued int            `json:"totalQueued"`
+	}
+
+	// MigrationEnqueueRequest is the request type for the /migrations/queue
+	// endpoint.
+	MigrationEnqueueRequest struct {
+		Key object.EncryptionKey `json:"key"`
+	}
+
+	// MigrationHistoryResponse is the response type for the
+	// /migrations/history endpoint.
+	MigrationHistoryResponse struct {
+		Outcomes  []MigrationOutcome `json:"outcomes"`
+		TotalCost types.Currency     `json:"totalCost"`
+	}
+)
+
+// Validate returns an error if any of the bucket priorities is not a positive
+// number.
+func (c MigrationsConfig) Validate() error {
+	for bucket, weight := range c.BucketPriorities {
+		if !(weight > 0) || math.IsInf(weight, 0) {
+			return fmt.Errorf("invalid priority for bucket '%s': %v, must be a positive number", bucket, weight)
+		}
+	}
+	return nil
+}
+
+// Priority returns the priority of a slab that is referenced by objects in the
+// given buckets.
+func (c MigrationsConfig) Priority(buckets []string) float64 {
+	if len(buckets) == 0 {
+		return 1
+	}
+	var priority float64
+	for _, bucket := range buckets {
+		weight, ok := c.BucketPriorities[bucket]
+		if !ok {
+			weight = 1
+		}
+		if weight > priority {
+			priority = weight
+		}
+	}
+	return priority
+}
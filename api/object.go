@@ -213,7 +213,9 @@ type (
 
 	DownloadObjectOptions struct {
 		GetObjectOptions
-		Range *DownloadRange
+		BandwidthLimit uint64
+		MemoryClass    MemoryClass
+		Range          *DownloadRange
 	}
 
 	GetObjectOptions struct {
@@ -247,6 +249,9 @@ type (
 		ContentLength int64
 		MimeType      string
 		Metadata      ObjectUserMetadata
+
+		// BandwidthLimit limits the upload speed in bytes per second.
+		BandwidthLimit uint64
 	}
 
 	UploadMultipartUploadPartOptions struct {
@@ -255,6 +260,9 @@ type (
 		TotalShards      int
 		EncryptionOffset *int
 		ContentLength    int64
+
+		// BandwidthLimit limits the upload speed in bytes per second.
+		BandwidthLimit uint64
 	}
 )
 
@@ -271,6 +279,9 @@ func (opts UploadObjectOptions) ApplyValues(values url.Values) {
 	if opts.MimeType != "" {
 		values.Set("mimetype", opts.MimeType)
 	}
+	if opts.BandwidthLimit != 0 {
+		values.Set("bandwidthlimit", fmt.Sprint(opts.BandwidthLimit))
+	}
 }
 
 func (opts UploadObjectOptions) ApplyHeaders(h http.Header) {
@@ -292,10 +303,19 @@ func (opts UploadMultipartUploadPartOptions) Apply(values url.Values) {
 	if opts.ContractSet != "" {
 		values.Set("contractset", opts.ContractSet)
 	}
+	if opts.BandwidthLimit != 0 {
+		values.Set("bandwidthlimit", fmt.Sprint(opts.BandwidthLimit))
+	}
 }
 
 func (opts DownloadObjectOptions) ApplyValues(values url.Values) {
 	opts.GetObjectOptions.Apply(values)
+	if opts.BandwidthLimit != 0 {
+		values.Set("bandwidthlimit", fmt.Sprint(opts.BandwidthLimit))
+	}
+	if opts.MemoryClass != "" {
+		values.Set("memoryclass", string(opts.MemoryClass))
+	}
 }
 
 func (opts DownloadObjectOptions) ApplyHeaders(h http.Header) {
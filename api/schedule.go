@@ -0,0 +1,313 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ScheduleActivityScanning, ScheduleActivityMigrations,
+	// ScheduleActivityPruning and ScheduleActivityRenewals are the autopilot
+	// activities that can be restricted to maintenance windows.
+	ScheduleActivityScanning   = "scanning"
+	ScheduleActivityMigrations = "migrations"
+	ScheduleActivityPruning    = "pruning"
+	ScheduleActivityRenewals   = "renewals"
+
+	// maxMaintenanceWindowDuration is the maximum duration of a maintenance
+	// window.
+	maxMaintenanceWindowDuration = 7 * 24 * time.Hour
+
+	// maxCachedCronExprs is the maximum number of parsed cron expressions
+	// that are cached.
+	maxCachedCronExprs = 1000
+)
+
+var (
+	// cronExprCache caches parsed cron expressions by their string
+	// representation to avoid parsing them every time a window is checked.
+	cronExprCacheMu sync.Mutex
+	cronExprCache   = make(map[string]cronExpr)
+)
+
+type (
+	// AutopilotSchedule restricts the autopilot's activities to maintenance
+	// windows. Activities without any windows are performed whenever the
+	// autopilot runs. Outside of the migration windows, slabs with a health
+	// below the critical migration health and slabs that were queued manually
+	// are still migrated. Outside of the
+	// renewal windows, contracts that are past the first half of their renew
+	// window are still renewed.
+	AutopilotSchedule struct {
+		Timezone string `json:"timezone,omitempty"`
+
+		Scanning   []MaintenanceWindow `json:"scanning,omitempty"`
+		Migrations []MaintenanceWindow `json:"migrations,omitempty"`
+		Pruning    []MaintenanceWindow `json:"pruning,omitempty"`
+		Renewals   []MaintenanceWindow `json:"renewals,omitempty"`
+
+		CriticalMigrationHealth float64 `json:"criticalMigrationHealth"`
+	}
+
+	// MaintenanceWindow is a window that opens whenever the time matches the
+	// cron expression and stays open for the given duration. The cron
+	// expression consists of the five standard fields: minute, hour, day of
+	// the month, month and day of the week.
+	MaintenanceWindow struct {
+		Start    string     `json:"start"`
+		Duration DurationMS `json:"duration"`
+	}
+
+	// cronExpr is a parsed cron expression, every field is a bitset of the
+	// values that match.
+	cronExpr struct {
+		minute, hour, dom, month, dow uint64
+		domStar, dowStar              bool
+	}
+)
+
+// Validate returns an error if the schedule contains an invalid timezone,
+// window or critical migration health.
+func (s AutopilotSchedule) Validate() error {
+	if _, err := s.location(); err != nil {
+		return fmt.Errorf("invalid schedule timezone '%s': %w", s.Timezone, err)
+	} else if !(s.CriticalMigrationHealth >= 0 && s.CriticalMigrationHealth <= 1) {
+		return fmt.Errorf("invalid critical migration health %v, must be between 0 and 1", s.CriticalMigrationHealth)
+	}
+	for _, activity := range []string{ScheduleActivityScanning, ScheduleActivityMigrations, ScheduleActivityPruning, ScheduleActivityRenewals} {
+		for _, w := range s.windows(activity) {
+			if err := w.Validate(); err != nil {
+				return fmt.Errorf("invalid %s window: %w", activity, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows returns true if the activity is allowed to run at the given time.
+// A nil schedule allows every activity at any time.
+func (s *AutopilotSchedule) Allows(activity string, t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	windows := s.windows(activity)
+	if len(windows) == 0 {
+		return true
+	}
+	loc, err := s.location()
+	if err != nil {
+		return true
+	}
+	for _, w := range windows {
+		if w.Active(t.In(loc)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s AutopilotSchedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+func (s AutopilotSchedule) windows(activity string) []MaintenanceWindow {
+	switch activity {
+	case ScheduleActivityScanning:
+		return s.Scanning
+	case ScheduleActivityMigrations:
+		return s.Migrations
+	case ScheduleActivityPruning:
+		return s.Pruning
+	case ScheduleActivityRenewals:
+		return s.Renewals
+	default:
+		return nil
+	}
+}
+
+// Validate returns an error if the window's cron expression or duration is
+// invalid.
+func (w MaintenanceWindow) Validate() error {
+	if _, err := parseCronExpr(w.Start); err != nil {
+		return err
+	} else if time.Duration(w.Duration) < time.Minute {
+		return errors.New("duration must be at least one minute")
+	} else if time.Duration(w.Duration) > maxMaintenanceWindowDuration {
+		return fmt.Errorf("duration must not exceed %v", maxMaintenanceWindowDuration)
+	}
+	return nil
+}
+
+// Active returns true if the window is open at the given time, the cron
+// expression is evaluated in the location of t.
+func (w MaintenanceWindow) Active(t time.Time) bool {
+	expr, err := cachedCronExpr(w.Start)
+	if err != nil {
+		return false
+	}
+	duration := time.Duration(w.Duration)
+	if duration > maxMaintenanceWindowDuration {
+		duration = maxMaintenanceWindowDuration
+	}
+
+	// the window is open if it was opened less than 'duration' ago
+	now := t.Truncate(time.Minute)
+	start, ok := expr.prev(now, now.Add(-duration))
+	return ok && now.Sub(start) < duration
+}
+
+// cachedCronExpr returns the parsed cron expression, parsing it only if it
+// wasn't parsed before.
+func cachedCronExpr(s string) (cronExpr, error) {
+	cronExprCacheMu.Lock()
+	defer cronExprCacheMu.Unlock()
+	if expr, ok := cronExprCache[s]; ok {
+		return expr, nil
+	}
+	expr, err := parseCronExpr(s)
+	if err != nil {
+		return cronExpr{}, err
+	}
+	if len(cronExprCache) >= maxCachedCronExprs {
+		cronExprCache = make(map[string]cronExpr)
+	}
+	cronExprCache[s] = expr
+	return expr, nil
+}
+
+func parseCronExpr(s string) (expr cronExpr, err error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return cronExpr{}, fmt.Errorf("invalid cron expression '%s': expected 5 fields, got %d", s, len(fields))
+	}
+	for _, f := range []struct {
+		field    string
+		min, max uint64
+		bits     *uint64
+		star     *bool
+	}{
+		{fields[0], 0, 59, &expr.minute, nil},
+		{fields[1], 0, 23, &expr.hour, nil},
+		{fields[2], 1, 31, &expr.dom, &expr.domStar},
+		{fields[3], 1, 12, &expr.month, nil},
+		{fields[4], 0, 7, &expr.dow, &expr.dowStar},
+	} {
+		*f.bits, err = parseCronField(f.field, f.min, f.max)
+		if err != nil {
+			return cronExpr{}, fmt.Errorf("invalid cron expression '%s': %w", s, err)
+		}
+		if f.star != nil {
+			*f.star = strings.HasPrefix(f.field, "*")
+		}
+	}
+
+	// both 0 and 7 are sunday
+	if expr.dow&(1<<7) != 0 {
+		expr.dow |= 1
+	}
+	return expr, nil
+}
+
+func parseCronField(field string, min, max uint64) (bits uint64, _ error) {
+	for _, part := range strings.Split(field, ",") {
+		// parse the step
+		step := uint64(1)
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			step, err = strconv.ParseUint(part[i+1:], 10, 64)
+			if err != nil || step == 0 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+			part = part[:i]
+		}
+
+		// parse the range
+		start, end := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			start, err = strconv.ParseUint(bounds[0], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value '%s'", bounds[0])
+			}
+			end = start
+			if len(bounds) == 2 {
+				end, err = strconv.ParseUint(bounds[1], 10, 64)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value '%s'", bounds[1])
+				}
+			} else if step > 1 {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("value '%s' out of range [%d-%d]", part, min, max)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// matchesDay returns true if the expression matches the month and day of t.
+func (e cronExpr) matchesDay(t time.Time) bool {
+	if e.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	// if both the day of the month and the day of the week are restricted,
+	// either of them has to match
+	domMatch := e.dom&(1<<uint(t.Day())) != 0
+	dowMatch := e.dow&(1<<uint(t.Weekday())) != 0
+	if !e.domStar && !e.dowStar {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// prev returns the most recent time at or before t, which is expected to be
+// truncated to the minute, that matches the expression. Times before 'limit'
+// are not considered, in which case false is returned.
+func (e cronExpr) prev(t, limit time.Time) (time.Time, bool) {
+	for day := t; !day.Before(limit); {
+		year, month, dom := day.Date()
+		if e.matchesDay(day) {
+			for hour := latestBit(e.hour, day.Hour()); hour >= 0; hour = latestBit(e.hour, hour-1) {
+				maxMinute := 59
+				if hour == day.Hour() {
+					maxMinute = day.Minute()
+				}
+				for minute := latestBit(e.minute, maxMinute); minute >= 0; minute = latestBit(e.minute, minute-1) {
+					// times that don't exist due to DST transitions are
+					// normalized and might end up after t
+					start := time.Date(year, month, dom, hour, minute, 0, 0, day.Location())
+					if !start.After(t) {
+						return start, !start.Before(limit)
+					}
+				}
+			}
+		}
+
+		// continue with the last minute of the previous day
+		day = time.Date(year, month, dom, 0, 0, 0, 0, day.Location()).Add(-time.Minute)
+	}
+	return time.Time{}, false
+}
+
+// latestBit returns the highest bit set in the bitset that is at most max, or
+// -1 if there is none.
+func latestBit(set uint64, max int) int {
+	if max < 0 {
+		return -1
+	}
+	return bits.Len64(set&(1<<uint(max+1)-1)) - 1
+}
@@ -10,11 +10,14 @@ import (
 )
 
 const (
-	SettingContractSet      = "contractset"
-	SettingGouging          = "gouging"
-	SettingRedundancy       = "redundancy"
-	SettingS3Authentication = "s3authentication"
-	SettingUploadPacking    = "uploadpacking"
+	SettingAutopilotPaused   = "autopilotpaused"
+	SettingContractSet       = "contractset"
+	SettingGouging           = "gouging"
+	SettingMigrationSpending = "migrationspending"
+	SettingRedundancy        = "redundancy"
+	SettingS3Authentication  = "s3authentication"
+	SettingUploadPacking     = "uploadpacking"
+	SettingWalletLabels      = "walletlabels"
 )
 
 const (
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"time"
@@ -10,7 +11,86 @@ import (
 	"go.sia.tech/core/types"
 )
 
+const (
+	// WalletExportFormatCSV and WalletExportFormatJSON are the formats
+	// supported by the /wallet/export endpoint.
+	WalletExportFormatCSV  = "csv"
+	WalletExportFormatJSON = "json"
+)
+
+const (
+	// WalletTxnTypeFormation is the type of transactions that form a
+	// contract.
+	WalletTxnTypeFormation = "formation"
+
+	// WalletTxnTypeRenewal is the type of transactions that renew a contract
+	// and extend its end height.
+	WalletTxnTypeRenewal = "renewal"
+
+	// WalletTxnTypeRefresh is the type of transactions that renew a contract
+	// without extending its end height, usually to add funds to it.
+	WalletTxnTypeRefresh = "refresh"
+
+	// WalletTxnTypeContract is the type of transactions that form or renew a
+	// contract the bus doesn't know about.
+	WalletTxnTypeContract = "contract"
+
+	// WalletTxnTypeSend is the type of transactions that send siacoins to an
+	// address outside of the wallet.
+	WalletTxnTypeSend = "send"
+
+	// WalletTxnTypeReceive is the type of transactions that only send
+	// siacoins to the wallet.
+	WalletTxnTypeReceive = "receive"
+
+	// WalletTxnTypeInternal is the type of transactions that only move
+	// siacoins between the wallet's outputs, e.g. redistributions.
+	WalletTxnTypeInternal = "internal"
+)
+
+var (
+	// ErrSignaturePending is returned when a transaction of a watch-only
+	// wallet was queued for signing by the external signer.
+	ErrSignaturePending = errors.New("waiting for transaction to be signed")
+
+	// ErrSigningBundleNotFound is returned when a signing bundle doesn't
+	// exist.
+	ErrSigningBundleNotFound = errors.New("signing bundle not found")
+)
+
 type (
+	// WalletAddress describes an address controlled by the wallet. Change
+	// addresses are used for the change outputs of the wallet's own
+	// transactions, Index is the derivation index of the address within its
+	// chain.
+	WalletAddress struct {
+		Address     types.Address  `json:"address"`
+		Index       uint64         `json:"index"`
+		Change      bool           `json:"change"`
+		Used        bool           `json:"used"`
+		Confirmed   types.Currency `json:"confirmed"`
+		Unconfirmed types.Currency `json:"unconfirmed"`
+	}
+
+	// WalletConsolidateRequest is the request type for the
+	// /wallet/consolidate endpoint.
+	WalletConsolidateRequest struct {
+		MaxInputs      int            `json:"maxInputs"`
+		MaxOutputValue types.Currency `json:"maxOutputValue"`
+	}
+
+	// WalletFragmentation describes how the wallet's funds are spread across
+	// its outputs. Uneconomical outputs are outputs that aren't worth the fee
+	// it costs to spend them at the recommended fee.
+	WalletFragmentation struct {
+		Outputs           int            `json:"outputs"`
+		Smallest          types.Currency `json:"smallest"`
+		Median            types.Currency `json:"median"`
+		Largest           types.Currency `json:"largest"`
+		Uneconomical      int            `json:"uneconomical"`
+		UneconomicalValue types.Currency `json:"uneconomicalValue"`
+	}
+
 	// WalletFundRequest is the request type for the /wallet/fund endpoint.
 	WalletFundRequest struct {
 		Transaction        types.Transaction `json:"transaction"`
@@ -75,6 +155,86 @@ type (
 		Spendable   types.Currency `json:"spendable"`
 		Confirmed   types.Currency `json:"confirmed"`
 		Unconfirmed types.Currency `json:"unconfirmed"`
+
+		Fragmentation WalletFragmentation `json:"fragmentation"`
+	}
+
+	// WalletSigningBundle is a transaction of a watch-only wallet that is
+	// waiting to be signed by the external signer. The last transaction of
+	// the set is the one to sign, the inputs in ToSign have to be signed
+	// using the given covered fields and the consensus state at the given
+	// height.
+	WalletSigningBundle struct {
+		ID             types.Hash256       `json:"id"`
+		Type           string              `json:"type"`
+		Height         uint64              `json:"height"`
+		TransactionSet []types.Transaction `json:"transactionSet"`
+		ToSign         []types.Hash256     `json:"toSign"`
+		CoveredFields  types.CoveredFields `json:"coveredFields"`
+		Signed         bool                `json:"signed"`
+		CreatedAt      TimeRFC3339         `json:"createdAt"`
+	}
+
+	// WalletSignedBundle contains the signatures the external signer created
+	// for a signing bundle.
+	WalletSignedBundle struct {
+		ID         types.Hash256                `json:"id"`
+		Signatures []types.TransactionSignature `json:"signatures"`
+	}
+
+	// WalletTransaction is a wallet transaction along with its
+	// classification and label. Amounts are denominated in hastings, the fee
+	// is only set if the wallet paid it.
+	WalletTransaction struct {
+		ID        types.TransactionID `json:"id"`
+		Index     types.ChainIndex    `json:"index"`
+		Timestamp time.Time           `json:"timestamp"`
+		Type      string              `json:"type"`
+		Label     string              `json:"label,omitempty"`
+		Inflow    types.Currency      `json:"inflow"`
+		Outflow   types.Currency      `json:"outflow"`
+		Fee       types.Currency      `json:"fee"`
+
+		// contract transactions only
+		ContractID  types.FileContractID `json:"contractID,omitempty"`
+		HostKey     types.PublicKey      `json:"hostKey,omitempty"`
+		RenewedFrom types.FileContractID `json:"renewedFrom,omitempty"`
+	}
+
+	// WalletLabelRequest is the request type for the /wallet/labels/:id
+	// endpoint. An empty label removes the label.
+	WalletLabelRequest struct {
+		Label string `json:"label"`
+	}
+
+	// WalletSendRequest is the request type for the /wallet/send endpoint. If
+	// no fee per byte is specified the recommended fee of the transaction pool
+	// is used. Inputs are the ids of the outputs to spend, if empty the wallet
+	// selects the inputs itself. If DryRun is set the transaction is built and
+	// signed but not broadcast.
+	WalletSendRequest struct {
+		Address            types.Address   `json:"address"`
+		Amount             types.Currency  `json:"amount"`
+		FeePerByte         types.Currency  `json:"feePerByte,omitempty"`
+		Inputs             []types.Hash256 `json:"inputs,omitempty"`
+		SubtractMinerFee   bool            `json:"subtractMinerFee"`
+		UseUnconfirmedTxns bool            `json:"useUnconfirmedTxns"`
+		DryRun             bool            `json:"dryRun"`
+	}
+
+	// WalletSendResponse is the response type for the /wallet/send endpoint.
+	WalletSendResponse struct {
+		ID          types.TransactionID `json:"id"`
+		Transaction types.Transaction   `json:"transaction"`
+		DependsOn   []types.Transaction `json:"dependsOn"`
+		Amount      types.Currency      `json:"amount"`
+		MinerFee    types.Currency      `json:"minerFee"`
+		FeePerByte  types.Currency      `json:"feePerByte"`
+		Broadcast   bool                `json:"broadcast"`
+
+		// SigningBundle is set if the wallet is watch-only, the transaction
+		// is broadcast once the bundle was signed.
+		SigningBundle *types.Hash256 `json:"signingBundle,omitempty"`
 	}
 
 	// WalletSignRequest is the request type for the /wallet/sign endpoint.
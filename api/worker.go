@@ -27,12 +27,40 @@ var (
 	// be scanned since it is on a private network.
 	ErrHostOnPrivateNetwork = errors.New("host is on a private network")
 
+	// ErrInvalidMemoryClass is returned by the worker API when an unknown
+	// memory class is specified.
+	ErrInvalidMemoryClass = errors.New("invalid memory class")
+
 	// ErrMultiRangeNotSupported is returned by the worker API when a request
 	// tries to download multiple ranges at once.
 	ErrMultiRangeNotSupported = errors.New("multipart ranges are not supported")
 )
 
+const (
+	// MemoryClassInteractive is the memory class used by user-facing uploads
+	// and downloads.
+	MemoryClassInteractive MemoryClass = "interactive"
+
+	// MemoryClassBackground is the memory class used by background work such
+	// as uploading packed slabs and batch downloads.
+	MemoryClassBackground MemoryClass = "background"
+
+	// MemoryClassMigration is the memory class used by slab migrations.
+	MemoryClassMigration MemoryClass = "migration"
+)
+
+// MemoryClasses contains all memory classes in order of priority.
+var MemoryClasses = []MemoryClass{
+	MemoryClassInteractive,
+	MemoryClassBackground,
+	MemoryClassMigration,
+}
+
 type (
+	// MemoryClass is the priority class memory is acquired with in the
+	// worker's memory manager.
+	MemoryClass string
+
 	// AccountsLockHandlerRequest is the request type for the /accounts/:id/lock
 	// endpoint.
 	AccountsLockHandlerRequest struct {
@@ -54,6 +82,15 @@ type (
 		LockID uint64 `json:"lockID"`
 	}
 
+	// BandwidthLimits contains the worker's bandwidth limits in bytes per
+	// second, a limit of 0 means the bandwidth is unlimited.
+	BandwidthLimits struct {
+		Download    uint64 `json:"download"`
+		Upload      uint64 `json:"upload"`
+		Migration   uint64 `json:"migration"`
+		PackedSlabs uint64 `json:"packedSlabs"`
+	}
+
 	// ContractsResponse is the response type for the /rhp/contracts endpoint.
 	ContractsResponse struct {
 		Contracts []Contract                 `json:"contracts"`
@@ -69,15 +106,24 @@ type (
 	}
 
 	MemoryStatus struct {
-		Available uint64 `json:"available"`
-		Total     uint64 `json:"total"`
+		Available uint64                            `json:"available"`
+		Total     uint64                            `json:"total"`
+		Classes   map[MemoryClass]MemoryClassStatus `json:"classes,omitempty"`
+	}
+
+	// MemoryClassStatus contains the memory reserved for and currently used
+	// by a memory class.
+	MemoryClassStatus struct {
+		Reserved uint64 `json:"reserved"`
+		Used     uint64 `json:"used"`
 	}
 
 	// MigrateSlabResponse is the response type for the /slab/migrate endpoint.
 	MigrateSlabResponse struct {
-		NumShardsMigrated int    `json:"numShardsMigrated"`
-		SurchargeApplied  bool   `json:"surchargeApplied,omitempty"`
-		Error             string `json:"error,omitempty"`
+		NumShardsMigrated int            `json:"numShardsMigrated"`
+		SurchargeApplied  bool           `json:"surchargeApplied,omitempty"`
+		Cost              types.Currency `json:"cost"`
+		Error             string         `json:"error,omitempty"`
 	}
 
 	// RHPFormRequest is the request type for the /rhp/form endpoint.
@@ -209,6 +255,29 @@ type (
 		AvgSectorUploadSpeedMBPS float64         `json:"avgSectorUploadSpeedMbps"`
 	}
 
+	// HostStatsResponse is the response type for the /stats/hosts endpoint.
+	HostStatsResponse struct {
+		Hosts []HostStats `json:"hosts"`
+	}
+	HostStats struct {
+		HostKey   types.PublicKey    `json:"hostKey"`
+		Downloads *HostTransferStats `json:"downloads,omitempty"`
+		Uploads   *HostTransferStats `json:"uploads,omitempty"`
+	}
+	HostTransferStats struct {
+		AvgSpeedMBPS        float64     `json:"avgSpeedMbps"`
+		ConsecutiveFailures uint64      `json:"consecutiveFailures"`
+		Healthy             bool        `json:"healthy"`
+		LastError           string      `json:"lastError,omitempty"`
+		LastErrorTime       TimeRFC3339 `json:"lastErrorTime"`
+		LatencyP50MS        float64     `json:"latencyP50Ms"`
+		LatencyP90MS        float64     `json:"latencyP90Ms"`
+		LatencyP99MS        float64     `json:"latencyP99Ms"`
+		NumFailures         uint64      `json:"numFailures"`
+		NumSuccesses        uint64      `json:"numSuccesses"`
+		QueueDepth          uint64      `json:"queueDepth"`
+	}
+
 	// WorkerStateResponse is the response type for the /worker/state endpoint.
 	WorkerStateResponse struct {
 		ID        string      `json:"id"`
@@ -240,6 +309,17 @@ type DownloadRange struct {
 	Length int64
 }
 
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *MemoryClass) UnmarshalText(b []byte) error {
+	for _, class := range MemoryClasses {
+		if string(b) == string(class) {
+			*c = class
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidMemoryClass, string(b))
+}
+
 func (r *DownloadRange) ContentRange(size int64) *ContentRange {
 	return &ContentRange{
 		Offset: r.Offset,
@@ -91,6 +91,11 @@ func (a *accounts) refillWorkersAccountsLoop(ctx context.Context) {
 		case <-ticker.C:
 		}
 
+		if a.ap.isPaused(api.AutopilotSubsystemAccountRefills) {
+			a.l.Debug("account refills skipped, account refills are paused")
+			continue
+		}
+
 		a.w.withWorker(func(w Worker) {
 			a.refillWorkerAccounts(ctx, w)
 		})
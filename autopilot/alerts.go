@@ -13,10 +13,11 @@ import (
 )
 
 var (
-	alertAccountRefillID = alerts.RandomAlertID() // constant until restarted
-	alertLowBalanceID    = alerts.RandomAlertID() // constant until restarted
-	alertMigrationID     = alerts.RandomAlertID() // constant until restarted
-	alertPruningID       = alerts.RandomAlertID() // constant until restarted
+	alertAccountRefillID   = alerts.RandomAlertID() // constant until restarted
+	alertLowBalanceID      = alerts.RandomAlertID() // constant until restarted
+	alertMigrationID       = alerts.RandomAlertID() // constant until restarted
+	alertMigrationBudgetID = alerts.RandomAlertID() // constant until restarted
+	alertPruningID         = alerts.RandomAlertID() // constant until restarted
 )
 
 func (ap *Autopilot) RegisterAlert(ctx context.Context, a alerts.Alert) {
@@ -104,6 +105,27 @@ func newOngoingMigrationsAlert(n int, estimate time.Duration) alerts.Alert {
 	}
 }
 
+func newMigrationBudgetAlert(spending api.MigrationSpending, budget types.Currency, threshold float64) alerts.Alert {
+	severity := alerts.SeverityWarning
+	message := fmt.Sprintf("Migrations spent %.0f%% of the budget", threshold*100)
+	if threshold >= 1 {
+		severity = alerts.SeverityCritical
+		message = "Migration budget exhausted, only critical slabs are migrated"
+	}
+
+	return alerts.Alert{
+		ID:       alertMigrationBudgetID,
+		Severity: severity,
+		Message:  message,
+		Data: map[string]interface{}{
+			"budget": budget,
+			"period": spending.Period,
+			"spent":  spending.Spent,
+		},
+		Timestamp: time.Now(),
+	}
+}
+
 func newCriticalMigrationSucceededAlert(slabKey object.EncryptionKey) alerts.Alert {
 	return alerts.Alert{
 		ID:       alerts.IDForSlab(alertMigrationID, slabKey),
@@ -176,3 +198,30 @@ func newRefreshHealthFailedAlert(err error) alerts.Alert {
 		Timestamp: time.Now(),
 	}
 }
+
+func newGougingSettingsUpdatedAlert(old, new api.GougingSettings, percentile float64, hosts int) alerts.Alert {
+	return alerts.Alert{
+		ID:       alerts.RandomAlertID(),
+		Severity: alerts.SeverityInfo,
+		Message:  "Gouging settings were updated automatically",
+		Data: map[string]interface{}{
+			"percentile": percentile,
+			"hosts":      hosts,
+			"old": map[string]types.Currency{
+				"maxRPCPrice":      old.MaxRPCPrice,
+				"maxContractPrice": old.MaxContractPrice,
+				"maxDownloadPrice": old.MaxDownloadPrice,
+				"maxUploadPrice":   old.MaxUploadPrice,
+				"maxStoragePrice":  old.MaxStoragePrice,
+			},
+			"new": map[string]types.Currency{
+				"maxRPCPrice":      new.MaxRPCPrice,
+				"maxContractPrice": new.MaxContractPrice,
+				"maxDownloadPrice": new.MaxDownloadPrice,
+				"maxUploadPrice":   new.MaxUploadPrice,
+				"maxStoragePrice":  new.MaxStoragePrice,
+			},
+		},
+		Timestamp: time.Now(),
+	}
+}
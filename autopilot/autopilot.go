@@ -72,6 +72,7 @@ type Bus interface {
 	SlabsForMigration(ctx context.Context, healthCutoff float64, set string, limit int) ([]api.UnhealthySlab, error)
 
 	// settings
+	Setting(ctx context.Context, key string, value interface{}) error
 	UpdateSetting(ctx context.Context, key string, value interface{}) error
 	GougingSettings(ctx context.Context) (gs api.GougingSettings, err error)
 	RedundancySettings(ctx context.Context) (rs api.RedundancySettings, err error)
@@ -85,10 +86,12 @@ type Bus interface {
 
 	// wallet
 	Wallet(ctx context.Context) (api.WalletResponse, error)
+	WalletConsolidate(ctx context.Context, maxInputs int, maxValue types.Currency) (ids []types.TransactionID, err error)
 	WalletDiscard(ctx context.Context, txn types.Transaction) error
 	WalletOutputs(ctx context.Context) (resp []wallet.SiacoinElement, err error)
 	WalletPending(ctx context.Context) (resp []types.Transaction, err error)
 	WalletRedistribute(ctx context.Context, outputs int, amount types.Currency) (ids []types.TransactionID, err error)
+	WalletSigningBundles(ctx context.Context) ([]api.WalletSigningBundle, error)
 }
 
 type Autopilot struct {
@@ -119,11 +122,14 @@ type Autopilot struct {
 	pruningLastStart time.Time
 	pruningAlertIDs  map[types.FileContractID]types.Hash256
 
+	pauseMu sync.Mutex
+	paused  api.AutopilotPausedSubsystems
+
 	maintenanceTxnIDs []types.TransactionID
 }
 
 // New initializes an Autopilot.
-func New(id string, bus Bus, workers []Worker, logger *zap.Logger, heartbeat time.Duration, scannerScanInterval time.Duration, scannerBatchSize, scannerNumThreads uint64, migrationHealthCutoff float64, accountsRefillInterval time.Duration, revisionSubmissionBuffer, migratorParallelSlabsPerWorker uint64, revisionBroadcastInterval time.Duration) (*Autopilot, error) {
+func New(id string, bus Bus, workers []Worker, logger *zap.Logger, heartbeat time.Duration, scannerScanInterval time.Duration, scannerBatchSize, scannerNumThreads uint64, migrationHealthCutoff float64, accountsRefillInterval time.Duration, revisionSubmissionBuffer, migratorParallelSlabsPerWorker uint64, revisionBroadcastInterval time.Duration, geoIPDatabase string) (*Autopilot, error) {
 	shutdownCtx, shutdownCtxCancel := context.WithCancel(context.Background())
 
 	ap := &Autopilot{
@@ -152,8 +158,17 @@ func New(id string, bus Bus, workers []Worker, logger *zap.Logger, heartbeat tim
 		return nil, err
 	}
 
+	// load the GeoIP database used to enforce host diversity
+	var geoIP *contractor.GeoIPDatabase
+	if geoIPDatabase != "" {
+		geoIP, err = contractor.LoadGeoIPDatabase(geoIPDatabase)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	ap.s = scanner
-	ap.c = contractor.New(bus, bus, ap.logger, revisionSubmissionBuffer, revisionBroadcastInterval)
+	ap.c = contractor.New(bus, bus, ap.logger, revisionSubmissionBuffer, revisionBroadcastInterval, geoIP)
 	ap.m = newMigrator(ap, migrationHealthCutoff, migratorParallelSlabsPerWorker)
 	ap.a = newAccounts(ap, ap.bus, ap.bus, ap.workers, ap.logger, accountsRefillInterval)
 
@@ -167,13 +182,22 @@ func (ap *Autopilot) Config(ctx context.Context) (api.Autopilot, error) {
 // Handler returns an HTTP handler that serves the autopilot api.
 func (ap *Autopilot) Handler() http.Handler {
 	return jape.Mux(map[string]jape.Handler{
-		"GET    /config":        ap.configHandlerGET,
-		"PUT    /config":        ap.configHandlerPUT,
-		"POST   /config":        ap.configHandlerPOST,
-		"POST   /hosts":         ap.hostsHandlerPOST,
-		"GET    /host/:hostKey": ap.hostHandlerGET,
-		"GET    /state":         ap.stateHandlerGET,
-		"POST   /trigger":       ap.triggerHandlerPOST,
+		"GET    /config":                ap.configHandlerGET,
+		"PUT    /config":                ap.configHandlerPUT,
+		"POST   /config":                ap.configHandlerPOST,
+		"POST   /hosts":                 ap.hostsHandlerPOST,
+		"GET    /forecast":              ap.forecastHandlerGET,
+		"GET    /host/:hostKey":         ap.hostHandlerGET,
+		"GET    /maintenance/plan":      ap.maintenancePlanHandlerGET,
+		"GET    /migrations/budget":     ap.migrationBudgetHandlerGET,
+		"GET    /migrations/history":    ap.migrationHistoryHandlerGET,
+		"GET    /migrations/queue":      ap.migrationQueueHandlerGET,
+		"POST   /migrations/queue":      ap.migrationQueueHandlerPOST,
+		"DELETE /migrations/queue/:key": ap.migrationQueueKeyHandlerDELETE,
+		"POST   /pause/:subsystem":      ap.pauseHandlerPOST,
+		"POST   /resume/:subsystem":     ap.resumeHandlerPOST,
+		"GET    /state":                 ap.stateHandlerGET,
+		"POST   /trigger":               ap.triggerHandlerPOST,
 	})
 }
 
@@ -214,6 +238,17 @@ func (ap *Autopilot) configHandlerPOST(jc jape.Context) {
 		jc.Error(err, http.StatusInternalServerError)
 		return
 	}
+
+	// preview how the config alters the selected hosts compared to the
+	// current config
+	autopilot, err := ap.Config(ctx)
+	if err != nil && !utils.IsErr(err, api.ErrAutopilotNotFound) {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	} else if err == nil {
+		sel := contractor.EvaluateHostSelection(autopilot.Config, reqCfg, cs, fee, rs, gs, hosts)
+		res.HostSelection = &sel
+	}
 	jc.Encode(res)
 }
 
@@ -253,9 +288,23 @@ func (ap *Autopilot) Run() error {
 		ap.workers.withWorker(func(w Worker) {
 			defer ap.logger.Info("autopilot iteration ended")
 
-			// initiate a host scan - no need to be synced or configured for scanning
+			// refresh the paused subsystems, on failure we continue using the
+			// subsystems that were paused before
+			if err := ap.loadPausedSubsystems(ap.shutdownCtx); err != nil {
+				ap.logger.Errorf("failed to load paused subsystems, err: %v", err)
+			}
+
+			// initiate a host scan - no need to be synced or configured for
+			// scanning, regular scans are only performed within the scanning
+			// windows
 			ap.s.tryUpdateTimeout()
-			ap.s.tryPerformHostScan(ap.shutdownCtx, w, forceScan)
+			if ap.isPaused(api.AutopilotSubsystemScanner) {
+				ap.logger.Info("host scan skipped, scanner is paused")
+			} else if forceScan || ap.isScheduled(ap.shutdownCtx, api.ScheduleActivityScanning) {
+				ap.s.tryPerformHostScan(ap.shutdownCtx, w, forceScan)
+			} else {
+				ap.logger.Info("host scan deferred until the next scanning window")
+			}
 
 			// reset forceScan
 			forceScan = false
@@ -269,7 +318,7 @@ func (ap *Autopilot) Run() error {
 				ap.logger.Info("autopilot stopped before consensus was synced")
 				return
 			} else if blocked {
-				if scanning, _ := ap.s.Status(); !scanning {
+				if scanning, _ := ap.s.Status(); !scanning && !ap.isPaused(api.AutopilotSubsystemScanner) {
 					ap.s.tryPerformHostScan(ap.shutdownCtx, w, true)
 				}
 			}
@@ -294,6 +343,11 @@ func (ap *Autopilot) Run() error {
 			// prune hosts that have been offline for too long
 			ap.s.PruneHosts(ap.shutdownCtx, autopilot.Config.Hosts)
 
+			// update the gouging settings using the prices of the network
+			if err := ap.updateGougingSettings(ap.shutdownCtx, autopilot.Config); err != nil {
+				ap.logger.Errorf("failed to update gouging settings, err: %v", err)
+			}
+
 			// Log worker id chosen for this maintenance iteration.
 			workerID, err := w.ID(ap.shutdownCtx)
 			if err != nil {
@@ -316,13 +370,18 @@ func (ap *Autopilot) Run() error {
 			}
 
 			// perform maintenance
-			setChanged, err := ap.c.PerformContractMaintenance(ap.shutdownCtx, w, state)
-			if err != nil && utils.IsErr(err, context.Canceled) {
-				return
-			} else if err != nil {
-				ap.logger.Errorf("contract maintenance failed, err: %v", err)
+			var setChanged, maintenanceSuccess bool
+			if ap.isPaused(api.AutopilotSubsystemContractor) {
+				ap.logger.Info("contract maintenance skipped, contractor is paused")
+			} else {
+				setChanged, err = ap.c.PerformContractMaintenance(ap.shutdownCtx, w, state)
+				if err != nil && utils.IsErr(err, context.Canceled) {
+					return
+				} else if err != nil {
+					ap.logger.Errorf("contract maintenance failed, err: %v", err)
+				}
+				maintenanceSuccess = err == nil
 			}
-			maintenanceSuccess := err == nil
 
 			// upon success, notify the migrator. The health of slabs might have
 			// changed.
@@ -339,13 +398,21 @@ func (ap *Autopilot) Run() error {
 			}
 
 			// migration
-			ap.m.tryPerformMigrations(ap.workers)
+			if ap.isPaused(api.AutopilotSubsystemMigrator) {
+				ap.logger.Info("migrations skipped, migrator is paused")
+			} else {
+				ap.m.tryPerformMigrations(ap.workers)
+			}
 
 			// pruning
-			if autopilot.Config.Contracts.Prune {
-				ap.tryPerformPruning(ap.workers)
-			} else {
+			if !autopilot.Config.Contracts.Prune {
 				ap.logger.Info("pruning disabled")
+			} else if ap.isPaused(api.AutopilotSubsystemPruning) {
+				ap.logger.Info("pruning skipped, pruning is paused")
+			} else if !autopilot.Config.Schedule.Allows(api.ScheduleActivityPruning, time.Now()) {
+				ap.logger.Info("pruning deferred until the next pruning window")
+			} else {
+				ap.tryPerformPruning(ap.workers)
 			}
 		})
 
@@ -638,21 +705,54 @@ func (ap *Autopilot) performWalletMaintenance(ctx context.Context) error {
 		}
 	}
 
-	wantedNumOutputs := 10
+	// pending maintenance transactions of a watch-only wallet that still need
+	// to be signed - nothing to do
+	bundles, err := b.WalletSigningBundles(ctx)
+	if err != nil {
+		return err
+	}
+	for _, bundle := range bundles {
+		if bundle.Type == api.WalletTxnTypeInternal && !bundle.Signed {
+			l.Debugf("wallet maintenance skipped, waiting for signatures of bundle %v", bundle.ID)
+			return nil
+		}
+	}
 
-	// enough outputs - nothing to do
+	wc := cfg.WalletMaintenance()
 	available, err := b.WalletOutputs(ctx)
 	if err != nil {
 		return err
 	}
-	if uint64(len(available)) >= uint64(wantedNumOutputs) {
-		l.Debugf("no wallet maintenance needed, plenty of outputs available (%v>=%v)", len(available), uint64(wantedNumOutputs))
+
+	// consolidate the small outputs
+	var small uint64
+	for _, sce := range available {
+		if sce.Value.Cmp(wc.ConsolidationThreshold) < 0 {
+			small++
+		}
+	}
+	if ids, err := ap.consolidateWallet(ctx, wc, small); err != nil {
+		l.Warnf("wallet consolidation failed, err: %v", err)
+	} else if len(ids) > 0 {
+		l.Debugf("wallet maintenance succeeded, consolidated %v small outputs, txns %v", small, ids)
+		ap.maintenanceTxnIDs = ids
+		return nil
+	}
+
+	// enough outputs - nothing to do
+	wantedNumOutputs := int(wc.Outputs)
+	usable := len(available) - int(small)
+	if usable >= wantedNumOutputs {
+		l.Debugf("no wallet maintenance needed, plenty of outputs available (%v>=%v)", usable, wantedNumOutputs)
 		return nil
 	}
-	wantedNumOutputs -= len(available)
+	wantedNumOutputs -= usable
 
 	// figure out the amount per output
-	amount := cfg.Contracts.Allowance.Div64(uint64(wantedNumOutputs))
+	amount := wc.OutputValue
+	if amount.IsZero() {
+		amount = cfg.Contracts.Allowance.Div64(uint64(wantedNumOutputs))
+	}
 
 	// redistribute outputs
 	ids, err := b.WalletRedistribute(ctx, wantedNumOutputs, amount)
@@ -665,6 +765,24 @@ func (ap *Autopilot) performWalletMaintenance(ctx context.Context) error {
 	return nil
 }
 
+// consolidateWallet merges the wallet's small outputs if there are too many of
+// them and the fees are low enough.
+func (ap *Autopilot) consolidateWallet(ctx context.Context, wc api.WalletMaintenanceConfig, small uint64) ([]types.TransactionID, error) {
+	if wc.DisableConsolidation || small <= wc.MaxSmallOutputs {
+		return nil, nil
+	}
+
+	// only consolidate when fees are low
+	fee, err := ap.bus.RecommendedFee(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recommended fee: %w", err)
+	} else if fee.Cmp(wc.MaxConsolidationFee) > 0 {
+		ap.logger.Debugf("wallet consolidation skipped, recommended fee %v exceeds %v", fee, wc.MaxConsolidationFee)
+		return nil, nil
+	}
+	return ap.bus.WalletConsolidate(ctx, int(wc.MaxConsolidationInputs), wc.ConsolidationThreshold)
+}
+
 func (ap *Autopilot) configHandlerGET(jc jape.Context) {
 	autopilot, err := ap.bus.Autopilot(jc.Request.Context(), ap.id)
 	if utils.IsErr(err, api.ErrAutopilotNotFound) {
@@ -737,6 +855,7 @@ func (ap *Autopilot) hostHandlerGET(jc jape.Context) {
 
 	check, ok := hi.Checks[ap.id]
 	if ok {
+		weights := state.AP.Config.Hosts.Weights()
 		jc.Encode(api.HostResponse{
 			Host: hi,
 			Checks: &api.HostChecks{
@@ -744,14 +863,80 @@ func (ap *Autopilot) hostHandlerGET(jc jape.Context) {
 				GougingBreakdown: check.Gouging,
 				Score:            check.Score.Score(),
 				ScoreBreakdown:   check.Score,
+				ScoreWeights:     &weights,
 				Usable:           check.Usability.IsUsable(),
 				UnusableReasons:  check.Usability.UnusableReasons(),
 			},
+			Location: ap.c.HostLocation(jc.Request.Context(), hi, true),
 		})
 		return
 	}
 
-	jc.Encode(api.HostResponse{Host: hi})
+	jc.Encode(api.HostResponse{
+		Host:     hi,
+		Location: ap.c.HostLocation(jc.Request.Context(), hi, true),
+	})
+}
+
+func (ap *Autopilot) forecastHandlerGET(jc jape.Context) {
+	ctx := jc.Request.Context()
+
+	autopilot, err := ap.Config(ctx)
+	if utils.IsErr(err, api.ErrAutopilotNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to fetch autopilot", err) != nil {
+		return
+	}
+	rs, err := ap.bus.RedundancySettings(ctx)
+	if jc.Check("failed to fetch redundancy settings", err) != nil {
+		return
+	}
+	cs, err := ap.bus.ConsensusState(ctx)
+	if jc.Check("failed to fetch consensus state", err) != nil {
+		return
+	}
+	contracts, err := ap.bus.Contracts(ctx, api.ContractsOpts{})
+	if jc.Check("failed to fetch contracts", err) != nil {
+		return
+	}
+	hosts, err := ap.bus.SearchHosts(ctx, api.SearchHostOptions{Limit: -1, FilterMode: api.HostFilterModeAllowed})
+	if jc.Check("failed to fetch hosts", err) != nil {
+		return
+	}
+	wallet, err := ap.bus.Wallet(ctx)
+	if jc.Check("failed to fetch wallet", err) != nil {
+		return
+	}
+	forecast, err := contractor.Forecast(ctx, ap.bus, autopilot, rs, contracts, hosts, wallet, cs.BlockHeight)
+	if jc.Check("failed to compute forecast", err) != nil {
+		return
+	}
+	jc.Encode(forecast)
+}
+
+func (ap *Autopilot) maintenancePlanHandlerGET(jc jape.Context) {
+	ctx := jc.Request.Context()
+
+	state, err := ap.buildState(ctx)
+	if utils.IsErr(err, api.ErrAutopilotNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to build state", err) != nil {
+		return
+	}
+
+	var plan api.ContractMaintenancePlan
+	ap.workers.withWorker(func(w Worker) {
+		plan, err = ap.c.PlanContractMaintenance(ctx, w, state)
+	})
+	if errors.Is(err, contractor.ErrMaintenanceInProgress) {
+		jc.Error(err, http.StatusConflict)
+		return
+	} else if jc.Check("failed to plan contract maintenance", err) != nil {
+		return
+	}
+	jc.Encode(plan)
 }
 
 func (ap *Autopilot) hostsHandlerPOST(jc jape.Context) {
@@ -797,6 +982,9 @@ func (ap *Autopilot) hostsHandlerPOST(jc jape.Context) {
 		} else {
 			resps[i] = api.HostResponse{Host: host}
 		}
+
+		// only use cached locations, resolving every host would be too slow
+		resps[i].Location = ap.c.HostLocation(jc.Request.Context(), host, false)
 	}
 	jc.Encode(resps)
 }
@@ -804,6 +992,7 @@ func (ap *Autopilot) hostsHandlerPOST(jc jape.Context) {
 func (ap *Autopilot) stateHandlerGET(jc jape.Context) {
 	ap.mu.Lock()
 	pruning, pLastStart := ap.pruning, ap.pruningLastStart // TODO: move to a 'pruner' type
+	paused := ap.paused
 	ap.mu.Unlock()
 	migrating, mLastStart := ap.m.Status()
 	scanning, sLastStart := ap.s.Status()
@@ -822,6 +1011,8 @@ func (ap *Autopilot) stateHandlerGET(jc jape.Context) {
 		Scanning:           scanning,
 		ScanningLastStart:  api.TimeRFC3339(sLastStart),
 		UptimeMS:           api.DurationMS(ap.Uptime()),
+		Paused:             paused,
+		RenewalPlan:        ap.c.RenewalPlan(),
 
 		StartTime: api.TimeRFC3339(ap.StartTime()),
 		BuildState: api.BuildState{
@@ -878,6 +1069,12 @@ func (ap *Autopilot) buildState(ctx context.Context) (*contractor.MaintenanceSta
 		ap.logger.Warn("contract formations skipped, wallet is empty")
 	}
 
+	// renewals are only performed within the renewal windows
+	skipContractRenewals := !autopilot.Config.Schedule.Allows(api.ScheduleActivityRenewals, time.Now())
+	if skipContractRenewals {
+		ap.logger.Info("contract renewals deferred until the next renewal window")
+	}
+
 	// update current period if necessary
 	if cs.Synced {
 		if autopilot.CurrentPeriod == 0 {
@@ -906,6 +1103,8 @@ func (ap *Autopilot) buildState(ctx context.Context) (*contractor.MaintenanceSta
 		Address:                address,
 		Fee:                    fee,
 		SkipContractFormations: skipContractFormations,
+		SkipContractRenewals:   skipContractRenewals,
+		WalletBalance:          wi.Spendable,
 	}, nil
 }
 
@@ -1,6 +1,13 @@
 package autopilot
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+)
 
 func TestComputeNextPeriod(t *testing.T) {
 	currentPeriod := uint64(100)
@@ -33,3 +40,60 @@ func TestComputeNextPeriod(t *testing.T) {
 		}
 	}
 }
+
+// walletBusMock implements the parts of the bus used when consolidating the
+// wallet, calling any other method panics.
+type walletBusMock struct {
+	Bus
+
+	fee          types.Currency
+	consolidated int
+}
+
+func (b *walletBusMock) RecommendedFee(ctx context.Context) (types.Currency, error) {
+	return b.fee, nil
+}
+
+func (b *walletBusMock) WalletConsolidate(ctx context.Context, maxInputs int, maxValue types.Currency) ([]types.TransactionID, error) {
+	b.consolidated++
+	return []types.TransactionID{{1}}, nil
+}
+
+func TestConsolidateWallet(t *testing.T) {
+	b := &walletBusMock{}
+	ap := &Autopilot{bus: b, logger: zap.NewNop().Sugar()}
+	cfg := api.AutopilotConfig{Contracts: api.ContractsConfig{Allowance: types.Siacoins(1000)}}
+	wc := cfg.WalletMaintenance()
+
+	// assert the defaults cap the fee and leave the output value unset
+	if !wc.MaxConsolidationFee.Equals(api.DefaultMaxConsolidationFee) {
+		t.Fatal("unexpected max consolidation fee", wc.MaxConsolidationFee)
+	} else if !wc.OutputValue.IsZero() {
+		t.Fatal("unexpected output value", wc.OutputValue)
+	} else if !wc.ConsolidationThreshold.Equals(types.Siacoins(10)) {
+		t.Fatal("unexpected consolidation threshold", wc.ConsolidationThreshold)
+	}
+
+	// not enough small outputs
+	if ids, err := ap.consolidateWallet(context.Background(), wc, wc.MaxSmallOutputs); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 0 || b.consolidated != 0 {
+		t.Fatal("unexpected consolidation")
+	}
+
+	// fees are too high
+	b.fee = api.DefaultMaxConsolidationFee.Add(types.NewCurrency64(1))
+	if ids, err := ap.consolidateWallet(context.Background(), wc, wc.MaxSmallOutputs+1); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 0 || b.consolidated != 0 {
+		t.Fatal("unexpected consolidation")
+	}
+
+	// fees are low enough
+	b.fee = api.DefaultMaxConsolidationFee
+	if ids, err := ap.consolidateWallet(context.Background(), wc, wc.MaxSmallOutputs+1); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 1 || b.consolidated != 1 {
+		t.Fatal("expected consolidation")
+	}
+}
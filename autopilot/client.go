@@ -3,10 +3,12 @@ package autopilot
 import (
 	"context"
 	"fmt"
+	"net/url"
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/object"
 )
 
 // A Client provides methods for interacting with an autopilot.
@@ -33,6 +35,12 @@ func (c *Client) UpdateConfig(cfg api.AutopilotConfig) error {
 	return c.c.PUT("/config", cfg)
 }
 
+// Forecast returns the projected spending for the current and next period.
+func (c *Client) Forecast(ctx context.Context) (forecast api.SpendingForecast, err error) {
+	err = c.c.WithContext(ctx).GET("/forecast", &forecast)
+	return
+}
+
 // HostInfo returns information about the host with given host key.
 func (c *Client) HostInfo(hostKey types.PublicKey) (resp api.HostResponse, err error) {
 	err = c.c.GET(fmt.Sprintf("/host/%s", hostKey), &resp)
@@ -52,12 +60,68 @@ func (c *Client) HostInfos(ctx context.Context, filterMode, usabilityMode, addre
 	return
 }
 
+// MaintenancePlan returns what the next contract maintenance would do without
+// performing it.
+func (c *Client) MaintenancePlan(ctx context.Context) (plan api.ContractMaintenancePlan, err error) {
+	err = c.c.WithContext(ctx).GET("/maintenance/plan", &plan)
+	return
+}
+
 // State returns the current state of the autopilot.
 func (c *Client) State() (state api.AutopilotStateResponse, err error) {
 	err = c.c.GET("/state", &state)
 	return
 }
 
+// MigrationQueue returns the slabs that are queued for migration, starting at
+// the given offset, and the slabs that are being migrated. If objects is true
+// the objects referencing the slabs are looked up.
+func (c *Client) MigrationQueue(ctx context.Context, offset, limit int, objects bool) (resp api.MigrationQueueResponse, err error) {
+	values := url.Values{}
+	values.Set("offset", fmt.Sprint(offset))
+	values.Set("limit", fmt.Sprint(limit))
+	values.Set("objects", fmt.Sprint(objects))
+	err = c.c.WithContext(ctx).GET("/migrations/queue?"+values.Encode(), &resp)
+	return
+}
+
+// EnqueueMigration queues the slab for migration, it is migrated before any
+// slab that was queued by the autopilot.
+func (c *Client) EnqueueMigration(ctx context.Context, key object.EncryptionKey) (job api.MigrationJob, err error) {
+	err = c.c.WithContext(ctx).POST("/migrations/queue", api.MigrationEnqueueRequest{Key: key}, &job)
+	return
+}
+
+// CancelMigration removes the slab from the migration queue or interrupts its
+// migration.
+func (c *Client) CancelMigration(ctx context.Context, key object.EncryptionKey) error {
+	return c.c.WithContext(ctx).DELETE(fmt.Sprintf("/migrations/queue/%s", key))
+}
+
+// MigrationBudget returns the amount spent on migrations in the current
+// period and the configured budget.
+func (c *Client) MigrationBudget(ctx context.Context) (resp api.MigrationBudgetResponse, err error) {
+	err = c.c.WithContext(ctx).GET("/migrations/budget", &resp)
+	return
+}
+
+// MigrationHistory returns the outcome of the most recent migrations.
+func (c *Client) MigrationHistory(ctx context.Context, limit int) (resp api.MigrationHistoryResponse, err error) {
+	err = c.c.WithContext(ctx).GET(fmt.Sprintf("/migrations/history?limit=%d", limit), &resp)
+	return
+}
+
+// PauseSubsystem pauses the given subsystem of the autopilot until it is
+// resumed.
+func (c *Client) PauseSubsystem(ctx context.Context, subsystem string) error {
+	return c.c.WithContext(ctx).POST(fmt.Sprintf("/pause/%s", subsystem), nil, nil)
+}
+
+// ResumeSubsystem resumes the given subsystem of the autopilot.
+func (c *Client) ResumeSubsystem(ctx context.Context, subsystem string) error {
+	return c.c.WithContext(ctx).POST(fmt.Sprintf("/resume/%s", subsystem), nil, nil)
+}
+
 // Trigger triggers an iteration of the autopilot's main loop.
 func (c *Client) Trigger(forceScan bool) (_ bool, err error) {
 	var resp api.AutopilotTriggerResponse
@@ -121,9 +121,12 @@ func (ap *Autopilot) performContractPruning(wp *workerPool) {
 	// loop prunable contracts
 	var total uint64
 	for _, contract := range prunable {
-		// check if we're stopped
+		// check if we're stopped or paused
 		if ap.isStopped() {
 			break
+		} else if ap.isPaused(api.AutopilotSubsystemPruning) {
+			ap.logger.Info("pruning interrupted, pruning is paused")
+			break
 		}
 
 		// fetch host
@@ -113,6 +113,7 @@ type (
 		alerter  alerts.Alerter
 		bus      Bus
 		churn    *accumulatedChurn
+		locator  *hostLocator
 		resolver *ipResolver
 		logger   *zap.SugaredLogger
 
@@ -121,8 +122,12 @@ type (
 		revisionSubmissionBuffer  uint64
 
 		firstRefreshFailure map[types.FileContractID]time.Time
+		renewalPlan         *api.RenewalPlan
 
-		mu sync.Mutex
+		// maintenanceMu prevents a maintenance plan from being computed
+		// while contract maintenance is being performed
+		maintenanceMu sync.Mutex
+		mu            sync.Mutex
 
 		shutdownCtx       context.Context
 		shutdownCtxCancel context.CancelFunc
@@ -139,6 +144,10 @@ type (
 		priceTable  rhpv3.HostPriceTable
 		usable      bool
 		recoverable bool
+
+		// early is set for contracts that are renewed before they enter the
+		// renew window to stagger renewals
+		early bool
 	}
 
 	contractSetAdditions struct {
@@ -167,9 +176,34 @@ type (
 		to   api.ContractMetadata
 		ci   contractInfo
 	}
+
+	// maintenanceDecisions contains the contracts and hosts the contract
+	// maintenance works with and the decisions it made before forming,
+	// renewing or refreshing any contracts.
+	maintenanceDecisions struct {
+		currentSet     []api.ContractMetadata
+		isInCurrentSet map[types.FileContractID]struct{}
+		contracts      []api.Contract
+		contractData   map[types.FileContractID]uint64
+		usedHosts      map[types.PublicKey]struct{}
+
+		hosts         []api.Host
+		candidates    scoredHosts
+		unusableHosts unusableHostsBreakdown
+		checks        map[types.PublicKey]*api.HostCheck
+
+		updatedSet  []api.ContractMetadata
+		toArchive   map[types.FileContractID]string
+		toStopUsing map[types.FileContractID]string
+		toRefresh   []contractInfo
+		toRenew     []contractInfo
+		renewLimit  int
+		renewalPlan api.RenewalPlan
+		remaining   types.Currency
+	}
 )
 
-func New(bus Bus, alerter alerts.Alerter, logger *zap.SugaredLogger, revisionSubmissionBuffer uint64, revisionBroadcastInterval time.Duration) *Contractor {
+func New(bus Bus, alerter alerts.Alerter, logger *zap.SugaredLogger, revisionSubmissionBuffer uint64, revisionBroadcastInterval time.Duration, geoIP *GeoIPDatabase) *Contractor {
 	logger = logger.Named("contractor")
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Contractor{
@@ -184,6 +218,7 @@ func New(bus Bus, alerter alerts.Alerter, logger *zap.SugaredLogger, revisionSub
 
 		firstRefreshFailure: make(map[types.FileContractID]time.Time),
 
+		locator:  newHostLocator(geoIP, resolverLookupTimeout),
 		resolver: newIPResolver(ctx, resolverLookupTimeout, logger.Named("resolver")),
 
 		shutdownCtx:       ctx,
@@ -196,6 +231,23 @@ func (c *Contractor) Close() error {
 	return nil
 }
 
+// HostLocation returns the location of the host according to the GeoIP
+// database, if resolve is false only previously resolved locations are
+// returned. It returns nil if the location is unknown.
+func (c *Contractor) HostLocation(ctx context.Context, h api.Host, resolve bool) *api.HostLocation {
+	var loc api.HostLocation
+	var ok bool
+	if resolve {
+		loc, ok = c.locator.Locate(ctx, h)
+	} else {
+		loc, ok = c.locator.Cached(h)
+	}
+	if !ok {
+		return nil
+	}
+	return &loc
+}
+
 func canSkipContractMaintenance(ctx context.Context, cfg api.ContractsConfig) (string, bool) {
 	select {
 	case <-ctx.Done():
@@ -224,6 +276,8 @@ func canSkipContractMaintenance(ctx context.Context, cfg api.ContractsConfig) (s
 }
 
 func (c *Contractor) PerformContractMaintenance(ctx context.Context, w Worker, state *MaintenanceState) (bool, error) {
+	c.maintenanceMu.Lock()
+	defer c.maintenanceMu.Unlock()
 	return c.performContractMaintenance(newMaintenanceCtx(ctx, state), w)
 }
 
@@ -241,63 +295,24 @@ func (c *Contractor) performContractMaintenance(ctx *mCtx, w Worker) (bool, erro
 	}
 	c.logger.Info("performing contract maintenance")
 
-	// fetch current contract set
-	currentSet, err := c.bus.Contracts(ctx, api.ContractsOpts{ContractSet: ctx.ContractSet()})
-	if err != nil && !strings.Contains(err.Error(), api.ErrContractSetNotFound.Error()) {
-		return false, err
-	}
-	isInCurrentSet := make(map[types.FileContractID]struct{})
-	for _, c := range currentSet {
-		isInCurrentSet[c.ID] = struct{}{}
-	}
-	c.logger.Infof("contract set '%s' holds %d contracts", ctx.ContractSet(), len(currentSet))
-
-	// fetch all contracts from the worker.
-	start := time.Now()
-	resp, err := w.Contracts(ctx, timeoutHostRevision)
+	// decide what to do with the contracts
+	d, err := c.decideContractMaintenance(mCtx, w)
 	if err != nil {
 		return false, err
 	}
-	if resp.Errors != nil {
-		for pk, err := range resp.Errors {
-			c.logger.With("hostKey", pk).With("error", err).Warn("failed to fetch revision")
-		}
-	}
-	contracts := resp.Contracts
-	c.logger.Infof("fetched %d contracts from the worker, took %v", len(resp.Contracts), time.Since(start))
+	contractData := d.contractData
+	updatedSet := d.updatedSet
+	toStopUsing := d.toStopUsing
 
 	// prune contract refresh failure map
-	c.pruneContractRefreshFailures(contracts)
+	c.pruneContractRefreshFailures(d.contracts)
 
 	// run revision broadcast
-	c.runRevisionBroadcast(ctx, w, contracts, isInCurrentSet)
-
-	// sort contracts by their size
-	sort.Slice(contracts, func(i, j int) bool {
-		return contracts[i].FileSize() > contracts[j].FileSize()
-	})
-
-	// get used hosts
-	usedHosts := make(map[types.PublicKey]struct{})
-	for _, contract := range contracts {
-		usedHosts[contract.HostKey] = struct{}{}
-	}
-
-	// compile map of stored data per contract
-	contractData := make(map[types.FileContractID]uint64)
-	for _, c := range contracts {
-		contractData[c.ID] = c.FileSize()
-	}
-
-	// fetch all hosts
-	hosts, err := c.bus.SearchHosts(ctx, api.SearchHostOptions{Limit: -1, FilterMode: api.HostFilterModeAllowed})
-	if err != nil {
-		return false, err
-	}
+	c.runRevisionBroadcast(ctx, w, d.contracts, d.isInCurrentSet)
 
 	// check if any used hosts have lost data to warn the user
 	var toDismiss []types.Hash256
-	for _, h := range hosts {
+	for _, h := range d.hosts {
 		if registerLostSectorsAlert(h.Interactions.LostSectors*rhpv2.SectorSize, h.StoredData) {
 			c.alerter.RegisterAlert(ctx, newLostSectorsAlert(h.PublicKey, h.Settings.Version, h.Settings.Release, h.Interactions.LostSectors))
 		} else {
@@ -308,81 +323,32 @@ func (c *Contractor) performContractMaintenance(ctx *mCtx, w Worker) (bool, erro
 		c.alerter.DismissAlerts(ctx, toDismiss...)
 	}
 
-	// fetch candidate hosts
-	candidates, unusableHosts, err := c.candidateHosts(mCtx, hosts, usedHosts, minValidScore) // avoid 0 score hosts
-	if err != nil {
-		return false, err
-	}
-
-	// min score to pass checks
-	var minScore float64
-	if len(hosts) > 0 {
-		minScore = c.calculateMinScore(candidates, ctx.WantedContracts())
-	} else {
-		c.logger.Warn("could not calculate min score, no hosts found")
-	}
-
-	// run host checks
-	checks, err := c.runHostChecks(mCtx, hosts, minScore)
-	if err != nil {
-		return false, fmt.Errorf("failed to run host checks, err: %v", err)
-	}
-
-	// fetch consensus state
-	cs, err := c.bus.ConsensusState(ctx)
-	if err != nil {
-		return false, fmt.Errorf("failed to fetch consensus state, err: %v", err)
-	}
-
-	// run contract checks
-	updatedSet, toArchive, toStopUsing, toRefresh, toRenew := c.runContractChecks(mCtx, checks, contracts, isInCurrentSet, cs.BlockHeight)
-
 	// update host checks
-	for hk, check := range checks {
+	for hk, check := range d.checks {
 		if err := c.bus.UpdateHostCheck(ctx, ctx.ApID(), hk, *check); err != nil {
 			c.logger.Errorf("failed to update host check for host %v, err: %v", hk, err)
 		}
 	}
 
 	// archive contracts
-	if len(toArchive) > 0 {
-		c.logger.Infof("archiving %d contracts: %+v", len(toArchive), toArchive)
-		if err := c.bus.ArchiveContracts(ctx, toArchive); err != nil {
+	if len(d.toArchive) > 0 {
+		c.logger.Infof("archiving %d contracts: %+v", len(d.toArchive), d.toArchive)
+		if err := c.bus.ArchiveContracts(ctx, d.toArchive); err != nil {
 			c.logger.Errorf("failed to archive contracts, err: %v", err) // continue
 		}
 	}
 
-	// calculate remaining funds
-	remaining := c.remainingFunds(contracts, mCtx.state)
-
-	// calculate 'limit' amount of contracts we want to renew
-	var limit int
-	if len(toRenew) > 0 {
-		// when renewing, prioritise contracts that have already been in the set
-		// before and out of those prefer the largest ones.
-		sort.Slice(toRenew, func(i, j int) bool {
-			_, icsI := isInCurrentSet[toRenew[i].contract.ID]
-			_, icsJ := isInCurrentSet[toRenew[j].contract.ID]
-			if icsI && !icsJ {
-				return true
-			} else if !icsI && icsJ {
-				return false
-			}
-			return toRenew[i].contract.FileSize() > toRenew[j].contract.FileSize()
-		})
-		for len(updatedSet)+limit < int(ctx.WantedContracts()) && limit < len(toRenew) {
-			// as long as we're missing contracts, increase the renewal limit
-			limit++
-		}
-	}
+	// update the renewal plan
+	c.setRenewalPlan(d.renewalPlan)
 
 	// run renewals on contracts that are not in updatedSet yet. We only renew
 	// up to 'limit' of those to avoid having too many contracts in the updated
 	// set afterwards
+	remaining := d.remaining
 	var renewed []renewal
-	if limit > 0 {
+	if d.renewLimit > 0 {
 		var toKeep []api.ContractMetadata
-		renewed, toKeep = c.runContractRenewals(ctx, w, toRenew, &remaining, limit)
+		renewed, toKeep = c.runContractRenewals(ctx, w, d.toRenew, &remaining, d.renewLimit)
 		for _, ri := range renewed {
 			if ri.ci.usable || ri.ci.recoverable {
 				updatedSet = append(updatedSet, ri.to)
@@ -393,7 +359,7 @@ func (c *Contractor) performContractMaintenance(ctx *mCtx, w Worker) (bool, erro
 	}
 
 	// run contract refreshes
-	refreshed, err := c.runContractRefreshes(ctx, w, toRefresh, &remaining)
+	refreshed, err := c.runContractRefreshes(ctx, w, d.toRefresh, &remaining)
 	if err != nil {
 		c.logger.Errorf("failed to refresh contracts, err: %v", err) // continue
 	} else {
@@ -405,18 +371,14 @@ func (c *Contractor) performContractMaintenance(ctx *mCtx, w Worker) (bool, erro
 		}
 	}
 
-	// to avoid forming new contracts as soon as we dip below
-	// 'Contracts.Amount', we define a threshold but only if we have more
-	// contracts than 'Contracts.Amount' already
-	threshold := ctx.WantedContracts()
-	if uint64(len(contracts)) > ctx.WantedContracts() {
-		threshold = addLeeway(threshold, leewayPctRequiredContracts)
-	}
-
 	// check if we need to form contracts and add them to the contract set
 	var formed []api.ContractMetadata
-	if uint64(len(updatedSet)) < threshold && !ctx.state.SkipContractFormations {
-		formed, err = c.runContractFormations(ctx, w, candidates, usedHosts, unusableHosts, ctx.WantedContracts()-uint64(len(updatedSet)), &remaining)
+	if missing := d.missingContracts(mCtx, updatedSet); missing > 0 {
+		updatedHosts := make(map[types.PublicKey]struct{})
+		for _, contract := range updatedSet {
+			updatedHosts[contract.HostKey] = struct{}{}
+		}
+		formed, err = c.runContractFormations(ctx, w, d.hosts, d.candidates, d.usedHosts, updatedHosts, d.unusableHosts, missing, &remaining)
 		if err != nil {
 			c.logger.Errorf("failed to form contracts, err: %v", err) // continue
 		} else {
@@ -433,16 +395,7 @@ func (c *Contractor) performContractMaintenance(ctx *mCtx, w Worker) (bool, erro
 			c.logger.Errorf("contract %v not found in contractData", contract.ID)
 		}
 	}
-	if len(updatedSet) > int(ctx.WantedContracts()) {
-		// sort by contract size
-		sort.Slice(updatedSet, func(i, j int) bool {
-			return contractData[updatedSet[i].ID] > contractData[updatedSet[j].ID]
-		})
-		for _, contract := range updatedSet[ctx.WantedContracts():] {
-			toStopUsing[contract.ID] = "truncated"
-		}
-		updatedSet = updatedSet[:ctx.WantedContracts()]
-	}
+	updatedSet = truncateContractSet(mCtx, updatedSet, contractData, toStopUsing)
 
 	// convert to set of file contract ids
 	var newSet []types.FileContractID
@@ -457,7 +410,158 @@ func (c *Contractor) performContractMaintenance(ctx *mCtx, w Worker) (bool, erro
 	}
 
 	// return whether the maintenance changed the contract set
-	return c.computeContractSetChanged(mCtx, currentSet, updatedSet, formed, refreshed, renewed, toStopUsing, contractData), nil
+	return c.computeContractSetChanged(mCtx, d.currentSet, updatedSet, formed, refreshed, renewed, toStopUsing, contractData), nil
+}
+
+// decideContractMaintenance fetches the contracts and hosts and decides which
+// contracts to keep, archive, renew and refresh and how many of the renewals
+// to perform. It doesn't perform any of these actions, the decisions are
+// shared by the contract maintenance and the maintenance planner.
+func (c *Contractor) decideContractMaintenance(ctx *mCtx, w Worker) (d maintenanceDecisions, err error) {
+	// fetch current contract set
+	d.currentSet, err = c.bus.Contracts(ctx, api.ContractsOpts{ContractSet: ctx.ContractSet()})
+	if err != nil && !strings.Contains(err.Error(), api.ErrContractSetNotFound.Error()) {
+		return maintenanceDecisions{}, err
+	}
+	d.isInCurrentSet = make(map[types.FileContractID]struct{})
+	setHosts := make(map[types.PublicKey]struct{})
+	for _, c := range d.currentSet {
+		d.isInCurrentSet[c.ID] = struct{}{}
+		setHosts[c.HostKey] = struct{}{}
+	}
+	c.logger.Infof("contract set '%s' holds %d contracts", ctx.ContractSet(), len(d.currentSet))
+
+	// fetch all contracts from the worker.
+	start := time.Now()
+	resp, err := w.Contracts(ctx, timeoutHostRevision)
+	if err != nil {
+		return maintenanceDecisions{}, err
+	}
+	if resp.Errors != nil {
+		for pk, err := range resp.Errors {
+			c.logger.With("hostKey", pk).With("error", err).Warn("failed to fetch revision")
+		}
+	}
+	d.contracts = resp.Contracts
+	c.logger.Infof("fetched %d contracts from the worker, took %v", len(resp.Contracts), time.Since(start))
+
+	// sort contracts by their size
+	sort.Slice(d.contracts, func(i, j int) bool {
+		return d.contracts[i].FileSize() > d.contracts[j].FileSize()
+	})
+
+	// get used hosts
+	d.usedHosts = make(map[types.PublicKey]struct{})
+	for _, contract := range d.contracts {
+		d.usedHosts[contract.HostKey] = struct{}{}
+	}
+
+	// compile map of stored data per contract
+	d.contractData = make(map[types.FileContractID]uint64)
+	for _, c := range d.contracts {
+		d.contractData[c.ID] = c.FileSize()
+	}
+
+	// fetch all hosts
+	d.hosts, err = c.bus.SearchHosts(ctx, api.SearchHostOptions{Limit: -1, FilterMode: api.HostFilterModeAllowed})
+	if err != nil {
+		return maintenanceDecisions{}, err
+	}
+
+	// resolve the locations of all hosts up front, that way the diversity
+	// filters don't have to resolve the hosts one by one
+	c.newDiversityFilter(ctx).Prefetch(ctx, d.hosts)
+
+	// fetch candidate hosts
+	d.candidates, d.unusableHosts, err = c.candidateHosts(ctx, d.hosts, d.usedHosts, setHosts, minValidScore) // avoid 0 score hosts
+	if err != nil {
+		return maintenanceDecisions{}, err
+	}
+
+	// min score to pass checks
+	var minScore float64
+	if len(d.hosts) > 0 {
+		minScore = c.calculateMinScore(d.candidates, ctx.WantedContracts())
+	} else {
+		c.logger.Warn("could not calculate min score, no hosts found")
+	}
+
+	// run host checks
+	d.checks, err = c.runHostChecks(ctx, d.hosts, minScore)
+	if err != nil {
+		return maintenanceDecisions{}, fmt.Errorf("failed to run host checks, err: %v", err)
+	}
+
+	// fetch consensus state
+	cs, err := c.bus.ConsensusState(ctx)
+	if err != nil {
+		return maintenanceDecisions{}, fmt.Errorf("failed to fetch consensus state, err: %v", err)
+	}
+
+	// run contract checks
+	var upcoming []contractInfo
+	d.updatedSet, d.toArchive, d.toStopUsing, d.toRefresh, d.toRenew, upcoming = c.runContractChecks(ctx, d.checks, d.contracts, d.isInCurrentSet, cs.BlockHeight)
+
+	// calculate remaining funds
+	d.remaining = c.remainingFunds(d.contracts, ctx.state)
+
+	// plan the renewals, contracts that are renewed early are removed from
+	// the set and renewed after the contracts that are due
+	var early []contractInfo
+	d.renewalPlan, early = c.planRenewals(ctx, d.toRenew, upcoming, cs.BlockHeight)
+	if len(early) > 0 {
+		c.logger.Infow("renewing contracts early to stagger renewals", "early", len(early), "walletBalance", d.renewalPlan.WalletBalance, "estimatedCost", d.renewalPlan.EstimatedCost)
+		d.updatedSet = withoutContracts(d.updatedSet, early)
+		for _, ci := range early {
+			d.toStopUsing[ci.contract.ID] = errContractRenewedEarly.Error()
+		}
+	}
+
+	// calculate 'limit' amount of contracts we want to renew
+	d.renewLimit = renewalLimit(d.toRenew, d.isInCurrentSet, len(d.updatedSet)+len(early), ctx.WantedContracts())
+	if len(early) > 0 {
+		d.toRenew = append(d.toRenew[:d.renewLimit:d.renewLimit], append(early, d.toRenew[d.renewLimit:]...)...)
+		d.renewLimit += len(early)
+	}
+
+	// outside of the maintenance windows only contracts that are about to
+	// expire are renewed, the others are kept in the set until the next window
+	if d.renewLimit > 0 && ctx.state.SkipContractRenewals {
+		var deferred []contractInfo
+		d.toRenew, deferred = criticalRenewals(d.toRenew, cs.BlockHeight, ctx.RenewWindow())
+		if d.renewLimit > len(d.toRenew) {
+			d.updatedSet = append(d.updatedSet, usableRenewals(deferred, d.renewLimit-len(d.toRenew))...)
+			d.renewLimit = len(d.toRenew)
+		}
+		c.logger.Infow("contract renewals deferred", "deferred", len(deferred), "critical", len(d.toRenew))
+	}
+	return d, nil
+}
+
+// missingContracts returns the number of contracts that have to be formed
+// given the contracts that end up in the set after the renewals and refreshes.
+func (d *maintenanceDecisions) missingContracts(ctx *mCtx, updatedSet []api.ContractMetadata) uint64 {
+	if ctx.state.SkipContractFormations || uint64(len(updatedSet)) >= formationThreshold(len(d.contracts), ctx.WantedContracts()) {
+		return 0
+	}
+	return ctx.WantedContracts() - uint64(len(updatedSet))
+}
+
+// truncateContractSet caps the given set at the wanted amount of contracts,
+// the largest contracts are kept.
+func truncateContractSet(ctx *mCtx, updatedSet []api.ContractMetadata, contractData map[types.FileContractID]uint64, toStopUsing map[types.FileContractID]string) []api.ContractMetadata {
+	if len(updatedSet) <= int(ctx.WantedContracts()) {
+		return updatedSet
+	}
+
+	// sort by contract size
+	sort.Slice(updatedSet, func(i, j int) bool {
+		return contractData[updatedSet[i].ID] > contractData[updatedSet[j].ID]
+	})
+	for _, contract := range updatedSet[ctx.WantedContracts():] {
+		toStopUsing[contract.ID] = "truncated"
+	}
+	return updatedSet[:ctx.WantedContracts()]
 }
 
 func (c *Contractor) computeContractSetChanged(ctx *mCtx, oldSet, newSet []api.ContractMetadata, formed []api.ContractMetadata, refreshed, renewed []renewal, toStopUsing map[types.FileContractID]string, contractData map[types.FileContractID]uint64) bool {
@@ -588,7 +692,7 @@ func (c *Contractor) computeContractSetChanged(ctx *mCtx, oldSet, newSet []api.C
 	return hasChanged
 }
 
-func (c *Contractor) runContractChecks(ctx *mCtx, hostChecks map[types.PublicKey]*api.HostCheck, contracts []api.Contract, inCurrentSet map[types.FileContractID]struct{}, bh uint64) (toKeep []api.ContractMetadata, toArchive, toStopUsing map[types.FileContractID]string, toRefresh, toRenew []contractInfo) {
+func (c *Contractor) runContractChecks(ctx *mCtx, hostChecks map[types.PublicKey]*api.HostCheck, contracts []api.Contract, inCurrentSet map[types.FileContractID]struct{}, bh uint64) (toKeep []api.ContractMetadata, toArchive, toStopUsing map[types.FileContractID]string, toRefresh, toRenew, upcoming []contractInfo) {
 	select {
 	case <-ctx.Done():
 		return
@@ -596,8 +700,9 @@ func (c *Contractor) runContractChecks(ctx *mCtx, hostChecks map[types.PublicKey
 	}
 	c.logger.Info("running contract checks")
 
-	// create new IP filter
+	// create new IP and diversity filters
 	ipFilter := c.newIPFilter()
+	diversityFilter := c.newDiversityFilter(ctx)
 
 	// calculate 'maxKeepLeeway' which defines the amount of contracts we'll be
 	// lenient towards when we fail to either fetch a valid price table or the
@@ -693,6 +798,15 @@ LOOP:
 			continue
 		}
 
+		// check if the contract set already holds too many contracts in the
+		// host's country or ASN, we check contracts from largest to smallest
+		// so we prefer keeping the larger ones
+		if !diversityFilter.Add(ctx, host) {
+			toStopUsing[fcid] = errContractExceedsDiversity.Error()
+			c.logger.Infow("unusable contract", "hk", hk, "fcid", fcid, "reasons", errContractExceedsDiversity.Error())
+			continue
+		}
+
 		// if we were not able to the contract's revision, we can't properly
 		// perform the checks that follow, however we do want to be lenient if
 		// this contract is in the current set and we still have leeway left
@@ -736,10 +850,13 @@ LOOP:
 			toRefresh = append(toRefresh, ci)
 		} else if usable {
 			toKeep = append(toKeep, ci.contract.ContractMetadata)
+			if isUpcomingRenewal(ctx.ContractsConfig(), contract.EndHeight(), bh) {
+				upcoming = append(upcoming, ci)
+			}
 		}
 	}
 
-	return toKeep, toArchive, toStopUsing, toRefresh, toRenew
+	return toKeep, toArchive, toStopUsing, toRefresh, toRenew, upcoming
 }
 
 func (c *Contractor) runHostChecks(ctx *mCtx, hosts []api.Host, minScore float64) (map[types.PublicKey]*api.HostCheck, error) {
@@ -761,7 +878,7 @@ func (c *Contractor) runHostChecks(ctx *mCtx, hosts []api.Host, minScore float64
 	return checks, nil
 }
 
-func (c *Contractor) runContractFormations(ctx *mCtx, w Worker, candidates scoredHosts, usedHosts map[types.PublicKey]struct{}, unusableHosts unusableHostsBreakdown, missing uint64, budget *types.Currency) (formed []api.ContractMetadata, _ error) {
+func (c *Contractor) runContractFormations(ctx *mCtx, w Worker, hosts []api.Host, candidates scoredHosts, usedHosts, setHosts map[types.PublicKey]struct{}, unusableHosts unusableHostsBreakdown, missing uint64, budget *types.Currency) (formed []api.ContractMetadata, _ error) {
 	select {
 	case <-c.shutdownCtx.Done():
 		return nil, nil
@@ -824,6 +941,14 @@ func (c *Contractor) runContractFormations(ctx *mCtx, w Worker, candidates score
 		}
 	}
 
+	// prepare a diversity filter that contains the hosts in the set
+	diversityFilter := c.newDiversityFilter(ctx)
+	for _, h := range hosts {
+		if _, inSet := setHosts[h.PublicKey]; inSet {
+			_ = diversityFilter.Add(ctx, h)
+		}
+	}
+
 	// calculate min/max contract funds
 	minInitialContractFunds, maxInitialContractFunds := initialContractFundingMinMax(ctx.AutopilotConfig())
 
@@ -867,10 +992,16 @@ LOOP:
 			continue
 		}
 
+		// check if the host's country or ASN is already at its limit
+		if !diversityFilter.Fits(ctx, host) {
+			continue
+		}
+
 		formedContract, proceed, err := c.formContract(ctx, w, host, minInitialContractFunds, maxInitialContractFunds, budget)
 		if err == nil {
 			// add contract to contract set
 			formed = append(formed, formedContract)
+			_ = diversityFilter.Add(ctx, host)
 			missing--
 		}
 		if !proceed {
@@ -985,8 +1116,9 @@ func (c *Contractor) runContractRenewals(ctx *mCtx, w Worker, toRenew []contract
 		renewed, proceed, err := c.renewContract(ctx, w, toRenew[i], budget)
 		if err != nil {
 			// don't register an alert for hosts that are out of funds since the
-			// user can't do anything about it
-			if !(worker.IsErrHost(err) && utils.IsErr(err, cwallet.ErrNotEnoughFunds)) {
+			// user can't do anything about it, renewals that are waiting for
+			// the wallet's signatures aren't failures either
+			if !(worker.IsErrHost(err) && utils.IsErr(err, cwallet.ErrNotEnoughFunds)) && !utils.IsErr(err, api.ErrSignaturePending) {
 				c.alerter.RegisterAlert(ctx, newContractRenewalFailedAlert(contract, !proceed, err))
 			}
 			c.logger.With(zap.Error(err)).
@@ -1144,7 +1276,7 @@ func (c *Contractor) calculateMinScore(candidates []scoredHost, numContracts uin
 	return minScore
 }
 
-func (c *Contractor) candidateHosts(ctx *mCtx, hosts []api.Host, usedHosts map[types.PublicKey]struct{}, minScore float64) ([]scoredHost, unusableHostsBreakdown, error) {
+func (c *Contractor) candidateHosts(ctx *mCtx, hosts []api.Host, usedHosts, setHosts map[types.PublicKey]struct{}, minScore float64) ([]scoredHost, unusableHostsBreakdown, error) {
 	start := time.Now()
 
 	// fetch consensus state
@@ -1156,9 +1288,17 @@ func (c *Contractor) candidateHosts(ctx *mCtx, hosts []api.Host, usedHosts map[t
 	// create a gouging checker
 	gc := ctx.GougingChecker(cs)
 
+	// create a diversity filter that contains the hosts in the set
+	diversityFilter := c.newDiversityFilter(ctx)
+	for _, h := range hosts {
+		if _, inSet := setHosts[h.PublicKey]; inSet {
+			_ = diversityFilter.Add(ctx, h)
+		}
+	}
+
 	// select unused hosts that passed a scan
 	var unused []api.Host
-	var blocked, excluded, notcompletedscan int
+	var blocked, excluded, notcompletedscan, notdiverse int
 	for _, h := range hosts {
 		// filter out used hosts
 		if _, exclude := usedHosts[h.PublicKey]; exclude {
@@ -1175,12 +1315,18 @@ func (c *Contractor) candidateHosts(ctx *mCtx, hosts []api.Host, usedHosts map[t
 			notcompletedscan++
 			continue
 		}
+		// filter out hosts in countries or ASNs that are at their limit
+		if !diversityFilter.Fits(ctx, h) {
+			notdiverse++
+			continue
+		}
 		unused = append(unused, h)
 	}
 
 	c.logger.Infow(fmt.Sprintf("selected %d (potentially) usable hosts for scoring out of %d", len(unused), len(hosts)),
 		"excluded", excluded,
 		"notcompletedscan", notcompletedscan,
+		"notdiverse", notdiverse,
 		"used", len(usedHosts))
 
 	// score all unused hosts
@@ -1250,7 +1396,7 @@ func (c *Contractor) renewContract(ctx *mCtx, w Worker, ci contractInfo, budget
 	}
 
 	// sanity check the endheight is not the same on renewals
-	endHeight := ctx.EndHeight()
+	endHeight := ctx.renewalEndHeight(ci)
 	if endHeight <= rev.EndHeight() {
 		log.Infow("invalid renewal endheight", "oldEndheight", rev.EndHeight(), "newEndHeight", endHeight, "period", ctx.state.Period, "bh", cs.BlockHeight)
 		return api.ContractMetadata{}, false, fmt.Errorf("renewal endheight should surpass the current contract endheight, %v <= %v", endHeight, rev.EndHeight())
@@ -1261,7 +1407,10 @@ func (c *Contractor) renewContract(ctx *mCtx, w Worker, ci contractInfo, budget
 
 	// renew the contract
 	resp, err := w.RHPRenew(ctx, fcid, endHeight, hk, contract.SiamuxAddr, settings.Address, ctx.state.Address, renterFunds, types.ZeroCurrency, *budget, expectedNewStorage, settings.WindowSize)
-	if err != nil {
+	if utils.IsErr(err, api.ErrSignaturePending) {
+		log.Infow("renewal is waiting for the wallet to sign the transaction", zap.Error(err))
+		return api.ContractMetadata{}, true, err
+	} else if err != nil {
 		log.Errorw(
 			"renewal failed",
 			zap.Error(err),
@@ -1341,7 +1490,10 @@ func (c *Contractor) refreshContract(ctx *mCtx, w Worker, ci contractInfo, budge
 
 	// renew the contract
 	resp, err := w.RHPRenew(ctx, contract.ID, contract.EndHeight(), hk, contract.SiamuxAddr, settings.Address, ctx.state.Address, renterFunds, minNewCollateral, maxFundAmount, expectedNewStorage, settings.WindowSize)
-	if err != nil {
+	if utils.IsErr(err, api.ErrSignaturePending) {
+		log.Infow("refresh is waiting for the wallet to sign the transaction", zap.Error(err), "hk", hk, "fcid", fcid)
+		return api.ContractMetadata{}, true, err
+	} else if err != nil {
 		if strings.Contains(err.Error(), "new collateral is too low") {
 			log.Infow("refresh failed: contract wouldn't have enough collateral after refresh",
 				"hk", hk,
@@ -1408,13 +1560,16 @@ func (c *Contractor) formContract(ctx *mCtx, w Worker, host api.Host, minInitial
 	}
 
 	// calculate the host collateral
-	endHeight := ctx.EndHeight()
+	endHeight := ctx.contractEndHeight(hk, 0)
 	expectedStorage := renterFundsToExpectedStorage(renterFunds, endHeight-cs.BlockHeight, scan.PriceTable)
 	hostCollateral := rhpv2.ContractFormationCollateral(ctx.Period(), expectedStorage, scan.Settings)
 
 	// form contract
 	contract, _, err := w.RHPForm(ctx, endHeight, hk, host.NetAddress, ctx.state.Address, renterFunds, hostCollateral)
-	if err != nil {
+	if utils.IsErr(err, api.ErrSignaturePending) {
+		log.Infow("formation is waiting for the wallet to sign the transaction", zap.Error(err))
+		return api.ContractMetadata{}, true, err
+	} else if err != nil {
 		// TODO: keep track of consecutive failures and break at some point
 		log.Errorw(fmt.Sprintf("contract formation failed, err: %v", err), "hk", hk)
 		if strings.Contains(err.Error(), wallet.ErrInsufficientBalance.Error()) {
@@ -1442,6 +1597,86 @@ func (c *Contractor) formContract(ctx *mCtx, w Worker, host api.Host, minInitial
 	return formedContract, true, nil
 }
 
+// renewalLimit sorts the contracts to renew by priority and returns the
+// amount of contracts we want to renew. When renewing, we prioritise contracts
+// that have already been in the set before and out of those prefer the
+// largest ones.
+func renewalLimit(toRenew []contractInfo, isInCurrentSet map[types.FileContractID]struct{}, setSize int, wanted uint64) (limit int) {
+	if len(toRenew) == 0 {
+		return 0
+	}
+	sort.Slice(toRenew, func(i, j int) bool {
+		_, icsI := isInCurrentSet[toRenew[i].contract.ID]
+		_, icsJ := isInCurrentSet[toRenew[j].contract.ID]
+		if icsI && !icsJ {
+			return true
+		} else if !icsI && icsJ {
+			return false
+		}
+		return toRenew[i].contract.FileSize() > toRenew[j].contract.FileSize()
+	})
+	for setSize+limit < int(wanted) && limit < len(toRenew) {
+		// as long as we're missing contracts, increase the renewal limit
+		limit++
+	}
+	return limit
+}
+
+// withoutContracts returns the given contracts without the contracts in
+// 'remove'.
+func withoutContracts(contracts []api.ContractMetadata, remove []contractInfo) []api.ContractMetadata {
+	toRemove := make(map[types.FileContractID]struct{})
+	for _, ci := range remove {
+		toRemove[ci.contract.ID] = struct{}{}
+	}
+	var filtered []api.ContractMetadata
+	for _, c := range contracts {
+		if _, ok := toRemove[c.ID]; !ok {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// usableRenewals returns up to 'limit' of the contracts that are due for
+// renewal but still usable, these are kept in the set when renewals are
+// deferred.
+func usableRenewals(toRenew []contractInfo, limit int) (toKeep []api.ContractMetadata) {
+	for _, ci := range toRenew {
+		if len(toKeep) < limit && ci.usable {
+			toKeep = append(toKeep, ci.contract.ContractMetadata)
+		}
+	}
+	return
+}
+
+// criticalRenewals splits the contracts that are due for renewal into the ones
+// that are renewed regardless of the maintenance windows because they're past
+// the first half of their renew window, and the ones whose renewal can be
+// deferred.
+func criticalRenewals(toRenew []contractInfo, bh, renewWindow uint64) (critical, deferred []contractInfo) {
+	for _, ci := range toRenew {
+		if bh+renewWindow/2 >= ci.contract.EndHeight() {
+			critical = append(critical, ci)
+		} else {
+			deferred = append(deferred, ci)
+		}
+	}
+	return
+}
+
+// formationThreshold returns the size of the contract set below which we form
+// new contracts. To avoid forming new contracts as soon as we dip below
+// 'Contracts.Amount', we define a threshold but only if we have more
+// contracts than 'Contracts.Amount' already.
+func formationThreshold(numContracts int, wanted uint64) uint64 {
+	threshold := wanted
+	if uint64(numContracts) > wanted {
+		threshold = addLeeway(threshold, leewayPctRequiredContracts)
+	}
+	return threshold
+}
+
 func addLeeway(n uint64, pct float64) uint64 {
 	if pct < 0 {
 		panic("given leeway percent has to be positive")
@@ -120,3 +120,62 @@ func TestShouldForgiveFailedRenewal(t *testing.T) {
 		t.Fatal("expected no failures")
 	}
 }
+
+func TestRenewalLimit(t *testing.T) {
+	newContract := func(id byte, size uint64) contractInfo {
+		return contractInfo{contract: api.Contract{ContractMetadata: api.ContractMetadata{ID: types.FileContractID{id}, Size: size}}}
+	}
+	toRenew := []contractInfo{newContract(1, 10), newContract(2, 20), newContract(3, 30), newContract(4, 5)}
+	inSet := map[types.FileContractID]struct{}{{1}: {}, {4}: {}}
+
+	// assert contracts in the set are prioritised, largest first
+	if limit := renewalLimit(toRenew, inSet, 1, 4); limit != 3 {
+		t.Fatal("unexpected limit", limit)
+	}
+	var order []byte
+	for _, ci := range toRenew {
+		order = append(order, ci.contract.ID[0])
+	}
+	if string(order) != string([]byte{1, 4, 3, 2}) {
+		t.Fatal("unexpected order", order)
+	}
+
+	// assert the limit doesn't exceed the number of contracts to renew
+	if limit := renewalLimit(toRenew, inSet, 0, 10); limit != 4 {
+		t.Fatal("unexpected limit", limit)
+	} else if limit := renewalLimit(toRenew, inSet, 10, 10); limit != 0 {
+		t.Fatal("unexpected limit", limit)
+	}
+
+	// assert the formation threshold only applies leeway if we have more
+	// contracts than we want
+	if threshold := formationThreshold(10, 10); threshold != 10 {
+		t.Fatal("unexpected threshold", threshold)
+	} else if threshold := formationThreshold(11, 10); threshold != 9 {
+		t.Fatal("unexpected threshold", threshold)
+	}
+}
+
+func TestCriticalRenewals(t *testing.T) {
+	newContract := func(id byte, endHeight uint64) contractInfo {
+		return contractInfo{contract: api.Contract{ContractMetadata: api.ContractMetadata{ID: types.FileContractID{id}, WindowStart: endHeight}}}
+	}
+	toRenew := []contractInfo{newContract(1, 200), newContract(2, 150), newContract(3, 149), newContract(4, 100)}
+
+	// assert contracts past the first half of the renew window are critical
+	critical, deferred := criticalRenewals(toRenew, 100, 100)
+	var ids []byte
+	for _, ci := range critical {
+		ids = append(ids, ci.contract.ID[0])
+	}
+	if string(ids) != string([]byte{2, 3, 4}) {
+		t.Fatal("unexpected critical renewals", ids)
+	} else if len(deferred) != 1 || deferred[0].contract.ID[0] != 1 {
+		t.Fatal("unexpected deferred renewals", deferred)
+	}
+
+	// assert the renewals are deferred early in the renew window
+	if critical, deferred := criticalRenewals(toRenew, 50, 100); len(critical) != 1 || len(deferred) != 3 {
+		t.Fatal("unexpected renewals", len(critical), len(deferred))
+	}
+}
@@ -2,6 +2,7 @@ package contractor
 
 import (
 	"errors"
+	"sort"
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
@@ -21,6 +22,60 @@ func countUsableHosts(cfg api.AutopilotConfig, cs api.ConsensusState, fee types.
 	return
 }
 
+// EvaluateHostSelection compares the hosts that would be selected using 'cfg'
+// to the hosts selected using the 'current' config, this allows for previewing
+// how changes to e.g. the score weights alter the selected host set. The same
+// gouging and redundancy settings are used for both configs.
+func EvaluateHostSelection(current, cfg api.AutopilotConfig, cs api.ConsensusState, fee types.Currency, rs api.RedundancySettings, gs api.GougingSettings, hosts []api.Host) (sel api.ConfigHostSelection) {
+	before := selectHosts(current, cs, fee, rs, gs, hosts)
+	sel.Selected = selectHosts(cfg, cs, fee, rs, gs, hosts)
+
+	inBefore := make(map[types.PublicKey]struct{})
+	for _, hk := range before {
+		inBefore[hk] = struct{}{}
+	}
+	inAfter := make(map[types.PublicKey]struct{})
+	for _, hk := range sel.Selected {
+		inAfter[hk] = struct{}{}
+		if _, ok := inBefore[hk]; !ok {
+			sel.Added = append(sel.Added, hk)
+		}
+	}
+	for _, hk := range before {
+		if _, ok := inAfter[hk]; !ok {
+			sel.Removed = append(sel.Removed, hk)
+		}
+	}
+	return
+}
+
+// selectHosts returns the usable hosts with the highest scores, up to the
+// amount of contracts the config asks for.
+func selectHosts(cfg api.AutopilotConfig, cs api.ConsensusState, fee types.Currency, rs api.RedundancySettings, gs api.GougingSettings, hosts []api.Host) []types.PublicKey {
+	gc := worker.NewGougingChecker(gs, cs, fee, cfg.Contracts.Period, cfg.Contracts.RenewWindow)
+
+	var candidates []scoredHost
+	for _, host := range hosts {
+		host.PriceTable.HostBlockHeight = cs.BlockHeight // ignore block height
+		hc := checkHost(cfg, rs, gc, host, minValidScore)
+		if hc.Usability.IsUsable() {
+			candidates = append(candidates, scoredHost{host, hc.Score.Score()})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if uint64(len(candidates)) > cfg.Contracts.Amount {
+		candidates = candidates[:cfg.Contracts.Amount]
+	}
+
+	selected := make([]types.PublicKey, len(candidates))
+	for i, c := range candidates {
+		selected[i] = c.host.PublicKey
+	}
+	return selected
+}
+
 // EvaluateConfig evaluates the given configuration and if the gouging settings
 // are too strict for the number of contracts required by 'cfg', it will provide
 // a recommendation on how to loosen it.
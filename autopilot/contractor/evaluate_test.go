@@ -9,6 +9,7 @@ import (
 	rhpv3 "go.sia.tech/core/rhp/v3"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/test"
 )
 
 func TestOptimiseGougingSetting(t *testing.T) {
@@ -114,3 +115,80 @@ func TestOptimiseGougingSetting(t *testing.T) {
 		t.Fatal("unexpected storage price", gs.MaxStoragePrice.ExactString())
 	}
 }
+
+func TestEvaluateHostSelection(t *testing.T) {
+	newHost := func() api.Host {
+		return api.Host{
+			KnownSince: time.Unix(0, 0),
+			PublicKey:  test.RandomHostKey(),
+			PriceTable: api.HostPriceTable{
+				HostPriceTable: rhpv3.HostPriceTable{
+					CollateralCost: types.Siacoins(1),
+					MaxCollateral:  types.Siacoins(1000),
+				},
+			},
+			Settings: rhpv2.HostSettings{
+				AcceptingContracts: true,
+				Collateral:         types.Siacoins(1),
+				MaxCollateral:      types.Siacoins(1000),
+				Version:            "1.6.0",
+			},
+			Interactions: api.HostInteractions{
+				Uptime:                  time.Hour * 1000,
+				LastScan:                time.Now(),
+				LastScanSuccess:         true,
+				SecondToLastScanSuccess: true,
+				TotalScans:              100,
+			},
+			LastAnnouncement: time.Unix(0, 0),
+			Scanned:          true,
+		}
+	}
+
+	// h1 has more failed interactions than h2 but a better uptime
+	h1 := newHost()
+	h1.Interactions.FailedInteractions = 10
+	h2 := newHost()
+	h2.Interactions.Downtime = time.Hour * 500
+	hosts := []api.Host{h1, h2}
+
+	cs := api.ConsensusState{BlockHeight: 100, LastBlockTime: api.TimeNow(), Synced: true}
+	rs := api.RedundancySettings{MinShards: 10, TotalShards: 30}
+	gs := api.GougingSettings{
+		MaxRPCPrice:           types.Siacoins(1),
+		MaxContractPrice:      types.Siacoins(1),
+		MaxDownloadPrice:      types.Siacoins(1),
+		MaxUploadPrice:        types.Siacoins(1),
+		MaxStoragePrice:       types.Siacoins(1),
+		HostBlockHeightLeeway: math.MaxInt32,
+	}
+
+	// the current config ignores the uptime, the evaluated config ignores the
+	// interactions
+	current := api.AutopilotConfig{
+		Contracts: api.ContractsConfig{
+			Allowance: types.Siacoins(100000),
+			Amount:    1,
+		},
+		Hosts: api.HostsConfig{
+			ScoreWeights: &api.HostScoreWeights{Age: 1, Collateral: 1, Interactions: 1, Prices: 1, StorageRemaining: 1, Uptime: 0, Version: 1},
+		},
+	}
+	cfg := current
+	cfg.Hosts.ScoreWeights = &api.HostScoreWeights{Age: 1, Collateral: 1, Interactions: 0, Prices: 1, StorageRemaining: 1, Uptime: 5, Version: 1}
+
+	sel := EvaluateHostSelection(current, cfg, cs, types.ZeroCurrency, rs, gs, hosts)
+	if len(sel.Selected) != 1 || sel.Selected[0] != h1.PublicKey {
+		t.Fatal("unexpected selection", sel.Selected)
+	} else if len(sel.Added) != 1 || sel.Added[0] != h1.PublicKey {
+		t.Fatal("unexpected added hosts", sel.Added)
+	} else if len(sel.Removed) != 1 || sel.Removed[0] != h2.PublicKey {
+		t.Fatal("unexpected removed hosts", sel.Removed)
+	}
+
+	// evaluating the current config doesn't change the selection
+	sel = EvaluateHostSelection(current, current, cs, types.ZeroCurrency, rs, gs, hosts)
+	if len(sel.Selected) != 1 || len(sel.Added) != 0 || len(sel.Removed) != 0 {
+		t.Fatal("unexpected selection", sel)
+	}
+}
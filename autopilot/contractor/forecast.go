@@ -0,0 +1,170 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+)
+
+// Forecast projects the spending of the autopilot for the current and the
+// next period. The current period is projected using the spending recorded on
+// the contracts, the next period is estimated using the configured storage,
+// upload and download estimates and the prices of the hosts in the contract
+// set. The siafund fee of the next period's contracts is computed by the bus.
+func Forecast(ctx context.Context, bus Bus, ap api.Autopilot, rs api.RedundancySettings, contracts []api.ContractMetadata, hosts []api.Host, wallet api.WalletResponse, bh uint64) (api.SpendingForecast, error) {
+	cfg := ap.Config.Contracts
+	forecast := api.SpendingForecast{
+		BlockHeight:   bh,
+		PeriodStart:   ap.CurrentPeriod,
+		PeriodEnd:     ap.CurrentPeriod + cfg.Period,
+		Allowance:     cfg.Allowance,
+		WalletBalance: wallet.Spendable,
+	}
+
+	// convenience variables
+	var elapsed, remaining uint64
+	if bh > forecast.PeriodStart {
+		elapsed = bh - forecast.PeriodStart
+	}
+	if forecast.PeriodEnd > bh {
+		remaining = forecast.PeriodEnd - bh
+	}
+
+	// sum up the spending in the current period, contracts that were formed
+	// before the current period only contribute the share of their spending
+	// that falls into the current period
+	var spent api.ContractSpending
+	for _, c := range contracts {
+		if c.StartHeight >= forecast.PeriodStart {
+			spent = spent.Add(c.Spending)
+			forecast.Allocated = forecast.Allocated.Add(c.TotalCost)
+			forecast.Spent.Contracts = forecast.Spent.Contracts.Add(c.ContractPrice)
+		} else if bh > c.StartHeight {
+			spent = spent.Add(scaleSpending(c.Spending, elapsed, bh-c.StartHeight))
+		}
+	}
+	forecast.Spent.ContractSpending = spent
+	forecast.Spent.Total = spendingTotal(spent).Add(forecast.Spent.Contracts)
+
+	// project the spending until the end of the period assuming it continues
+	// at the current rate
+	forecast.Projected = forecast.Spent
+	if elapsed > 0 {
+		forecast.Projected.ContractSpending = spent.Add(scaleSpending(spent, remaining, elapsed))
+		forecast.Projected.Total = spendingTotal(forecast.Projected.ContractSpending).Add(forecast.Projected.Contracts)
+	}
+
+	// figure out when the allowance runs out
+	if forecast.Spent.Total.Cmp(cfg.Allowance) >= 0 {
+		forecast.AllowanceExhaustedHeight = &bh
+	} else if usage := spendingTotal(spent); elapsed > 0 && !usage.IsZero() {
+		blocks := cfg.Allowance.Sub(forecast.Spent.Total).Mul64(elapsed).Div(usage)
+		if blocks.Cmp(types.NewCurrency64(remaining)) < 0 {
+			height := bh + blocks.Big().Uint64()
+			forecast.AllowanceExhaustedHeight = &height
+		}
+	}
+
+	// estimate the spending of the next period
+	nextPeriod, err := estimatePeriodSpending(ctx, bus, ap.Config, rs, contracts, hosts)
+	if err != nil {
+		return api.SpendingForecast{}, err
+	}
+	forecast.NextPeriod = nextPeriod
+
+	// sum up the funds needed to renew the contracts in the set that expire
+	// before the end of the next period
+	for _, c := range contracts {
+		if c.InSet(cfg.Set) && c.WindowStart <= forecast.PeriodEnd+cfg.Period {
+			forecast.UpcomingRenewals = forecast.UpcomingRenewals.Add(c.TotalCost)
+		}
+	}
+	if forecast.UpcomingRenewals.Cmp(cfg.Allowance) > 0 {
+		forecast.UpcomingRenewals = cfg.Allowance
+	}
+	if forecast.UpcomingRenewals.Cmp(wallet.Spendable) > 0 {
+		forecast.WalletShortfall = forecast.UpcomingRenewals.Sub(wallet.Spendable)
+	}
+	return forecast, nil
+}
+
+// estimatePeriodSpending estimates the spending of a period using the average
+// cost of the hosts in the contract set, if the set is empty all given hosts
+// are considered.
+func estimatePeriodSpending(ctx context.Context, bus Bus, cfg api.AutopilotConfig, rs api.RedundancySettings, contracts []api.ContractMetadata, hosts []api.Host) (estimate api.SpendingBreakdown, _ error) {
+	if cfg.Contracts.Amount == 0 {
+		return
+	}
+
+	// collect the hosts in the set
+	inSet := make(map[types.PublicKey]struct{})
+	for _, c := range contracts {
+		if c.InSet(cfg.Contracts.Set) {
+			inSet[c.HostKey] = struct{}{}
+		}
+	}
+	var setHosts []api.Host
+	for _, h := range hosts {
+		if _, ok := inSet[h.PublicKey]; ok {
+			setHosts = append(setHosts, h)
+		}
+	}
+	if len(setHosts) == 0 {
+		setHosts = hosts
+	}
+	if len(setHosts) == 0 {
+		return
+	}
+
+	// compute how much data we upload, download and store per host
+	redundancy := rs.Redundancy()
+	uploadPerHost := uint64(float64(cfg.Contracts.Upload) * redundancy / float64(cfg.Contracts.Amount))
+	downloadPerHost := uint64(float64(cfg.Contracts.Download) * redundancy / float64(cfg.Contracts.Amount))
+	storagePerHost := uint64(float64(cfg.Contracts.Storage) * redundancy / float64(cfg.Contracts.Amount))
+
+	// sum up the costs, uploads include the cost of storing the data
+	var payout types.Currency
+	for _, h := range setHosts {
+		collateral := rhpv2.ContractFormationCollateral(cfg.Contracts.Period, storagePerHost, h.Settings)
+		contractPrice := contractPriceForScore(h)
+		uploads := uploadCostForScore(cfg.Contracts, h, uploadPerHost).Add(storageCostForScore(cfg.Contracts, h, storagePerHost))
+		downloads := downloadCostForScore(h, downloadPerHost)
+		payout = payout.Add(collateral).Add(contractPrice).Add(uploads).Add(downloads)
+
+		estimate.Uploads = estimate.Uploads.Add(uploads)
+		estimate.Downloads = estimate.Downloads.Add(downloads)
+		estimate.Contracts = estimate.Contracts.Add(contractPrice)
+	}
+
+	// extrapolate the average cost to the amount of contracts we want
+	n := uint64(len(setHosts))
+	estimate.Uploads = estimate.Uploads.Mul64(cfg.Contracts.Amount).Div64(n)
+	estimate.Downloads = estimate.Downloads.Mul64(cfg.Contracts.Amount).Div64(n)
+	estimate.Contracts = estimate.Contracts.Mul64(cfg.Contracts.Amount).Div64(n)
+
+	// add the siafund fee that's paid on the payout of the contracts
+	siafundFee, err := bus.FileContractTax(ctx, payout.Mul64(cfg.Contracts.Amount).Div64(n))
+	if err != nil {
+		return api.SpendingBreakdown{}, fmt.Errorf("failed to fetch file contract tax: %w", err)
+	}
+	estimate.Contracts = estimate.Contracts.Add(siafundFee)
+	estimate.Total = spendingTotal(estimate.ContractSpending).Add(estimate.Contracts)
+	return
+}
+
+func scaleSpending(s api.ContractSpending, num, denom uint64) api.ContractSpending {
+	return api.ContractSpending{
+		Uploads:     s.Uploads.Mul64(num).Div64(denom),
+		Downloads:   s.Downloads.Mul64(num).Div64(denom),
+		FundAccount: s.FundAccount.Mul64(num).Div64(denom),
+		Deletions:   s.Deletions.Mul64(num).Div64(denom),
+		SectorRoots: s.SectorRoots.Mul64(num).Div64(denom),
+	}
+}
+
+func spendingTotal(s api.ContractSpending) types.Currency {
+	return s.Uploads.Add(s.Downloads).Add(s.FundAccount).Add(s.Deletions).Add(s.SectorRoots)
+}
@@ -0,0 +1,109 @@
+package contractor
+
+import (
+	"context"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/test"
+)
+
+// taxBusMock implements the file contract tax endpoint of the bus, calling any
+// other method panics.
+type taxBusMock struct {
+	Bus
+	payouts []types.Currency
+}
+
+func (b *taxBusMock) FileContractTax(ctx context.Context, payout types.Currency) (types.Currency, error) {
+	b.payouts = append(b.payouts, payout)
+	return payout.Div64(10), nil
+}
+
+func TestForecast(t *testing.T) {
+	ap := api.Autopilot{
+		CurrentPeriod: 100,
+		Config: api.AutopilotConfig{
+			Contracts: api.ContractsConfig{
+				Set:       "autopilot",
+				Amount:    1,
+				Allowance: types.NewCurrency64(500),
+				Period:    100,
+			},
+		},
+	}
+	contracts := []api.ContractMetadata{
+		{
+			// contract formed in the current period
+			HostKey:       types.PublicKey{1},
+			StartHeight:   100,
+			WindowStart:   200,
+			ContractPrice: types.NewCurrency64(10),
+			TotalCost:     types.NewCurrency64(200),
+			Spending:      api.ContractSpending{Uploads: types.NewCurrency64(60)},
+			ContractSets:  []string{"autopilot"},
+		},
+		{
+			// contract formed in the previous period, half its spending
+			// falls into the current period
+			HostKey:     types.PublicKey{2},
+			StartHeight: 90,
+			WindowStart: 190,
+			TotalCost:   types.NewCurrency64(1000),
+			Spending:    api.ContractSpending{Downloads: types.NewCurrency64(60)},
+		},
+	}
+
+	b := &taxBusMock{}
+	f, err := Forecast(context.Background(), b, ap, api.RedundancySettings{MinShards: 1, TotalShards: 1}, contracts, nil, api.WalletResponse{Spendable: types.NewCurrency64(50)}, 110)
+	if err != nil {
+		t.Fatal(err)
+	} else if f.PeriodStart != 100 || f.PeriodEnd != 200 {
+		t.Fatal("unexpected period", f.PeriodStart, f.PeriodEnd)
+	} else if !f.Allocated.Equals(types.NewCurrency64(200)) {
+		t.Fatal("unexpected allocated", f.Allocated)
+	} else if !f.Spent.Uploads.Equals(types.NewCurrency64(60)) || !f.Spent.Downloads.Equals(types.NewCurrency64(30)) || !f.Spent.Total.Equals(types.NewCurrency64(100)) {
+		t.Fatalf("unexpected spending %+v", f.Spent)
+	}
+
+	// 10 blocks have passed, 90 remain
+	if !f.Projected.Uploads.Equals(types.NewCurrency64(600)) || !f.Projected.Downloads.Equals(types.NewCurrency64(300)) || !f.Projected.Total.Equals(types.NewCurrency64(910)) {
+		t.Fatalf("unexpected projection %+v", f.Projected)
+	}
+
+	// we spend 9 per block, so the remaining 400 last another 44 blocks
+	if f.AllowanceExhaustedHeight == nil || *f.AllowanceExhaustedHeight != 154 {
+		t.Fatal("unexpected exhaustion height", f.AllowanceExhaustedHeight)
+	}
+
+	// only the contract in the set needs to be renewed
+	if !f.UpcomingRenewals.Equals(types.NewCurrency64(200)) || !f.WalletShortfall.Equals(types.NewCurrency64(150)) {
+		t.Fatal("unexpected renewals", f.UpcomingRenewals, f.WalletShortfall)
+	}
+
+	// raise the allowance and assert it lasts until the end of the period
+	ap.Config.Contracts.Allowance = types.NewCurrency64(1000)
+	if f, err := Forecast(context.Background(), b, ap, api.RedundancySettings{MinShards: 1, TotalShards: 1}, contracts, nil, api.WalletResponse{}, 110); err != nil {
+		t.Fatal(err)
+	} else if f.AllowanceExhaustedHeight != nil {
+		t.Fatal("expected allowance to last", *f.AllowanceExhaustedHeight)
+	}
+
+	// assert the next period is estimated using the hosts in the set
+	h := test.NewHost(types.PublicKey{1}, test.NewHostPriceTable(), test.NewHostSettings())
+	ap.Config.Contracts.Storage = 1 << 30
+	f, err = Forecast(context.Background(), b, ap, api.RedundancySettings{MinShards: 1, TotalShards: 1}, contracts, []api.Host{h}, api.WalletResponse{}, 110)
+	if err != nil {
+		t.Fatal(err)
+	} else if f.NextPeriod.Total.IsZero() || f.NextPeriod.Uploads.IsZero() {
+		t.Fatalf("unexpected estimate %+v", f.NextPeriod)
+	}
+
+	// assert the siafund fee is the tax on the payout as computed by the bus
+	if len(b.payouts) != 1 {
+		t.Fatal("expected the tax to be fetched once", len(b.payouts))
+	} else if fee := f.NextPeriod.Contracts.Sub(contractPriceForScore(h)); !fee.Equals(b.payouts[0].Div64(10)) {
+		t.Fatal("unexpected siafund fee", fee, b.payouts[0])
+	}
+}
@@ -0,0 +1,380 @@
+package contractor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+)
+
+const (
+	// geoIPUnknownASN is the ASN the database uses for ranges that are not
+	// routed
+	geoIPUnknownASN = 0
+
+	// geoIPUnknownCountry is the country code the database uses for ranges
+	// that aren't assigned to a country
+	geoIPUnknownCountry = "None"
+
+	// locatorMaxConcurrentLookups is the maximum number of hostnames that are
+	// resolved concurrently when prefetching host locations
+	locatorMaxConcurrentLookups = 16
+)
+
+type (
+	// GeoIPDatabase maps IP ranges to the country and autonomous system they
+	// belong to. The database is loaded from a tab separated file in the
+	// format published by iptoasn.com, every line contains the start and end
+	// of the range, the AS number, the country code and the AS description.
+	GeoIPDatabase struct {
+		ranges []geoIPRange
+	}
+
+	geoIPRange struct {
+		start    netip.Addr
+		end      netip.Addr
+		location api.HostLocation
+	}
+
+	// hostLocator resolves a host's net address and looks up its location in
+	// the GeoIP database, lookups are cached to avoid resolving every host
+	// on every maintenance iteration.
+	hostLocator struct {
+		db       *GeoIPDatabase
+		resolver resolver
+		timeout  time.Duration
+
+		mu    sync.Mutex
+		cache map[types.PublicKey]locationCacheEntry
+	}
+
+	locationCacheEntry struct {
+		created  time.Time
+		address  string
+		location api.HostLocation
+		found    bool
+	}
+
+	// diversityFilter keeps track of the amount of contracts per country and
+	// ASN and rejects hosts that would exceed the configured limits.
+	diversityFilter struct {
+		locator *hostLocator
+		logger  *zap.SugaredLogger
+
+		maxPerASN     int
+		maxPerCountry int
+
+		asns      map[uint32]int
+		countries map[string]int
+		hosts     map[types.PublicKey]struct{}
+	}
+)
+
+// LoadGeoIPDatabase loads the GeoIP/ASN database at the given path.
+func LoadGeoIPDatabase(path string) (*GeoIPDatabase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	defer f.Close()
+	return parseGeoIPDatabase(f)
+}
+
+func parseGeoIPDatabase(r io.Reader) (*GeoIPDatabase, error) {
+	var ranges []geoIPRange
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(text, "\t", 5)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("invalid GeoIP database entry on line %d: expected at least 4 fields, got %d", line, len(fields))
+		}
+		start, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid GeoIP database entry on line %d: %w", line, err)
+		}
+		end, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid GeoIP database entry on line %d: %w", line, err)
+		} else if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("invalid GeoIP database entry on line %d: invalid range %v-%v", line, start, end)
+		}
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GeoIP database entry on line %d: %w", line, err)
+		}
+
+		// skip ranges that are neither routed nor assigned to a country
+		if asn == geoIPUnknownASN && fields[3] == geoIPUnknownCountry {
+			continue
+		}
+
+		location := api.HostLocation{ASN: uint32(asn)}
+		if fields[3] != geoIPUnknownCountry {
+			location.Country = fields[3]
+		}
+		if len(fields) == 5 && fields[4] != "Not routed" {
+			location.ASDescription = fields[4]
+		}
+		ranges = append(ranges, geoIPRange{start: start.Unmap(), end: end.Unmap(), location: location})
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start.Less(ranges[j].start)
+	})
+	return &GeoIPDatabase{ranges: ranges}, nil
+}
+
+// Lookup returns the location of the given address, the boolean indicates
+// whether the address was found in the database.
+func (db *GeoIPDatabase) Lookup(addr netip.Addr) (api.HostLocation, bool) {
+	addr = addr.Unmap()
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return addr.Less(db.ranges[i].start)
+	})
+	if i == 0 {
+		return api.HostLocation{}, false
+	}
+	r := db.ranges[i-1]
+	if r.end.Less(addr) || r.start.Is4() != addr.Is4() {
+		return api.HostLocation{}, false
+	}
+	return r.location, true
+}
+
+func newHostLocator(db *GeoIPDatabase, timeout time.Duration) *hostLocator {
+	return &hostLocator{
+		db:       db,
+		resolver: &net.Resolver{},
+		timeout:  timeout,
+		cache:    make(map[types.PublicKey]locationCacheEntry),
+	}
+}
+
+// Cached returns the cached location of the host, it never resolves the
+// host's address.
+func (l *hostLocator) Cached(h api.Host) (api.HostLocation, bool) {
+	if l == nil || l.db == nil {
+		return api.HostLocation{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.cache[h.PublicKey]
+	if !ok || entry.address != h.NetAddress || !entry.found {
+		return api.HostLocation{}, false
+	}
+	return entry.location, true
+}
+
+// Locate returns the location of the host, the boolean indicates whether the
+// host's location is known.
+func (l *hostLocator) Locate(ctx context.Context, h api.Host) (api.HostLocation, bool) {
+	if l == nil || l.db == nil {
+		return api.HostLocation{}, false
+	}
+
+	// check the cache
+	entry, ok, fresh := l.cached(h)
+	if fresh {
+		return entry.location, entry.found
+	}
+
+	// resolve the host's address
+	host, _, err := net.SplitHostPort(h.NetAddress)
+	if err != nil {
+		return api.HostLocation{}, false
+	}
+	location, found, err := l.lookup(ctx, host)
+	if err != nil {
+		// use a stale entry rather than no entry at all
+		return entry.location, ok && entry.found
+	}
+	l.update(h, location, found)
+	return location, found
+}
+
+// Prefetch resolves the locations of all hosts that aren't cached yet, the
+// lookups are performed concurrently and hosts that share a hostname are only
+// resolved once.
+func (l *hostLocator) Prefetch(ctx context.Context, hosts []api.Host) {
+	if l == nil || l.db == nil {
+		return
+	}
+
+	// group the hosts that need resolving by hostname
+	pending := make(map[string][]api.Host)
+	for _, h := range hosts {
+		if _, _, fresh := l.cached(h); fresh {
+			continue
+		}
+		host, _, err := net.SplitHostPort(h.NetAddress)
+		if err != nil {
+			continue
+		}
+		pending[host] = append(pending[host], h)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, locatorMaxConcurrentLookups)
+LOOP:
+	for host, hosts := range pending {
+		select {
+		case <-ctx.Done():
+			break LOOP
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(host string, hosts []api.Host) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			location, found, err := l.lookup(ctx, host)
+			if err != nil {
+				return
+			}
+			for _, h := range hosts {
+				l.update(h, location, found)
+			}
+		}(host, hosts)
+	}
+	wg.Wait()
+}
+
+// cached returns the cache entry for the host if its address didn't change,
+// fresh indicates whether the entry can be used without resolving the host.
+func (l *hostLocator) cached(h api.Host) (entry locationCacheEntry, ok, fresh bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok = l.cache[h.PublicKey]
+	if !ok || entry.address != h.NetAddress {
+		return locationCacheEntry{}, false, false
+	}
+	return entry, true, time.Since(entry.created) < ipCacheEntryValidity
+}
+
+// lookup resolves the hostname and returns the location of the first address
+// that's in the database.
+func (l *hostLocator) lookup(ctx context.Context, host string) (api.HostLocation, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	addrs, err := l.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return api.HostLocation{}, false, err
+	}
+	for _, addr := range addrs {
+		if ip, ok := netip.AddrFromSlice(addr.IP); ok {
+			if location, found := l.db.Lookup(ip); found {
+				return location, true, nil
+			}
+		}
+	}
+	return api.HostLocation{}, false, nil
+}
+
+func (l *hostLocator) update(h api.Host, location api.HostLocation, found bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cache[h.PublicKey] = locationCacheEntry{
+		created:  time.Now(),
+		address:  h.NetAddress,
+		location: location,
+		found:    found,
+	}
+}
+
+func (c *Contractor) newDiversityFilter(ctx *mCtx) *diversityFilter {
+	hosts := ctx.AutopilotConfig().Hosts
+	limit := func(pct float64) int {
+		if pct == 0 || c.locator == nil || c.locator.db == nil {
+			return 0
+		}
+		return int(math.Max(1, math.Floor(pct*float64(ctx.WantedContracts()))))
+	}
+	return &diversityFilter{
+		locator: c.locator,
+		logger:  c.logger,
+
+		maxPerASN:     limit(hosts.MaxASNPct),
+		maxPerCountry: limit(hosts.MaxCountryPct),
+
+		asns:      make(map[uint32]int),
+		countries: make(map[string]int),
+		hosts:     make(map[types.PublicKey]struct{}),
+	}
+}
+
+func (f *diversityFilter) enabled() bool {
+	return f.maxPerASN > 0 || f.maxPerCountry > 0
+}
+
+// Prefetch resolves the locations of the given hosts up front so checking
+// them against the filter doesn't block on a DNS lookup per host.
+func (f *diversityFilter) Prefetch(ctx context.Context, hosts []api.Host) {
+	if f.enabled() {
+		f.locator.Prefetch(ctx, hosts)
+	}
+}
+
+// Fits returns true if a contract with the given host doesn't exceed the
+// limits, the host is not added to the filter. Hosts whose location is
+// unknown are never filtered.
+func (f *diversityFilter) Fits(ctx context.Context, h api.Host) bool {
+	if !f.enabled() {
+		return true
+	} else if _, exists := f.hosts[h.PublicKey]; exists {
+		return true
+	}
+	loc, ok := f.locator.Locate(ctx, h)
+	if !ok {
+		return true
+	}
+	if f.maxPerASN > 0 && loc.ASN != geoIPUnknownASN && f.asns[loc.ASN] >= f.maxPerASN {
+		return false
+	} else if f.maxPerCountry > 0 && loc.Country != "" && f.countries[loc.Country] >= f.maxPerCountry {
+		return false
+	}
+	return true
+}
+
+// Add adds the host to the filter if it fits, returning false if it doesn't.
+func (f *diversityFilter) Add(ctx context.Context, h api.Host) bool {
+	if !f.Fits(ctx, h) {
+		f.logger.Debugw("host exceeds diversity limits", "hk", h.PublicKey, "netAddress", h.NetAddress)
+		return false
+	} else if !f.enabled() {
+		return true
+	} else if _, exists := f.hosts[h.PublicKey]; exists {
+		return true
+	}
+	f.hosts[h.PublicKey] = struct{}{}
+	if loc, ok := f.locator.Locate(ctx, h); ok {
+		if loc.ASN != geoIPUnknownASN {
+			f.asns[loc.ASN]++
+		}
+		if loc.Country != "" {
+			f.countries[loc.Country]++
+		}
+	}
+	return true
+}
@@ -0,0 +1,199 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+)
+
+const testGeoIPDatabase = `
+1.0.0.0	1.0.0.255	13335	US	CLOUDFLARENET
+1.0.1.0	1.0.3.255	0	None	Not routed
+2.0.0.0	2.0.0.255	3215	FR	Orange
+3.0.0.0	3.0.0.255	3215	FR	Orange
+4.0.0.0	4.0.0.255	3320	DE	DTAG
+2001:db8::	2001:db8::ffff	3320	DE	DTAG
+`
+
+func TestGeoIPDatabase(t *testing.T) {
+	db, err := parseGeoIPDatabase(strings.NewReader(testGeoIPDatabase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		addr     string
+		location api.HostLocation
+		found    bool
+	}{
+		{"0.255.255.255", api.HostLocation{}, false},
+		{"1.0.0.0", api.HostLocation{Country: "US", ASN: 13335, ASDescription: "CLOUDFLARENET"}, true},
+		{"1.0.0.255", api.HostLocation{Country: "US", ASN: 13335, ASDescription: "CLOUDFLARENET"}, true},
+		{"1.0.2.1", api.HostLocation{}, false},
+		{"2.0.1.0", api.HostLocation{}, false},
+		{"3.0.0.1", api.HostLocation{Country: "FR", ASN: 3215, ASDescription: "Orange"}, true},
+		{"::ffff:4.0.0.1", api.HostLocation{Country: "DE", ASN: 3320, ASDescription: "DTAG"}, true},
+		{"2001:db8::1", api.HostLocation{Country: "DE", ASN: 3320, ASDescription: "DTAG"}, true},
+		{"2001:db9::1", api.HostLocation{}, false},
+	}
+	for _, test := range tests {
+		location, found := db.Lookup(netip.MustParseAddr(test.addr))
+		if found != test.found || location != test.location {
+			t.Fatalf("unexpected location for %v: %+v %v", test.addr, location, found)
+		}
+	}
+
+	// assert invalid entries are rejected
+	if _, err := parseGeoIPDatabase(strings.NewReader("1.0.0.0\t1.0.0.255\tAS13335\tUS")); err == nil {
+		t.Fatal("expected error")
+	} else if _, err := parseGeoIPDatabase(strings.NewReader("1.0.0.255\t1.0.0.0\t13335\tUS")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDiversityFilter(t *testing.T) {
+	db, err := parseGeoIPDatabase(strings.NewReader(testGeoIPDatabase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := newTestResolver()
+	r.setAddr("us.com", []net.IPAddr{{IP: net.ParseIP("1.0.0.1")}})
+	r.setAddr("fr1.com", []net.IPAddr{{IP: net.ParseIP("2.0.0.1")}})
+	r.setAddr("fr2.com", []net.IPAddr{{IP: net.ParseIP("3.0.0.1")}})
+	r.setAddr("de.com", []net.IPAddr{{IP: net.ParseIP("4.0.0.1")}})
+
+	locator := newHostLocator(db, time.Minute)
+	locator.resolver = r
+
+	host := func(i byte, addr string) api.Host {
+		return api.Host{PublicKey: types.PublicKey{i}, NetAddress: addr + ":9982"}
+	}
+
+	// allow a single contract per ASN and two per country
+	f := &diversityFilter{
+		locator:       locator,
+		logger:        zap.NewNop().Sugar(),
+		maxPerASN:     1,
+		maxPerCountry: 2,
+		asns:          make(map[uint32]int),
+		countries:     make(map[string]int),
+		hosts:         make(map[types.PublicKey]struct{}),
+	}
+	ctx := context.Background()
+	if !f.Add(ctx, host(1, "fr1.com")) {
+		t.Fatal("expected host to fit")
+	} else if !f.Add(ctx, host(1, "fr1.com")) {
+		t.Fatal("expected host that was already added to fit")
+	} else if f.Fits(ctx, host(2, "fr2.com")) {
+		t.Fatal("expected host in the same ASN to be filtered")
+	} else if !f.Add(ctx, host(3, "us.com")) || !f.Add(ctx, host(4, "de.com")) {
+		t.Fatal("expected hosts to fit")
+	} else if !f.Add(ctx, host(5, "unknown.com")) {
+		t.Fatal("expected host with unknown location to fit")
+	}
+
+	// assert the location is cached
+	if loc, ok := locator.Cached(host(4, "de.com")); !ok || loc.Country != "DE" {
+		t.Fatal("unexpected location", loc, ok)
+	} else if _, ok := locator.Cached(host(4, "changed.com")); ok {
+		t.Fatal("expected cache miss after the host's address changed")
+	}
+
+	// raise the ASN limit and assert the country limit is enforced
+	f.maxPerASN = 2
+	if !f.Add(ctx, host(2, "fr2.com")) {
+		t.Fatal("expected host to fit")
+	}
+	f.maxPerASN = 3
+	f.hosts = make(map[types.PublicKey]struct{})
+	if f.Fits(ctx, host(6, "fr1.com")) {
+		t.Fatal("expected host in the same country to be filtered")
+	}
+}
+
+// blockingResolver resolves every hostname to the same address after a short
+// delay and tracks the number of concurrent lookups.
+type blockingResolver struct {
+	mu        sync.Mutex
+	lookups   map[string]int
+	active    int
+	maxActive int
+}
+
+func (r *blockingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.mu.Lock()
+	r.lookups[host]++
+	r.active++
+	if r.active > r.maxActive {
+		r.maxActive = r.active
+	}
+	r.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	r.mu.Lock()
+	r.active--
+	r.mu.Unlock()
+	return []net.IPAddr{{IP: net.ParseIP("1.0.0.1")}}, nil
+}
+
+func TestHostLocatorPrefetch(t *testing.T) {
+	db, err := parseGeoIPDatabase(strings.NewReader(testGeoIPDatabase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &blockingResolver{lookups: make(map[string]int)}
+	locator := newHostLocator(db, time.Minute)
+	locator.resolver = r
+
+	// prepare hosts, every hostname is shared by two hosts
+	var hosts []api.Host
+	for i := 0; i < 4*locatorMaxConcurrentLookups; i++ {
+		hosts = append(hosts, api.Host{
+			PublicKey:  types.PublicKey{byte(i)},
+			NetAddress: fmt.Sprintf("host%d.com:%d", i/2, 9982+i%2),
+		})
+	}
+
+	// prefetch the locations and assert every hostname was resolved once,
+	// concurrently but without exceeding the limit
+	locator.Prefetch(context.Background(), hosts)
+	if len(r.lookups) != len(hosts)/2 {
+		t.Fatal("unexpected number of hostnames resolved", len(r.lookups))
+	}
+	for host, n := range r.lookups {
+		if n != 1 {
+			t.Fatalf("%v was resolved %d times", host, n)
+		}
+	}
+	if r.maxActive <= 1 || r.maxActive > locatorMaxConcurrentLookups {
+		t.Fatal("unexpected number of concurrent lookups", r.maxActive)
+	}
+
+	// assert the locations are cached and prefetching again is a no-op
+	for _, h := range hosts {
+		if loc, ok := locator.Cached(h); !ok || loc.Country != "US" {
+			t.Fatal("unexpected location", loc, ok)
+		}
+	}
+	locator.Prefetch(context.Background(), hosts)
+	if loc, ok := locator.Locate(context.Background(), hosts[0]); !ok || loc.Country != "US" {
+		t.Fatal("unexpected location", loc, ok)
+	}
+	for host, n := range r.lookups {
+		if n != 1 {
+			t.Fatalf("%v was resolved %d times", host, n)
+		}
+	}
+}
@@ -37,6 +37,8 @@ var (
 	errContractNoRevision        = errors.New("contract has no revision")
 	errContractExpired           = errors.New("contract has expired")
 	errContractNotConfirmed      = errors.New("contract hasn't been confirmed on chain in time")
+	errContractExceedsDiversity  = errors.New("contract exceeds the country or ASN diversity limits")
+	errContractRenewedEarly      = errors.New("contract is renewed early to stagger renewals")
 )
 
 type unusableHostsBreakdown struct {
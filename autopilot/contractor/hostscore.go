@@ -39,6 +39,7 @@ func hostScore(cfg api.AutopilotConfig, h api.Host, expectedRedundancy float64)
 	allocationPerHost := idealDataPerHost * 2
 	// hostPeriodCost is the amount of money we expect to spend on a host in a period.
 	hostPeriodCost := hostPeriodCostForScore(h, cCfg, expectedRedundancy)
+	// the breakdown contains the weighted contribution of every component
 	return api.HostScoreBreakdown{
 		Age:              ageScore(h),
 		Collateral:       collateralScore(cCfg, h.PriceTable.HostPriceTable, uint64(allocationPerHost)),
@@ -47,7 +48,7 @@ func hostScore(cfg api.AutopilotConfig, h api.Host, expectedRedundancy float64)
 		StorageRemaining: storageRemainingScore(h.Settings, h.StoredData, allocationPerHost),
 		Uptime:           uptimeScore(h),
 		Version:          versionScore(h.Settings, cfg.Hosts.MinProtocolVersion),
-	}
+	}.Weighted(cfg.Hosts.Weights())
 }
 
 // priceAdjustmentScore computes a score between 0 and 1 for a host giving its
@@ -1,6 +1,7 @@
 package contractor
 
 import (
+	"encoding/json"
 	"math"
 	"testing"
 	"time"
@@ -240,3 +241,52 @@ func TestCollateralScore(t *testing.T) {
 		t.Errorf("expected %v but got %v", 0, s)
 	}
 }
+
+func TestHostScoreWeights(t *testing.T) {
+	cfg := test.AutopilotConfig
+
+	// h1 has more failed interactions than h2 but a better uptime
+	h1 := test.NewHost(test.RandomHostKey(), test.NewHostPriceTable(), test.NewHostSettings())
+	h1.Interactions.FailedInteractions = 10
+	h2 := test.NewHost(test.RandomHostKey(), test.NewHostPriceTable(), test.NewHostSettings())
+	h2.Interactions.SecondToLastScanSuccess = false
+
+	// assert the default weights leave the components untouched
+	if sb := hostScore(cfg, h1, 3); sb.Interactions != interactionScore(h1) || sb.Uptime != uptimeScore(h1) {
+		t.Fatal("unexpected breakdown", sb)
+	}
+
+	// ignore the uptime, h2 should score better
+	cfg.Hosts.ScoreWeights = &api.HostScoreWeights{Age: 1, Collateral: 1, Interactions: 1, Prices: 1, StorageRemaining: 1, Uptime: 0, Version: 1}
+	if sb := hostScore(cfg, h2, 3); sb.Uptime != 1 {
+		t.Fatal("expected ignored component to contribute 1", sb.Uptime)
+	} else if hostScore(cfg, h1, 3).Score() >= sb.Score() {
+		t.Fatal("expected h2 to score better")
+	}
+
+	// ignore the interactions and weigh the uptime heavily, h1 should score
+	// better
+	cfg.Hosts.ScoreWeights = &api.HostScoreWeights{Age: 1, Collateral: 1, Interactions: 0, Prices: 1, StorageRemaining: 1, Uptime: 5, Version: 1}
+	if sb := hostScore(cfg, h2, 3); sb.Uptime != math.Pow(uptimeScore(h2), 5) {
+		t.Fatal("unexpected weighted uptime", sb.Uptime)
+	} else if hostScore(cfg, h1, 3).Score() <= sb.Score() {
+		t.Fatal("expected h1 to score better")
+	}
+
+	// assert negative weights are invalid
+	cfg.Hosts.ScoreWeights.Uptime = -1
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// assert omitted weights default to 1
+	var hc api.HostsConfig
+	if err := json.Unmarshal([]byte(`{"scoreWeights":{"uptime":5,"interactions":0}}`), &hc); err != nil {
+		t.Fatal(err)
+	}
+	expected := api.DefaultHostScoreWeights
+	expected.Uptime, expected.Interactions = 5, 0
+	if hc.Weights() != expected {
+		t.Fatalf("unexpected weights %+v", hc.Weights())
+	}
+}
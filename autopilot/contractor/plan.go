@@ -0,0 +1,206 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sort"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+)
+
+// ErrMaintenanceInProgress is returned when a maintenance plan is requested
+// while the contractor is performing contract maintenance.
+var ErrMaintenanceInProgress = errors.New("contract maintenance is in progress")
+
+// PlanContractMaintenance returns the plan of what the next contract
+// maintenance would do given the current state. It runs the same host checks,
+// contract checks and candidate selection as the maintenance but doesn't
+// form, renew, refresh or archive any contracts nor does it update the
+// contract set or the host checks.
+func (c *Contractor) PlanContractMaintenance(ctx context.Context, w Worker, state *MaintenanceState) (api.ContractMaintenancePlan, error) {
+	if !c.maintenanceMu.TryLock() {
+		return api.ContractMaintenancePlan{}, ErrMaintenanceInProgress
+	}
+	defer c.maintenanceMu.Unlock()
+
+	// the contract checks keep track of when a contract first failed to be
+	// refreshed, restore them so the plan doesn't affect the maintenance
+	firstRefreshFailure := maps.Clone(c.firstRefreshFailure)
+	defer func() { c.firstRefreshFailure = firstRefreshFailure }()
+
+	return c.planContractMaintenance(newMaintenanceCtx(ctx, state), w)
+}
+
+func (c *Contractor) planContractMaintenance(ctx *mCtx, w Worker) (api.ContractMaintenancePlan, error) {
+	plan := api.ContractMaintenancePlan{
+		ContractSet: ctx.ContractSet(),
+		Form:        []api.PlannedFormation{},
+		Renew:       []api.PlannedRenewal{},
+		Refresh:     []api.PlannedRenewal{},
+		Drop:        []api.PlannedRemoval{},
+	}
+
+	// check if the maintenance would be skipped
+	if reason, skip := canSkipContractMaintenance(ctx, ctx.ContractsConfig()); skip {
+		if reason == "" {
+			return api.ContractMaintenancePlan{}, ctx.Err()
+		}
+		plan.SkipReason = reason
+		return plan, nil
+	}
+
+	// decide what to do with the contracts
+	d, err := c.decideContractMaintenance(ctx, w)
+	if err != nil {
+		return api.ContractMaintenancePlan{}, err
+	}
+
+	// keep track of the contracts that would end up in the set, renewed
+	// contracts are tracked by the id of the contract they're renewed from
+	updatedSet := d.updatedSet
+	toStopUsing := d.toStopUsing
+	budget := d.remaining
+	txnFee := ctx.state.Fee.Mul64(estimatedFileContractTransactionSetSize)
+	minInitialContractFunds, maxInitialContractFunds := initialContractFundingMinMax(ctx.AutopilotConfig())
+
+	// estimate the renewals, once the budget runs out the usable contracts
+	// are kept in the set as long as we're within the limit
+	var kept int
+	var outOfBudget bool
+	for _, ci := range d.toRenew {
+		if len(plan.Renew)+kept >= d.renewLimit {
+			break
+		}
+		renterFunds := renewFundingEstimate(minInitialContractFunds, ci.contract.TotalCost, ci.contract.RenterFunds(), c.logger)
+		cost := renterFunds.Add(ci.settings.ContractPrice).Add(txnFee)
+		outOfBudget = outOfBudget || budget.Cmp(cost) < 0
+		if outOfBudget {
+			if ci.usable {
+				updatedSet = append(updatedSet, ci.contract.ContractMetadata)
+				kept++
+			}
+			continue
+		}
+		budget = budget.Sub(cost)
+		plan.Renew = append(plan.Renew, api.PlannedRenewal{
+			ContractID:    ci.contract.ID,
+			HostKey:       ci.contract.HostKey,
+			Reason:        toStopUsing[ci.contract.ID],
+			EstimatedCost: cost,
+		})
+		if ci.usable || ci.recoverable {
+			updatedSet = append(updatedSet, ci.contract.ContractMetadata)
+		}
+	}
+
+	// estimate the refreshes
+	for _, ci := range d.toRefresh {
+		renterFunds := ci.contract.Revision.ValidRenterPayout()
+		if isOutOfFunds(ctx.AutopilotConfig(), ci.priceTable, ci.contract) {
+			renterFunds = c.refreshFundingEstimate(ctx.AutopilotConfig(), ci, ctx.state.Fee)
+		}
+		cost := renterFunds.Add(ci.settings.ContractPrice).Add(txnFee)
+		if budget.Cmp(cost) < 0 {
+			break
+		}
+		budget = budget.Sub(cost)
+		plan.Refresh = append(plan.Refresh, api.PlannedRenewal{
+			ContractID:    ci.contract.ID,
+			HostKey:       ci.contract.HostKey,
+			Reason:        toStopUsing[ci.contract.ID],
+			EstimatedCost: cost,
+		})
+		if ci.usable || ci.recoverable {
+			updatedSet = append(updatedSet, ci.contract.ContractMetadata)
+		}
+	}
+
+	// estimate the formations, the maintenance selects candidates randomly
+	// weighted by their score so we assume the best candidates are selected
+	if missing := d.missingContracts(ctx, updatedSet); missing > 0 {
+		// prepare the filters
+		ipFilter := c.newIPFilter()
+		diversityFilter := c.newDiversityFilter(ctx)
+		updatedHosts := make(map[types.PublicKey]struct{})
+		for _, contract := range updatedSet {
+			updatedHosts[contract.HostKey] = struct{}{}
+		}
+		for _, h := range d.hosts {
+			if _, used := d.usedHosts[h.PublicKey]; used && !ctx.AllowRedundantIPs() {
+				_ = ipFilter.IsRedundantIP(h.NetAddress, h.PublicKey)
+			}
+			if _, inSet := updatedHosts[h.PublicKey]; inSet {
+				_ = diversityFilter.Add(ctx, h)
+			}
+		}
+
+		candidates := append(scoredHosts{}, d.candidates...)
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].score > candidates[j].score
+		})
+		for _, candidate := range candidates {
+			if missing == 0 {
+				break
+			}
+			host := candidate.host
+			if !ctx.AllowRedundantIPs() && ipFilter.IsRedundantIP(host.NetAddress, host.PublicKey) {
+				continue
+			} else if !diversityFilter.Add(ctx, host) {
+				continue
+			}
+
+			renterFunds := initialContractFunding(host.Settings, txnFee, minInitialContractFunds, maxInitialContractFunds)
+			cost := renterFunds.Add(host.Settings.ContractPrice).Add(txnFee)
+			if budget.Cmp(cost) < 0 {
+				break
+			}
+			budget = budget.Sub(cost)
+			plan.Form = append(plan.Form, api.PlannedFormation{
+				HostKey:       host.PublicKey,
+				Score:         candidate.score,
+				EstimatedCost: cost,
+			})
+			missing--
+		}
+	}
+
+	// cap the amount of contracts we want to keep to the configured amount,
+	// formed contracts are empty so they're never truncated
+	updatedSet = truncateContractSet(ctx, updatedSet, d.contractData, toStopUsing)
+
+	// compile the contracts that would be dropped from the set or archived
+	inUpdatedSet := make(map[types.FileContractID]struct{})
+	for _, contract := range updatedSet {
+		inUpdatedSet[contract.ID] = struct{}{}
+	}
+	drop := func(fcid types.FileContractID, hk types.PublicKey) {
+		reason, ok := toStopUsing[fcid]
+		if !ok {
+			reason = "unknown"
+		}
+		_, archive := d.toArchive[fcid]
+		plan.Drop = append(plan.Drop, api.PlannedRemoval{
+			ContractID: fcid,
+			HostKey:    hk,
+			Reason:     reason,
+			Archive:    archive,
+		})
+	}
+	for _, contract := range d.currentSet {
+		if _, keep := inUpdatedSet[contract.ID]; !keep {
+			drop(contract.ID, contract.HostKey)
+		}
+	}
+	for _, contract := range d.contracts {
+		_, inSet := d.isInCurrentSet[contract.ID]
+		if _, archive := d.toArchive[contract.ID]; archive && !inSet {
+			drop(contract.ID, contract.HostKey)
+		}
+	}
+
+	plan.Remaining = d.remaining
+	plan.EstimatedCost = d.remaining.Sub(budget)
+	return plan, nil
+}
@@ -0,0 +1,270 @@
+package contractor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/alerts"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/test"
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+type maintenanceBusMock struct {
+	Bus
+
+	cs    api.ConsensusState
+	hosts []api.Host
+	set   []api.ContractMetadata
+
+	archived    map[types.FileContractID]string
+	renewedFrom map[types.FileContractID]types.FileContractID
+	newSet      []types.FileContractID
+	setUpdated  bool
+	checks      int
+}
+
+func (b *maintenanceBusMock) AddContract(_ context.Context, c rhpv2.ContractRevision, _, _ types.Currency, _ uint64, _ string) (api.ContractMetadata, error) {
+	return api.ContractMetadata{ID: c.ID()}, nil
+}
+
+func (b *maintenanceBusMock) AddRenewedContract(_ context.Context, c rhpv2.ContractRevision, _, _ types.Currency, _ uint64, renewedFrom types.FileContractID, _ string) (api.ContractMetadata, error) {
+	b.renewedFrom[c.ID()] = renewedFrom
+	return api.ContractMetadata{ID: c.ID(), RenewedFrom: renewedFrom}, nil
+}
+
+func (b *maintenanceBusMock) ArchiveContracts(_ context.Context, toArchive map[types.FileContractID]string) error {
+	for fcid, reason := range toArchive {
+		b.archived[fcid] = reason
+	}
+	return nil
+}
+
+func (b *maintenanceBusMock) ConsensusState(context.Context) (api.ConsensusState, error) {
+	return b.cs, nil
+}
+
+func (b *maintenanceBusMock) Contracts(context.Context, api.ContractsOpts) ([]api.ContractMetadata, error) {
+	return b.set, nil
+}
+
+func (b *maintenanceBusMock) Host(_ context.Context, hk types.PublicKey) (api.Host, error) {
+	for _, h := range b.hosts {
+		if h.PublicKey == hk {
+			return h, nil
+		}
+	}
+	return api.Host{}, api.ErrHostNotFound
+}
+
+func (b *maintenanceBusMock) RecordContractSetChurnMetric(context.Context, ...api.ContractSetChurnMetric) error {
+	return nil
+}
+
+func (b *maintenanceBusMock) SearchHosts(context.Context, api.SearchHostOptions) ([]api.Host, error) {
+	return b.hosts, nil
+}
+
+func (b *maintenanceBusMock) SetContractSet(_ context.Context, _ string, contracts []types.FileContractID) error {
+	b.newSet = contracts
+	b.setUpdated = true
+	return nil
+}
+
+func (b *maintenanceBusMock) UpdateHostCheck(context.Context, string, types.PublicKey, api.HostCheck) error {
+	b.checks++
+	return nil
+}
+
+type maintenanceWorkerMock struct {
+	Worker
+
+	contracts []api.Contract
+	hosts     map[types.PublicKey]api.Host
+
+	formed  []types.PublicKey
+	renewed map[types.FileContractID]uint64
+}
+
+func (w *maintenanceWorkerMock) Contracts(context.Context, time.Duration) (api.ContractsResponse, error) {
+	return api.ContractsResponse{Contracts: append([]api.Contract{}, w.contracts...)}, nil
+}
+
+func (w *maintenanceWorkerMock) RHPForm(_ context.Context, endHeight uint64, hk types.PublicKey, _ string, _ types.Address, _, _ types.Currency) (rhpv2.ContractRevision, []types.Transaction, error) {
+	w.formed = append(w.formed, hk)
+	return newTestRevision(endHeight), nil, nil
+}
+
+func (w *maintenanceWorkerMock) RHPRenew(_ context.Context, fcid types.FileContractID, endHeight uint64, _ types.PublicKey, _ string, _, _ types.Address, renterFunds, _, _ types.Currency, _, _ uint64) (api.RHPRenewResponse, error) {
+	w.renewed[fcid] = endHeight
+	return api.RHPRenewResponse{Contract: newTestRevision(endHeight), FundAmount: renterFunds}, nil
+}
+
+func (w *maintenanceWorkerMock) RHPScan(_ context.Context, hk types.PublicKey, _ string, _ time.Duration) (api.RHPScanResponse, error) {
+	h := w.hosts[hk]
+	return api.RHPScanResponse{Settings: h.Settings, PriceTable: h.PriceTable.HostPriceTable}, nil
+}
+
+func newTestRevision(endHeight uint64) rhpv2.ContractRevision {
+	var fcid types.FileContractID
+	frand.Read(fcid[:])
+	return rhpv2.ContractRevision{Revision: types.FileContractRevision{
+		ParentID: fcid,
+		FileContract: types.FileContract{
+			WindowStart:        endHeight,
+			WindowEnd:          endHeight + 144,
+			ValidProofOutputs:  []types.SiacoinOutput{{Value: types.Siacoins(10)}, {}},
+			MissedProofOutputs: []types.SiacoinOutput{{Value: types.Siacoins(10)}, {Value: types.Siacoins(1e6)}, {}},
+		},
+	}}
+}
+
+func TestPlanContractMaintenance(t *testing.T) {
+	const bh = 1000
+
+	// prepare five usable hosts
+	var hosts []api.Host
+	for i := 0; i < 5; i++ {
+		settings := test.NewHostSettings()
+		settings.DownloadBandwidthPrice = types.NewCurrency64(1)
+		settings.EphemeralAccountExpiry = time.Hour
+		settings.MaxEphemeralAccountBalance = types.Siacoins(1)
+		pt := test.NewHostPriceTable()
+		pt.MaxDuration = settings.MaxDuration
+		hosts = append(hosts, test.NewHost(test.RandomHostKey(), pt, settings))
+	}
+
+	// prepare contracts with the first four hosts, the fifth host is the only
+	// candidate for a new contract
+	newContract := func(h api.Host, endHeight uint64, renterFunds types.Currency) api.Contract {
+		rev := newTestRevision(endHeight).Revision
+		rev.ValidProofOutputs[0].Value = renterFunds
+		return api.Contract{
+			ContractMetadata: api.ContractMetadata{
+				ID:          rev.ParentID,
+				HostIP:      h.NetAddress,
+				HostKey:     h.PublicKey,
+				StartHeight: bh - 100,
+				State:       api.ContractStateActive,
+				TotalCost:   types.Siacoins(10),
+				WindowStart: rev.WindowStart,
+				WindowEnd:   rev.WindowEnd,
+			},
+			Revision: &rev,
+		}
+	}
+	good := newContract(hosts[0], bh+200, types.Siacoins(10))
+	expiring := newContract(hosts[1], bh+50, types.Siacoins(10))
+	expired := newContract(hosts[2], bh-10, types.Siacoins(10))
+	outOfFunds := newContract(hosts[3], bh+200, types.Siacoins(1).Div64(10))
+	contracts := []api.Contract{good, expiring, expired, outOfFunds}
+
+	var set []api.ContractMetadata
+	for _, c := range contracts {
+		set = append(set, c.ContractMetadata)
+	}
+	b := &maintenanceBusMock{
+		cs:          api.ConsensusState{BlockHeight: bh, LastBlockTime: api.TimeNow(), Synced: true},
+		hosts:       hosts,
+		set:         set,
+		archived:    make(map[types.FileContractID]string),
+		renewedFrom: make(map[types.FileContractID]types.FileContractID),
+	}
+	w := &maintenanceWorkerMock{
+		contracts: contracts,
+		hosts:     make(map[types.PublicKey]api.Host),
+		renewed:   make(map[types.FileContractID]uint64),
+	}
+	for _, h := range hosts {
+		w.hosts[h.PublicKey] = h
+	}
+
+	cfg := test.AutopilotConfig
+	cfg.Contracts.Amount = 4
+	state := &MaintenanceState{
+		GS:            test.GougingSettings,
+		RS:            test.RedundancySettings,
+		AP:            api.Autopilot{ID: api.DefaultAutopilotID, Config: cfg, CurrentPeriod: bh},
+		WalletBalance: types.Siacoins(1e3),
+	}
+
+	c := New(b, alerts.NewManager(), zap.NewNop().Sugar(), 10, 0, nil)
+	defer c.Close()
+
+	// plan the maintenance
+	plan, err := c.PlanContractMaintenance(context.Background(), w, state)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(plan.Form) != 1 || len(plan.Renew) != 1 || len(plan.Refresh) != 1 || len(plan.Drop) != 1 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+
+	// assert planning didn't perform any of the actions
+	if b.setUpdated || b.checks > 0 || len(b.archived) > 0 || len(w.formed) > 0 || len(w.renewed) > 0 {
+		t.Fatal("planning shouldn't perform any actions")
+	}
+
+	// perform the maintenance
+	if _, err := c.PerformContractMaintenance(context.Background(), w, state); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the formations match
+	if len(w.formed) != len(plan.Form) {
+		t.Fatalf("expected %d formations, got %d", len(plan.Form), len(w.formed))
+	}
+	for i, f := range plan.Form {
+		if w.formed[i] != f.HostKey {
+			t.Fatalf("expected formation with host %v, got %v", f.HostKey, w.formed[i])
+		}
+	}
+
+	// assert the renewals and refreshes match, refreshes don't change the end
+	// height of the contract
+	endHeights := make(map[types.FileContractID]uint64)
+	for _, c := range contracts {
+		endHeights[c.ID] = c.EndHeight()
+	}
+	if len(w.renewed) != len(plan.Renew)+len(plan.Refresh) {
+		t.Fatalf("expected %d renewals, got %d", len(plan.Renew)+len(plan.Refresh), len(w.renewed))
+	}
+	for _, r := range plan.Renew {
+		if endHeight, ok := w.renewed[r.ContractID]; !ok || endHeight == endHeights[r.ContractID] {
+			t.Fatalf("expected contract %v to be renewed", r.ContractID)
+		}
+	}
+	for _, r := range plan.Refresh {
+		if endHeight, ok := w.renewed[r.ContractID]; !ok || endHeight != endHeights[r.ContractID] {
+			t.Fatalf("expected contract %v to be refreshed", r.ContractID)
+		}
+	}
+
+	// assert the dropped and archived contracts match
+	inNewSet := make(map[types.FileContractID]struct{})
+	for _, fcid := range b.newSet {
+		inNewSet[fcid] = struct{}{}
+		inNewSet[b.renewedFrom[fcid]] = struct{}{}
+	}
+	dropped := make(map[types.FileContractID]bool)
+	for _, d := range plan.Drop {
+		dropped[d.ContractID] = d.Archive
+	}
+	for _, c := range set {
+		_, isDropped := dropped[c.ID]
+		if _, ok := inNewSet[c.ID]; ok == isDropped {
+			t.Fatalf("contract %v: expected dropped to be %v", c.ID, !ok)
+		}
+	}
+	for fcid, archive := range dropped {
+		if _, archived := b.archived[fcid]; archived != archive {
+			t.Fatalf("contract %v: expected archived to be %v", fcid, archive)
+		}
+	}
+	if len(b.archived) != 1 || len(b.newSet) != len(set)-len(plan.Drop)+len(plan.Form) {
+		t.Fatalf("unexpected set %v or archived contracts %v", b.newSet, b.archived)
+	}
+}
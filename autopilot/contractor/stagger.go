@@ -0,0 +1,164 @@
+package contractor
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+)
+
+// staggerOffset returns the number of blocks that is added to the end height
+// of contracts with the given host. The offset is derived from the host key so
+// offsets are spread uniformly across the window and remain the same across
+// periods.
+func staggerOffset(hk types.PublicKey, window uint64) uint64 {
+	if window == 0 {
+		return 0
+	}
+	h := types.HashBytes(hk[:])
+	return binary.LittleEndian.Uint64(h[:8]) % window
+}
+
+// contractEndHeight returns the end height of a contract with the given host
+// that is formed or renewed in the current period. If the end height doesn't
+// surpass the given minimum, the contract is extended into the next period.
+func (ctx *mCtx) contractEndHeight(hk types.PublicKey, minEndHeight uint64) uint64 {
+	endHeight := ctx.EndHeight() + staggerOffset(hk, ctx.ContractsConfig().StaggerWindow)
+	for endHeight <= minEndHeight {
+		endHeight += ctx.Period()
+	}
+	return endHeight
+}
+
+// renewalEndHeight returns the end height of the given contract after it has
+// been renewed. Contracts that are renewed early have to be extended by at
+// least the renew window, otherwise they'd be due again right away.
+func (ctx *mCtx) renewalEndHeight(ci contractInfo) uint64 {
+	if !ci.early {
+		return ctx.contractEndHeight(ci.contract.HostKey, 0)
+	}
+	return ctx.contractEndHeight(ci.contract.HostKey, ci.contract.EndHeight()+ctx.RenewWindow())
+}
+
+// isUpcomingRenewal returns true if the given contract enters the renew window
+// within the stagger window. These contracts are candidates for being renewed
+// early.
+func isUpcomingRenewal(cfg api.ContractsConfig, endHeight, bh uint64) bool {
+	return cfg.StaggerWindow > 0 && bh+cfg.RenewWindow+cfg.StaggerWindow >= endHeight
+}
+
+// planRenewals compiles the renewal plan for the contracts that are due for
+// renewal and the ones that are coming up for renewal. If the wallet can't
+// afford all of these renewals at once, a portion of the upcoming renewals is
+// renewed early, in order of their end height, using the funds that remain
+// after the due renewals. Renewing early spreads the wallet usage and, since
+// renewed contracts receive a staggered end height, gradually removes the
+// renewal cliff.
+func (c *Contractor) planRenewals(ctx *mCtx, toRenew, upcoming []contractInfo, bh uint64) (plan api.RenewalPlan, early []contractInfo) {
+	cfg := ctx.ContractsConfig()
+	plan = api.RenewalPlan{
+		BlockHeight:   bh,
+		StaggerWindow: cfg.StaggerWindow,
+		WalletBalance: ctx.state.WalletBalance,
+		Renewals:      []api.ScheduledRenewal{},
+	}
+
+	minRenterFunds, _ := initialContractFundingMinMax(ctx.AutopilotConfig())
+	txnFee := ctx.state.Fee.Mul64(estimatedFileContractTransactionSetSize)
+	estimate := func(ci contractInfo) types.Currency {
+		renterFunds := renewFundingEstimate(minRenterFunds, ci.contract.TotalCost, ci.contract.RenterFunds(), c.logger)
+		return renterFunds.Add(ci.settings.ContractPrice).Add(txnFee)
+	}
+	renewHeight := func(ci contractInfo) uint64 {
+		if ci.contract.EndHeight() < cfg.RenewWindow {
+			return 0
+		}
+		return ci.contract.EndHeight() - cfg.RenewWindow
+	}
+
+	// add the renewals that are due
+	var dueCost types.Currency
+	for _, ci := range toRenew {
+		cost := estimate(ci)
+		dueCost = dueCost.Add(cost)
+		plan.Renewals = append(plan.Renewals, api.ScheduledRenewal{
+			ContractID:      ci.contract.ID,
+			HostKey:         ci.contract.HostKey,
+			EndHeight:       ci.contract.EndHeight(),
+			RenewHeight:     renewHeight(ci),
+			TargetEndHeight: ctx.renewalEndHeight(ci),
+			EstimatedCost:   cost,
+			Due:             true,
+		})
+	}
+
+	// add the upcoming renewals in order of their end height
+	upcoming = append([]contractInfo{}, upcoming...)
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].contract.EndHeight() < upcoming[j].contract.EndHeight()
+	})
+	costs := make([]types.Currency, len(upcoming))
+	upcomingCost := types.ZeroCurrency
+	for i, ci := range upcoming {
+		costs[i] = estimate(ci)
+		upcomingCost = upcomingCost.Add(costs[i])
+	}
+	plan.EstimatedCost = dueCost.Add(upcomingCost)
+
+	// renew early if the wallet can't afford all renewals at once
+	var available types.Currency
+	if ctx.state.WalletBalance.Cmp(plan.EstimatedCost) < 0 && ctx.state.WalletBalance.Cmp(dueCost) > 0 && !ctx.state.SkipContractRenewals {
+		available = ctx.state.WalletBalance.Sub(dueCost)
+	}
+	for i, ci := range upcoming {
+		ci.early = true
+		endHeight := ctx.renewalEndHeight(ci)
+		renewEarly := available.Cmp(costs[i]) >= 0 && endHeight-bh <= ci.settings.MaxDuration
+		if renewEarly {
+			available = available.Sub(costs[i])
+			early = append(early, ci)
+		} else {
+			endHeight = expectedEndHeight(ctx, ci, renewHeight(ci))
+		}
+		plan.Renewals = append(plan.Renewals, api.ScheduledRenewal{
+			ContractID:      ci.contract.ID,
+			HostKey:         ci.contract.HostKey,
+			EndHeight:       ci.contract.EndHeight(),
+			RenewHeight:     renewHeight(ci),
+			TargetEndHeight: endHeight,
+			EstimatedCost:   costs[i],
+			Early:           renewEarly,
+		})
+	}
+	return
+}
+
+// expectedEndHeight returns the end height the given contract is expected to
+// be renewed to once it enters the renew window at the given height.
+func expectedEndHeight(ctx *mCtx, ci contractInfo, renewHeight uint64) uint64 {
+	period := ctx.state.AP.CurrentPeriod
+	for renewHeight >= period+ctx.Period() {
+		period += ctx.Period()
+	}
+	return period + ctx.Period() + ctx.RenewWindow() + staggerOffset(ci.contract.HostKey, ctx.ContractsConfig().StaggerWindow)
+}
+
+// RenewalPlan returns the renewal plan of the last contract maintenance, nil
+// is returned if no maintenance was performed yet.
+func (c *Contractor) RenewalPlan() *api.RenewalPlan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.renewalPlan == nil {
+		return nil
+	}
+	plan := *c.renewalPlan
+	plan.Renewals = append([]api.ScheduledRenewal{}, plan.Renewals...)
+	return &plan
+}
+
+func (c *Contractor) setRenewalPlan(plan api.RenewalPlan) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.renewalPlan = &plan
+}
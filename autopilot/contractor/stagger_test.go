@@ -0,0 +1,129 @@
+package contractor
+
+import (
+	"context"
+	"testing"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+func TestStaggerOffset(t *testing.T) {
+	if offset := staggerOffset(types.PublicKey{1}, 0); offset != 0 {
+		t.Fatal("expected no offset without a window", offset)
+	}
+
+	// assert offsets are within the window and spread across it
+	offsets := make(map[uint64]struct{})
+	for i := 0; i < 1000; i++ {
+		var hk types.PublicKey
+		frand.Read(hk[:])
+		offset := staggerOffset(hk, 144)
+		if offset >= 144 {
+			t.Fatal("offset exceeds window", offset)
+		} else if offset != staggerOffset(hk, 144) {
+			t.Fatal("offset is not deterministic")
+		}
+		offsets[offset] = struct{}{}
+	}
+	if len(offsets) < 100 {
+		t.Fatal("offsets are not spread across the window", len(offsets))
+	}
+}
+
+func TestPlanRenewals(t *testing.T) {
+	c := &Contractor{logger: zap.NewNop().Sugar()}
+	state := &MaintenanceState{
+		AP: api.Autopilot{
+			CurrentPeriod: 1000,
+			Config: api.AutopilotConfig{
+				Contracts: api.ContractsConfig{
+					Amount:        10,
+					Period:        1000,
+					RenewWindow:   100,
+					StaggerWindow: 50,
+				},
+			},
+		},
+		WalletBalance: types.Siacoins(12),
+	}
+	ctx := newMaintenanceCtx(context.Background(), state)
+
+	// every renewal costs half of the contract's funds since none of them were
+	// used
+	newContract := func(id byte, endHeight uint64) contractInfo {
+		return contractInfo{
+			contract: api.Contract{
+				ContractMetadata: api.ContractMetadata{
+					ID:          types.FileContractID{id},
+					HostKey:     types.PublicKey{id},
+					TotalCost:   types.Siacoins(10),
+					WindowStart: endHeight,
+				},
+				Revision: &types.FileContractRevision{
+					FileContract: types.FileContract{
+						ValidProofOutputs: []types.SiacoinOutput{{Value: types.Siacoins(10)}},
+					},
+				},
+			},
+			settings: rhpv2.HostSettings{MaxDuration: 10000},
+			usable:   true,
+		}
+	}
+	const bh = 1890
+	toRenew := []contractInfo{newContract(1, 1950)}
+	upcoming := []contractInfo{newContract(4, 2030), newContract(2, 2010), newContract(3, 2020)}
+
+	// the wallet can afford the due renewal and one early renewal
+	plan, early := c.planRenewals(ctx, toRenew, upcoming, bh)
+	if !plan.EstimatedCost.Equals(types.Siacoins(20)) {
+		t.Fatal("unexpected estimated cost", plan.EstimatedCost)
+	} else if len(plan.Renewals) != 4 {
+		t.Fatal("unexpected number of renewals", len(plan.Renewals))
+	} else if len(early) != 1 || early[0].contract.ID != (types.FileContractID{2}) || !early[0].early {
+		t.Fatal("unexpected early renewals", early)
+	}
+
+	// assert the due renewal is renewed to the staggered end height of the
+	// current period
+	due := plan.Renewals[0]
+	if !due.Due || due.Early || due.RenewHeight != 1850 {
+		t.Fatal("unexpected renewal", due)
+	} else if due.TargetEndHeight != 2100+staggerOffset(types.PublicKey{1}, 50) {
+		t.Fatal("unexpected target end height", due.TargetEndHeight)
+	}
+
+	// assert the early renewal is extended by at least the renew window
+	renewal := plan.Renewals[1]
+	if !renewal.Early || renewal.ContractID != (types.FileContractID{2}) {
+		t.Fatal("unexpected renewal", renewal)
+	} else if renewal.TargetEndHeight <= renewal.EndHeight+100 {
+		t.Fatal("unexpected target end height", renewal.TargetEndHeight)
+	}
+
+	// assert the other renewals are renewed when they enter the renew window
+	for i, id := range []byte{3, 4} {
+		renewal := plan.Renewals[i+2]
+		if renewal.Early || renewal.Due || renewal.ContractID != (types.FileContractID{id}) {
+			t.Fatal("unexpected renewal", renewal)
+		} else if renewal.TargetEndHeight != 2100+staggerOffset(types.PublicKey{id}, 50) {
+			t.Fatal("unexpected target end height", renewal.TargetEndHeight)
+		}
+	}
+
+	// assert nothing is renewed early if the wallet can afford all renewals
+	state.WalletBalance = types.Siacoins(20)
+	if _, early := c.planRenewals(ctx, toRenew, upcoming, bh); len(early) != 0 {
+		t.Fatal("unexpected early renewals", len(early))
+	}
+
+	// assert nothing is renewed early if renewals are deferred
+	state.WalletBalance = types.Siacoins(12)
+	state.SkipContractRenewals = true
+	if _, early := c.planRenewals(ctx, toRenew, upcoming, bh); len(early) != 0 {
+		t.Fatal("unexpected early renewals", len(early))
+	}
+}
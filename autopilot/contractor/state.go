@@ -21,6 +21,8 @@ type (
 		Address                types.Address
 		Fee                    types.Currency
 		SkipContractFormations bool
+		SkipContractRenewals   bool
+		WalletBalance          types.Currency
 	}
 
 	mCtx struct {
@@ -0,0 +1,133 @@
+package autopilot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/worker"
+)
+
+const (
+	// autoGougingMinHosts is the minimum number of hosts with a recent price
+	// table we need before we derive the gouging settings from their prices
+	autoGougingMinHosts = 10
+
+	// autoGougingMaxPriceTableAge is the maximum age of a host's price table
+	// for its prices to be considered
+	autoGougingMaxPriceTableAge = 7 * 24 * time.Hour
+
+	// autoGougingMinChange is the minimum relative change of a limit before
+	// the gouging settings are updated, it avoids updating the settings every
+	// iteration because of small fluctuations in the host prices
+	autoGougingMinChange = 0.05
+)
+
+// updateGougingSettings derives the gouging settings from the prices of the
+// hosts on the network if automatic gouging settings are enabled and updates
+// them in the bus if they changed.
+func (ap *Autopilot) updateGougingSettings(ctx context.Context, cfg api.AutopilotConfig) error {
+	if cfg.AutoGouging == nil || !cfg.AutoGouging.Enabled {
+		return nil
+	}
+
+	gs, err := ap.bus.GougingSettings(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch gouging settings: %w", err)
+	}
+	hosts, err := ap.bus.SearchHosts(ctx, api.SearchHostOptions{Limit: -1, FilterMode: api.HostFilterModeAllowed})
+	if err != nil {
+		return fmt.Errorf("failed to fetch hosts: %w", err)
+	}
+
+	updated, n, ok := autoGougingSettings(*cfg.AutoGouging, gs, hosts)
+	if !ok {
+		ap.logger.Debugw("gouging settings unchanged", "hosts", n)
+		return nil
+	}
+	if err := ap.bus.UpdateSetting(ctx, api.SettingGouging, updated); err != nil {
+		return fmt.Errorf("failed to update gouging settings: %w", err)
+	}
+	ap.logger.Infow("updated gouging settings", "hosts", n, "percentile", cfg.AutoGouging.Percentile)
+	ap.RegisterAlert(ctx, newGougingSettingsUpdatedAlert(gs, updated, cfg.AutoGouging.Percentile, n))
+	return nil
+}
+
+// autoGougingSettings returns the gouging settings derived from the prices of
+// the hosts with a recent price table. It returns the number of hosts that
+// were considered and whether the settings should be updated.
+func autoGougingSettings(cfg api.AutoGougingConfig, gs api.GougingSettings, hosts []api.Host) (api.GougingSettings, int, bool) {
+	var rpc, contract, download, upload, storage []types.Currency
+	for _, h := range hosts {
+		pt := h.PriceTable
+		if h.Blocked || !h.Scanned || pt.Expiry.IsZero() || time.Since(pt.Expiry) > autoGougingMaxPriceTableAge {
+			continue
+		}
+		dppt, err := worker.DownloadPricePerTiB(pt.HostPriceTable)
+		if err != nil {
+			continue
+		}
+		uppt, err := worker.UploadPricePerTiB(pt.HostPriceTable)
+		if err != nil {
+			continue
+		}
+		rpc = append(rpc, pt.InitBaseCost)
+		contract = append(contract, pt.ContractPrice)
+		download = append(download, dppt)
+		upload = append(upload, uppt)
+		storage = append(storage, pt.WriteStoreCost)
+	}
+	if len(rpc) < autoGougingMinHosts {
+		return gs, len(rpc), false
+	}
+
+	updated := gs
+	updated.MaxRPCPrice = autoGougingLimit(rpc, cfg.Percentile, cfg.MaxRPCPrice)
+	updated.MaxContractPrice = autoGougingLimit(contract, cfg.Percentile, cfg.MaxContractPrice)
+	updated.MaxDownloadPrice = autoGougingLimit(download, cfg.Percentile, cfg.MaxDownloadPrice)
+	updated.MaxUploadPrice = autoGougingLimit(upload, cfg.Percentile, cfg.MaxUploadPrice)
+	updated.MaxStoragePrice = autoGougingLimit(storage, cfg.Percentile, cfg.MaxStoragePrice)
+
+	changed := significantChange(gs.MaxRPCPrice, updated.MaxRPCPrice) ||
+		significantChange(gs.MaxContractPrice, updated.MaxContractPrice) ||
+		significantChange(gs.MaxDownloadPrice, updated.MaxDownloadPrice) ||
+		significantChange(gs.MaxUploadPrice, updated.MaxUploadPrice) ||
+		significantChange(gs.MaxStoragePrice, updated.MaxStoragePrice)
+	return updated, len(rpc), changed
+}
+
+// autoGougingLimit returns the given percentile of the prices, capped at the
+// ceiling.
+func autoGougingLimit(prices []types.Currency, percentile float64, ceiling types.Currency) types.Currency {
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].Cmp(prices[j]) < 0
+	})
+	idx := int(math.Ceil(percentile/100*float64(len(prices)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	limit := prices[idx]
+	if limit.Cmp(ceiling) > 0 {
+		return ceiling
+	}
+	return limit
+}
+
+// significantChange returns true if the relative difference between the
+// values exceeds autoGougingMinChange.
+func significantChange(old, new types.Currency) bool {
+	if old.IsZero() || new.IsZero() {
+		return !old.Equals(new)
+	}
+	var diff types.Currency
+	if old.Cmp(new) < 0 {
+		diff = new.Sub(old)
+	} else {
+		diff = old.Sub(new)
+	}
+	return diff.Mul64(100).Cmp(old.Mul64(uint64(autoGougingMinChange*100))) > 0
+}
@@ -0,0 +1,61 @@
+package autopilot
+
+import (
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/test"
+)
+
+func TestAutoGougingSettings(t *testing.T) {
+	// create hosts with a contract price of 1..20 SC
+	var hosts []api.Host
+	for i := 1; i <= 20; i++ {
+		pt := test.NewHostPriceTable()
+		pt.ContractPrice = types.Siacoins(uint32(i))
+		hosts = append(hosts, test.NewHost(test.RandomHostKey(), pt, test.NewHostSettings()))
+	}
+
+	cfg := api.AutoGougingConfig{
+		Enabled:          true,
+		Percentile:       50,
+		MaxRPCPrice:      types.Siacoins(1),
+		MaxContractPrice: types.Siacoins(100),
+		MaxDownloadPrice: types.Siacoins(3000),
+		MaxUploadPrice:   types.Siacoins(3000),
+		MaxStoragePrice:  types.Siacoins(1),
+	}
+	gs := api.GougingSettings{MaxContractPrice: types.Siacoins(100)}
+
+	// assert the contract price is the median
+	updated, n, ok := autoGougingSettings(cfg, gs, hosts)
+	if !ok {
+		t.Fatal("expected settings to be updated")
+	} else if n != len(hosts) {
+		t.Fatal("unexpected number of hosts", n)
+	} else if !updated.MaxContractPrice.Equals(types.Siacoins(10)) {
+		t.Fatal("unexpected contract price", updated.MaxContractPrice)
+	}
+
+	// assert the ceiling is respected
+	cfg.MaxContractPrice = types.Siacoins(5)
+	if capped, _, _ := autoGougingSettings(cfg, gs, hosts); !capped.MaxContractPrice.Equals(types.Siacoins(5)) {
+		t.Fatal("unexpected contract price", capped.MaxContractPrice)
+	}
+
+	// assert small changes are ignored
+	cfg.MaxContractPrice = types.Siacoins(100)
+	if _, _, ok := autoGougingSettings(cfg, updated, hosts); ok {
+		t.Fatal("expected settings to be unchanged")
+	}
+
+	// assert hosts with outdated price tables are ignored
+	for i := range hosts[:15] {
+		hosts[i].PriceTable.Expiry = time.Now().Add(-2 * autoGougingMaxPriceTableAge)
+	}
+	if _, n, ok := autoGougingSettings(cfg, gs, hosts); ok || n != 5 {
+		t.Fatal("expected settings to be unchanged", n, ok)
+	}
+}
@@ -0,0 +1,214 @@
+package autopilot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/jape"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/utils"
+)
+
+const (
+	// migrationSpendingPersistInterval is the interval at which the migration
+	// spending is persisted while migrations are running
+	migrationSpendingPersistInterval = time.Minute
+
+	// defaultCriticalMigrationHealth is the health below which slabs are
+	// still migrated once the budget is exhausted if the schedule doesn't
+	// specify a critical migration health
+	defaultCriticalMigrationHealth = 0.25
+)
+
+var (
+	// migrationBudgetAlertThresholds are the fractions of the migration budget
+	// at which an alert is registered
+	migrationBudgetAlertThresholds = []float64{0.5, 0.75, 0.9, 1}
+)
+
+// migrationBudget keeps track of the amount spent on migrations in the
+// current period. The spending is persisted in the bus so it survives
+// restarts.
+type migrationBudget struct {
+	ap *Autopilot
+
+	mu          sync.Mutex
+	loaded      bool
+	budget      types.Currency
+	spending    api.MigrationSpending
+	alerted     float64
+	dirty       bool
+	lastPersist time.Time
+}
+
+func newMigrationBudget(ap *Autopilot) *migrationBudget {
+	return &migrationBudget{ap: ap}
+}
+
+// Refresh updates the budget using the given autopilot, the spending is
+// reset when a new period starts.
+func (b *migrationBudget) Refresh(ctx context.Context, autopilot api.Autopilot) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// load the spending from the bus
+	if !b.loaded {
+		var spending api.MigrationSpending
+		if err := b.ap.bus.Setting(ctx, api.SettingMigrationSpending, &spending); err != nil && !utils.IsErr(err, api.ErrSettingNotFound) {
+			return fmt.Errorf("failed to fetch migration spending: %w", err)
+		}
+		b.spending = spending
+		b.loaded = true
+	}
+
+	b.budget = types.ZeroCurrency
+	if autopilot.Config.Migrations != nil {
+		b.budget = autopilot.Config.Migrations.Budget
+	}
+
+	// reset the spending when a new period starts
+	if b.spending.Period != autopilot.CurrentPeriod {
+		b.spending = api.MigrationSpending{Period: autopilot.CurrentPeriod}
+		b.alerted = 0
+		b.dirty = true
+		b.ap.DismissAlert(ctx, alertMigrationBudgetID)
+	}
+
+	// the budget might have changed, only alert if we crossed a threshold we
+	// haven't alerted for yet
+	b.alerted = alertedThreshold(b.spending.Spent, b.budget)
+	return b.persistLocked(ctx)
+}
+
+// Track adds the cost of a migration to the spending and registers an alert
+// if a threshold was crossed.
+func (b *migrationBudget) Track(ctx context.Context, cost types.Currency) {
+	if cost.IsZero() {
+		return
+	}
+
+	b.mu.Lock()
+	b.spending.Spent = b.spending.Spent.Add(cost)
+	b.dirty = true
+	var alert bool
+	if threshold := alertedThreshold(b.spending.Spent, b.budget); threshold > b.alerted {
+		b.alerted = threshold
+		alert = true
+	}
+	spending, budget, threshold := b.spending, b.budget, b.alerted
+	var err error
+	if time.Since(b.lastPersist) >= migrationSpendingPersistInterval {
+		err = b.persistLocked(ctx)
+	}
+	b.mu.Unlock()
+
+	if err != nil {
+		b.ap.logger.Errorf("failed to persist migration spending: %v", err)
+	}
+	if alert {
+		b.ap.RegisterAlert(ctx, newMigrationBudgetAlert(spending, budget, threshold))
+	}
+}
+
+// Persist persists the spending in the bus if it changed.
+func (b *migrationBudget) Persist(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.persistLocked(ctx)
+}
+
+func (b *migrationBudget) persistLocked(ctx context.Context) error {
+	if !b.dirty {
+		return nil
+	} else if err := b.ap.bus.UpdateSetting(ctx, api.SettingMigrationSpending, b.spending); err != nil {
+		return err
+	}
+	b.dirty = false
+	b.lastPersist = time.Now()
+	return nil
+}
+
+// Exhausted returns true if the spending exceeds the budget.
+func (b *migrationBudget) Exhausted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.budget.IsZero() && b.spending.Spent.Cmp(b.budget) >= 0
+}
+
+// HealthCutoff returns the health cutoff to use for migrations given the
+// budget. Once the budget is exhausted only critical slabs are migrated, the
+// budget never stops critical migrations.
+func (b *migrationBudget) HealthCutoff(cfg api.AutopilotConfig, healthCutoff float64) float64 {
+	if !b.Exhausted() {
+		return healthCutoff
+	} else if cutoff, ok := criticalHealthCutoff(cfg, healthCutoff); ok {
+		return cutoff
+	}
+	return math.Min(defaultCriticalMigrationHealth, healthCutoff)
+}
+
+// Status returns the spending and budget of the autopilot's current period.
+// Unlike Refresh it doesn't update the budget, if the spending wasn't loaded
+// yet it's fetched from the bus without being cached.
+func (b *migrationBudget) Status(ctx context.Context, autopilot api.Autopilot) (api.MigrationBudgetResponse, error) {
+	b.mu.Lock()
+	spending, loaded := b.spending, b.loaded
+	b.mu.Unlock()
+
+	if !loaded {
+		if err := b.ap.bus.Setting(ctx, api.SettingMigrationSpending, &spending); err != nil && !utils.IsErr(err, api.ErrSettingNotFound) {
+			return api.MigrationBudgetResponse{}, fmt.Errorf("failed to fetch migration spending: %w", err)
+		}
+	}
+	if spending.Period != autopilot.CurrentPeriod {
+		spending = api.MigrationSpending{Period: autopilot.CurrentPeriod}
+	}
+
+	var budget types.Currency
+	if autopilot.Config.Migrations != nil {
+		budget = autopilot.Config.Migrations.Budget
+	}
+	resp := api.MigrationBudgetResponse{
+		MigrationSpending: spending,
+		Budget:            budget,
+	}
+	if spending.Spent.Cmp(budget) < 0 {
+		resp.Remaining = budget.Sub(spending.Spent)
+	}
+	resp.Exhausted = !budget.IsZero() && resp.Remaining.IsZero()
+	return resp, nil
+}
+
+// alertedThreshold returns the highest threshold that was crossed.
+func alertedThreshold(spent, budget types.Currency) (threshold float64) {
+	if budget.IsZero() {
+		return 0
+	}
+	for _, t := range migrationBudgetAlertThresholds {
+		// spent/budget >= t <=> spent*100 >= budget*t*100
+		if spent.Mul64(100).Cmp(budget.Mul64(uint64(t*100))) >= 0 {
+			threshold = t
+		}
+	}
+	return
+}
+
+func (ap *Autopilot) migrationBudgetHandlerGET(jc jape.Context) {
+	autopilot, err := ap.Config(jc.Request.Context())
+	if utils.IsErr(err, api.ErrAutopilotNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to fetch autopilot", err) != nil {
+		return
+	}
+	status, err := ap.m.budget.Status(jc.Request.Context(), autopilot)
+	if jc.Check("failed to fetch migration budget", err) != nil {
+		return
+	}
+	jc.Encode(status)
+}
@@ -0,0 +1,95 @@
+package autopilot
+
+import (
+	"context"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+)
+
+func TestMigrationBudget(t *testing.T) {
+	budget := types.Siacoins(100)
+	tests := []struct {
+		spent     types.Currency
+		threshold float64
+	}{
+		{types.ZeroCurrency, 0},
+		{types.Siacoins(49), 0},
+		{types.Siacoins(50), 0.5},
+		{types.Siacoins(89), 0.75},
+		{types.Siacoins(90), 0.9},
+		{types.Siacoins(100), 1},
+		{types.Siacoins(200), 1},
+	}
+	for _, test := range tests {
+		if threshold := alertedThreshold(test.spent, budget); threshold != test.threshold {
+			t.Fatalf("unexpected threshold for %v: %v != %v", test.spent, threshold, test.threshold)
+		}
+	}
+	if threshold := alertedThreshold(types.Siacoins(1), types.ZeroCurrency); threshold != 0 {
+		t.Fatal("expected no threshold without a budget", threshold)
+	}
+
+	// assert the health cutoff is lowered once the budget is exhausted
+	b := &migrationBudget{budget: budget, spending: api.MigrationSpending{Spent: types.Siacoins(99)}, loaded: true}
+	cfg := api.AutopilotConfig{
+		Migrations: &api.MigrationsConfig{Budget: budget},
+		Schedule:   &api.AutopilotSchedule{CriticalMigrationHealth: 0.25},
+	}
+	if cutoff := b.HealthCutoff(cfg, 0.75); cutoff != 0.75 {
+		t.Fatal("unexpected cutoff", cutoff)
+	}
+	b.spending.Spent = budget
+	if cutoff := b.HealthCutoff(cfg, 0.75); cutoff != 0.25 {
+		t.Fatal("unexpected cutoff", cutoff)
+	}
+	cfg.Schedule.CriticalMigrationHealth = 0.1
+	if cutoff := b.HealthCutoff(cfg, 0.75); cutoff != 0.1 {
+		t.Fatal("unexpected cutoff", cutoff)
+	}
+
+	// assert critical slabs are still migrated without a critical migration
+	// health
+	cfg.Schedule.CriticalMigrationHealth = 0
+	if cutoff := b.HealthCutoff(cfg, 0.75); cutoff != defaultCriticalMigrationHealth {
+		t.Fatal("unexpected cutoff", cutoff)
+	}
+	cfg.Schedule = nil
+	if cutoff := b.HealthCutoff(cfg, 0.75); cutoff != defaultCriticalMigrationHealth {
+		t.Fatal("unexpected cutoff", cutoff)
+	} else if cutoff := b.HealthCutoff(cfg, 0.2); cutoff != 0.2 {
+		t.Fatal("unexpected cutoff", cutoff)
+	}
+
+	// assert the status
+	if status, err := b.Status(context.Background(), api.Autopilot{Config: cfg}); err != nil {
+		t.Fatal(err)
+	} else if !status.Exhausted || !status.Remaining.IsZero() {
+		t.Fatal("unexpected status", status)
+	}
+
+	// assert the status of a new period doesn't reset the spending
+	if status, err := b.Status(context.Background(), api.Autopilot{Config: cfg, CurrentPeriod: 1}); err != nil {
+		t.Fatal(err)
+	} else if status.Exhausted || !status.Spent.IsZero() || status.Period != 1 {
+		t.Fatal("unexpected status", status)
+	} else if b.spending.Period != 0 || !b.spending.Spent.Equals(budget) {
+		t.Fatal("status updated the spending", b.spending)
+	}
+
+	// assert the status of an unloaded budget is fetched from the bus without
+	// persisting anything
+	bus := newPauseBusMock()
+	if err := bus.UpdateSetting(context.Background(), api.SettingMigrationSpending, api.MigrationSpending{Period: 1, Spent: types.Siacoins(10)}); err != nil {
+		t.Fatal(err)
+	}
+	b = newMigrationBudget(&Autopilot{bus: bus})
+	if status, err := b.Status(context.Background(), api.Autopilot{Config: cfg, CurrentPeriod: 1}); err != nil {
+		t.Fatal(err)
+	} else if !status.Spent.Equals(types.Siacoins(10)) || !status.Remaining.Equals(types.Siacoins(90)) {
+		t.Fatal("unexpected status", status)
+	} else if b.loaded || b.dirty {
+		t.Fatal("status updated the budget")
+	}
+}
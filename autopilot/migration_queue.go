@@ -0,0 +1,479 @@
+package autopilot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.sia.tech/jape"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/utils"
+	"go.sia.tech/renterd/object"
+)
+
+const (
+	// migrationHistorySize is the number of migration outcomes we keep track
+	// of
+	migrationHistorySize = 1000
+)
+
+type (
+	// migrationQueue keeps track of the slabs that are queued for migration,
+	// the slabs that are being migrated and the outcome of recent migrations.
+	migrationQueue struct {
+		mu         sync.Mutex
+		generation uint64
+		queued     map[object.EncryptionKey]*queuedMigration
+		order      []*queuedMigration
+		inFlight   map[object.EncryptionKey]*inFlightMigration
+		history    []api.MigrationOutcome
+	}
+
+	queuedMigration struct {
+		api.MigrationJob
+
+		// generation is the iteration in which the slab was first queued,
+		// slabs that were queued earlier are migrated first to avoid
+		// starvation
+		generation uint64
+	}
+
+	inFlightMigration struct {
+		api.MigrationJob
+		generation uint64
+		cancel     context.CancelFunc
+		cancelled  bool
+	}
+)
+
+func newMigrationQueue() *migrationQueue {
+	return &migrationQueue{
+		queued:   make(map[object.EncryptionKey]*queuedMigration),
+		inFlight: make(map[object.EncryptionKey]*inFlightMigration),
+	}
+}
+
+// Update updates the queue with the given unhealthy slabs. Slabs that are no
+// longer unhealthy are removed from the queue unless they were queued
+// manually. The buckets map contains the buckets of the objects referencing
+// the slabs, it's used to compute the slab's priority.
+func (q *migrationQueue) Update(slabs []api.UnhealthySlab, buckets map[object.EncryptionKey]map[string][]string, cfg api.MigrationsConfig) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.generation++
+
+	// remove slabs that no longer need to be migrated
+	unhealthy := make(map[object.EncryptionKey]api.UnhealthySlab)
+	for _, slab := range slabs {
+		unhealthy[slab.Key] = slab
+	}
+	for key, m := range q.queued {
+		if _, ok := unhealthy[key]; !ok && !m.Manual {
+			delete(q.queued, key)
+		}
+	}
+
+	// add new slabs and update existing ones
+	for key, slab := range unhealthy {
+		if _, ok := q.inFlight[key]; ok {
+			continue
+		}
+		m, ok := q.queued[key]
+		if !ok {
+			m = &queuedMigration{
+				MigrationJob: api.MigrationJob{
+					Key:      key,
+					QueuedAt: api.TimeNow(),
+				},
+				generation: q.generation,
+			}
+			q.queued[key] = m
+		}
+		m.Health = slab.Health
+		if objects, ok := buckets[key]; ok {
+			m.Objects = objects
+		}
+	}
+
+	// recompute the priorities since the weights might have changed
+	for _, m := range q.queued {
+		m.Priority = cfg.Priority(bucketNames(m.Objects))
+	}
+	q.sortLocked()
+}
+
+// Enqueue manually queues the slab for migration, manually queued slabs are
+// migrated before any other slabs.
+func (q *migrationQueue) Enqueue(slab api.UnhealthySlab, objects map[string][]string, cfg api.MigrationsConfig) api.MigrationJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if m, ok := q.inFlight[slab.Key]; ok {
+		return m.MigrationJob
+	}
+	m, ok := q.queued[slab.Key]
+	if !ok {
+		m = &queuedMigration{
+			MigrationJob: api.MigrationJob{
+				Key:      slab.Key,
+				QueuedAt: api.TimeNow(),
+			},
+			generation: q.generation,
+		}
+		q.queued[slab.Key] = m
+	}
+	m.Health = slab.Health
+	m.Manual = true
+	m.Objects = objects
+	m.Priority = cfg.Priority(bucketNames(objects))
+	q.sortLocked()
+	return m.MigrationJob
+}
+
+// Cancel removes the slab from the queue or interrupts its migration if it's
+// being migrated.
+func (q *migrationQueue) Cancel(key object.EncryptionKey) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.queued[key]; ok {
+		delete(q.queued, key)
+		return nil
+	} else if m, ok := q.inFlight[key]; ok {
+		m.cancelled = true
+		if m.cancel != nil {
+			m.cancel()
+		}
+		return nil
+	}
+	return api.ErrMigrationNotFound
+}
+
+// Pop removes the slab with the highest priority from the queue and marks it
+// as in-flight.
+func (q *migrationQueue) Pop() (api.MigrationJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.order) > 0 {
+		m := q.order[0]
+		q.order = q.order[1:]
+		if q.queued[m.Key] != m {
+			continue // cancelled or updated
+		}
+		delete(q.queued, m.Key)
+		q.inFlight[m.Key] = &inFlightMigration{MigrationJob: m.MigrationJob, generation: m.generation}
+		return m.MigrationJob, true
+	}
+	return api.MigrationJob{}, false
+}
+
+// Requeue puts a slab that was popped but never started back into the queue.
+func (q *migrationQueue) Requeue(key object.EncryptionKey) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	m, ok := q.inFlight[key]
+	if !ok {
+		return
+	}
+	delete(q.inFlight, key)
+	if m.cancelled {
+		return
+	}
+	qm := &queuedMigration{MigrationJob: m.MigrationJob, generation: m.generation}
+	q.queued[key] = qm
+	q.order = append([]*queuedMigration{qm}, q.order...)
+}
+
+// Start marks the migration of the slab as started by the given worker, it
+// returns false if the migration was cancelled in the meantime.
+func (q *migrationQueue) Start(key object.EncryptionKey, worker string, cancel context.CancelFunc) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	m, ok := q.inFlight[key]
+	if !ok || m.cancelled {
+		delete(q.inFlight, key)
+		return false
+	}
+	m.StartedAt = api.TimeNow()
+	m.Worker = worker
+	m.cancel = cancel
+	return true
+}
+
+// Finish records the outcome of the slab's migration.
+func (q *migrationQueue) Finish(key object.EncryptionKey, res api.MigrateSlabResponse, err error) api.MigrationOutcome {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	m, ok := q.inFlight[key]
+	if !ok {
+		return api.MigrationOutcome{}
+	}
+	delete(q.inFlight, key)
+
+	outcome := api.MigrationOutcome{
+		Key:              key,
+		Health:           m.Health,
+		Manual:           m.Manual,
+		Worker:           m.Worker,
+		StartedAt:        m.StartedAt,
+		Duration:         api.DurationMS(time.Since(m.StartedAt.Std())),
+		ShardsMigrated:   res.NumShardsMigrated,
+		SurchargeApplied: res.SurchargeApplied,
+		Cost:             res.Cost,
+	}
+	if m.cancelled && errors.Is(err, context.Canceled) {
+		outcome.Error = "migration cancelled"
+	} else if err != nil {
+		outcome.Error = err.Error()
+	}
+
+	q.history = append(q.history, outcome)
+	if len(q.history) > migrationHistorySize {
+		q.history = q.history[len(q.history)-migrationHistorySize:]
+	}
+	return outcome
+}
+
+// Cancelled returns true if the in-flight migration of the slab was
+// cancelled.
+func (q *migrationQueue) Cancelled(key object.EncryptionKey) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	m, ok := q.inFlight[key]
+	return ok && m.cancelled
+}
+
+// NeedsObjects returns true if the objects referencing the slab haven't been
+// looked up yet.
+func (q *migrationQueue) NeedsObjects(key object.EncryptionKey) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if m, ok := q.queued[key]; ok {
+		return m.Objects == nil
+	} else if m, ok := q.inFlight[key]; ok {
+		return m.Objects == nil
+	}
+	return true
+}
+
+// HasManual returns true if any slabs were queued manually.
+func (q *migrationQueue) HasManual() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, m := range q.queued {
+		if m.Manual {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of queued slabs.
+func (q *migrationQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queued)
+}
+
+// Jobs returns the queued slabs in the order they'll be migrated, starting at
+// the given offset, and all in-flight migrations.
+func (q *migrationQueue) Jobs(offset, limit int) api.MigrationQueueResponse {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	resp := api.MigrationQueueResponse{
+		Queued:      []api.MigrationJob{},
+		InFlight:    []api.MigrationJob{},
+		TotalQueued: len(q.queued),
+	}
+	var skipped int
+	for _, m := range q.order {
+		if q.queued[m.Key] != m {
+			continue
+		} else if skipped < offset {
+			skipped++
+			continue
+		} else if limit >= 0 && len(resp.Queued) >= limit {
+			break
+		}
+		resp.Queued = append(resp.Queued, m.MigrationJob)
+	}
+	for _, m := range q.inFlight {
+		resp.InFlight = append(resp.InFlight, m.MigrationJob)
+	}
+	sort.Slice(resp.InFlight, func(i, j int) bool {
+		return resp.InFlight[i].StartedAt.Std().Before(resp.InFlight[j].StartedAt.Std())
+	})
+	return resp
+}
+
+// SetObjects sets the objects referencing the slab if it's still queued or
+// in-flight.
+func (q *migrationQueue) SetObjects(key object.EncryptionKey, objects map[string][]string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if m, ok := q.queued[key]; ok {
+		m.Objects = objects
+	} else if m, ok := q.inFlight[key]; ok {
+		m.Objects = objects
+	}
+}
+
+// History returns the most recent migration outcomes, most recent first.
+func (q *migrationQueue) History(limit int) api.MigrationHistoryResponse {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	resp := api.MigrationHistoryResponse{Outcomes: []api.MigrationOutcome{}}
+	for i := len(q.history) - 1; i >= 0; i-- {
+		resp.TotalCost = resp.TotalCost.Add(q.history[i].Cost)
+		if limit < 0 || len(resp.Outcomes) < limit {
+			resp.Outcomes = append(resp.Outcomes, q.history[i])
+		}
+	}
+	return resp
+}
+
+// sortLocked rebuilds the order in which the queued slabs are migrated.
+// Manually queued slabs go first, followed by the slabs with the highest
+// priority. Slabs with the same priority are migrated in the order they were
+// queued in, slabs queued in the same iteration are sorted by health.
+func (q *migrationQueue) sortLocked() {
+	q.order = q.order[:0]
+	for _, m := range q.queued {
+		q.order = append(q.order, m)
+	}
+	sort.Slice(q.order, func(i, j int) bool {
+		a, b := q.order[i], q.order[j]
+		if a.Manual != b.Manual {
+			return a.Manual
+		} else if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		} else if a.generation != b.generation {
+			return a.generation < b.generation
+		}
+		return a.Health < b.Health
+	})
+}
+
+func bucketNames(objects map[string][]string) []string {
+	buckets := make([]string, 0, len(objects))
+	for bucket := range objects {
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+func (ap *Autopilot) migrationQueueHandlerGET(jc jape.Context) {
+	offset, limit, objects := 0, 100, false
+	if jc.DecodeForm("offset", &offset) != nil {
+		return
+	} else if jc.DecodeForm("limit", &limit) != nil {
+		return
+	} else if jc.DecodeForm("objects", &objects) != nil {
+		return
+	} else if offset < 0 {
+		jc.Error(errors.New("offset must be non-negative"), http.StatusBadRequest)
+		return
+	}
+	resp := ap.m.queue.Jobs(offset, limit)
+
+	// look up the objects referencing the slabs if requested
+	if objects {
+		lookup := func(jobs []api.MigrationJob) error {
+			for i := range jobs {
+				if jobs[i].Objects != nil {
+					continue
+				}
+				objects, err := ap.m.objectIDsForSlabKey(jc.Request.Context(), jobs[i].Key)
+				if err != nil {
+					return err
+				}
+				jobs[i].Objects = objects
+				ap.m.queue.SetObjects(jobs[i].Key, objects)
+			}
+			return nil
+		}
+		if jc.Check("failed to fetch objects", lookup(resp.InFlight)) != nil {
+			return
+		} else if jc.Check("failed to fetch objects", lookup(resp.Queued)) != nil {
+			return
+		}
+	}
+	jc.Encode(resp)
+}
+
+func (ap *Autopilot) migrationQueueHandlerPOST(jc jape.Context) {
+	ctx := jc.Request.Context()
+
+	var req api.MigrationEnqueueRequest
+	if jc.Decode(&req) != nil {
+		return
+	}
+
+	// fetch the autopilot
+	autopilot, err := ap.Config(ctx)
+	if utils.IsErr(err, api.ErrAutopilotNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to fetch autopilot", err) != nil {
+		return
+	}
+
+	// fetch the slab and compute its health
+	slab, err := ap.bus.Slab(ctx, req.Key)
+	if utils.IsErr(err, api.ErrSlabNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	} else if jc.Check("failed to fetch slab", err) != nil {
+		return
+	}
+	set, err := ap.bus.Contracts(ctx, api.ContractsOpts{ContractSet: autopilot.Config.Contracts.Set})
+	if jc.Check("failed to fetch contract set", err) != nil {
+		return
+	}
+	objects, err := ap.m.objectIDsForSlabKey(ctx, req.Key)
+	if jc.Check("failed to fetch objects", err) != nil {
+		return
+	}
+
+	var cfg api.MigrationsConfig
+	if autopilot.Config.Migrations != nil {
+		cfg = *autopilot.Config.Migrations
+	}
+	job := ap.m.queue.Enqueue(api.UnhealthySlab{Key: req.Key, Health: slabHealth(slab, set)}, objects, cfg)
+
+	// start migrating right away
+	if !ap.isPaused(api.AutopilotSubsystemMigrator) {
+		ap.m.tryPerformMigrations(ap.workers)
+	}
+	jc.Encode(job)
+}
+
+func (ap *Autopilot) migrationQueueKeyHandlerDELETE(jc jape.Context) {
+	var key object.EncryptionKey
+	if jc.DecodeParam("key", &key) != nil {
+		return
+	}
+	err := ap.m.queue.Cancel(key)
+	if errors.Is(err, api.ErrMigrationNotFound) {
+		jc.Error(err, http.StatusNotFound)
+		return
+	}
+	jc.Check("failed to cancel migration", err)
+}
+
+func (ap *Autopilot) migrationHistoryHandlerGET(jc jape.Context) {
+	limit := 100
+	if jc.DecodeForm("limit", &limit) != nil {
+		return
+	}
+	jc.Encode(ap.m.queue.History(limit))
+}
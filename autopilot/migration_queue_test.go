@@ -0,0 +1,199 @@
+package autopilot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/alerts"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/object"
+	"go.uber.org/zap"
+)
+
+func TestMigrationQueue(t *testing.T) {
+	q := newMigrationQueue()
+	k1 := object.GenerateEncryptionKey()
+	k2 := object.GenerateEncryptionKey()
+	k3 := object.GenerateEncryptionKey()
+	k4 := object.GenerateEncryptionKey()
+
+	// queue three slabs, k3 is referenced by an object in a bucket with a
+	// higher priority
+	cfg := api.MigrationsConfig{BucketPriorities: map[string]float64{"important": 2}}
+	q.Update([]api.UnhealthySlab{
+		{Key: k1, Health: 0.5},
+		{Key: k2, Health: 0.2},
+		{Key: k3, Health: 0.9},
+	}, map[object.EncryptionKey]map[string][]string{
+		k3: {"important": {"foo"}},
+	}, cfg)
+
+	// add a new slab with the lowest health, it should be migrated after the
+	// slabs that were queued before
+	q.Update([]api.UnhealthySlab{
+		{Key: k1, Health: 0.5},
+		{Key: k2, Health: 0.2},
+		{Key: k3, Health: 0.9},
+		{Key: k4, Health: 0.1},
+	}, nil, cfg)
+
+	// manually queue k1 which should move it to the front
+	q.Enqueue(api.UnhealthySlab{Key: k1, Health: 0.5}, nil, cfg)
+
+	resp := q.Jobs(0, -1)
+	if resp.TotalQueued != 4 || len(resp.Queued) != 4 {
+		t.Fatal("unexpected number of queued slabs", resp.TotalQueued, len(resp.Queued))
+	}
+	for i, expected := range []object.EncryptionKey{k1, k3, k2, k4} {
+		if resp.Queued[i].Key != expected {
+			t.Fatalf("unexpected slab at position %d", i)
+		}
+	}
+	if !resp.Queued[0].Manual || resp.Queued[1].Priority != 2 {
+		t.Fatal("unexpected jobs", resp.Queued[0], resp.Queued[1])
+	}
+
+	// assert offset and limit are applied
+	if resp := q.Jobs(1, 2); len(resp.Queued) != 2 || resp.Queued[0].Key != k3 || resp.Queued[1].Key != k2 {
+		t.Fatal("unexpected page", resp.Queued)
+	}
+
+	// pop the first slab and start migrating it
+	j, ok := q.Pop()
+	if !ok || j.Key != k1 {
+		t.Fatal("unexpected job", j, ok)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if !q.Start(j.Key, "worker", cancel) {
+		t.Fatal("expected migration to start")
+	}
+	if resp := q.Jobs(0, -1); len(resp.InFlight) != 1 || resp.InFlight[0].Worker != "worker" || resp.TotalQueued != 3 {
+		t.Fatal("unexpected queue", resp)
+	}
+
+	// cancel it and assert the context was cancelled
+	if err := q.Cancel(k1); err != nil {
+		t.Fatal(err)
+	} else if ctx.Err() == nil {
+		t.Fatal("expected context to be cancelled")
+	}
+	outcome := q.Finish(k1, api.MigrateSlabResponse{}, context.Canceled)
+	if outcome.Error != "migration cancelled" {
+		t.Fatal("unexpected outcome", outcome)
+	}
+
+	// cancel a queued slab
+	if err := q.Cancel(k3); err != nil {
+		t.Fatal(err)
+	} else if err := q.Cancel(k3); !errors.Is(err, api.ErrMigrationNotFound) {
+		t.Fatal("unexpected error", err)
+	}
+
+	// pop k2 and requeue it, it should be popped again
+	if j, ok := q.Pop(); !ok || j.Key != k2 {
+		t.Fatal("unexpected job", j, ok)
+	}
+	q.Requeue(k2)
+	if j, ok := q.Pop(); !ok || j.Key != k2 {
+		t.Fatal("unexpected job", j, ok)
+	}
+	q.Start(k2, "worker", func() {})
+	q.Finish(k2, api.MigrateSlabResponse{NumShardsMigrated: 2, Cost: types.Siacoins(1)}, nil)
+
+	// k4 is no longer unhealthy
+	q.Update(nil, nil, cfg)
+	if _, ok := q.Pop(); ok {
+		t.Fatal("expected queue to be empty")
+	}
+
+	// assert the history
+	history := q.History(1)
+	if len(history.Outcomes) != 1 || history.Outcomes[0].Key != k2 || history.Outcomes[0].ShardsMigrated != 2 {
+		t.Fatal("unexpected history", history)
+	} else if !history.TotalCost.Equals(types.Siacoins(1)) {
+		t.Fatal("unexpected total cost", history.TotalCost)
+	}
+}
+
+// migrationBusMock implements the parts of the bus used when migrating
+// manually queued slabs.
+type migrationBusMock struct {
+	*pauseBusMock
+	autopilot api.Autopilot
+}
+
+func (b *migrationBusMock) Autopilot(ctx context.Context, id string) (api.Autopilot, error) {
+	return b.autopilot, nil
+}
+
+func (b *migrationBusMock) DismissAlerts(ctx context.Context, ids ...types.Hash256) error {
+	return nil
+}
+
+func (b *migrationBusMock) RegisterAlert(ctx context.Context, a alerts.Alert) error {
+	return nil
+}
+
+func (b *migrationBusMock) Slab(ctx context.Context, key object.EncryptionKey) (object.Slab, error) {
+	return object.Slab{Key: key}, nil
+}
+
+// migrationWorkerMock records the slabs it migrates.
+type migrationWorkerMock struct {
+	Worker
+
+	mu       sync.Mutex
+	migrated []object.EncryptionKey
+}
+
+func (w *migrationWorkerMock) ID(ctx context.Context) (string, error) {
+	return "worker", nil
+}
+
+func (w *migrationWorkerMock) MigrateSlab(ctx context.Context, s object.Slab, set string) (api.MigrateSlabResponse, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.migrated = append(w.migrated, s.Key)
+	return api.MigrateSlabResponse{}, nil
+}
+
+func TestMigrateManualOutsideWindow(t *testing.T) {
+	// configure a migration window that never opens and exhaust the budget
+	b := &migrationBusMock{pauseBusMock: newPauseBusMock()}
+	b.autopilot.Config.Contracts.Set = "set"
+	b.autopilot.Config.Schedule = &api.AutopilotSchedule{
+		Migrations: []api.MaintenanceWindow{{Start: "0 0 30 2 *", Duration: api.DurationMS(time.Hour)}},
+	}
+	b.autopilot.Config.Migrations = &api.MigrationsConfig{Budget: types.Siacoins(1)}
+	if err := b.UpdateSetting(context.Background(), api.SettingMigrationSpending, api.MigrationSpending{Spent: types.Siacoins(1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	ap, err := New("test", b, nil, zap.NewNop(), time.Hour, time.Hour, 10, 1, 0.75, time.Hour, 0, 1, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &migrationWorkerMock{}
+
+	// queue two slabs, only the manually queued one should be migrated,
+	// without refreshing the health of the other slabs
+	manual, other := object.GenerateEncryptionKey(), object.GenerateEncryptionKey()
+	ap.m.queue.Update([]api.UnhealthySlab{{Key: other, Health: 0.5}}, nil, api.MigrationsConfig{})
+	ap.m.queue.Enqueue(api.UnhealthySlab{Key: manual, Health: 0.5}, nil, api.MigrationsConfig{})
+	ap.m.performMigrations(newWorkerPool([]Worker{w}))
+	if len(w.migrated) != 1 || w.migrated[0] != manual {
+		t.Fatal("unexpected migrations", w.migrated)
+	} else if resp := ap.m.queue.Jobs(0, -1); len(resp.Queued) != 1 || resp.Queued[0].Key != other {
+		t.Fatal("unexpected queue", resp.Queued)
+	}
+
+	// without manually queued slabs the migrations are deferred
+	ap.m.performMigrations(newWorkerPool([]Worker{w}))
+	if len(w.migrated) != 1 {
+		t.Fatal("unexpected migrations", w.migrated)
+	}
+}
@@ -5,10 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"sort"
 	"sync"
 	"time"
 
+	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/alerts"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/internal/utils"
@@ -29,6 +29,8 @@ type (
 		parallelSlabsPerWorker    uint64
 		signalMaintenanceFinished chan struct{}
 		statsSlabMigrationSpeedMS *stats.DataPoints
+		queue                     *migrationQueue
+		budget                    *migrationBudget
 
 		mu                 sync.Mutex
 		migrating          bool
@@ -36,7 +38,7 @@ type (
 	}
 
 	job struct {
-		api.UnhealthySlab
+		api.MigrationJob
 		slabIdx   int
 		batchSize int
 		set       string
@@ -69,6 +71,8 @@ func newMigrator(ap *Autopilot, healthCutoff float64, parallelSlabsPerWorker uin
 		parallelSlabsPerWorker:    parallelSlabsPerWorker,
 		signalMaintenanceFinished: make(chan struct{}, 1),
 		statsSlabMigrationSpeedMS: stats.New(time.Hour),
+		queue:                     newMigrationQueue(),
+		budget:                    newMigrationBudget(ap),
 	}
 }
 
@@ -129,6 +133,11 @@ func (m *migrator) performMigrations(p *workerPool) {
 	defer func() {
 		close(jobs)
 		wg.Wait()
+
+		// persist the migration spending once all jobs are done
+		if err := m.budget.Persist(m.ap.shutdownCtx); err != nil {
+			m.logger.Errorf("failed to persist migration spending: %v", err)
+		}
 	}()
 
 	// launch workers
@@ -152,9 +161,25 @@ func (m *migrator) performMigrations(p *workerPool) {
 
 					// process jobs
 					for j := range jobs {
+						// every job can be cancelled individually
+						jobCtx, jobCancel := context.WithCancel(ctx)
+						if !m.queue.Start(j.Key, id, jobCancel) {
+							jobCancel()
+							m.logger.Infof("%v: migration %d/%d cancelled, key: %v", id, j.slabIdx+1, j.batchSize, j.Key)
+							continue
+						}
+
 						start := time.Now()
-						res, err := j.execute(ctx, w)
+						res, err := j.execute(jobCtx, w)
+						jobCancel()
 						m.statsSlabMigrationSpeedMS.Track(float64(time.Since(start).Milliseconds()))
+						m.budget.Track(ctx, res.Cost)
+						if err != nil && m.queue.Cancelled(j.Key) {
+							m.queue.Finish(j.Key, res, err)
+							m.logger.Infof("%v: migration %d/%d cancelled, key: %v", id, j.slabIdx+1, j.batchSize, j.Key)
+							continue
+						}
+						m.queue.Finish(j.Key, res, err)
 						if err != nil {
 							m.logger.Errorf("%v: migration %d/%d failed, key: %v, health: %v, overpaid: %v, err: %v", id, j.slabIdx+1, j.batchSize, j.Key, j.Health, res.SurchargeApplied, err)
 							skipAlert := utils.IsErr(err, api.ErrSlabNotFound)
@@ -190,8 +215,6 @@ func (m *migrator) performMigrations(p *workerPool) {
 			}
 		}
 	})
-	var toMigrate []api.UnhealthySlab
-
 	// ignore a potential signal before the first iteration of the 'OUTER' loop
 	select {
 	case <-m.signalMaintenanceFinished:
@@ -200,6 +223,12 @@ func (m *migrator) performMigrations(p *workerPool) {
 
 OUTER:
 	for {
+		// check if the migrator was paused
+		if m.ap.isPaused(api.AutopilotSubsystemMigrator) {
+			m.logger.Info("migrations interrupted, migrator is paused")
+			return
+		}
+
 		// fetch currently configured set
 		autopilot, err := m.ap.Config(m.ap.shutdownCtx)
 		if err != nil {
@@ -212,76 +241,71 @@ OUTER:
 			return
 		}
 
-		// recompute health.
-		start := time.Now()
-		if err := b.RefreshHealth(m.ap.shutdownCtx); err != nil {
-			m.ap.RegisterAlert(m.ap.shutdownCtx, newRefreshHealthFailedAlert(err))
-			m.logger.Errorf("failed to recompute cached health before migration: %v", err)
+		// outside of the migration windows only critical slabs and slabs
+		// that were queued manually are migrated
+		healthCutoff, ok := migrationHealthCutoff(autopilot.Config, m.healthCutoff, time.Now())
+		manualOnly := !ok
+		if manualOnly && !m.queue.HasManual() {
+			m.logger.Info("migrations deferred until the next migration window")
 			return
 		}
-		m.logger.Infof("recomputed slab health in %v", time.Since(start))
 
-		// fetch slabs for migration
-		toMigrateNew, err := b.SlabsForMigration(m.ap.shutdownCtx, m.healthCutoff, set, migratorBatchSize)
-		if err != nil {
-			m.logger.Errorf("failed to fetch slabs for migration, err: %v", err)
-			return
+		// once the budget is exhausted only critical slabs are migrated
+		var cfg api.MigrationsConfig
+		if autopilot.Config.Migrations != nil {
+			cfg = *autopilot.Config.Migrations
 		}
-		m.logger.Infof("%d potential slabs fetched for migration", len(toMigrateNew))
-
-		// merge toMigrateNew with toMigrate
-		// NOTE: when merging, we remove all slabs from toMigrate that don't
-		// require migration anymore. However, slabs that have been in toMigrate
-		// before will be repaired before any new slabs. This is to prevent
-		// starvation.
-		migrateNewMap := make(map[object.EncryptionKey]*api.UnhealthySlab)
-		for i, slab := range toMigrateNew {
-			migrateNewMap[slab.Key] = &toMigrateNew[i]
-		}
-		removed := 0
-		for i := 0; i < len(toMigrate)-removed; {
-			slab := toMigrate[i]
-			if _, exists := migrateNewMap[slab.Key]; exists {
-				delete(migrateNewMap, slab.Key) // delete from map to leave only new slabs
-				i++
-			} else {
-				toMigrate[i] = toMigrate[len(toMigrate)-1-removed]
-				removed++
-			}
-		}
-		toMigrate = toMigrate[:len(toMigrate)-removed]
-		for _, slab := range migrateNewMap {
-			toMigrate = append(toMigrate, *slab)
+		if err := m.budget.Refresh(m.ap.shutdownCtx, autopilot); err != nil {
+			m.logger.Errorf("failed to refresh migration budget: %v", err)
+			return
 		}
+		exhausted := m.budget.Exhausted()
+		healthCutoff = m.budget.HealthCutoff(autopilot.Config, healthCutoff)
 
-		// sort the newly added slabs by health
-		newSlabs := toMigrate[len(toMigrate)-len(migrateNewMap):]
-		sort.Slice(newSlabs, func(i, j int) bool {
-			return newSlabs[i].Health < newSlabs[j].Health
-		})
-		migrateNewMap = nil // free map
+		if manualOnly {
+			m.logger.Info("migrating manually queued slabs outside of the migration windows")
+		} else if !m.updateQueue(set, healthCutoff, cfg) {
+			return
+		}
 
-		// log the updated list of slabs to migrate
-		m.logger.Infof("%d slabs to migrate", len(toMigrate))
+		// log the updated number of slabs to migrate
+		queued := m.queue.Len()
+		m.logger.Infof("%d slabs to migrate", queued)
 
 		// register an alert to notify users about ongoing migrations.
-		if len(toMigrate) > 0 {
-			m.ap.RegisterAlert(m.ap.shutdownCtx, newOngoingMigrationsAlert(len(toMigrate), m.slabMigrationEstimate(len(toMigrate))))
+		if queued > 0 {
+			m.ap.RegisterAlert(m.ap.shutdownCtx, newOngoingMigrationsAlert(queued, m.slabMigrationEstimate(queued)))
 		}
 
 		// return if there are no slabs to migrate
-		if len(toMigrate) == 0 {
+		if queued == 0 {
 			return
 		}
 
-		for i, slab := range toMigrate {
+		for i := 0; ; i++ {
+			mj, ok := m.queue.Pop()
+			if !ok {
+				break
+			} else if manualOnly && !mj.Manual {
+				// manually queued slabs are popped first
+				m.queue.Requeue(mj.Key)
+				break
+			}
 			select {
 			case <-m.ap.shutdownCtx.Done():
+				m.queue.Requeue(mj.Key)
 				return
 			case <-m.signalMaintenanceFinished:
+				m.queue.Requeue(mj.Key)
 				m.logger.Info("migrations interrupted - updating slabs for migration")
 				continue OUTER
-			case jobs <- job{slab, i, len(toMigrate), set, b}:
+			case jobs <- job{mj, i, queued, set, b}:
+			}
+
+			// update the slabs for migration if the budget was exhausted
+			if !exhausted && m.budget.Exhausted() {
+				m.logger.Info("migrations interrupted - migration budget exhausted")
+				continue OUTER
 			}
 		}
 
@@ -289,6 +313,50 @@ OUTER:
 	}
 }
 
+// updateQueue refreshes the slab health and updates the queue with the slabs
+// below the given health cutoff, it returns false if that failed.
+func (m *migrator) updateQueue(set string, healthCutoff float64, cfg api.MigrationsConfig) bool {
+	b := m.ap.bus
+
+	// recompute health.
+	start := time.Now()
+	if err := b.RefreshHealth(m.ap.shutdownCtx); err != nil {
+		m.ap.RegisterAlert(m.ap.shutdownCtx, newRefreshHealthFailedAlert(err))
+		m.logger.Errorf("failed to recompute cached health before migration: %v", err)
+		return false
+	}
+	m.logger.Infof("recomputed slab health in %v", time.Since(start))
+
+	// fetch slabs for migration
+	toMigrateNew, err := b.SlabsForMigration(m.ap.shutdownCtx, healthCutoff, set, migratorBatchSize)
+	if err != nil {
+		m.logger.Errorf("failed to fetch slabs for migration, err: %v", err)
+		return false
+	}
+	m.logger.Infof("%d potential slabs fetched for migration", len(toMigrateNew))
+
+	// look up the buckets of the slabs if bucket priorities are configured,
+	// slabs that were looked up before are skipped
+	buckets := make(map[object.EncryptionKey]map[string][]string)
+	if len(cfg.BucketPriorities) > 0 {
+		for _, slab := range toMigrateNew {
+			if !m.queue.NeedsObjects(slab.Key) {
+				continue
+			} else if objects, err := m.objectIDsForSlabKey(m.ap.shutdownCtx, slab.Key); err != nil {
+				m.logger.Errorf("failed to fetch object ids for slab key; %v", err)
+			} else {
+				buckets[slab.Key] = objects
+			}
+		}
+	}
+
+	// update the queue, slabs that no longer require migration are removed,
+	// slabs that have been queued before will be repaired before any new
+	// slabs of the same priority to prevent starvation
+	m.queue.Update(toMigrateNew, buckets, cfg)
+	return true
+}
+
 func (m *migrator) objectIDsForSlabKey(ctx context.Context, key object.EncryptionKey) (map[string][]string, error) {
 	// fetch all buckets
 	//
@@ -319,3 +387,32 @@ func (m *migrator) objectIDsForSlabKey(ctx context.Context, key object.Encryptio
 
 	return idsPerBucket, nil
 }
+
+// slabHealth computes the health of the slab given the contracts in the
+// contract set, it mirrors the way the bus computes the health of a slab.
+func slabHealth(slab object.Slab, set []api.ContractMetadata) float64 {
+	inSet := make(map[types.FileContractID]struct{})
+	for _, c := range set {
+		inSet[c.ID] = struct{}{}
+	}
+	hosts := make(map[types.PublicKey]struct{})
+	for _, shard := range slab.Shards {
+		for hk, fcids := range shard.Contracts {
+			for _, fcid := range fcids {
+				if _, ok := inSet[fcid]; ok {
+					hosts[hk] = struct{}{}
+					break
+				}
+			}
+		}
+	}
+	good := len(hosts)
+	minShards, totalShards := int(slab.MinShards), len(slab.Shards)
+	if minShards == totalShards {
+		if good < minShards {
+			return -1
+		}
+		return 1
+	}
+	return float64(good-minShards) / float64(totalShards-minShards)
+}
@@ -0,0 +1,89 @@
+package autopilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.sia.tech/jape"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/utils"
+)
+
+// loadPausedSubsystems loads the paused subsystems from the bus. It holds the
+// same lock as setPaused so a stale load can't overwrite a concurrent update.
+func (ap *Autopilot) loadPausedSubsystems(ctx context.Context) error {
+	ap.pauseMu.Lock()
+	defer ap.pauseMu.Unlock()
+
+	var paused api.AutopilotPausedSubsystems
+	if err := ap.bus.Setting(ctx, api.SettingAutopilotPaused, &paused); err != nil && !utils.IsErr(err, api.ErrSettingNotFound) {
+		return fmt.Errorf("failed to fetch paused subsystems: %w", err)
+	}
+
+	ap.mu.Lock()
+	ap.paused = paused
+	ap.mu.Unlock()
+	if paused != (api.AutopilotPausedSubsystems{}) {
+		ap.logger.Infow("loaded paused subsystems", "paused", paused)
+	}
+	return nil
+}
+
+// isPaused returns whether the given subsystem is paused.
+func (ap *Autopilot) isPaused(subsystem string) bool {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	return ap.paused.IsPaused(subsystem)
+}
+
+// setPaused pauses or resumes the given subsystem and persists the change in
+// the bus.
+func (ap *Autopilot) setPaused(ctx context.Context, subsystem string, paused bool) error {
+	ap.pauseMu.Lock()
+	defer ap.pauseMu.Unlock()
+
+	ap.mu.Lock()
+	update := ap.paused
+	ap.mu.Unlock()
+	if err := update.SetPaused(subsystem, paused); err != nil {
+		return err
+	} else if err := ap.bus.UpdateSetting(ctx, api.SettingAutopilotPaused, update); err != nil {
+		return fmt.Errorf("failed to persist paused subsystems: %w", err)
+	}
+
+	ap.mu.Lock()
+	ap.paused = update
+	ap.mu.Unlock()
+	if paused {
+		ap.logger.Infof("%s paused", subsystem)
+	} else {
+		ap.logger.Infof("%s resumed", subsystem)
+	}
+	return nil
+}
+
+func (ap *Autopilot) pauseHandlerPOST(jc jape.Context) {
+	ap.handlePauseRequest(jc, true)
+}
+
+func (ap *Autopilot) resumeHandlerPOST(jc jape.Context) {
+	ap.handlePauseRequest(jc, false)
+}
+
+func (ap *Autopilot) handlePauseRequest(jc jape.Context, paused bool) {
+	err := ap.setPaused(jc.Request.Context(), jc.PathParam("subsystem"), paused)
+	if errors.Is(err, api.ErrUnknownAutopilotSubsystem) {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	} else if jc.Check("failed to update paused subsystems", err) != nil {
+		return
+	}
+
+	// resuming a subsystem triggers an iteration so it doesn't have to wait
+	// for the next tick
+	if !paused {
+		ap.Trigger(false)
+	}
+}
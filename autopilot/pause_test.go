@@ -0,0 +1,250 @@
+package autopilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+)
+
+// pauseBusMock implements the parts of the bus used when pausing and resuming
+// subsystems, calling any other method panics.
+type pauseBusMock struct {
+	Bus
+
+	// blockSetting, if set, is closed to unblock the next call to Setting,
+	// settingCalled is closed once that call was made
+	blockSetting  chan struct{}
+	settingCalled chan struct{}
+
+	mu             sync.Mutex
+	settings       map[string][]byte
+	autopilotCalls int
+	scanCalls      int
+}
+
+func newPauseBusMock() *pauseBusMock {
+	return &pauseBusMock{settings: make(map[string][]byte)}
+}
+
+func (b *pauseBusMock) Setting(ctx context.Context, key string, value interface{}) error {
+	b.mu.Lock()
+	setting, exists := b.settings[key]
+	blockChan, calledChan := b.blockSetting, b.settingCalled
+	b.blockSetting, b.settingCalled = nil, nil
+	b.mu.Unlock()
+
+	if blockChan != nil {
+		close(calledChan)
+		<-blockChan
+	}
+	if !exists {
+		return api.ErrSettingNotFound
+	}
+	return json.Unmarshal(setting, value)
+}
+
+func (b *pauseBusMock) UpdateSetting(ctx context.Context, key string, value interface{}) error {
+	setting, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.settings[key] = setting
+	return nil
+}
+
+func (b *pauseBusMock) Autopilot(ctx context.Context, id string) (api.Autopilot, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.autopilotCalls++
+	return api.Autopilot{}, api.ErrAutopilotNotFound
+}
+
+func (b *pauseBusMock) ConsensusState(ctx context.Context) (api.ConsensusState, error) {
+	return api.ConsensusState{}, nil // never synced
+}
+
+func (b *pauseBusMock) HostsForScanning(ctx context.Context, opts api.HostsForScanningOptions) ([]api.HostAddress, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.scanCalls++
+	return nil, nil
+}
+
+func (b *pauseBusMock) RemoveOfflineHosts(ctx context.Context, minRecentScanFailures uint64, maxDowntime time.Duration) (uint64, error) {
+	return 0, nil
+}
+
+func (b *pauseBusMock) SyncerPeers(ctx context.Context) ([]string, error) {
+	return []string{"peer"}, nil
+}
+
+func (b *pauseBusMock) Wallet(ctx context.Context) (api.WalletResponse, error) {
+	return api.WalletResponse{Confirmed: types.Siacoins(1)}, nil
+}
+
+func (b *pauseBusMock) calls() (autopilot, scans int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.autopilotCalls, b.scanCalls
+}
+
+func newPauseTestAutopilot(t *testing.T, b *pauseBusMock) *Autopilot {
+	t.Helper()
+	ap, err := New("test", b, []Worker{struct{ Worker }{}}, zap.NewNop(), 10*time.Millisecond, time.Hour, 10, 1, 0.75, 10*time.Millisecond, 0, 1, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ap
+}
+
+func TestPausedSubsystems(t *testing.T) {
+	b := newPauseBusMock()
+	ap := newPauseTestAutopilot(t, b)
+	subsystems := []string{
+		api.AutopilotSubsystemAccountRefills,
+		api.AutopilotSubsystemContractor,
+		api.AutopilotSubsystemMigrator,
+		api.AutopilotSubsystemPruning,
+		api.AutopilotSubsystemScanner,
+	}
+
+	// assert unknown subsystems are rejected
+	if err := ap.setPaused(context.Background(), "foo", true); !errors.Is(err, api.ErrUnknownAutopilotSubsystem) {
+		t.Fatal("unexpected error", err)
+	}
+
+	// pause and resume every subsystem, assert the others are unaffected
+	for _, subsystem := range subsystems {
+		if err := ap.setPaused(context.Background(), subsystem, true); err != nil {
+			t.Fatal(err)
+		}
+		for _, other := range subsystems {
+			if paused := ap.isPaused(other); paused != (other == subsystem) {
+				t.Fatalf("unexpected paused state for %v after pausing %v: %v", other, subsystem, paused)
+			}
+		}
+		if err := ap.setPaused(context.Background(), subsystem, false); err != nil {
+			t.Fatal(err)
+		} else if ap.isPaused(subsystem) {
+			t.Fatalf("%v wasn't resumed", subsystem)
+		}
+	}
+
+	// pause the migrator and assert the state survives a restart
+	if err := ap.setPaused(context.Background(), api.AutopilotSubsystemMigrator, true); err != nil {
+		t.Fatal(err)
+	}
+	ap = newPauseTestAutopilot(t, b)
+	if ap.isPaused(api.AutopilotSubsystemMigrator) {
+		t.Fatal("expected paused subsystems to be loaded lazily")
+	} else if err := ap.loadPausedSubsystems(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !ap.isPaused(api.AutopilotSubsystemMigrator) {
+		t.Fatal("expected migrator to be paused after restart")
+	} else if ap.isPaused(api.AutopilotSubsystemScanner) {
+		t.Fatal("expected scanner not to be paused after restart")
+	}
+}
+
+func TestPausedSubsystemsStaleLoad(t *testing.T) {
+	b := newPauseBusMock()
+	ap := newPauseTestAutopilot(t, b)
+
+	// start loading the paused subsystems and block it after it fetched the
+	// setting from the bus
+	b.blockSetting, b.settingCalled = make(chan struct{}), make(chan struct{})
+	blockChan, calledChan := b.blockSetting, b.settingCalled
+	loadErr := make(chan error, 1)
+	go func() { loadErr <- ap.loadPausedSubsystems(context.Background()) }()
+	<-calledChan
+
+	// pause the scanner while the load is in progress
+	pauseErr := make(chan error, 1)
+	go func() { pauseErr <- ap.setPaused(context.Background(), api.AutopilotSubsystemScanner, true) }()
+	select {
+	case err := <-pauseErr:
+		t.Fatal("pause didn't wait for the load to finish", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// unblock the load and assert it didn't overwrite the pause
+	close(blockChan)
+	if err := <-loadErr; err != nil {
+		t.Fatal(err)
+	} else if err := <-pauseErr; err != nil {
+		t.Fatal(err)
+	} else if !ap.isPaused(api.AutopilotSubsystemScanner) {
+		t.Fatal("expected scanner to be paused")
+	}
+}
+
+func TestPausedSubsystemsHonored(t *testing.T) {
+	b := newPauseBusMock()
+	ap := newPauseTestAutopilot(t, b)
+	defer ap.Shutdown(context.Background())
+
+	// pause everything
+	for _, subsystem := range []string{api.AutopilotSubsystemAccountRefills, api.AutopilotSubsystemMigrator, api.AutopilotSubsystemScanner} {
+		if err := ap.setPaused(context.Background(), subsystem, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// assert the migrator returns before fetching the config
+	ap.m.performMigrations(newWorkerPool(nil))
+	if autopilotCalls, _ := b.calls(); autopilotCalls != 0 {
+		t.Fatal("migrator wasn't paused", autopilotCalls)
+	}
+
+	// assert account refills don't fetch the config
+	ctx, cancel := context.WithCancel(context.Background())
+	refillsDone := make(chan struct{})
+	go func() {
+		ap.a.refillWorkersAccountsLoop(ctx)
+		close(refillsDone)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if autopilotCalls, _ := b.calls(); autopilotCalls != 0 {
+		t.Fatal("account refills weren't paused", autopilotCalls)
+	}
+
+	// run another autopilot that loads the state from the bus, assert it
+	// doesn't scan hosts while the scanner is paused
+	ap2 := newPauseTestAutopilot(t, b)
+	defer ap2.Shutdown(context.Background())
+	go ap2.Run()
+	time.Sleep(100 * time.Millisecond)
+	if _, scans := b.calls(); scans != 0 {
+		t.Fatal("scanner wasn't paused", scans)
+	}
+
+	// resume everything and assert the subsystems pick up work again
+	for _, subsystem := range []string{api.AutopilotSubsystemAccountRefills, api.AutopilotSubsystemMigrator, api.AutopilotSubsystemScanner} {
+		if err := ap.setPaused(context.Background(), subsystem, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	if autopilotCalls, scans := b.calls(); scans == 0 {
+		t.Fatal("scanner wasn't resumed")
+	} else if autopilotCalls == 0 {
+		t.Fatal("account refills weren't resumed")
+	}
+	cancel()
+	<-refillsDone
+
+	autopilotCalls, _ := b.calls()
+	ap.m.performMigrations(newWorkerPool(nil))
+	if n, _ := b.calls(); n == autopilotCalls {
+		t.Fatal("migrator wasn't resumed")
+	}
+}
@@ -0,0 +1,42 @@
+package autopilot
+
+import (
+	"context"
+	"time"
+
+	"go.sia.tech/renterd/api"
+)
+
+// isScheduled returns true if the autopilot's schedule allows the activity to
+// run right now. Activities are allowed if the config can't be fetched, e.g.
+// because the autopilot isn't configured yet.
+func (ap *Autopilot) isScheduled(ctx context.Context, activity string) bool {
+	autopilot, err := ap.Config(ctx)
+	if err != nil {
+		return true
+	}
+	return autopilot.Config.Schedule.Allows(activity, time.Now())
+}
+
+// migrationHealthCutoff returns the health cutoff to use for migrations at the
+// given time. Outside of the migration windows only critical slabs are
+// migrated.
+func migrationHealthCutoff(cfg api.AutopilotConfig, healthCutoff float64, t time.Time) (float64, bool) {
+	if cfg.Schedule.Allows(api.ScheduleActivityMigrations, t) {
+		return healthCutoff, true
+	}
+	return criticalHealthCutoff(cfg, healthCutoff)
+}
+
+// criticalHealthCutoff returns the health cutoff to use when only slabs below
+// the critical migration health are migrated, if the critical migration
+// health is not set migrations are stopped entirely which is indicated by the
+// boolean.
+func criticalHealthCutoff(cfg api.AutopilotConfig, healthCutoff float64) (float64, bool) {
+	if cfg.Schedule == nil || cfg.Schedule.CriticalMigrationHealth == 0 {
+		return 0, false
+	} else if cfg.Schedule.CriticalMigrationHealth < healthCutoff {
+		return cfg.Schedule.CriticalMigrationHealth, true
+	}
+	return healthCutoff, true
+}
@@ -0,0 +1,125 @@
+package autopilot
+
+import (
+	"testing"
+	"time"
+
+	"go.sia.tech/renterd/api"
+)
+
+func TestMigrationHealthCutoff(t *testing.T) {
+	// monday 2024-01-01 is used as reference
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	// no schedule means migrations are always allowed
+	var cfg api.AutopilotConfig
+	if cutoff, ok := migrationHealthCutoff(cfg, 0.75, monday(12, 0)); !ok || cutoff != 0.75 {
+		t.Fatal("unexpected", cutoff, ok)
+	}
+
+	// allow migrations on weekdays between 22:00 and 06:00
+	cfg.Schedule = &api.AutopilotSchedule{
+		Migrations: []api.MaintenanceWindow{{
+			Start:    "0 22 * * 1-5",
+			Duration: api.DurationMS(8 * time.Hour),
+		}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		t       time.Time
+		allowed bool
+	}{
+		{monday(21, 59), false},
+		{monday(22, 0), true},
+		{monday(23, 30), true},
+		{monday(5, 59).Add(24 * time.Hour), true},       // tuesday 05:59
+		{monday(6, 0).Add(24 * time.Hour), false},       // tuesday 06:00
+		{monday(22, 0).Add(-2 * 24 * time.Hour), false}, // saturday 22:00
+		{monday(2, 0), false},                           // monday 02:00, window opened on sunday
+		{monday(2, 0).Add(-2 * 24 * time.Hour), true},   // saturday 02:00, window opened on friday
+	}
+	for i, test := range tests {
+		if _, ok := migrationHealthCutoff(cfg, 0.75, test.t); ok != test.allowed {
+			t.Fatalf("%d: unexpected result for %v: %v", i, test.t, ok)
+		}
+	}
+
+	// assert critical slabs are still migrated outside of the window
+	cfg.Schedule.CriticalMigrationHealth = 0.25
+	if cutoff, ok := migrationHealthCutoff(cfg, 0.75, monday(12, 0)); !ok || cutoff != 0.25 {
+		t.Fatal("unexpected", cutoff, ok)
+	} else if cutoff, ok := migrationHealthCutoff(cfg, 0.75, monday(23, 0)); !ok || cutoff != 0.75 {
+		t.Fatal("unexpected", cutoff, ok)
+	}
+
+	// assert the timezone is taken into account
+	cfg.Schedule.Timezone = "Etc/GMT-2" // UTC+2
+	if _, ok := migrationHealthCutoff(cfg, 0.75, monday(20, 0)); !ok {
+		t.Fatal("expected migrations to be allowed")
+	}
+
+	// assert invalid schedules are rejected
+	for _, start := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		cfg.Schedule.Migrations[0].Start = start
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("expected '%s' to be invalid", start)
+		}
+	}
+	cfg.Schedule.Migrations[0].Start = "*/15 0-6,22,23 * * *"
+	cfg.Schedule.Migrations[0].Duration = 0
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected zero duration to be invalid")
+	}
+}
+
+func TestMaintenanceWindowActive(t *testing.T) {
+	// activeAt is the reference implementation, a window is active if it
+	// started less than its duration ago, a one minute window is active
+	// exactly when its expression matches
+	activeAt := func(w api.MaintenanceWindow, at time.Time) bool {
+		match := api.MaintenanceWindow{Start: w.Start, Duration: api.DurationMS(time.Minute)}
+		for elapsed := time.Duration(0); elapsed < time.Duration(w.Duration); elapsed += time.Minute {
+			if match.Active(at.Add(-elapsed)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	windows := []api.MaintenanceWindow{
+		{Start: "0 22 * * 1-5", Duration: api.DurationMS(8 * time.Hour)},
+		{Start: "*/15 0-6,22,23 * * *", Duration: api.DurationMS(5 * time.Minute)},
+		{Start: "30 2 1,15 * 0", Duration: api.DurationMS(36 * time.Hour)},
+		{Start: "0 0 29 2 *", Duration: api.DurationMS(7 * 24 * time.Hour)},
+		{Start: "59 23 31 12 *", Duration: api.DurationMS(2 * time.Minute)},
+	}
+
+	ams, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	times := []time.Time{
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 0, 0, 30, 0, time.UTC),
+		time.Date(2024, 3, 6, 23, 59, 0, 0, time.UTC),
+		time.Date(2024, 3, 31, 3, 10, 0, 0, ams), // DST starts at 02:00
+		time.Date(2024, 12, 15, 14, 0, 0, 0, ams),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for _, w := range windows {
+		for _, at := range times {
+			for _, offset := range []time.Duration{0, -time.Minute, 7 * time.Hour, 22*time.Hour + 14*time.Minute, 2 * 24 * time.Hour} {
+				at := at.Add(offset)
+				if got, want := w.Active(at), activeAt(w, at); got != want {
+					t.Fatalf("window '%s' (%v) at %v: expected %v, got %v", w.Start, time.Duration(w.Duration), at, want, got)
+				}
+			}
+		}
+	}
+}
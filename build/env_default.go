@@ -15,6 +15,7 @@ const (
 	DefaultAPIAddress     = "localhost:9980"
 	DefaultGatewayAddress = ":9981"
 	DefaultS3Address      = "localhost:8080"
+	DefaultWebDAVAddress  = "localhost:8081"
 )
 
 var (
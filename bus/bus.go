@@ -8,9 +8,11 @@ import (
 	"io"
 	"math"
 	"net/http"
+	"os"
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"go.sia.tech/core/consensus"
@@ -23,6 +25,7 @@ import (
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/build"
 	"go.sia.tech/renterd/bus/client"
+	"go.sia.tech/renterd/internal/backup"
 	ibus "go.sia.tech/renterd/internal/bus"
 	"go.sia.tech/renterd/object"
 	"go.sia.tech/renterd/wallet"
@@ -79,14 +82,19 @@ type (
 	// A Wallet can spend and receive siacoins.
 	Wallet interface {
 		Address() types.Address
+		Addresses() ([]api.WalletAddress, error)
 		Balance() (spendable, confirmed, unconfirmed types.Currency, _ error)
+		Consolidate(cs consensus.State, maxValue types.Currency, maxInputs int, feePerByte types.Currency, pool []types.Transaction) (types.Transaction, []types.Hash256, error)
 		FundTransaction(cs consensus.State, txn *types.Transaction, amount types.Currency, useUnconfirmedTxns bool) ([]types.Hash256, error)
 		Height() uint64
+		NewAddress() (types.Address, error)
 		Redistribute(cs consensus.State, outputs int, amount, feePerByte types.Currency, pool []types.Transaction) ([]types.Transaction, []types.Hash256, error)
 		ReleaseInputs(txn ...types.Transaction)
+		Send(cs consensus.State, addr types.Address, amount, feePerByte types.Currency, opts wallet.SendOptions) (types.Transaction, []types.Hash256, error)
 		SignTransaction(cs consensus.State, txn *types.Transaction, toSign []types.Hash256, cf types.CoveredFields) error
 		Transactions(before, since time.Time, offset, limit int) ([]wallet.Transaction, error)
 		UnspentOutputs() ([]wallet.SiacoinElement, error)
+		WatchOnly() bool
 	}
 
 	// A HostDB stores information about hosts.
@@ -113,6 +121,7 @@ type (
 		ArchiveContract(ctx context.Context, id types.FileContractID, reason string) error
 		ArchiveContracts(ctx context.Context, toArchive map[types.FileContractID]string) error
 		ArchiveAllContracts(ctx context.Context, reason string) error
+		Backup(ctx context.Context, dir string) error
 		Contract(ctx context.Context, id types.FileContractID) (api.ContractMetadata, error)
 		Contracts(ctx context.Context, opts api.ContractsOpts) ([]api.ContractMetadata, error)
 		ContractSets(ctx context.Context) ([]string, error)
@@ -121,6 +130,7 @@ type (
 		RenewedContract(ctx context.Context, renewedFrom types.FileContractID) (api.ContractMetadata, error)
 		SetContractSet(ctx context.Context, set string, contracts []types.FileContractID) error
 
+		ContractHostKeys(ctx context.Context) (map[types.FileContractID]types.PublicKey, error)
 		ContractRoots(ctx context.Context, id types.FileContractID) ([]types.Hash256, error)
 		ContractSizes(ctx context.Context) (map[types.FileContractID]api.ContractSize, error)
 		ContractSize(ctx context.Context, id types.FileContractID) (api.ContractSize, error)
@@ -163,6 +173,7 @@ type (
 		FetchPartialSlab(ctx context.Context, key object.EncryptionKey, offset, length uint32) ([]byte, error)
 		Slab(ctx context.Context, key object.EncryptionKey) (object.Slab, error)
 		RefreshHealth(ctx context.Context) error
+		Fsck(ctx context.Context, repair bool) ([]api.FsckIssue, error)
 		UnhealthySlabs(ctx context.Context, healthCutoff float64, set string, limit int) ([]api.UnhealthySlab, error)
 		UpdateSlab(ctx context.Context, s object.Slab, contractSet string) error
 	}
@@ -226,12 +237,19 @@ type bus struct {
 	accounts         *accounts
 	contractLocks    *contractLocks
 	uploadingSectors *uploadingSectorsCache
+	signing          *signingQueue
 
 	alerts   alerts.Alerter
 	alertMgr *alerts.Manager
 	events   ibus.EventBroadcaster
 	hooks    *webhooks.Manager
 	logger   *zap.SugaredLogger
+
+	// backupDir is the directory backups are staged in before they are
+	// written to the client
+	backupDir string
+
+	labelsMu sync.Mutex
 }
 
 // Handler returns an HTTP handler that serves the bus API.
@@ -256,6 +274,8 @@ func (b *bus) Handler() http.Handler {
 
 		"PUT    /autopilot/:id/host/:hostkey/check": b.autopilotHostCheckHandlerPUT,
 
+		"POST   /backup": b.backupHandlerPOST,
+
 		"GET    /buckets":             b.bucketsHandlerGET,
 		"POST   /buckets":             b.bucketsHandlerPOST,
 		"PUT    /bucket/:name/policy": b.bucketsHandlerPolicyPUT,
@@ -287,6 +307,8 @@ func (b *bus) Handler() http.Handler {
 		"GET    /contract/:id/roots":     b.contractIDRootsHandlerGET,
 		"GET    /contract/:id/size":      b.contractSizeHandlerGET,
 
+		"POST   /fsck": b.fsckHandlerPOST,
+
 		"GET    /hosts":                          b.hostsHandlerGETDeprecated,
 		"GET    /hosts/allowlist":                b.hostsAllowlistHandlerGET,
 		"PUT    /hosts/allowlist":                b.hostsAllowlistHandlerPUT,
@@ -359,14 +381,24 @@ func (b *bus) Handler() http.Handler {
 		"POST   /upload/:id/sector": b.uploadAddSectorHandlerPOST,
 
 		"GET    /wallet":               b.walletHandler,
+		"GET    /wallet/addresses":     b.walletAddressesHandlerGET,
+		"POST   /wallet/addresses":     b.walletAddressesHandlerPOST,
+		"POST   /wallet/consolidate":   b.walletConsolidateHandler,
 		"POST   /wallet/discard":       b.walletDiscardHandler,
+		"GET    /wallet/export":        b.walletExportHandler,
 		"POST   /wallet/fund":          b.walletFundHandler,
+		"GET    /wallet/labels":        b.walletLabelsHandlerGET,
+		"PUT    /wallet/labels/:id":    b.walletLabelHandlerPUT,
 		"GET    /wallet/outputs":       b.walletOutputsHandler,
 		"GET    /wallet/pending":       b.walletPendingHandler,
 		"POST   /wallet/prepare/form":  b.walletPrepareFormHandler,
 		"POST   /wallet/prepare/renew": b.walletPrepareRenewHandler,
 		"POST   /wallet/redistribute":  b.walletRedistributeHandler,
+		"POST   /wallet/send":          b.walletSendHandler,
 		"POST   /wallet/sign":          b.walletSignHandler,
+		"GET    /wallet/signing":       b.walletSigningHandlerGET,
+		"POST   /wallet/signing":       b.walletSigningHandlerPOST,
+		"DELETE /wallet/signing/:id":   b.walletSigningHandlerDELETE,
 		"GET    /wallet/transactions":  b.walletTransactionsHandler,
 
 		"GET    /webhooks":        b.webhookHandlerGet,
@@ -468,6 +500,9 @@ func (b *bus) bucketsHandlerPOST(jc jape.Context) {
 	} else if bucket.Name == "" {
 		jc.Error(errors.New("no name provided"), http.StatusBadRequest)
 		return
+	} else if err := bucket.Policy.Validate(); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
 	} else if jc.Check("failed to create bucket", b.ms.CreateBucket(jc.Request.Context(), bucket.Name, bucket.Policy)) != nil {
 		return
 	}
@@ -480,6 +515,9 @@ func (b *bus) bucketsHandlerPolicyPUT(jc jape.Context) {
 	} else if bucket := jc.PathParam("name"); bucket == "" {
 		jc.Error(errors.New("no bucket name provided"), http.StatusBadRequest)
 		return
+	} else if err := req.Policy.Validate(); err != nil {
+		jc.Error(err, http.StatusBadRequest)
+		return
 	} else if jc.Check("failed to create bucket", b.ms.UpdateBucketPolicy(jc.Request.Context(), bucket, req.Policy)) != nil {
 		return
 	}
@@ -521,15 +559,35 @@ func (b *bus) walletHandler(jc jape.Context) {
 	if jc.Check("couldn't fetch wallet balance", err) != nil {
 		return
 	}
+	utxos, err := b.w.UnspentOutputs()
+	if jc.Check("couldn't load outputs", err) != nil {
+		return
+	}
 	jc.Encode(api.WalletResponse{
 		ScanHeight:  b.w.Height(),
 		Address:     address,
 		Confirmed:   confirmed,
 		Spendable:   spendable,
 		Unconfirmed: unconfirmed,
+
+		Fragmentation: wallet.Fragmentation(utxos, b.tp.RecommendedFee()),
 	})
 }
 
+func (b *bus) walletAddressesHandlerGET(jc jape.Context) {
+	addrs, err := b.w.Addresses()
+	if jc.Check("couldn't fetch wallet addresses", err) == nil {
+		jc.Encode(addrs)
+	}
+}
+
+func (b *bus) walletAddressesHandlerPOST(jc jape.Context) {
+	addr, err := b.w.NewAddress()
+	if jc.Check("couldn't generate wallet address", err) == nil {
+		jc.Encode(addr)
+	}
+}
+
 func (b *bus) walletTransactionsHandler(jc jape.Context) {
 	var before, since time.Time
 	offset := 0
@@ -546,6 +604,64 @@ func (b *bus) walletTransactionsHandler(jc jape.Context) {
 	}
 }
 
+func (b *bus) walletExportHandler(jc jape.Context) {
+	var before, since time.Time
+	format := api.WalletExportFormatJSON
+	if jc.DecodeForm("before", (*api.TimeRFC3339)(&before)) != nil ||
+		jc.DecodeForm("since", (*api.TimeRFC3339)(&since)) != nil ||
+		jc.DecodeForm("format", &format) != nil {
+		return
+	} else if format != api.WalletExportFormatCSV && format != api.WalletExportFormatJSON {
+		jc.Error(fmt.Errorf("invalid format '%s'", format), http.StatusBadRequest)
+		return
+	}
+
+	txns, err := b.w.Transactions(before, since, 0, -1)
+	if jc.Check("couldn't load transactions", err) != nil {
+		return
+	}
+	contracts, err := b.knownContracts(jc.Request.Context())
+	if jc.Check("couldn't load contracts", err) != nil {
+		return
+	}
+	labels, err := b.walletLabels(jc.Request.Context())
+	if jc.Check("couldn't load labels", err) != nil {
+		return
+	}
+
+	// export the transactions in chronological order
+	exported := make([]api.WalletTransaction, len(txns))
+	for i, txn := range txns {
+		exported[len(txns)-1-i] = classifyTransaction(txn, contracts, labels)
+	}
+
+	if format == api.WalletExportFormatJSON {
+		jc.Encode(exported)
+		return
+	}
+	jc.ResponseWriter.Header().Set("Content-Type", "text/csv")
+	jc.ResponseWriter.Header().Set("Content-Disposition", `attachment; filename="transactions.csv"`)
+	if err := writeTransactionsCSV(jc.ResponseWriter, exported); err != nil {
+		b.logger.Errorf("failed to write transactions export: %v", err)
+	}
+}
+
+func (b *bus) walletLabelsHandlerGET(jc jape.Context) {
+	labels, err := b.walletLabels(jc.Request.Context())
+	if jc.Check("couldn't load labels", err) == nil {
+		jc.Encode(labels)
+	}
+}
+
+func (b *bus) walletLabelHandlerPUT(jc jape.Context) {
+	var id types.TransactionID
+	var req api.WalletLabelRequest
+	if jc.DecodeParam("id", &id) != nil || jc.Decode(&req) != nil {
+		return
+	}
+	jc.Check("couldn't update label", b.updateWalletLabel(jc.Request.Context(), id, req.Label))
+}
+
 func (b *bus) walletOutputsHandler(jc jape.Context) {
 	utxos, err := b.w.UnspentOutputs()
 	if jc.Check("couldn't load outputs", err) == nil {
@@ -580,12 +696,89 @@ func (b *bus) walletFundHandler(jc jape.Context) {
 	})
 }
 
+func (b *bus) walletSendHandler(jc jape.Context) {
+	var req api.WalletSendRequest
+	if jc.Decode(&req) != nil {
+		return
+	} else if req.Address == types.VoidAddress {
+		jc.Error(errors.New("'address' is required"), http.StatusBadRequest)
+		return
+	} else if req.Amount.IsZero() {
+		jc.Error(errors.New("'amount' has to be greater than zero"), http.StatusBadRequest)
+		return
+	}
+
+	// estimate the fee
+	feePerByte := req.FeePerByte
+	if feePerByte.IsZero() {
+		feePerByte = b.tp.RecommendedFee()
+	}
+
+	// build the transaction
+	cs := b.cm.TipState()
+	txn, toSign, err := b.w.Send(cs, req.Address, req.Amount, feePerByte, wallet.SendOptions{
+		Inputs:         req.Inputs,
+		SubtractFee:    req.SubtractMinerFee,
+		UseUnconfirmed: req.UseUnconfirmedTxns,
+		DryRun:         req.DryRun,
+	})
+	if errors.Is(err, wallet.ErrInvalidInput) || errors.Is(err, wallet.ErrAmountTooSmall) {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	} else if jc.Check("couldn't fund transaction", err) != nil {
+		return
+	}
+
+	// sign it, a watch-only wallet queues it for the external signer instead
+	cf := types.CoveredFields{WholeTransaction: true}
+	if !b.w.WatchOnly() {
+		err = b.w.SignTransaction(cs, &txn, toSign, cf)
+		if jc.Check("couldn't sign transaction", err) != nil {
+			b.w.ReleaseInputs(txn)
+			return
+		}
+	}
+	parents, err := b.tp.UnconfirmedParents(txn)
+	if jc.Check("couldn't load transaction dependencies", err) != nil {
+		b.w.ReleaseInputs(txn)
+		return
+	}
+
+	// broadcast it, unless it's a dry run
+	var bundleID *types.Hash256
+	if req.DryRun {
+		b.w.ReleaseInputs(txn)
+	} else if b.w.WatchOnly() {
+		bundle := b.signing.Add("", api.WalletTxnTypeSend, cs.Index.Height, append(parents, txn), toSign, cf, true, types.ZeroCurrency)
+		bundleID = &bundle.ID
+	} else if jc.Check("couldn't broadcast transaction", b.tp.AcceptTransactionSet(append(parents, txn))) != nil {
+		b.w.ReleaseInputs(txn)
+		return
+	}
+
+	jc.Encode(api.WalletSendResponse{
+		ID:            txn.ID(),
+		Transaction:   txn,
+		DependsOn:     parents,
+		Amount:        txn.SiacoinOutputs[0].Value,
+		MinerFee:      txn.MinerFees[0],
+		FeePerByte:    feePerByte,
+		Broadcast:     !req.DryRun && bundleID == nil,
+		SigningBundle: bundleID,
+	})
+}
+
 func (b *bus) walletSignHandler(jc jape.Context) {
 	var wsr api.WalletSignRequest
 	if jc.Decode(&wsr) != nil {
 		return
 	}
 	err := b.w.SignTransaction(b.cm.TipState(), &wsr.Transaction, wsr.ToSign, wsr.CoveredFields)
+	if errors.Is(err, wallet.ErrWatchOnly) {
+		// the inputs of renewals were signed by the external signer before
+		// the host added its inputs
+		err = b.signing.ApplyPresigned(&wsr.Transaction, wsr.ToSign)
+	}
 	if jc.Check("couldn't sign transaction", err) == nil {
 		jc.Encode(wsr.Transaction)
 	}
@@ -613,8 +806,18 @@ func (b *bus) walletRedistributeHandler(jc jape.Context) {
 		return
 	}
 
+	cf := types.CoveredFields{WholeTransaction: true}
+	if b.w.WatchOnly() {
+		for _, txn := range txns {
+			b.signing.Add("", api.WalletTxnTypeInternal, cs.Index.Height, []types.Transaction{txn}, inputsOf(txn, toSign), cf, true, types.ZeroCurrency)
+			ids = append(ids, txn.ID())
+		}
+		jc.Encode(ids)
+		return
+	}
+
 	for i := 0; i < len(txns); i++ {
-		err = b.w.SignTransaction(cs, &txns[i], toSign, types.CoveredFields{WholeTransaction: true})
+		err = b.w.SignTransaction(cs, &txns[i], inputsOf(txns[i], toSign), cf)
 		if jc.Check("couldn't sign the transaction", err) != nil {
 			b.w.ReleaseInputs(txns...)
 			return
@@ -630,6 +833,50 @@ func (b *bus) walletRedistributeHandler(jc jape.Context) {
 	jc.Encode(ids)
 }
 
+func (b *bus) walletConsolidateHandler(jc jape.Context) {
+	var req api.WalletConsolidateRequest
+	if jc.Decode(&req) != nil {
+		return
+	}
+	if req.MaxInputs < 2 {
+		jc.Error(errors.New("'maxInputs' has to be at least 2"), http.StatusBadRequest)
+		return
+	} else if req.MaxOutputValue.IsZero() {
+		jc.Error(errors.New("'maxOutputValue' has to be greater than zero"), http.StatusBadRequest)
+		return
+	}
+
+	cs := b.cm.TipState()
+	txn, toSign, err := b.w.Consolidate(cs, req.MaxOutputValue, req.MaxInputs, b.tp.RecommendedFee(), b.tp.Transactions())
+	if jc.Check("couldn't consolidate the outputs in the wallet", err) != nil {
+		return
+	}
+
+	ids := []types.TransactionID{}
+	if len(txn.SiacoinInputs) == 0 {
+		jc.Encode(ids)
+		return
+	}
+
+	cf := types.CoveredFields{WholeTransaction: true}
+	if b.w.WatchOnly() {
+		b.signing.Add("", api.WalletTxnTypeInternal, cs.Index.Height, []types.Transaction{txn}, toSign, cf, true, types.ZeroCurrency)
+		jc.Encode(append(ids, txn.ID()))
+		return
+	}
+
+	err = b.w.SignTransaction(cs, &txn, toSign, cf)
+	if jc.Check("couldn't sign the transaction", err) != nil {
+		b.w.ReleaseInputs(txn)
+		return
+	}
+	if jc.Check("couldn't broadcast the transaction", b.tp.AcceptTransactionSet([]types.Transaction{txn})) != nil {
+		b.w.ReleaseInputs(txn)
+		return
+	}
+	jc.Encode(append(ids, txn.ID()))
+}
+
 func (b *bus) walletDiscardHandler(jc jape.Context) {
 	var txn types.Transaction
 	if jc.Decode(&txn) == nil {
@@ -637,6 +884,71 @@ func (b *bus) walletDiscardHandler(jc jape.Context) {
 	}
 }
 
+func (b *bus) walletSigningHandlerGET(jc jape.Context) {
+	jc.Encode(b.signing.Bundles())
+}
+
+func (b *bus) walletSigningHandlerPOST(jc jape.Context) {
+	var signed []api.WalletSignedBundle
+	if jc.Decode(&signed) != nil {
+		return
+	}
+
+	cs := b.cm.TipState()
+	for _, sb := range signed {
+		bundle, ok := b.signing.Bundle(sb.ID)
+		if !ok {
+			jc.Error(fmt.Errorf("%w: %v", api.ErrSigningBundleNotFound, sb.ID), http.StatusNotFound)
+			return
+		}
+
+		// add the signatures of the bundle's inputs to its last transaction
+		toSign := make(map[types.Hash256]bool)
+		for _, id := range bundle.ToSign {
+			toSign[id] = true
+		}
+		txnSet := bundle.TransactionSet
+		txn := txnSet[len(txnSet)-1]
+		txn.Signatures = append([]types.TransactionSignature(nil), txn.Signatures...)
+		for _, sig := range sb.Signatures {
+			if !toSign[sig.ParentID] {
+				jc.Error(fmt.Errorf("signature for unexpected input %v in bundle %v", sig.ParentID, sb.ID), http.StatusBadRequest)
+				return
+			}
+			txn.Signatures = append(txn.Signatures, sig)
+		}
+		if err := wallet.VerifySignatures(cs, txn, bundle.ToSign); err != nil {
+			jc.Error(fmt.Errorf("invalid signatures for bundle %v: %w", sb.ID, err), http.StatusBadRequest)
+			return
+		}
+
+		// formations and renewals are picked up by the next request of the
+		// autopilot, everything else is broadcast right away
+		if !bundle.broadcast {
+			b.signing.MarkSigned(bundle.ID, txn)
+			continue
+		}
+		txnSet = append(append([]types.Transaction(nil), txnSet[:len(txnSet)-1]...), txn)
+		if jc.Check(fmt.Sprintf("couldn't broadcast bundle %v", sb.ID), b.tp.AcceptTransactionSet(txnSet)) != nil {
+			return
+		}
+		b.signing.Remove(bundle.ID)
+	}
+}
+
+func (b *bus) walletSigningHandlerDELETE(jc jape.Context) {
+	var id types.Hash256
+	if jc.DecodeParam("id", &id) != nil {
+		return
+	}
+	bundle, ok := b.signing.Remove(id)
+	if !ok {
+		jc.Error(fmt.Errorf("%w: %v", api.ErrSigningBundleNotFound, id), http.StatusNotFound)
+		return
+	}
+	b.w.ReleaseInputs(bundle.TransactionSet[len(bundle.TransactionSet)-1])
+}
+
 func (b *bus) walletPrepareFormHandler(jc jape.Context) {
 	var wpfr api.WalletPrepareFormRequest
 	if jc.Decode(&wpfr) != nil {
@@ -652,6 +964,18 @@ func (b *bus) walletPrepareFormHandler(jc jape.Context) {
 	}
 	cs := b.cm.TipState()
 
+	// a watch-only wallet can't sign the formation, it's queued for the
+	// external signer and the autopilot retries until it's signed
+	key := signingKey("form", wpfr.HostKey, wpfr.EndHeight)
+	if bundle, ok := b.signing.Lookup(key); ok && bundle.Signed {
+		b.signing.Remove(bundle.ID)
+		jc.Encode(bundle.TransactionSet)
+		return
+	} else if ok {
+		jc.Error(fmt.Errorf("%w: bundle %v", api.ErrSignaturePending, bundle.ID), http.StatusConflict)
+		return
+	}
+
 	fc := rhpv2.PrepareContractFormation(wpfr.RenterKey, wpfr.HostKey, wpfr.RenterFunds, wpfr.HostCollateral, wpfr.EndHeight, wpfr.HostSettings, wpfr.RenterAddress)
 	cost := rhpv2.ContractFormationCost(cs, fc, wpfr.HostSettings.ContractPrice)
 	txn := types.Transaction{
@@ -664,7 +988,8 @@ func (b *bus) walletPrepareFormHandler(jc jape.Context) {
 	}
 	cf := wallet.ExplicitCoveredFields(txn)
 	err = b.w.SignTransaction(cs, &txn, toSign, cf)
-	if jc.Check("couldn't sign transaction", err) != nil {
+	if err != nil && !errors.Is(err, wallet.ErrWatchOnly) {
+		jc.Error(fmt.Errorf("couldn't sign transaction: %w", err), http.StatusInternalServerError)
 		b.w.ReleaseInputs(txn)
 		return
 	}
@@ -673,6 +998,11 @@ func (b *bus) walletPrepareFormHandler(jc jape.Context) {
 		b.w.ReleaseInputs(txn)
 		return
 	}
+	if b.w.WatchOnly() {
+		bundle := b.signing.Add(key, api.WalletTxnTypeFormation, cs.Index.Height, append(parents, txn), toSign, cf, false, types.ZeroCurrency)
+		jc.Error(fmt.Errorf("%w: bundle %v", api.ErrSignaturePending, bundle.ID), http.StatusConflict)
+		return
+	}
 	jc.Encode(append(parents, txn))
 }
 
@@ -687,6 +1017,24 @@ func (b *bus) walletPrepareRenewHandler(jc jape.Context) {
 	}
 	cs := b.cm.TipState()
 
+	// A watch-only wallet can't sign the renewal at the end of the RPC, the
+	// inputs are signed by the external signer before the renewal is started.
+	key := signingKey("renew", wprr.Revision.ParentID, wprr.EndHeight)
+	if bundle, ok := b.signing.Lookup(key); ok && bundle.Signed {
+		b.signing.Remove(bundle.ID)
+		txnSet := append([]types.Transaction(nil), bundle.TransactionSet...)
+		txnSet[len(txnSet)-1].Signatures = nil
+		jc.Encode(api.WalletPrepareRenewResponse{
+			FundAmount:     bundle.fundAmount,
+			ToSign:         bundle.ToSign,
+			TransactionSet: txnSet,
+		})
+		return
+	} else if ok {
+		jc.Error(fmt.Errorf("%w: bundle %v", api.ErrSignaturePending, bundle.ID), http.StatusConflict)
+		return
+	}
+
 	// Create the final revision from the provided revision.
 	finalRevision := wprr.Revision
 	finalRevision.MissedProofOutputs = finalRevision.ValidProofOutputs
@@ -732,6 +1080,11 @@ func (b *bus) walletPrepareRenewHandler(jc jape.Context) {
 		b.w.ReleaseInputs(txn)
 		return
 	}
+	if b.w.WatchOnly() {
+		bundle := b.signing.Add(key, api.WalletTxnTypeRenewal, cs.Index.Height, append(parents, txn), toSign, wallet.ExplicitCoveredFields(txn), false, cost)
+		jc.Error(fmt.Errorf("%w: bundle %v", api.ErrSignaturePending, bundle.ID), http.StatusConflict)
+		return
+	}
 	jc.Encode(api.WalletPrepareRenewResponse{
 		FundAmount:     cost,
 		ToSign:         toSign,
@@ -2047,6 +2400,52 @@ func (b *bus) contractTaxHandlerGET(jc jape.Context) {
 	jc.Encode(cs.FileContractTax(types.FileContract{Payout: payout}))
 }
 
+func (b *bus) backupHandlerPOST(jc jape.Context) {
+	var req api.BackupRequest
+	if jc.Decode(&req) != nil {
+		return
+	}
+
+	// back up the databases and buffers to a directory next to the node's
+	// data first, the archive is only written once the backup succeeded
+	dir, err := os.MkdirTemp(b.backupDir, "renterd-backup-*")
+	if jc.Check("failed to create backup directory", err) != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	if err := b.ms.Backup(jc.Request.Context(), dir); errors.Is(err, api.ErrBackupNotSupported) {
+		jc.Error(err, http.StatusNotImplemented)
+		return
+	} else if jc.Check("failed to back up database", err) != nil {
+		return
+	}
+
+	filename := fmt.Sprintf("renterd-backup-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	if req.Password != "" {
+		filename += ".enc"
+	}
+	jc.ResponseWriter.Header().Set("Content-Type", "application/octet-stream")
+	jc.ResponseWriter.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if err := backup.WriteArchive(jc.ResponseWriter, dir, req.Password); err != nil {
+		b.logger.Errorf("failed to write backup: %v", err)
+	}
+}
+
+func (b *bus) fsckHandlerPOST(jc jape.Context) {
+	var req api.FsckRequest
+	if jc.Decode(&req) != nil {
+		return
+	}
+	issues, err := b.ms.Fsck(jc.Request.Context(), req.Repair)
+	if jc.Check("failed to check metadata", err) != nil {
+		return
+	}
+	jc.Encode(api.FsckResponse{
+		Repair: req.Repair,
+		Issues: issues,
+	})
+}
+
 func (b *bus) stateHandlerGET(jc jape.Context) {
 	jc.Encode(api.BusStateResponse{
 		StartTime: api.TimeRFC3339(b.startTime),
@@ -2401,7 +2800,7 @@ func (b *bus) ProcessConsensusChange(cc modules.ConsensusChange) {
 }
 
 // New returns a new Bus.
-func New(s Syncer, am *alerts.Manager, hm *webhooks.Manager, cm ChainManager, tp TransactionPool, w Wallet, hdb HostDB, as AutopilotStore, ms MetadataStore, ss SettingStore, eas EphemeralAccountStore, mtrcs MetricsStore, l *zap.Logger) (*bus, error) {
+func New(s Syncer, am *alerts.Manager, hm *webhooks.Manager, cm ChainManager, tp TransactionPool, w Wallet, hdb HostDB, as AutopilotStore, ms MetadataStore, ss SettingStore, eas EphemeralAccountStore, mtrcs MetricsStore, backupDir string, l *zap.Logger) (*bus, error) {
 	b := &bus{
 		alerts:           alerts.WithOrigin(am, "bus"),
 		alertMgr:         am,
@@ -2419,7 +2818,9 @@ func New(s Syncer, am *alerts.Manager, hm *webhooks.Manager, cm ChainManager, tp
 		eas:              eas,
 		contractLocks:    newContractLocks(),
 		uploadingSectors: newUploadingSectorsCache(),
+		signing:          newSigningQueue(),
 		logger:           l.Sugar().Named("bus"),
+		backupDir:        backupDir,
 
 		startTime: time.Now(),
 	}
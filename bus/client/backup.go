@@ -0,0 +1,43 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.sia.tech/renterd/api"
+)
+
+// Backup creates an online backup of the bus's databases and partial slab
+// buffers and writes the archive to w. If a password is provided the archive
+// is encrypted.
+func (c *Client) Backup(ctx context.Context, w io.Writer, password string) error {
+	c.c.Custom("POST", "/backup", api.BackupRequest{}, (*[]byte)(nil))
+
+	body, err := json.Marshal(api.BackupRequest{Password: password})
+	if err != nil {
+		panic(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%v/backup", c.c.BaseURL), bytes.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth("", c.c.WithContext(ctx).Password)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer io.Copy(io.Discard, resp.Body)
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		err, _ := io.ReadAll(resp.Body)
+		return errors.New(string(err))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,14 @@
+package client
+
+import (
+	"context"
+
+	"go.sia.tech/renterd/api"
+)
+
+// Fsck checks the metadata for inconsistencies. If repair is true, the issues
+// that can be repaired are fixed.
+func (c *Client) Fsck(ctx context.Context, repair bool) (resp api.FsckResponse, err error) {
+	err = c.c.WithContext(ctx).POST("/fsck", api.FsckRequest{Repair: repair}, &resp)
+	return
+}
@@ -2,7 +2,9 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 
@@ -44,6 +46,30 @@ func (c *Client) Wallet(ctx context.Context) (resp api.WalletResponse, err error
 	return
 }
 
+// WalletAddresses returns the addresses controlled by the wallet along with
+// their balances.
+func (c *Client) WalletAddresses(ctx context.Context) (resp []api.WalletAddress, err error) {
+	err = c.c.WithContext(ctx).GET("/wallet/addresses", &resp)
+	return
+}
+
+// WalletNewAddress generates a new address to receive funds.
+func (c *Client) WalletNewAddress(ctx context.Context) (addr types.Address, err error) {
+	err = c.c.WithContext(ctx).POST("/wallet/addresses", nil, &addr)
+	return
+}
+
+// WalletConsolidate merges up to maxInputs of the wallet's outputs with a value
+// below maxValue into a single output.
+func (c *Client) WalletConsolidate(ctx context.Context, maxInputs int, maxValue types.Currency) (ids []types.TransactionID, err error) {
+	req := api.WalletConsolidateRequest{
+		MaxInputs:      maxInputs,
+		MaxOutputValue: maxValue,
+	}
+	err = c.c.WithContext(ctx).POST("/wallet/consolidate", req, &ids)
+	return
+}
+
 // WalletDiscard discards the provided txn, make its inputs usable again. This
 // should only be called on transactions that will never be broadcast.
 func (c *Client) WalletDiscard(ctx context.Context, txn types.Transaction) error {
@@ -66,6 +92,13 @@ func (c *Client) WalletFund(ctx context.Context, txn *types.Transaction, amount
 	return resp.ToSign, resp.DependsOn, nil
 }
 
+// WalletSend sends siacoins to an address. If the request is a dry run the
+// transaction is returned without being broadcast.
+func (c *Client) WalletSend(ctx context.Context, req api.WalletSendRequest) (resp api.WalletSendResponse, err error) {
+	err = c.c.WithContext(ctx).POST("/wallet/send", req, &resp)
+	return
+}
+
 // WalletOutputs returns the set of unspent outputs controlled by the wallet.
 func (c *Client) WalletOutputs(ctx context.Context) (resp []wallet.SiacoinElement, err error) {
 	err = c.c.WithContext(ctx).GET("/wallet/outputs", &resp)
@@ -157,3 +190,90 @@ func (c *Client) WalletTransactions(ctx context.Context, opts ...api.WalletTrans
 	err = c.do(req, &resp)
 	return
 }
+
+// WalletExport returns the wallet's transactions in chronological order along
+// with their classification and label.
+func (c *Client) WalletExport(ctx context.Context, opts ...api.WalletTransactionsOption) (resp []api.WalletTransaction, err error) {
+	c.c.Custom("GET", "/wallet/export", nil, &resp)
+
+	values := url.Values{}
+	for _, opt := range opts {
+		opt(values)
+	}
+	values.Set("format", api.WalletExportFormatJSON)
+	u, err := url.Parse(fmt.Sprintf("%v/wallet/export", c.c.BaseURL))
+	if err != nil {
+		panic(err)
+	}
+	u.RawQuery = values.Encode()
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), http.NoBody)
+	if err != nil {
+		panic(err)
+	}
+	err = c.do(req, &resp)
+	return
+}
+
+// WalletExportCSV writes the wallet's transactions in CSV format to w.
+func (c *Client) WalletExportCSV(ctx context.Context, w io.Writer, opts ...api.WalletTransactionsOption) error {
+	c.c.Custom("GET", "/wallet/export", nil, (*[]byte)(nil))
+
+	values := url.Values{}
+	for _, opt := range opts {
+		opt(values)
+	}
+	values.Set("format", api.WalletExportFormatCSV)
+	u, err := url.Parse(fmt.Sprintf("%v/wallet/export", c.c.BaseURL))
+	if err != nil {
+		panic(err)
+	}
+	u.RawQuery = values.Encode()
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), http.NoBody)
+	if err != nil {
+		panic(err)
+	}
+	req.SetBasicAuth("", c.c.WithContext(ctx).Password)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer io.Copy(io.Discard, resp.Body)
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		err, _ := io.ReadAll(resp.Body)
+		return errors.New(string(err))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// WalletLabels returns the labels of the wallet's transactions.
+func (c *Client) WalletLabels(ctx context.Context) (labels map[types.TransactionID]string, err error) {
+	err = c.c.WithContext(ctx).GET("/wallet/labels", &labels)
+	return
+}
+
+// UpdateWalletLabel sets the label of a wallet transaction, an empty label
+// removes it.
+func (c *Client) UpdateWalletLabel(ctx context.Context, id types.TransactionID, label string) error {
+	return c.c.WithContext(ctx).PUT(fmt.Sprintf("/wallet/labels/%s", id), api.WalletLabelRequest{Label: label})
+}
+
+// WalletSigningBundles returns the transactions of a watch-only wallet that
+// are waiting to be signed.
+func (c *Client) WalletSigningBundles(ctx context.Context) (bundles []api.WalletSigningBundle, err error) {
+	err = c.c.WithContext(ctx).GET("/wallet/signing", &bundles)
+	return
+}
+
+// WalletSubmitSignatures submits the signatures created by the external signer
+// for the given bundles.
+func (c *Client) WalletSubmitSignatures(ctx context.Context, signed []api.WalletSignedBundle) error {
+	return c.c.WithContext(ctx).POST("/wallet/signing", signed, nil)
+}
+
+// WalletDiscardSigningBundle discards the bundle with the given id, making its
+// inputs usable again.
+func (c *Client) WalletDiscardSigningBundle(ctx context.Context, id types.Hash256) error {
+	return c.c.WithContext(ctx).DELETE(fmt.Sprintf("/wallet/signing/%s", id))
+}
@@ -0,0 +1,185 @@
+package bus
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/wallet"
+	"lukechampine.com/frand"
+)
+
+type (
+	// signingQueue holds the transactions of a watch-only wallet that are
+	// waiting to be signed by the external signer. Bundles are kept in memory,
+	// they are lost when the bus restarts which releases their inputs.
+	signingQueue struct {
+		mu      sync.Mutex
+		bundles map[types.Hash256]*signingBundle
+
+		// presigned contains the signatures of the inputs of renewals, the
+		// inputs are signed before the host adds its inputs so the worker
+		// can't have them signed by the bus at the end of the RPC
+		presigned map[types.Hash256]types.TransactionSignature
+	}
+
+	signingBundle struct {
+		api.WalletSigningBundle
+
+		// key identifies the request that created the bundle, requests for
+		// formations and renewals are retried by the autopilot until the
+		// bundle is signed
+		key string
+
+		// broadcast indicates whether the bundle is broadcast once it's
+		// signed, if not it's returned to the next request with the same key
+		broadcast bool
+
+		// fundAmount is the amount the renewal was funded with
+		fundAmount types.Currency
+	}
+)
+
+func newSigningQueue() *signingQueue {
+	return &signingQueue{
+		bundles:   make(map[types.Hash256]*signingBundle),
+		presigned: make(map[types.Hash256]types.TransactionSignature),
+	}
+}
+
+// Add adds a bundle to the queue.
+func (q *signingQueue) Add(key, typ string, height uint64, txnSet []types.Transaction, toSign []types.Hash256, cf types.CoveredFields, broadcast bool, fundAmount types.Currency) api.WalletSigningBundle {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	b := &signingBundle{
+		WalletSigningBundle: api.WalletSigningBundle{
+			ID:             frand.Entropy256(),
+			Type:           typ,
+			Height:         height,
+			TransactionSet: txnSet,
+			ToSign:         toSign,
+			CoveredFields:  cf,
+			CreatedAt:      api.TimeRFC3339(time.Now()),
+		},
+		key:        key,
+		broadcast:  broadcast,
+		fundAmount: fundAmount,
+	}
+	q.bundles[b.ID] = b
+	return b.WalletSigningBundle
+}
+
+// Bundles returns all bundles in the order they were added.
+func (q *signingQueue) Bundles() []api.WalletSigningBundle {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	bundles := make([]api.WalletSigningBundle, 0, len(q.bundles))
+	for _, b := range q.bundles {
+		bundles = append(bundles, b.WalletSigningBundle)
+	}
+	sort.Slice(bundles, func(i, j int) bool {
+		return time.Time(bundles[i].CreatedAt).Before(time.Time(bundles[j].CreatedAt))
+	})
+	return bundles
+}
+
+// Bundle returns the bundle with the given id.
+func (q *signingQueue) Bundle(id types.Hash256) (signingBundle, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	b, ok := q.bundles[id]
+	if !ok {
+		return signingBundle{}, false
+	}
+	return *b, true
+}
+
+// Lookup returns the bundle that was created by the request with the given
+// key.
+func (q *signingQueue) Lookup(key string) (signingBundle, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, b := range q.bundles {
+		if b.key == key {
+			return *b, true
+		}
+	}
+	return signingBundle{}, false
+}
+
+// MarkSigned replaces the transaction of the bundle with its signed version.
+// The signatures of renewals are stored to be applied once the host added its
+// inputs.
+func (q *signingQueue) MarkSigned(id types.Hash256, signed types.Transaction) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	b, ok := q.bundles[id]
+	if !ok {
+		return
+	}
+	b.Signed = true
+	b.TransactionSet = append(append([]types.Transaction(nil), b.TransactionSet[:len(b.TransactionSet)-1]...), signed)
+	if b.Type == api.WalletTxnTypeRenewal {
+		for _, ts := range signed.Signatures {
+			q.presigned[ts.ParentID] = ts
+		}
+	}
+}
+
+// Remove removes the bundle with the given id from the queue.
+func (q *signingQueue) Remove(id types.Hash256) (signingBundle, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	b, ok := q.bundles[id]
+	if !ok {
+		return signingBundle{}, false
+	}
+	delete(q.bundles, id)
+	return *b, true
+}
+
+// ApplyPresigned adds the signatures of the given inputs that were created
+// before the transaction was completed.
+func (q *signingQueue) ApplyPresigned(txn *types.Transaction, toSign []types.Hash256) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, id := range toSign {
+		if _, ok := q.presigned[id]; !ok {
+			return fmt.Errorf("%w: no signature found for input %v", wallet.ErrWatchOnly, id)
+		}
+	}
+	for _, id := range toSign {
+		txn.Signatures = append(txn.Signatures, q.presigned[id])
+		delete(q.presigned, id)
+	}
+	return nil
+}
+
+// signingKey returns the key that identifies the formation or renewal of a
+// contract with the given end height. The key doesn't depend on the funding
+// of the contract so the request matches the bundle even if the autopilot's
+// estimates changed while the bundle was waiting to be signed.
+func signingKey(typ string, id fmt.Stringer, endHeight uint64) string {
+	return fmt.Sprintf("%s:%v:%d", typ, id, endHeight)
+}
+
+// inputsOf returns the ids in toSign that are inputs of the given
+// transaction.
+func inputsOf(txn types.Transaction, toSign []types.Hash256) []types.Hash256 {
+	inputs := make(map[types.Hash256]bool)
+	for _, sci := range txn.SiacoinInputs {
+		inputs[types.Hash256(sci.ParentID)] = true
+	}
+	var ids []types.Hash256
+	for _, id := range toSign {
+		if inputs[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,74 @@
+package bus
+
+import (
+	"errors"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/wallet"
+)
+
+func TestSigningQueue(t *testing.T) {
+	q := newSigningQueue()
+	input := types.Hash256{1}
+	txn := types.Transaction{SiacoinInputs: []types.SiacoinInput{{ParentID: types.SiacoinOutputID(input)}}}
+
+	// add a renewal and a send
+	key := signingKey("renew", types.FileContractID{1}, 100)
+	renewal := q.Add(key, api.WalletTxnTypeRenewal, 10, []types.Transaction{txn}, []types.Hash256{input}, types.CoveredFields{}, false, types.Siacoins(1))
+	send := q.Add("", api.WalletTxnTypeSend, 10, []types.Transaction{{}}, nil, types.CoveredFields{}, true, types.ZeroCurrency)
+	if bundles := q.Bundles(); len(bundles) != 2 {
+		t.Fatal("unexpected number of bundles", len(bundles))
+	}
+
+	// lookup the renewal by its key
+	if b, ok := q.Lookup(key); !ok || b.ID != renewal.ID || b.Signed {
+		t.Fatal("unexpected bundle", b, ok)
+	} else if _, ok := q.Lookup(signingKey("renew", types.FileContractID{1}, 101)); ok {
+		t.Fatal("unexpected bundle for different end height")
+	}
+
+	// the signatures of the renewal aren't available until it's signed
+	if err := q.ApplyPresigned(&txn, []types.Hash256{input}); !errors.Is(err, wallet.ErrWatchOnly) {
+		t.Fatal("unexpected error", err)
+	}
+
+	// mark it as signed
+	signed := txn
+	signed.Signatures = []types.TransactionSignature{{ParentID: input}}
+	q.MarkSigned(renewal.ID, signed)
+	if b, ok := q.Bundle(renewal.ID); !ok || !b.Signed || len(b.TransactionSet[0].Signatures) != 1 {
+		t.Fatal("bundle should be signed", b)
+	} else if !b.fundAmount.Equals(types.Siacoins(1)) {
+		t.Fatal("unexpected fund amount", b.fundAmount)
+	}
+
+	// apply the presigned signatures once, they're consumed
+	if err := q.ApplyPresigned(&txn, []types.Hash256{input}); err != nil {
+		t.Fatal(err)
+	} else if len(txn.Signatures) != 1 || txn.Signatures[0].ParentID != input {
+		t.Fatal("unexpected signatures", txn.Signatures)
+	} else if err := q.ApplyPresigned(&txn, []types.Hash256{input}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// remove both bundles
+	if _, ok := q.Remove(renewal.ID); !ok {
+		t.Fatal("bundle not found")
+	} else if _, ok := q.Remove(send.ID); !ok {
+		t.Fatal("bundle not found")
+	} else if _, ok := q.Remove(send.ID); ok {
+		t.Fatal("bundle should be gone")
+	} else if len(q.Bundles()) != 0 {
+		t.Fatal("queue should be empty")
+	}
+}
+
+func TestInputsOf(t *testing.T) {
+	txn := types.Transaction{SiacoinInputs: []types.SiacoinInput{{ParentID: types.SiacoinOutputID{1}}, {ParentID: types.SiacoinOutputID{3}}}}
+	ids := inputsOf(txn, []types.Hash256{{1}, {2}, {3}})
+	if len(ids) != 2 || ids[0] != (types.Hash256{1}) || ids[1] != (types.Hash256{3}) {
+		t.Fatal("unexpected inputs", ids)
+	}
+}
@@ -0,0 +1,155 @@
+package bus
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/wallet"
+)
+
+// walletExportCSVHeader is the header of the CSV export of the wallet's
+// transactions.
+var walletExportCSVHeader = []string{"timestamp", "height", "id", "type", "label", "contract_id", "host_key", "inflow_sc", "outflow_sc", "fee_sc"}
+
+// classifyTransaction classifies the given wallet transaction using the
+// contracts the bus knows about. Contract transactions are recognized by the
+// file contract they create, renewals also contain the final revision of the
+// contract they renew. A renewal that doesn't change the end height of the
+// contract is considered a refresh.
+func classifyTransaction(txn wallet.Transaction, contracts map[types.FileContractID]types.PublicKey, labels map[types.TransactionID]string) api.WalletTransaction {
+	wt := api.WalletTransaction{
+		ID:        txn.ID,
+		Index:     txn.Index,
+		Timestamp: txn.Timestamp,
+		Label:     labels[txn.ID],
+		Inflow:    txn.Inflow,
+		Outflow:   txn.Outflow,
+	}
+
+	// the wallet only pays the fee if it funded the transaction
+	if !txn.Outflow.IsZero() {
+		for _, fee := range txn.Raw.MinerFees {
+			wt.Fee = wt.Fee.Add(fee)
+		}
+	}
+
+	switch {
+	case len(txn.Raw.FileContracts) > 0:
+		fc := txn.Raw.FileContracts[0]
+		wt.ContractID = txn.Raw.FileContractID(0)
+		hk, known := contracts[wt.ContractID]
+		if !known {
+			wt.Type = api.WalletTxnTypeContract
+			break
+		}
+		wt.HostKey = hk
+		if len(txn.Raw.FileContractRevisions) == 0 {
+			wt.Type = api.WalletTxnTypeFormation
+			break
+		}
+		rev := txn.Raw.FileContractRevisions[0]
+		wt.RenewedFrom = rev.ParentID
+		if rev.WindowStart == fc.WindowStart {
+			wt.Type = api.WalletTxnTypeRefresh
+		} else {
+			wt.Type = api.WalletTxnTypeRenewal
+		}
+	case txn.Outflow.IsZero():
+		wt.Type = api.WalletTxnTypeReceive
+	case txn.Outflow.Cmp(txn.Inflow.Add(wt.Fee)) <= 0:
+		wt.Type = api.WalletTxnTypeInternal
+	default:
+		wt.Type = api.WalletTxnTypeSend
+	}
+	return wt
+}
+
+// knownContracts returns the host keys of all contracts the bus knows about,
+// this includes the active contracts as well as the archived ones.
+func (b *bus) knownContracts(ctx context.Context) (map[types.FileContractID]types.PublicKey, error) {
+	contracts, err := b.ms.ContractHostKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch contracts: %w", err)
+	}
+	return contracts, nil
+}
+
+// walletLabels returns the labels of the wallet's transactions.
+func (b *bus) walletLabels(ctx context.Context) (map[types.TransactionID]string, error) {
+	labels := make(map[types.TransactionID]string)
+	if err := b.fetchSetting(ctx, api.SettingWalletLabels, &labels); err != nil && !errors.Is(err, api.ErrSettingNotFound) {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// updateWalletLabel sets the label of a transaction, an empty label removes
+// it.
+func (b *bus) updateWalletLabel(ctx context.Context, id types.TransactionID, label string) error {
+	b.labelsMu.Lock()
+	defer b.labelsMu.Unlock()
+
+	labels, err := b.walletLabels(ctx)
+	if err != nil {
+		return err
+	}
+	if label == "" {
+		delete(labels, id)
+	} else {
+		labels[id] = label
+	}
+	data, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+	return b.ss.UpdateSetting(ctx, api.SettingWalletLabels, string(data))
+}
+
+// writeTransactionsCSV writes the given transactions to w in CSV format.
+// Amounts are denominated in siacoins and written with full precision.
+func writeTransactionsCSV(w io.Writer, txns []api.WalletTransaction) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(walletExportCSVHeader); err != nil {
+		return err
+	}
+	for _, txn := range txns {
+		var contractID, hostKey string
+		if txn.ContractID != (types.FileContractID{}) {
+			contractID = txn.ContractID.String()
+			hostKey = txn.HostKey.String()
+		}
+		if err := cw.Write([]string{
+			txn.Timestamp.UTC().Format(time.RFC3339),
+			fmt.Sprint(txn.Index.Height),
+			txn.ID.String(),
+			txn.Type,
+			txn.Label,
+			contractID,
+			hostKey,
+			siacoinString(txn.Inflow),
+			siacoinString(txn.Outflow),
+			siacoinString(txn.Fee),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// siacoinString formats the given amount of hastings as a decimal amount of
+// siacoins without losing precision.
+func siacoinString(c types.Currency) string {
+	s := new(big.Rat).SetFrac(c.Big(), types.Siacoins(1).Big()).FloatString(24)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,109 @@
+package bus
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/wallet"
+)
+
+func TestClassifyTransaction(t *testing.T) {
+	oneSC := types.Siacoins(1)
+	fee := types.Siacoins(1).Div64(100)
+	hk := types.PublicKey{1}
+
+	formation := types.Transaction{
+		FileContracts: []types.FileContract{{WindowStart: 100}},
+		MinerFees:     []types.Currency{fee},
+	}
+	renewal := types.Transaction{
+		FileContracts:         []types.FileContract{{WindowStart: 200}},
+		FileContractRevisions: []types.FileContractRevision{{ParentID: formation.FileContractID(0), FileContract: types.FileContract{WindowStart: 100}}},
+		MinerFees:             []types.Currency{fee},
+	}
+	refresh := types.Transaction{
+		FileContracts:         []types.FileContract{{WindowStart: 200, Filesize: 1}},
+		FileContractRevisions: []types.FileContractRevision{{ParentID: renewal.FileContractID(0), FileContract: types.FileContract{WindowStart: 200}}},
+		MinerFees:             []types.Currency{fee},
+	}
+	unknown := types.Transaction{
+		FileContracts: []types.FileContract{{WindowStart: 300}},
+		MinerFees:     []types.Currency{fee},
+	}
+	contracts := map[types.FileContractID]types.PublicKey{
+		formation.FileContractID(0): hk,
+		renewal.FileContractID(0):   hk,
+		refresh.FileContractID(0):   hk,
+	}
+	labels := map[types.TransactionID]string{
+		formation.ID(): "first contract",
+	}
+
+	tests := []struct {
+		txn         wallet.Transaction
+		typ         string
+		fee         types.Currency
+		renewedFrom types.FileContractID
+	}{
+		{wallet.Transaction{Raw: formation, ID: formation.ID(), Outflow: oneSC}, api.WalletTxnTypeFormation, fee, types.FileContractID{}},
+		{wallet.Transaction{Raw: renewal, ID: renewal.ID(), Outflow: oneSC}, api.WalletTxnTypeRenewal, fee, formation.FileContractID(0)},
+		{wallet.Transaction{Raw: refresh, ID: refresh.ID(), Outflow: oneSC}, api.WalletTxnTypeRefresh, fee, renewal.FileContractID(0)},
+		{wallet.Transaction{Raw: unknown, ID: unknown.ID(), Outflow: oneSC}, api.WalletTxnTypeContract, fee, types.FileContractID{}},
+		{wallet.Transaction{Raw: types.Transaction{MinerFees: []types.Currency{fee}}, Inflow: oneSC}, api.WalletTxnTypeReceive, types.ZeroCurrency, types.FileContractID{}},
+		{wallet.Transaction{Raw: types.Transaction{MinerFees: []types.Currency{fee}}, Inflow: oneSC.Sub(fee), Outflow: oneSC}, api.WalletTxnTypeInternal, fee, types.FileContractID{}},
+		{wallet.Transaction{Raw: types.Transaction{MinerFees: []types.Currency{fee}}, Inflow: oneSC, Outflow: oneSC.Mul64(2)}, api.WalletTxnTypeSend, fee, types.FileContractID{}},
+	}
+	for i, test := range tests {
+		wt := classifyTransaction(test.txn, contracts, labels)
+		if wt.Type != test.typ {
+			t.Fatalf("%d: unexpected type %v, expected %v", i, wt.Type, test.typ)
+		} else if !wt.Fee.Equals(test.fee) {
+			t.Fatalf("%d: unexpected fee %v", i, wt.Fee)
+		} else if wt.RenewedFrom != test.renewedFrom {
+			t.Fatalf("%d: unexpected renewed from %v", i, wt.RenewedFrom)
+		}
+		if _, known := contracts[wt.ContractID]; known && wt.HostKey != hk {
+			t.Fatalf("%d: unexpected host key %v", i, wt.HostKey)
+		}
+	}
+	if wt := classifyTransaction(tests[0].txn, contracts, labels); wt.Label != "first contract" {
+		t.Fatal("unexpected label", wt.Label)
+	}
+}
+
+func TestWriteTransactionsCSV(t *testing.T) {
+	txns := []api.WalletTransaction{
+		{
+			ID:        types.TransactionID{1},
+			Index:     types.ChainIndex{Height: 10},
+			Timestamp: time.Unix(0, 0),
+			Type:      api.WalletTxnTypeSend,
+			Label:     "rent, office",
+			Inflow:    types.Siacoins(1).Div64(2),
+			Outflow:   types.Siacoins(3),
+			Fee:       types.NewCurrency64(1),
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeTransactionsCSV(&buf, txns); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 2 || len(records[1]) != len(walletExportCSVHeader) {
+		t.Fatal("unexpected records", records)
+	}
+	record := records[1]
+	if record[0] != "1970-01-01T00:00:00Z" || record[1] != "10" || record[3] != api.WalletTxnTypeSend || record[4] != "rent, office" {
+		t.Fatal("unexpected record", record)
+	} else if record[5] != "" || record[6] != "" {
+		t.Fatal("unexpected contract columns", record)
+	} else if record[7] != "0.5" || record[8] != "3" || record[9] != "0.000000000000000000000001" {
+		t.Fatal("unexpected amounts", record[7:])
+	}
+}
@@ -1,15 +1,214 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"go.sia.tech/core/consensus"
 	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/wallet"
+	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/build"
+	"go.sia.tech/renterd/bus"
+	"go.sia.tech/renterd/internal/backup"
+	"go.sia.tech/renterd/stores"
+	rwallet "go.sia.tech/renterd/wallet"
 	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
 )
 
+// cmdBackup creates an online backup of the bus's databases and partial slab
+// buffers and writes it to 'path'. The backup is encrypted with the password
+// in RENTERD_BACKUP_PASSWORD, if it's not set the user is prompted for one.
+func cmdBackup(path string) {
+	if path == "" {
+		stdoutFatalError("Usage: renterd backup <backup.tar.gz>")
+		return
+	}
+
+	// connect to the bus, either the remote one or the one of the local node
+	busAddr, busPassword := cfg.Bus.RemoteAddr, cfg.Bus.RemotePassword
+	if busAddr == "" {
+		busAddr, busPassword = cfg.HTTP.Address+"/api/bus", cfg.HTTP.Password
+		if !strings.HasPrefix(busAddr, "http") {
+			busAddr = "http://" + busAddr
+		}
+	}
+	if busPassword == "" {
+		busPassword = readPasswordInput("Enter API password")
+		fmt.Println("")
+	}
+
+	password, ok := os.LookupEnv("RENTERD_BACKUP_PASSWORD")
+	if !ok {
+		password = readPasswordInput("Enter backup password (leave empty to not encrypt the backup)")
+		fmt.Println("")
+	}
+
+	// write the backup to a temporary file first to avoid leaving a partial
+	// backup behind
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		stdoutFatalError("Failed to create backup file: " + err.Error())
+		return
+	}
+	fail := func(msg string, err error) {
+		f.Close()
+		os.Remove(f.Name())
+		stdoutFatalError(msg + ": " + err.Error())
+	}
+
+	fmt.Println("Creating backup...")
+	if err := bus.NewClient(busAddr, busPassword).Backup(context.Background(), f, password); err != nil {
+		fail("Failed to create backup", err)
+		return
+	} else if err := f.Sync(); err != nil {
+		fail("Failed to sync backup", err)
+		return
+	} else if err := f.Close(); err != nil {
+		fail("Failed to close backup", err)
+		return
+	} else if err := os.Rename(f.Name(), path); err != nil {
+		fail("Failed to move backup", err)
+		return
+	}
+	fmt.Println("Backup written to", path)
+}
+
+// cmdCopyDB copies the main and metrics databases from one of the configured
+// backends to another. renterd must not be running while the data is copied.
+func cmdCopyDB(args []string) {
+	const usage = "Usage: renterd copy-db [-resume] <sqlite|mysql|postgresql> <sqlite|mysql|postgresql>"
+
+	fs := flag.NewFlagSet("copy-db", flag.ExitOnError)
+	resume := fs.Bool("resume", false, "resume an interrupted copy")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		stdoutFatalError(usage)
+		return
+	} else if fs.Arg(0) == fs.Arg(1) {
+		stdoutFatalError("Source and destination backend must differ")
+		return
+	}
+
+	// the environment isn't parsed before running commands
+	parseEnvVar("RENTERD_DB_URI", &cfg.Database.MySQL.URI)
+	parseEnvVar("RENTERD_DB_USER", &cfg.Database.MySQL.User)
+	parseEnvVar("RENTERD_DB_PASSWORD", &cfg.Database.MySQL.Password)
+	parseEnvVar("RENTERD_DB_NAME", &cfg.Database.MySQL.Database)
+	parseEnvVar("RENTERD_DB_METRICS_NAME", &cfg.Database.MySQL.MetricsDatabase)
+
+	conns := func(backend string) (gorm.Dialector, gorm.Dialector) {
+		switch backend {
+		case "sqlite":
+			dbDir := filepath.Join(cfg.Directory, "db")
+			if err := os.MkdirAll(dbDir, 0700); err != nil {
+				stdoutFatalError("Failed to create database directory: " + err.Error())
+			}
+			return stores.NewSQLiteConnection(filepath.Join(dbDir, "db.sqlite")), stores.NewMetricsSQLiteConnection(filepath.Join(dbDir, "metrics.sqlite"))
+		case "mysql":
+			c := cfg.Database.MySQL
+			if c.URI == "" {
+				stdoutFatalError("MySQL database is not configured")
+			}
+			return stores.NewMySQLConnection(c.User, c.Password, c.URI, c.Database), stores.NewMySQLConnection(c.User, c.Password, c.URI, c.MetricsDatabase)
+		case "postgresql":
+			c := cfg.Database.PostgreSQL
+			if c.URI == "" {
+				stdoutFatalError("PostgreSQL database is not configured")
+			}
+			host, portStr, err := net.SplitHostPort(c.URI)
+			if err != nil {
+				stdoutFatalError("Invalid PostgreSQL URI: " + err.Error())
+			}
+			port, err := strconv.Atoi(portStr)
+			if err != nil {
+				stdoutFatalError("Invalid PostgreSQL port: " + err.Error())
+			}
+			return stores.NewPostgreSQLConnection(host, c.User, c.Password, c.Database, port), stores.NewPostgreSQLConnection(host, c.User, c.Password, c.MetricsDatabase, port)
+		default:
+			stdoutFatalError(usage)
+		}
+		return nil, nil
+	}
+	src, srcMetrics := conns(fs.Arg(0))
+	dst, dstMetrics := conns(fs.Arg(1))
+
+	fmt.Printf("Copying databases from %s to %s...\n", fs.Arg(0), fs.Arg(1))
+	err := stores.CopyDatabases(context.Background(), stores.CopyConfig{
+		Src:        src,
+		SrcMetrics: srcMetrics,
+		Dst:        dst,
+		DstMetrics: dstMetrics,
+		Resume:     *resume,
+		Progress: func(db, table string, copied, total uint64) {
+			fmt.Printf("\r%s.%s: %d/%d rows", db, table, copied, total)
+			if copied >= total {
+				fmt.Println()
+			}
+		},
+	})
+	if errors.Is(err, stores.ErrCopyDestinationNotEmpty) {
+		stdoutFatalError("Destination already contains data, run with -resume to continue an interrupted copy")
+		return
+	} else if err != nil {
+		stdoutFatalError("Failed to copy databases: " + err.Error())
+		return
+	}
+	fmt.Println("Copy verified, update your configuration to use", fs.Arg(1), "before restarting renterd")
+}
+
+// cmdDecryptBackup decrypts an encrypted backup so it can be extracted.
+func cmdDecryptBackup(src, dst string) {
+	if src == "" || dst == "" {
+		stdoutFatalError("Usage: renterd decrypt-backup <backup.tar.gz.enc> <backup.tar.gz>")
+		return
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		stdoutFatalError("Failed to open backup: " + err.Error())
+		return
+	}
+	defer in.Close()
+
+	password, ok := os.LookupEnv("RENTERD_BACKUP_PASSWORD")
+	if !ok {
+		password = readPasswordInput("Enter backup password")
+		fmt.Println("")
+	}
+	r, err := backup.NewDecryptReader(in, password)
+	if errors.Is(err, backup.ErrNotEncrypted) {
+		stdoutFatalError("The backup is not encrypted")
+		return
+	} else if err != nil {
+		stdoutFatalError("Failed to decrypt backup: " + err.Error())
+		return
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		stdoutFatalError("Failed to create decrypted backup: " + err.Error())
+		return
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(dst)
+		stdoutFatalError("Failed to decrypt backup: " + err.Error())
+		return
+	}
+	fmt.Println("Decrypted backup written to", dst)
+}
+
 func cmdBuildConfig() {
 	if _, err := os.Stat("renterd.yml"); err == nil {
 		if !promptYesNo("renterd.yml already exists. Would you like to overwrite it?") {
@@ -76,6 +275,74 @@ func cmdSeed() {
 	key := wallet.KeyFromSeed(&seed, 0)
 	fmt.Println("Recovery Phrase:", phrase)
 	fmt.Println("Address", types.StandardUnlockHash(key.PublicKey()))
+	fmt.Println("Public Key", key.PublicKey())
+}
+
+// cmdSign signs the bundles of a watch-only wallet. It reads the bundles
+// fetched from the bus's /wallet/signing endpoint from 'bundlesPath' and
+// writes the signatures to 'signedPath', which are then submitted to the same
+// endpoint. It doesn't require a connection to the bus, so it can be run on an
+// air-gapped machine that holds the wallet's seed.
+func cmdSign(bundlesPath, signedPath string) {
+	if bundlesPath == "" || signedPath == "" {
+		stdoutFatalError("Usage: renterd sign <bundles.json> <signed.json>")
+		return
+	}
+
+	// read the bundles
+	data, err := os.ReadFile(bundlesPath)
+	if err != nil {
+		stdoutFatalError("Failed to read bundles: " + err.Error())
+		return
+	}
+	var bundles []api.WalletSigningBundle
+	if err := json.Unmarshal(data, &bundles); err != nil {
+		stdoutFatalError("Failed to decode bundles: " + err.Error())
+		return
+	}
+
+	// derive the wallet's key
+	var seed [32]byte
+	if err := wallet.SeedFromPhrase(&seed, readPasswordInput("Enter seed phrase")); err != nil {
+		stdoutFatalError("Failed to decode seed phrase: " + err.Error())
+		return
+	}
+	key := wallet.KeyFromSeed(&seed, 0)
+	fmt.Println("")
+	fmt.Println("Address", types.StandardUnlockHash(key.PublicKey()))
+
+	// sign the last transaction of every bundle that isn't signed yet, the
+	// other transactions in the set are its unconfirmed parents
+	network, _ := build.Network()
+	var signed []api.WalletSignedBundle
+	for _, b := range bundles {
+		if b.Signed || len(b.TransactionSet) == 0 {
+			continue
+		}
+		txn := b.TransactionSet[len(b.TransactionSet)-1]
+		txn.Signatures = nil
+		cs := consensus.State{Network: network, Index: types.ChainIndex{Height: b.Height}}
+		if err := rwallet.SignTransactionWithKey(cs, &txn, b.ToSign, b.CoveredFields, key); err != nil {
+			stdoutFatalError(fmt.Sprintf("Failed to sign bundle %v: %v", b.ID, err))
+			return
+		}
+		fmt.Printf("Signed %v bundle %v (%d inputs)\n", b.Type, b.ID, len(b.ToSign))
+		signed = append(signed, api.WalletSignedBundle{
+			ID:         b.ID,
+			Signatures: txn.Signatures,
+		})
+	}
+
+	// write the signatures
+	data, err = json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		stdoutFatalError("Failed to encode signatures: " + err.Error())
+		return
+	} else if err := os.WriteFile(signedPath, data, 0600); err != nil {
+		stdoutFatalError("Failed to write signatures: " + err.Error())
+		return
+	}
+	fmt.Printf("Wrote signatures for %d bundles to %v\n", len(signed), signedPath)
 }
 
 func cmdVersion() {
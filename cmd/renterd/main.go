@@ -18,6 +18,7 @@ import (
 	"syscall"
 	"time"
 
+	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/wallet"
 	"go.sia.tech/jape"
 	"go.sia.tech/renterd/api"
@@ -32,6 +33,7 @@ import (
 	"go.sia.tech/renterd/worker/s3"
 	"go.sia.tech/web/renterd"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/blake2b"
 	"golang.org/x/sys/cpu"
 	"golang.org/x/term"
 	"gopkg.in/yaml.v3"
@@ -62,10 +64,14 @@ Usage:
 `
 	// usageFooter is the footer for the CLI usage text.
 	usageFooter = `
-There are 3 commands:
+There are 7 commands:
   - version: prints the network as well as build information
   - config: builds a YAML config file through a series of prompts
   - seed: generates a new seed and prints the recovery phrase
+  - sign: signs the transactions of a watch-only wallet, usage: sign <bundles.json> <signed.json>
+  - backup: creates an online backup of the bus, usage: backup <backup.tar.gz>
+  - decrypt-backup: decrypts an encrypted backup, usage: decrypt-backup <backup.tar.gz.enc> <backup.tar.gz>
+  - copy-db: copies all data between database backends, usage: copy-db [-resume] <from> <to>
 
 See the documentation (https://docs.sia.tech/) for more information and examples
 on how to configure and use renterd.
@@ -73,6 +79,12 @@ on how to configure and use renterd.
 )
 
 var (
+	// defaultReservedMemory is the memory reserved for downloads and uploads
+	// by default, it's capped to a quarter of the max memory
+	defaultReservedMemory = config.MemoryReservations{
+		Interactive: 1 << 28, // 256 MiB
+	}
+
 	cfg = config.Config{
 		Directory:     ".",
 		Seed:          os.Getenv("RENTERD_SEED"),
@@ -115,6 +127,10 @@ var (
 			PersistInterval:               time.Minute,
 			UsedUTXOExpiry:                24 * time.Hour,
 			SlabBufferCompletionThreshold: 1 << 12,
+			MetricsRetention: config.MetricsRetention{
+				Contract:    config.MetricRetention{Raw: 7 * 24 * time.Hour, Hourly: 90 * 24 * time.Hour},
+				Performance: config.MetricRetention{Raw: 7 * 24 * time.Hour, Hourly: 30 * 24 * time.Hour},
+			},
 		},
 		Worker: config.Worker{
 			Enabled: true,
@@ -130,6 +146,9 @@ var (
 			UploadMaxMemory:        1 << 30, // 1 GiB
 			UploadMaxOverdrive:     5,
 			UploadOverdriveTimeout: 3 * time.Second,
+
+			DownloadReservedMemory: defaultReservedMemory,
+			UploadReservedMemory:   defaultReservedMemory,
 		},
 		Autopilot: config.Autopilot{
 			Enabled:                        true,
@@ -149,6 +168,10 @@ var (
 			DisableAuth: false,
 			KeypairsV4:  nil,
 		},
+		WebDAV: config.WebDAV{
+			Address: build.DefaultWebDAVAddress,
+			Enabled: false,
+		},
 	}
 	disableStdin bool
 )
@@ -211,6 +234,13 @@ func tryLoadConfig() {
 	}
 }
 
+// workerKeyFromSecret derives the key a worker derives its renter and account
+// keys from when it's configured with a secret instead of the wallet seed.
+func workerKeyFromSecret(secret string) types.PrivateKey {
+	seed := blake2b.Sum256([]byte(secret))
+	return wallet.KeyFromSeed(&seed, 0)
+}
+
 func parseEnvVar(s string, v interface{}) {
 	if env, ok := os.LookupEnv(s); ok {
 		if _, err := fmt.Sscan(env, v); err != nil {
@@ -220,6 +250,16 @@ func parseEnvVar(s string, v interface{}) {
 	}
 }
 
+// capDefaultReservedMemory caps the default memory reservations to a quarter
+// of the max memory, that way lowering the max memory doesn't prevent the
+// worker from starting.
+func capDefaultReservedMemory(reserved config.MemoryReservations, maxMemory uint64) config.MemoryReservations {
+	if reserved == defaultReservedMemory && reserved.Interactive > maxMemory/4 {
+		reserved.Interactive = maxMemory / 4
+	}
+	return reserved
+}
+
 func listenTCP(logger *zap.Logger, addr string) (net.Listener, error) {
 	l, err := net.Listen("tcp", addr)
 	if utils.IsErr(err, errors.New("no such host")) && strings.Contains(addr, "localhost") {
@@ -281,16 +321,29 @@ func main() {
 	flag.StringVar(&cfg.Bus.GatewayAddr, "bus.gatewayAddr", cfg.Bus.GatewayAddr, "Address for Sia peer connections (overrides with RENTERD_BUS_GATEWAY_ADDR)")
 	flag.DurationVar(&cfg.Bus.PersistInterval, "bus.persistInterval", cfg.Bus.PersistInterval, "Interval for persisting consensus updates")
 	flag.DurationVar(&cfg.Bus.UsedUTXOExpiry, "bus.usedUTXOExpiry", cfg.Bus.UsedUTXOExpiry, "Expiry for used UTXOs in transactions")
+	flag.BoolVar(&cfg.Bus.HDWallet, "bus.hdWallet", cfg.Bus.HDWallet, "Enables the HD wallet which derives a new address for every transaction")
+	flag.Uint64Var(&cfg.Bus.WalletGapLimit, "bus.walletGapLimit", cfg.Bus.WalletGapLimit, "Number of unused addresses the HD wallet derives ahead")
+	flag.StringVar(&cfg.Bus.WalletPublicKey, "bus.walletPublicKey", cfg.Bus.WalletPublicKey, "Public key of a watch-only wallet whose transactions are signed by an external signer")
 	flag.Int64Var(&cfg.Bus.SlabBufferCompletionThreshold, "bus.slabBufferCompletionThreshold", cfg.Bus.SlabBufferCompletionThreshold, "Threshold for slab buffer upload (overrides with RENTERD_BUS_SLAB_BUFFER_COMPLETION_THRESHOLD)")
 
 	// worker
 	flag.BoolVar(&cfg.Worker.AllowPrivateIPs, "worker.allowPrivateIPs", cfg.Worker.AllowPrivateIPs, "Allows hosts with private IPs")
+	flag.Uint64Var(&cfg.Worker.BandwidthLimits.Download, "worker.downloadBandwidthLimit", cfg.Worker.BandwidthLimits.Download, "Max download bandwidth in bytes per second, 0 is unlimited (overrides with RENTERD_WORKER_DOWNLOAD_BANDWIDTH_LIMIT)")
+	flag.Uint64Var(&cfg.Worker.BandwidthLimits.Upload, "worker.uploadBandwidthLimit", cfg.Worker.BandwidthLimits.Upload, "Max upload bandwidth in bytes per second, 0 is unlimited (overrides with RENTERD_WORKER_UPLOAD_BANDWIDTH_LIMIT)")
+	flag.Uint64Var(&cfg.Worker.BandwidthLimits.Migration, "worker.migrationBandwidthLimit", cfg.Worker.BandwidthLimits.Migration, "Max bandwidth used by migrations in bytes per second, 0 is unlimited")
+	flag.Uint64Var(&cfg.Worker.BandwidthLimits.PackedSlabs, "worker.packedSlabsBandwidthLimit", cfg.Worker.BandwidthLimits.PackedSlabs, "Max bandwidth used by packed slab uploads in bytes per second, 0 is unlimited")
 	flag.DurationVar(&cfg.Worker.BusFlushInterval, "worker.busFlushInterval", cfg.Worker.BusFlushInterval, "Interval for flushing data to bus")
 	flag.Uint64Var(&cfg.Worker.DownloadMaxMemory, "worker.downloadMaxMemory", cfg.Worker.DownloadMaxMemory, "Max amount of RAM the worker allocates for slabs when downloading (overrides with RENTERD_WORKER_DOWNLOAD_MAX_MEMORY)")
+	flag.Uint64Var(&cfg.Worker.DownloadReservedMemory.Interactive, "worker.downloadReservedMemory.interactive", cfg.Worker.DownloadReservedMemory.Interactive, "Memory reserved for interactive downloads (overrides with RENTERD_WORKER_DOWNLOAD_RESERVED_MEMORY_INTERACTIVE)")
+	flag.Uint64Var(&cfg.Worker.DownloadReservedMemory.Background, "worker.downloadReservedMemory.background", cfg.Worker.DownloadReservedMemory.Background, "Memory reserved for background downloads (overrides with RENTERD_WORKER_DOWNLOAD_RESERVED_MEMORY_BACKGROUND)")
+	flag.Uint64Var(&cfg.Worker.DownloadReservedMemory.Migration, "worker.downloadReservedMemory.migration", cfg.Worker.DownloadReservedMemory.Migration, "Memory reserved for migration downloads (overrides with RENTERD_WORKER_DOWNLOAD_RESERVED_MEMORY_MIGRATION)")
 	flag.Uint64Var(&cfg.Worker.DownloadMaxOverdrive, "worker.downloadMaxOverdrive", cfg.Worker.DownloadMaxOverdrive, "Max overdrive workers for downloads")
 	flag.StringVar(&cfg.Worker.ID, "worker.id", cfg.Worker.ID, "Unique ID for worker (overrides with RENTERD_WORKER_ID)")
 	flag.DurationVar(&cfg.Worker.DownloadOverdriveTimeout, "worker.downloadOverdriveTimeout", cfg.Worker.DownloadOverdriveTimeout, "Timeout for overdriving slab downloads")
 	flag.Uint64Var(&cfg.Worker.UploadMaxMemory, "worker.uploadMaxMemory", cfg.Worker.UploadMaxMemory, "Max amount of RAM the worker allocates for slabs when uploading (overrides with RENTERD_WORKER_UPLOAD_MAX_MEMORY)")
+	flag.Uint64Var(&cfg.Worker.UploadReservedMemory.Interactive, "worker.uploadReservedMemory.interactive", cfg.Worker.UploadReservedMemory.Interactive, "Memory reserved for interactive uploads (overrides with RENTERD_WORKER_UPLOAD_RESERVED_MEMORY_INTERACTIVE)")
+	flag.Uint64Var(&cfg.Worker.UploadReservedMemory.Background, "worker.uploadReservedMemory.background", cfg.Worker.UploadReservedMemory.Background, "Memory reserved for background uploads (overrides with RENTERD_WORKER_UPLOAD_RESERVED_MEMORY_BACKGROUND)")
+	flag.Uint64Var(&cfg.Worker.UploadReservedMemory.Migration, "worker.uploadReservedMemory.migration", cfg.Worker.UploadReservedMemory.Migration, "Memory reserved for migration uploads (overrides with RENTERD_WORKER_UPLOAD_RESERVED_MEMORY_MIGRATION)")
 	flag.Uint64Var(&cfg.Worker.UploadMaxOverdrive, "worker.uploadMaxOverdrive", cfg.Worker.UploadMaxOverdrive, "Max overdrive workers for uploads")
 	flag.DurationVar(&cfg.Worker.UploadOverdriveTimeout, "worker.uploadOverdriveTimeout", cfg.Worker.UploadOverdriveTimeout, "Timeout for overdriving slab uploads")
 	flag.BoolVar(&cfg.Worker.Enabled, "worker.enabled", cfg.Worker.Enabled, "Enables/disables worker (overrides with RENTERD_WORKER_ENABLED)")
@@ -298,6 +351,7 @@ func main() {
 
 	// autopilot
 	flag.DurationVar(&cfg.Autopilot.AccountsRefillInterval, "autopilot.accountRefillInterval", cfg.Autopilot.AccountsRefillInterval, "Interval for refilling workers' account balances")
+	flag.StringVar(&cfg.Autopilot.GeoIPDatabase, "autopilot.geoIPDatabase", cfg.Autopilot.GeoIPDatabase, "Path to a GeoIP/ASN database used to enforce host diversity limits (overrides with RENTERD_AUTOPILOT_GEOIP_DATABASE)")
 	flag.DurationVar(&cfg.Autopilot.Heartbeat, "autopilot.heartbeat", cfg.Autopilot.Heartbeat, "Interval for autopilot loop execution")
 	flag.Float64Var(&cfg.Autopilot.MigrationHealthCutoff, "autopilot.migrationHealthCutoff", cfg.Autopilot.MigrationHealthCutoff, "Threshold for migrating slabs based on health")
 	flag.DurationVar(&cfg.Autopilot.RevisionBroadcastInterval, "autopilot.revisionBroadcastInterval", cfg.Autopilot.RevisionBroadcastInterval, "Interval for broadcasting contract revisions (overrides with RENTERD_AUTOPILOT_REVISION_BROADCAST_INTERVAL)")
@@ -314,6 +368,10 @@ func main() {
 	flag.BoolVar(&cfg.S3.Enabled, "s3.enabled", cfg.S3.Enabled, "Enables/disables S3 API (requires worker.enabled to be 'true', overrides with RENTERD_S3_ENABLED)")
 	flag.BoolVar(&cfg.S3.HostBucketEnabled, "s3.hostBucketEnabled", cfg.S3.HostBucketEnabled, "Enables bucket rewriting in the router (overrides with RENTERD_S3_HOST_BUCKET_ENABLED)")
 
+	// webdav
+	flag.StringVar(&cfg.WebDAV.Address, "webdav.address", cfg.WebDAV.Address, "Address for serving the WebDAV API (overrides with RENTERD_WEBDAV_ADDRESS)")
+	flag.BoolVar(&cfg.WebDAV.Enabled, "webdav.enabled", cfg.WebDAV.Enabled, "Enables/disables the WebDAV API (requires worker.enabled to be 'true', overrides with RENTERD_WEBDAV_ENABLED)")
+
 	// custom usage
 	flag.Usage = func() {
 		log.Print(usageHeader)
@@ -333,6 +391,18 @@ func main() {
 	} else if flag.Arg(0) == "config" {
 		cmdBuildConfig()
 		return
+	} else if flag.Arg(0) == "sign" {
+		cmdSign(flag.Arg(1), flag.Arg(2))
+		return
+	} else if flag.Arg(0) == "backup" {
+		cmdBackup(flag.Arg(1))
+		return
+	} else if flag.Arg(0) == "decrypt-backup" {
+		cmdDecryptBackup(flag.Arg(1), flag.Arg(2))
+		return
+	} else if flag.Arg(0) == "copy-db" {
+		cmdCopyDB(flag.Args()[1:])
+		return
 	} else if flag.Arg(0) != "" {
 		flag.Usage()
 		return
@@ -362,12 +432,22 @@ func main() {
 	parseEnvVar("RENTERD_WORKER_API_PASSWORD", &depWorkerRemotePassStr)
 	parseEnvVar("RENTERD_WORKER_ENABLED", &cfg.Worker.Enabled)
 	parseEnvVar("RENTERD_WORKER_ID", &cfg.Worker.ID)
+	parseEnvVar("RENTERD_WORKER_SECRET", &cfg.Worker.Secret)
 	parseEnvVar("RENTERD_WORKER_UNAUTHENTICATED_DOWNLOADS", &cfg.Worker.AllowUnauthenticatedDownloads)
 	parseEnvVar("RENTERD_WORKER_DOWNLOAD_MAX_MEMORY", &cfg.Worker.DownloadMaxMemory)
 	parseEnvVar("RENTERD_WORKER_UPLOAD_MAX_MEMORY", &cfg.Worker.UploadMaxMemory)
+	parseEnvVar("RENTERD_WORKER_DOWNLOAD_RESERVED_MEMORY_INTERACTIVE", &cfg.Worker.DownloadReservedMemory.Interactive)
+	parseEnvVar("RENTERD_WORKER_DOWNLOAD_RESERVED_MEMORY_BACKGROUND", &cfg.Worker.DownloadReservedMemory.Background)
+	parseEnvVar("RENTERD_WORKER_DOWNLOAD_RESERVED_MEMORY_MIGRATION", &cfg.Worker.DownloadReservedMemory.Migration)
+	parseEnvVar("RENTERD_WORKER_UPLOAD_RESERVED_MEMORY_INTERACTIVE", &cfg.Worker.UploadReservedMemory.Interactive)
+	parseEnvVar("RENTERD_WORKER_UPLOAD_RESERVED_MEMORY_BACKGROUND", &cfg.Worker.UploadReservedMemory.Background)
+	parseEnvVar("RENTERD_WORKER_UPLOAD_RESERVED_MEMORY_MIGRATION", &cfg.Worker.UploadReservedMemory.Migration)
+	parseEnvVar("RENTERD_WORKER_DOWNLOAD_BANDWIDTH_LIMIT", &cfg.Worker.BandwidthLimits.Download)
+	parseEnvVar("RENTERD_WORKER_UPLOAD_BANDWIDTH_LIMIT", &cfg.Worker.BandwidthLimits.Upload)
 
 	parseEnvVar("RENTERD_AUTOPILOT_ENABLED", &cfg.Autopilot.Enabled)
 	parseEnvVar("RENTERD_AUTOPILOT_REVISION_BROADCAST_INTERVAL", &cfg.Autopilot.RevisionBroadcastInterval)
+	parseEnvVar("RENTERD_AUTOPILOT_GEOIP_DATABASE", &cfg.Autopilot.GeoIPDatabase)
 	parseEnvVar("RENTERD_MIGRATOR_PARALLEL_SLABS_PER_WORKER", &cfg.Autopilot.MigratorParallelSlabsPerWorker)
 
 	parseEnvVar("RENTERD_S3_ADDRESS", &cfg.S3.Address)
@@ -375,6 +455,9 @@ func main() {
 	parseEnvVar("RENTERD_S3_DISABLE_AUTH", &cfg.S3.DisableAuth)
 	parseEnvVar("RENTERD_S3_HOST_BUCKET_ENABLED", &cfg.S3.HostBucketEnabled)
 
+	parseEnvVar("RENTERD_WEBDAV_ADDRESS", &cfg.WebDAV.Address)
+	parseEnvVar("RENTERD_WEBDAV_ENABLED", &cfg.WebDAV.Enabled)
+
 	parseEnvVar("RENTERD_LOG_LEVEL", &cfg.Log.Level)
 	parseEnvVar("RENTERD_LOG_FILE_ENABLED", &cfg.Log.File.Enabled)
 	parseEnvVar("RENTERD_LOG_FILE_FORMAT", &cfg.Log.File.Format)
@@ -387,6 +470,11 @@ func main() {
 	parseEnvVar("RENTERD_LOG_DATABASE_IGNORE_RECORD_NOT_FOUND_ERROR", &cfg.Log.Database.IgnoreRecordNotFoundError)
 	parseEnvVar("RENTERD_LOG_DATABASE_SLOW_THRESHOLD", &cfg.Log.Database.SlowThreshold)
 
+	// cap the default memory reservations, reservations that were configured
+	// explicitly are used as is
+	cfg.Worker.DownloadReservedMemory = capDefaultReservedMemory(cfg.Worker.DownloadReservedMemory, cfg.Worker.DownloadMaxMemory)
+	cfg.Worker.UploadReservedMemory = capDefaultReservedMemory(cfg.Worker.UploadReservedMemory, cfg.Worker.UploadMaxMemory)
+
 	// check that the API password is set
 	if cfg.HTTP.Password == "" {
 		if disableStdin {
@@ -396,21 +484,6 @@ func main() {
 		setAPIPassword()
 	}
 
-	// check that the seed is set
-	if cfg.Seed == "" {
-		if disableStdin {
-			stdoutFatalError("Seed must be set via environment variable or config file when --env flag is set")
-			return
-		}
-		setSeedPhrase()
-	}
-
-	var rawSeed [32]byte
-	if err := wallet.SeedFromPhrase(&rawSeed, cfg.Seed); err != nil {
-		log.Fatal("failed to load wallet", zap.Error(err))
-	}
-	seed := wallet.KeyFromSeed(&rawSeed, 0)
-
 	if cfg.S3.Enabled {
 		var keyPairsV4 string
 		parseEnvVar("RENTERD_S3_KEYPAIRS_V4", &keyPairsV4)
@@ -428,6 +501,33 @@ func main() {
 		mustParseWorkers(depWorkerRemoteAddrsStr, depWorkerRemotePassStr)
 	}
 
+	// check that the seed is set, a bus with a watch-only wallet doesn't need
+	// it and a worker only needs it if it doesn't have its own secret
+	busNeedsSeed := cfg.Bus.RemoteAddr == "" && (cfg.Bus.WalletPublicKey == "" || cfg.Bus.HDWallet)
+	workerNeedsSeed := len(cfg.Worker.Remotes) == 0 && cfg.Worker.Enabled && cfg.Worker.Secret == ""
+	if cfg.Seed == "" && (busNeedsSeed || workerNeedsSeed) {
+		if disableStdin {
+			stdoutFatalError("Seed must be set via environment variable or config file when --env flag is set")
+			return
+		}
+		setSeedPhrase()
+	}
+
+	var rawSeed [32]byte
+	var seed types.PrivateKey
+	if cfg.Seed != "" {
+		if err := wallet.SeedFromPhrase(&rawSeed, cfg.Seed); err != nil {
+			log.Fatal("failed to load wallet", zap.Error(err))
+		}
+		seed = wallet.KeyFromSeed(&rawSeed, 0)
+	}
+
+	// the worker derives its keys from the seed unless it has its own secret
+	workerSeed := seed
+	if cfg.Worker.Secret != "" {
+		workerSeed = workerKeyFromSecret(cfg.Worker.Secret)
+	}
+
 	// Create logger.
 	if cfg.Log.Level == "" {
 		cfg.Log.Level = "info" // default to 'info' if not set
@@ -455,6 +555,9 @@ func main() {
 		Logger:      logger,
 		Network:     network,
 	}
+	if cfg.Bus.HDWallet && cfg.Seed != "" {
+		busCfg.WalletSeed = &rawSeed
+	}
 
 	type shutdownFnEntry struct {
 		name string
@@ -519,16 +622,18 @@ func main() {
 
 	var s3Srv *http.Server
 	var s3Listener net.Listener
+	var webdavSrv *http.Server
+	var webdavListener net.Listener
 	var workers []autopilot.Worker
 	setupWorkerFn := node.NoopFn
 	if len(cfg.Worker.Remotes) == 0 {
 		if cfg.Worker.Enabled {
 			workerAddr := cfg.HTTP.Address + "/api/worker"
 			var shutdownFn node.ShutdownFn
-			w, s3Handler, setupFn, shutdownFn, err := node.NewWorker(cfg.Worker, s3.Opts{
+			w, s3Handler, webdavHandler, setupFn, shutdownFn, err := node.NewWorker(cfg.Worker, s3.Opts{
 				AuthDisabled:      cfg.S3.DisableAuth,
 				HostBucketEnabled: cfg.S3.HostBucketEnabled,
-			}, bc, seed, logger)
+			}, bc, workerSeed, logger)
 			if err != nil {
 				logger.Fatal("failed to create worker: " + err.Error())
 			}
@@ -540,7 +645,7 @@ func main() {
 				fn:   shutdownFn,
 			})
 
-			mux.Sub["/api/worker"] = utils.TreeMux{Handler: iworker.Auth(cfg.HTTP.Password, cfg.Worker.AllowUnauthenticatedDownloads)(w)}
+			mux.Sub["/api/worker"] = utils.TreeMux{Handler: iworker.APIAuth(cfg.HTTP.Password, cfg.Worker.AllowUnauthenticatedDownloads)(w)}
 			wc := worker.NewClient(workerAddr, cfg.HTTP.Password)
 			workers = append(workers, wc)
 
@@ -558,6 +663,21 @@ func main() {
 					fn:   s3Srv.Shutdown,
 				})
 			}
+
+			if cfg.WebDAV.Enabled {
+				webdavSrv = &http.Server{
+					Addr:    cfg.WebDAV.Address,
+					Handler: jape.BasicAuth(cfg.HTTP.Password)(webdavHandler),
+				}
+				webdavListener, err = listenTCP(logger, cfg.WebDAV.Address)
+				if err != nil {
+					logger.Fatal("failed to create listener: " + err.Error())
+				}
+				shutdownFns = append(shutdownFns, shutdownFnEntry{
+					name: "WebDAV",
+					fn:   webdavSrv.Shutdown,
+				})
+			}
 		}
 	} else {
 		for _, remote := range cfg.Worker.Remotes {
@@ -632,6 +752,11 @@ func main() {
 		logger.Info("s3: Listening on " + s3Listener.Addr().String())
 	}
 
+	if webdavSrv != nil {
+		go webdavSrv.Serve(webdavListener)
+		logger.Info("webdav: Listening on " + webdavListener.Addr().String())
+	}
+
 	syncerAddress, err := bc.SyncerAddress(context.Background())
 	if err != nil {
 		logger.Fatal("failed to fetch syncer address: " + err.Error())
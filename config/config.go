@@ -20,6 +20,7 @@ type (
 		Bus       Bus       `yaml:"bus,omitempty"`
 		Worker    Worker    `yaml:"worker,omitempty"`
 		S3        S3        `yaml:"s3,omitempty"`
+		WebDAV    WebDAV    `yaml:"webdav,omitempty"`
 		Autopilot Autopilot `yaml:"autopilot,omitempty"`
 
 		Database Database `yaml:"database,omitempty"`
@@ -55,6 +56,31 @@ type (
 		PersistInterval               time.Duration `yaml:"persistInterval,omitempty"`
 		UsedUTXOExpiry                time.Duration `yaml:"usedUtxoExpiry,omitempty"`
 		SlabBufferCompletionThreshold int64         `yaml:"slabBufferCompleionThreshold,omitempty"`
+		HDWallet                      bool          `yaml:"hdWallet,omitempty"`
+		WalletGapLimit                uint64        `yaml:"walletGapLimit,omitempty"`
+		WalletPublicKey               string        `yaml:"walletPublicKey,omitempty"`
+
+		MetricsRetention MetricsRetention `yaml:"metricsRetention,omitempty"`
+	}
+
+	// MetricsRetention configures how long the bus keeps the data points of
+	// every metric.
+	MetricsRetention struct {
+		Contract         MetricRetention `yaml:"contract,omitempty"`
+		ContractPrune    MetricRetention `yaml:"contractPrune,omitempty"`
+		ContractSet      MetricRetention `yaml:"contractSet,omitempty"`
+		ContractSetChurn MetricRetention `yaml:"contractSetChurn,omitempty"`
+		Performance      MetricRetention `yaml:"performance,omitempty"`
+		Wallet           MetricRetention `yaml:"wallet,omitempty"`
+	}
+
+	// MetricRetention configures how long the raw data points and the hourly
+	// and daily rollups of a metric are kept, zero means forever. Only
+	// contract and performance metrics have rollups.
+	MetricRetention struct {
+		Raw    time.Duration `yaml:"raw,omitempty"`
+		Hourly time.Duration `yaml:"hourly,omitempty"`
+		Daily  time.Duration `yaml:"daily,omitempty"`
 	}
 
 	// LogFile configures the file output of the logger.
@@ -95,6 +121,11 @@ type (
 		Password        string `yaml:"password,omitempty"`
 		Database        string `yaml:"database,omitempty"`
 		MetricsDatabase string `yaml:"metricsDatabase,omitempty"`
+
+		// ReadReplicas are the URIs of read replicas of the main database,
+		// they share the credentials and database name of the primary.
+		ReadReplicas  []string      `yaml:"readReplicas,omitempty"`
+		MaxReplicaLag time.Duration `yaml:"maxReplicaLag,omitempty"`
 	}
 
 	// PostgreSQL contains the configuration for a PostgreSQL database.
@@ -104,6 +135,11 @@ type (
 		Password        string `yaml:"password,omitempty"`
 		Database        string `yaml:"database,omitempty"`
 		MetricsDatabase string `yaml:"metricsDatabase,omitempty"`
+
+		// ReadReplicas are the URIs of read replicas of the main database,
+		// they share the credentials and database name of the primary.
+		ReadReplicas  []string      `yaml:"readReplicas,omitempty"`
+		MaxReplicaLag time.Duration `yaml:"maxReplicaLag,omitempty"`
 	}
 
 	RemoteWorker struct {
@@ -119,27 +155,56 @@ type (
 		HostBucketEnabled bool              `yaml:"hostBucketEnabled,omitempty"`
 	}
 
+	// WebDAV contains the configuration for the worker's WebDAV server.
+	WebDAV struct {
+		Address string `yaml:"address,omitempty"`
+		Enabled bool   `yaml:"enabled,omitempty"`
+	}
+
 	// Worker contains the configuration for a worker.
 	Worker struct {
-		Enabled                       bool           `yaml:"enabled,omitempty"`
-		ID                            string         `yaml:"id,omitempty"`
-		Remotes                       []RemoteWorker `yaml:"remotes,omitempty"`
-		AllowPrivateIPs               bool           `yaml:"allowPrivateIPs,omitempty"`
-		BusFlushInterval              time.Duration  `yaml:"busFlushInterval,omitempty"`
-		ContractLockTimeout           time.Duration  `yaml:"contractLockTimeout,omitempty"`
-		DownloadOverdriveTimeout      time.Duration  `yaml:"downloadOverdriveTimeout,omitempty"`
-		UploadOverdriveTimeout        time.Duration  `yaml:"uploadOverdriveTimeout,omitempty"`
-		DownloadMaxOverdrive          uint64         `yaml:"downloadMaxOverdrive,omitempty"`
-		DownloadMaxMemory             uint64         `yaml:"downloadMaxMemory,omitempty"`
-		UploadMaxMemory               uint64         `yaml:"uploadMaxMemory,omitempty"`
-		UploadMaxOverdrive            uint64         `yaml:"uploadMaxOverdrive,omitempty"`
-		AllowUnauthenticatedDownloads bool           `yaml:"allowUnauthenticatedDownloads,omitempty"`
+		Enabled                       bool               `yaml:"enabled,omitempty"`
+		ID                            string             `yaml:"id,omitempty"`
+		Secret                        string             `yaml:"secret,omitempty"`
+		Remotes                       []RemoteWorker     `yaml:"remotes,omitempty"`
+		AllowPrivateIPs               bool               `yaml:"allowPrivateIPs,omitempty"`
+		BusFlushInterval              time.Duration      `yaml:"busFlushInterval,omitempty"`
+		ContractLockTimeout           time.Duration      `yaml:"contractLockTimeout,omitempty"`
+		DownloadOverdriveTimeout      time.Duration      `yaml:"downloadOverdriveTimeout,omitempty"`
+		UploadOverdriveTimeout        time.Duration      `yaml:"uploadOverdriveTimeout,omitempty"`
+		DownloadMaxOverdrive          uint64             `yaml:"downloadMaxOverdrive,omitempty"`
+		DownloadMaxMemory             uint64             `yaml:"downloadMaxMemory,omitempty"`
+		DownloadReservedMemory        MemoryReservations `yaml:"downloadReservedMemory,omitempty"`
+		UploadMaxMemory               uint64             `yaml:"uploadMaxMemory,omitempty"`
+		UploadReservedMemory          MemoryReservations `yaml:"uploadReservedMemory,omitempty"`
+		UploadMaxOverdrive            uint64             `yaml:"uploadMaxOverdrive,omitempty"`
+		AllowUnauthenticatedDownloads bool               `yaml:"allowUnauthenticatedDownloads,omitempty"`
+		BandwidthLimits               BandwidthLimits    `yaml:"bandwidthLimits,omitempty"`
+	}
+
+	// BandwidthLimits contains the worker's bandwidth limits in bytes per
+	// second, a limit of 0 means the bandwidth is unlimited. The migration
+	// and packed slabs limits apply on top of the download and upload limits.
+	BandwidthLimits struct {
+		Download    uint64 `yaml:"download,omitempty"`
+		Upload      uint64 `yaml:"upload,omitempty"`
+		Migration   uint64 `yaml:"migration,omitempty"`
+		PackedSlabs uint64 `yaml:"packedSlabs,omitempty"`
+	}
+
+	// MemoryReservations contains the minimum amount of memory, in bytes,
+	// that is reserved for each memory priority class.
+	MemoryReservations struct {
+		Interactive uint64 `yaml:"interactive,omitempty"`
+		Background  uint64 `yaml:"background,omitempty"`
+		Migration   uint64 `yaml:"migration,omitempty"`
 	}
 
 	// Autopilot contains the configuration for an autopilot.
 	Autopilot struct {
 		Enabled                        bool          `yaml:"enabled,omitempty"`
 		AccountsRefillInterval         time.Duration `yaml:"accountsRefillInterval,omitempty"`
+		GeoIPDatabase                  string        `yaml:"geoIPDatabase,omitempty"`
 		Heartbeat                      time.Duration `yaml:"heartbeat,omitempty"`
 		MigrationHealthCutoff          float64       `yaml:"migrationHealthCutoff,omitempty"`
 		RevisionBroadcastInterval      time.Duration `yaml:"revisionBroadcastInterval,omitempty"`
@@ -0,0 +1,72 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteArchive writes the contents of dir to w as a gzipped tar archive. If a
+// password is provided the archive is encrypted.
+func WriteArchive(w io.Writer, dir, password string) (err error) {
+	if password != "" {
+		ew, err := NewEncryptWriter(w, password)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := ew.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = ew
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if path == dir {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(name)
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		} else if d.IsDir() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	} else if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lukechampine.com/frand"
+)
+
+func TestEncryption(t *testing.T) {
+	for _, size := range []int{0, 1, chunkSize, chunkSize + 1, 3*chunkSize + 123} {
+		data := frand.Bytes(size)
+
+		// encrypt the data
+		var buf bytes.Buffer
+		w, err := NewEncryptWriter(&buf, "foo")
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		} else if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		encrypted := buf.Bytes()
+		if !IsEncrypted(encrypted) {
+			t.Fatal("expected encrypted backup")
+		}
+
+		// decrypt it
+		r, err := NewDecryptReader(bytes.NewReader(encrypted), "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(decrypted, data) {
+			t.Fatal("data mismatch", size)
+		}
+
+		// decrypting with the wrong password fails
+		r, err = NewDecryptReader(bytes.NewReader(encrypted), "bar")
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := io.ReadAll(r); !errors.Is(err, ErrInvalidPassword) {
+			t.Fatal("unexpected error", err)
+		}
+
+		// decrypting a truncated backup fails
+		r, err = NewDecryptReader(bytes.NewReader(encrypted[:len(encrypted)-1]), "foo")
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := io.ReadAll(r); !errors.Is(err, ErrInvalidPassword) {
+			t.Fatal("unexpected error", err)
+		}
+	}
+
+	// decrypting a backup that isn't encrypted fails
+	if _, err := NewDecryptReader(bytes.NewReader([]byte("not encrypted")), "foo"); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	// create a directory that resembles a backup
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"db/db.sqlite":       frand.Bytes(100),
+		"db/metrics.sqlite":  frand.Bytes(10),
+		"partial_slabs/1-2-": frand.Bytes(chunkSize + 1),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// write an encrypted archive
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, dir, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewDecryptReader(&buf, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// extract it
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	var n int
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		} else if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(data, files[hdr.Name]) {
+			t.Fatal("unexpected contents", hdr.Name)
+		}
+		n++
+	}
+	if n != len(files) {
+		t.Fatalf("expected %v files, got %v", len(files), n)
+	}
+}
@@ -0,0 +1,215 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"lukechampine.com/frand"
+)
+
+const (
+	// chunkSize is the size of the plaintext chunks the archive is split into
+	// before they are encrypted.
+	chunkSize = 1 << 16
+
+	saltSize   = 16
+	prefixSize = chacha20poly1305.NonceSizeX - 8
+
+	flagChunk     = 0
+	flagLastChunk = 1
+)
+
+var (
+	// ErrInvalidPassword is returned when an encrypted backup can't be
+	// decrypted with the given password.
+	ErrInvalidPassword = errors.New("invalid password or corrupted backup")
+
+	// ErrNotEncrypted is returned when decrypting a backup that isn't
+	// encrypted.
+	ErrNotEncrypted = errors.New("backup is not encrypted")
+
+	// magic identifies an encrypted backup.
+	magic = []byte("RNTDBKP1")
+)
+
+type (
+	// encryptWriter encrypts everything written to it in chunks using
+	// XChaCha20-Poly1305. Every chunk is authenticated together with its
+	// position and a flag that marks the last chunk, which prevents chunks from
+	// being reordered or the backup from being truncated.
+	encryptWriter struct {
+		w      io.Writer
+		aead   cipher.AEAD
+		prefix []byte
+		n      uint64
+		buf    []byte
+		closed bool
+	}
+
+	decryptReader struct {
+		r      io.Reader
+		aead   cipher.AEAD
+		prefix []byte
+		n      uint64
+		buf    []byte
+		done   bool
+	}
+)
+
+// IsEncrypted returns true if the given header belongs to an encrypted backup.
+func IsEncrypted(header []byte) bool {
+	return bytes.HasPrefix(header, magic)
+}
+
+// NewEncryptWriter returns a writer that encrypts everything written to it
+// with a key derived from the given password. The writer has to be closed to
+// write the final chunk, it doesn't close the underlying writer.
+func NewEncryptWriter(w io.Writer, password string) (io.WriteCloser, error) {
+	salt := frand.Bytes(saltSize)
+	prefix := frand.Bytes(prefixSize)
+	aead, err := chacha20poly1305.NewX(deriveKey(password, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	// write the header
+	header := append(append(append([]byte(nil), magic...), salt...), prefix...)
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	return &encryptWriter{
+		w:      w,
+		aead:   aead,
+		prefix: prefix,
+		buf:    make([]byte, 0, chunkSize),
+	}, nil
+}
+
+// NewDecryptReader returns a reader that decrypts a backup that was encrypted
+// with the given password.
+func NewDecryptReader(r io.Reader, password string) (io.Reader, error) {
+	header := make([]byte, len(magic)+saltSize+prefixSize)
+	if _, err := io.ReadFull(r, header); errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return nil, ErrNotEncrypted
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	} else if !IsEncrypted(header) {
+		return nil, ErrNotEncrypted
+	}
+	salt := header[len(magic) : len(magic)+saltSize]
+	aead, err := chacha20poly1305.NewX(deriveKey(password, salt))
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		r:      r,
+		aead:   aead,
+		prefix: header[len(magic)+saltSize:],
+	}, nil
+}
+
+// Write implements io.Writer.
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed writer")
+	}
+	var written int
+	for len(p) > 0 {
+		n := copy(w.buf[len(w.buf):chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+		if len(w.buf) == chunkSize && len(p) > 0 {
+			if err := w.writeChunk(flagChunk); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close writes the last chunk.
+func (w *encryptWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.writeChunk(flagLastChunk)
+}
+
+func (w *encryptWriter) writeChunk(flag byte) error {
+	ad := chunkHeader(flag, len(w.buf))
+	sealed := w.aead.Seal(nil, chunkNonce(w.prefix, w.n), w.buf, ad)
+	w.n++
+	w.buf = w.buf[:0]
+	if _, err := w.w.Write(ad); err != nil {
+		return err
+	}
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+// Read implements io.Reader.
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		} else if err := r.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *decryptReader) readChunk() error {
+	ad := make([]byte, 5)
+	if _, err := io.ReadFull(r.r, ad); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: backup is truncated", ErrInvalidPassword)
+	} else if err != nil {
+		return err
+	}
+	flag, size := ad[0], binary.LittleEndian.Uint32(ad[1:])
+	if (flag != flagChunk && flag != flagLastChunk) || size > chunkSize {
+		return ErrInvalidPassword
+	}
+	sealed := make([]byte, int(size)+r.aead.Overhead())
+	if _, err := io.ReadFull(r.r, sealed); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: backup is truncated", ErrInvalidPassword)
+	} else if err != nil {
+		return err
+	}
+	plain, err := r.aead.Open(sealed[:0], chunkNonce(r.prefix, r.n), sealed, ad)
+	if err != nil {
+		return ErrInvalidPassword
+	}
+	r.n++
+	r.buf = plain
+	r.done = flag == flagLastChunk
+	return nil
+}
+
+func chunkHeader(flag byte, size int) []byte {
+	ad := make([]byte, 5)
+	ad[0] = flag
+	binary.LittleEndian.PutUint32(ad[1:], uint32(size))
+	return ad
+}
+
+func chunkNonce(prefix []byte, n uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	copy(nonce, prefix)
+	binary.LittleEndian.PutUint64(nonce[prefixSize:], n)
+	return nonce
+}
+
+func deriveKey(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, chacha20poly1305.KeySize)
+}
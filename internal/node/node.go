@@ -5,15 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"go.sia.tech/core/consensus"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/alerts"
+	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/autopilot"
 	"go.sia.tech/renterd/bus"
 	"go.sia.tech/renterd/config"
@@ -22,6 +25,7 @@ import (
 	"go.sia.tech/renterd/webhooks"
 	"go.sia.tech/renterd/worker"
 	"go.sia.tech/renterd/worker/s3"
+	"go.sia.tech/renterd/worker/webdav"
 	"go.sia.tech/siad/modules"
 	mconsensus "go.sia.tech/siad/modules/consensus"
 	"go.sia.tech/siad/modules/gateway"
@@ -37,6 +41,7 @@ import (
 type Bus interface {
 	worker.Bus
 	s3.Bus
+	webdav.Bus
 }
 
 type BusConfig struct {
@@ -46,6 +51,10 @@ type BusConfig struct {
 	Network     *consensus.Network
 	Logger      *zap.Logger
 	Miner       *Miner
+
+	// WalletSeed is the seed the HD wallet derives its keys from, it's
+	// required if the HD wallet is enabled.
+	WalletSeed *[32]byte
 }
 
 type AutopilotConfig struct {
@@ -98,6 +107,8 @@ func NewBus(cfg BusConfig, dir string, seed types.PrivateKey, l *zap.Logger) (ht
 
 	// create database connections
 	var dbConn, dbMetricsConn gorm.Dialector
+	var dbReplicaConns []gorm.Dialector
+	var maxReplicaLag time.Duration
 	if cfg.Database.MySQL.URI != "" {
 		// create MySQL connections
 		dbConn = stores.NewMySQLConnection(
@@ -112,6 +123,34 @@ func NewBus(cfg BusConfig, dir string, seed types.PrivateKey, l *zap.Logger) (ht
 			cfg.Database.MySQL.URI,
 			cfg.Database.MySQL.MetricsDatabase,
 		)
+		for _, uri := range cfg.Database.MySQL.ReadReplicas {
+			dbReplicaConns = append(dbReplicaConns, stores.NewMySQLConnection(
+				cfg.Database.MySQL.User,
+				cfg.Database.MySQL.Password,
+				uri,
+				cfg.Database.MySQL.Database,
+			))
+		}
+		maxReplicaLag = cfg.Database.MySQL.MaxReplicaLag
+	} else if cfg.Database.PostgreSQL.URI != "" {
+		// create PostgreSQL connections
+		c := cfg.Database.PostgreSQL
+		dbConn, err = newPostgreSQLConnection(c, c.URI, c.Database)
+		if err != nil {
+			return nil, nil, err
+		}
+		dbMetricsConn, err = newPostgreSQLConnection(c, c.URI, c.MetricsDatabase)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, uri := range c.ReadReplicas {
+			conn, err := newPostgreSQLConnection(c, uri, c.Database)
+			if err != nil {
+				return nil, nil, err
+			}
+			dbReplicaConns = append(dbReplicaConns, conn)
+		}
+		maxReplicaLag = c.MaxReplicaLag
 	} else {
 		// create database directory
 		dbDir := filepath.Join(dir, "db")
@@ -134,8 +173,43 @@ func NewBus(cfg BusConfig, dir string, seed types.PrivateKey, l *zap.Logger) (ht
 		Context:                   nil,
 	}
 
+	// create the keychain of the HD wallet, the store needs it to track the
+	// wallet's addresses
+	var keychain *wallet.Keychain
+	var walletAddresses wallet.AddressTracker
+	if cfg.HDWallet {
+		if cfg.WalletSeed == nil {
+			return nil, nil, errors.New("the HD wallet requires the wallet seed")
+		}
+		keychain = wallet.NewKeychain(*cfg.WalletSeed, cfg.WalletGapLimit)
+		walletAddresses = keychain
+	}
+
+	// a watch-only wallet only knows the public key of its address, its
+	// transactions are signed by an external signer
+	var walletKey *types.PublicKey
+	if cfg.WalletPublicKey != "" {
+		if keychain != nil {
+			return nil, nil, errors.New("the HD wallet can't be watch-only")
+		}
+		var pk types.PublicKey
+		if err := pk.UnmarshalText([]byte(cfg.WalletPublicKey)); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse wallet public key: %w", err)
+		}
+		walletKey = &pk
+	}
+
+	// the seed is only required if the wallet isn't watch-only
+	var walletAddr types.Address
+	if walletKey != nil {
+		walletAddr = wallet.StandardAddress(*walletKey)
+	} else if len(seed) == 0 {
+		return nil, nil, errors.New("the wallet seed is required unless the wallet is watch-only")
+	} else {
+		walletAddr = wallet.StandardAddress(seed.PublicKey())
+	}
+
 	alertsMgr := alerts.NewManager()
-	walletAddr := wallet.StandardAddress(seed.PublicKey())
 	sqlStoreDir := filepath.Join(dir, "partial_slabs")
 	announcementMaxAge := time.Duration(cfg.AnnouncementMaxAgeHours) * time.Hour
 	sqlStore, ccid, err := stores.NewSQLStore(stores.Config{
@@ -147,12 +221,16 @@ func NewBus(cfg BusConfig, dir string, seed types.PrivateKey, l *zap.Logger) (ht
 		AnnouncementMaxAge:            announcementMaxAge,
 		PersistInterval:               cfg.PersistInterval,
 		WalletAddress:                 walletAddr,
+		WalletAddresses:               walletAddresses,
 		SlabBufferCompletionThreshold: cfg.SlabBufferCompletionThreshold,
 		Logger:                        l.Sugar(),
 		GormLogger:                    dbLogger,
 		RetryTransactionIntervals:     []time.Duration{200 * time.Millisecond, 500 * time.Millisecond, time.Second, 3 * time.Second, 10 * time.Second, 10 * time.Second},
 		LongQueryDuration:             cfg.DatabaseLog.SlowThreshold,
 		LongTxDuration:                cfg.DatabaseLog.SlowThreshold,
+		MetricsRetention:              metricsRetention(cfg.MetricsRetention),
+		ReadReplicas:                  dbReplicaConns,
+		MaxReplicaLag:                 maxReplicaLag,
 	})
 	if err != nil {
 		return nil, nil, err
@@ -165,6 +243,24 @@ func NewBus(cfg BusConfig, dir string, seed types.PrivateKey, l *zap.Logger) (ht
 	// Hook up webhooks to alerts.
 	alertsMgr.RegisterWebhookBroadcaster(hooksMgr)
 
+	// create the wallet, the HD wallet is created before the store subscribes
+	// to consensus since its gap limit scan might derive more addresses
+	var w interface {
+		bus.Wallet
+		modules.ConsensusSetSubscriber
+		modules.TransactionPoolSubscriber
+	}
+	if keychain != nil {
+		w, err = wallet.NewHDWallet(keychain, sqlStore, cfg.UsedUTXOExpiry, zap.NewNop().Sugar())
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if walletKey != nil {
+		w = wallet.NewWatchOnlyWallet(*walletKey, sqlStore, cfg.UsedUTXOExpiry, zap.NewNop().Sugar())
+	} else {
+		w = wallet.NewSingleAddressWallet(seed, sqlStore, cfg.UsedUTXOExpiry, zap.NewNop().Sugar())
+	}
+
 	cancelSubscribe := make(chan struct{})
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
@@ -186,7 +282,6 @@ func NewBus(cfg BusConfig, dir string, seed types.PrivateKey, l *zap.Logger) (ht
 		}
 	}()
 
-	w := wallet.NewSingleAddressWallet(seed, sqlStore, cfg.UsedUTXOExpiry, zap.NewNop().Sugar())
 	tp.TransactionPoolSubscribe(w)
 	if err := cs.ConsensusSetSubscribe(w, modules.ConsensusChangeRecent, nil); err != nil {
 		return nil, nil, err
@@ -204,7 +299,17 @@ func NewBus(cfg BusConfig, dir string, seed types.PrivateKey, l *zap.Logger) (ht
 		return nil, nil, err
 	}
 
-	b, err := bus.New(syncer{g, tp}, alertsMgr, hooksMgr, cm, NewTransactionPool(tp), w, sqlStore, sqlStore, sqlStore, sqlStore, sqlStore, sqlStore, l)
+	// backups are staged in the node's directory rather than the system's
+	// temp dir since they are as large as the databases and partial slabs,
+	// leftovers of interrupted backups are removed on startup
+	backupDir := filepath.Join(dir, "backups")
+	if err := os.RemoveAll(backupDir); err != nil {
+		return nil, nil, err
+	} else if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return nil, nil, err
+	}
+
+	b, err := bus.New(syncer{g, tp}, alertsMgr, hooksMgr, cm, NewTransactionPool(tp), w, sqlStore, sqlStore, sqlStore, sqlStore, sqlStore, sqlStore, backupDir, l)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -222,22 +327,23 @@ func NewBus(cfg BusConfig, dir string, seed types.PrivateKey, l *zap.Logger) (ht
 	return b.Handler(), shutdownFn, nil
 }
 
-func NewWorker(cfg config.Worker, s3Opts s3.Opts, b Bus, seed types.PrivateKey, l *zap.Logger) (http.Handler, http.Handler, SetupFn, ShutdownFn, error) {
+func NewWorker(cfg config.Worker, s3Opts s3.Opts, b Bus, seed types.PrivateKey, l *zap.Logger) (http.Handler, http.Handler, http.Handler, SetupFn, ShutdownFn, error) {
 	workerKey := blake2b.Sum256(append([]byte("worker"), seed...))
-	w, err := worker.New(workerKey, cfg.ID, b, cfg.ContractLockTimeout, cfg.BusFlushInterval, cfg.DownloadOverdriveTimeout, cfg.UploadOverdriveTimeout, cfg.DownloadMaxOverdrive, cfg.UploadMaxOverdrive, cfg.DownloadMaxMemory, cfg.UploadMaxMemory, cfg.AllowPrivateIPs, l)
+	w, err := worker.New(workerKey, cfg.ID, b, cfg.ContractLockTimeout, cfg.BusFlushInterval, cfg.DownloadOverdriveTimeout, cfg.UploadOverdriveTimeout, cfg.DownloadMaxOverdrive, cfg.UploadMaxOverdrive, cfg.DownloadMaxMemory, cfg.UploadMaxMemory, memoryReservations(cfg.DownloadReservedMemory), memoryReservations(cfg.UploadReservedMemory), api.BandwidthLimits(cfg.BandwidthLimits), cfg.AllowPrivateIPs, l)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, err
 	}
 	s3Handler, err := s3.New(b, w, l.Named("s3").Sugar(), s3Opts)
 	if err != nil {
 		err = errors.Join(err, w.Shutdown(context.Background()))
-		return nil, nil, nil, nil, fmt.Errorf("failed to create s3 handler: %w", err)
+		return nil, nil, nil, nil, nil, fmt.Errorf("failed to create s3 handler: %w", err)
 	}
-	return w.Handler(), s3Handler, w.Setup, w.Shutdown, nil
+	webdavHandler := webdav.New(b, w, l.Sugar())
+	return w.Handler(), s3Handler, webdavHandler, w.Setup, w.Shutdown, nil
 }
 
 func NewAutopilot(cfg AutopilotConfig, b autopilot.Bus, workers []autopilot.Worker, l *zap.Logger) (http.Handler, RunFn, ShutdownFn, error) {
-	ap, err := autopilot.New(cfg.ID, b, workers, l, cfg.Heartbeat, cfg.ScannerInterval, cfg.ScannerBatchSize, cfg.ScannerNumThreads, cfg.MigrationHealthCutoff, cfg.AccountsRefillInterval, cfg.RevisionSubmissionBuffer, cfg.MigratorParallelSlabsPerWorker, cfg.RevisionBroadcastInterval)
+	ap, err := autopilot.New(cfg.ID, b, workers, l, cfg.Heartbeat, cfg.ScannerInterval, cfg.ScannerBatchSize, cfg.ScannerNumThreads, cfg.MigrationHealthCutoff, cfg.AccountsRefillInterval, cfg.RevisionSubmissionBuffer, cfg.MigratorParallelSlabsPerWorker, cfg.RevisionBroadcastInterval, cfg.GeoIPDatabase)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -264,3 +370,40 @@ func gormLogLevel(cfg config.DatabaseLog) logger.LogLevel {
 	}
 	return level
 }
+
+func memoryReservations(cfg config.MemoryReservations) map[api.MemoryClass]uint64 {
+	return map[api.MemoryClass]uint64{
+		api.MemoryClassInteractive: cfg.Interactive,
+		api.MemoryClassBackground:  cfg.Background,
+		api.MemoryClassMigration:   cfg.Migration,
+	}
+}
+
+func metricsRetention(cfg config.MetricsRetention) map[string]stores.MetricsRetention {
+	retention := make(map[string]stores.MetricsRetention)
+	for metric, r := range map[string]config.MetricRetention{
+		api.MetricContract:         cfg.Contract,
+		api.MetricContractPrune:    cfg.ContractPrune,
+		api.MetricContractSet:      cfg.ContractSet,
+		api.MetricContractSetChurn: cfg.ContractSetChurn,
+		api.MetricPerformance:      cfg.Performance,
+		api.MetricWallet:           cfg.Wallet,
+	} {
+		if r != (config.MetricRetention{}) {
+			retention[metric] = stores.MetricsRetention{Raw: r.Raw, Hourly: r.Hourly, Daily: r.Daily}
+		}
+	}
+	return retention
+}
+
+func newPostgreSQLConnection(cfg config.PostgreSQL, uri, dbName string) (gorm.Dialector, error) {
+	host, portStr, err := net.SplitHostPort(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PostgreSQL URI %q: %w", uri, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PostgreSQL port %q: %w", portStr, err)
+	}
+	return stores.NewPostgreSQLConnection(host, cfg.User, cfg.Password, dbName, port), nil
+}
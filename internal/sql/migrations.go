@@ -190,6 +190,12 @@ var (
 					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00001_idx_contracts_fcid_timestamp", log)
 				},
 			},
+			{
+				ID: "00002_metrics_rollups",
+				Migrate: func(tx Tx) error {
+					return performMigration(ctx, tx, migrationsFs, dbIdentifier, "00002_metrics_rollups", log)
+				},
+			},
 		}
 	}
 )
@@ -137,6 +137,18 @@ func (s *DB) QueryRow(ctx context.Context, query string, args ...any) *LoggedRow
 // transaction is committed. If the transaction fails due to a busy error, it is
 // retried up to 'maxRetryAttempts' times before returning.
 func (s *DB) Transaction(ctx context.Context, fn func(Tx) error) error {
+	return s.retryTransaction(ctx, nil, fn)
+}
+
+// ReadOnlyTransaction is like Transaction but starts a read-only transaction.
+// Drivers that don't support read-only transactions, like SQLite's, start a
+// regular transaction instead which only acquires a write lock once fn writes
+// to the database.
+func (s *DB) ReadOnlyTransaction(ctx context.Context, fn func(Tx) error) error {
+	return s.retryTransaction(ctx, &sql.TxOptions{ReadOnly: true}, fn)
+}
+
+func (s *DB) retryTransaction(ctx context.Context, opts *sql.TxOptions, fn func(Tx) error) error {
 	var err error
 	txnID := hex.EncodeToString(frand.Bytes(4))
 	log := s.log.Named("transaction").With(zap.String("id", txnID))
@@ -146,7 +158,7 @@ LOOP:
 	for ; attempt < maxRetryAttempts; attempt++ {
 		attemptStart := time.Now()
 		log := log.With(zap.Int("attempt", attempt))
-		err = s.transaction(ctx, fn)
+		err = s.transaction(ctx, opts, fn)
 		if errors.Is(err, context.Canceled) && context.Cause(ctx) != nil {
 			err = context.Cause(ctx)
 			break LOOP
@@ -191,9 +203,9 @@ func (s *DB) Close() error {
 // transaction is a helper function to execute a function within a transaction.
 // If fn returns an error, the transaction is rolled back. Otherwise, the
 // transaction is committed.
-func (s *DB) transaction(ctx context.Context, fn func(tx Tx) error) error {
+func (s *DB) transaction(ctx context.Context, opts *sql.TxOptions, fn func(tx Tx) error) error {
 	start := time.Now()
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.db.BeginTx(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
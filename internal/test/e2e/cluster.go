@@ -333,10 +333,10 @@ func newTestCluster(t *testing.T, opts testClusterOptions) *TestCluster {
 	busShutdownFns = append(busShutdownFns, bStopFn)
 
 	// Create worker.
-	w, s3Handler, wSetupFn, wShutdownFn, err := node.NewWorker(workerCfg, s3.Opts{}, busClient, wk, logger)
+	w, s3Handler, _, wSetupFn, wShutdownFn, err := node.NewWorker(workerCfg, s3.Opts{}, busClient, wk, logger)
 	tt.OK(err)
 	workerServer := http.Server{
-		Handler: iworker.Auth(workerPassword, false)(w),
+		Handler: iworker.APIAuth(workerPassword, false)(w),
 	}
 
 	var workerShutdownFns []func(context.Context) error
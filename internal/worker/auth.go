@@ -18,3 +18,20 @@ func Auth(password string, unauthenticatedDownloads bool) func(http.Handler) htt
 		})
 	}
 }
+
+// APIAuth is like Auth but also lets unauthenticated GET and HEAD requests
+// for websites through, the worker only serves websites for public buckets.
+// It should only wrap the worker API since other handlers might map the
+// website paths to something else.
+func APIAuth(password string, unauthenticatedDownloads bool) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		auth := Auth(password, unauthenticatedDownloads)(h)
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if (req.Method == http.MethodGet || req.Method == http.MethodHead) && strings.HasPrefix(req.URL.Path, "/website/") {
+				h.ServeHTTP(w, req)
+			} else {
+				auth.ServeHTTP(w, req)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthWebsites(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	status := func(h http.Handler, method, path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec.Code
+	}
+
+	// assert websites can be fetched without credentials from the API
+	api := APIAuth("password", false)(h)
+	if code := status(api, http.MethodGet, "/website/bucket/index.html"); code != http.StatusOK {
+		t.Fatal("unexpected status", code)
+	} else if code := status(api, http.MethodHead, "/website/bucket/index.html"); code != http.StatusOK {
+		t.Fatal("unexpected status", code)
+	} else if code := status(api, http.MethodPut, "/website/bucket/index.html"); code != http.StatusUnauthorized {
+		t.Fatal("unexpected status", code)
+	} else if code := status(api, http.MethodGet, "/objects/index.html"); code != http.StatusUnauthorized {
+		t.Fatal("unexpected status", code)
+	}
+
+	// assert other handlers don't let website requests through
+	if code := status(Auth("password", false)(h), http.MethodGet, "/website/bucket/index.html"); code != http.StatusUnauthorized {
+		t.Fatal("unexpected status", code)
+	}
+}
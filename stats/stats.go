@@ -62,6 +62,23 @@ func (a *DataPoints) P90() float64 {
 	return a.p90
 }
 
+// Percentiles returns the requested percentiles of the tracked data points,
+// computed on the fly rather than using the cached p90.
+func (a *DataPoints) Percentiles(ps ...float64) []float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	res := make([]float64, len(ps))
+	for i, p := range ps {
+		v, err := a.Percentile(p)
+		if err != nil {
+			v = 0
+		}
+		res[i] = v
+	}
+	return res
+}
+
 func (a *DataPoints) Recompute() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
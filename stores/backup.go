@@ -0,0 +1,35 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// the paths within a backup match the layout of the renter's directory so
+	// a backup can be restored by extracting it there
+	backupDBDir      = "db"
+	backupMainDB     = "db.sqlite"
+	backupMetricsDB  = "metrics.sqlite"
+	backupPartialDir = "partial_slabs"
+)
+
+// Backup writes a consistent backup of the main and metrics databases as well
+// as the partial slab buffers to dir while the store remains online. The
+// metrics database is backed up first, it's not required to be consistent
+// with the main database.
+func (s *SQLStore) Backup(ctx context.Context, dir string) error {
+	if err := os.MkdirAll(filepath.Join(dir, backupDBDir), 0700); err != nil {
+		return fmt.Errorf("failed to create backup dir: %w", err)
+	} else if err := s.bMetrics.Backup(ctx, filepath.Join(dir, backupDBDir, backupMetricsDB)); err != nil {
+		return fmt.Errorf("failed to back up metrics database: %w", err)
+	}
+	return s.slabBufferMgr.Backup(filepath.Join(dir, backupPartialDir), func() error {
+		if err := s.bMain.Backup(ctx, filepath.Join(dir, backupDBDir, backupMainDB)); err != nil {
+			return fmt.Errorf("failed to back up main database: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,109 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.sia.tech/renterd/api"
+	"lukechampine.com/frand"
+)
+
+func TestBackup(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add a partial slab
+	if _, _, err := ss.AddPartialSlab(context.Background(), frand.Bytes(100), 1, 2, testContractSet); err != nil {
+		t.Fatal(err)
+	}
+	buffers, err := ss.SlabBuffers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(buffers) != 1 {
+		t.Fatal("expected 1 buffer, got", len(buffers))
+	}
+
+	// back up the store
+	dir := t.TempDir()
+	if err := ss.Backup(context.Background(), dir); errors.Is(err, api.ErrBackupNotSupported) {
+		t.Skip("database doesn't support online backups")
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the databases and the buffer were backed up
+	for _, path := range []string{
+		filepath.Join(dir, backupDBDir, backupMainDB),
+		filepath.Join(dir, backupDBDir, backupMetricsDB),
+	} {
+		if fi, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		} else if fi.Size() == 0 {
+			t.Fatal("empty database backup", path)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(dir, backupPartialDir, buffers[0].Filename)); err != nil {
+		t.Fatal(err)
+	} else if fi.Size() < 100 {
+		t.Fatal("unexpected buffer size", fi.Size())
+	}
+
+	// backing up to the same directory again fails instead of overwriting the
+	// existing backup
+	if err := ss.Backup(context.Background(), dir); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestBackupRemoveBuffers(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add a complete buffer
+	if _, _, err := ss.AddPartialSlab(context.Background(), frand.Bytes(bufferedSlabSize(1)), 1, 2, testContractSet); err != nil {
+		t.Fatal(err)
+	}
+	buffers, err := ss.SlabBuffers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(buffers) != 1 || !buffers[0].Complete {
+		t.Fatal("expected 1 complete buffer", buffers)
+	}
+	filename := buffers[0].Filename
+	path := filepath.Join(ss.slabBufferMgr.dir, filename)
+
+	// remove the buffer while the snapshot is taken, the manager shouldn't be
+	// locked and the file should only be deleted after the backup is done
+	dir := t.TempDir()
+	err = ss.slabBufferMgr.Backup(dir, func() error {
+		removed := make(chan struct{})
+		go func() {
+			ss.slabBufferMgr.RemoveBuffers(filename)
+			close(removed)
+		}()
+		select {
+		case <-removed:
+		case <-time.After(10 * time.Second):
+			return errors.New("manager is locked during the snapshot")
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("buffer was deleted during the backup: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the buffer is part of the backup and was deleted afterwards
+	if _, err := os.Stat(filepath.Join(dir, filename)); err != nil {
+		t.Fatal(err)
+	} else if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("expected buffer to be deleted", err)
+	}
+}
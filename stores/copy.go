@@ -0,0 +1,187 @@
+package stores
+
+import (
+	"context"
+	dsql "database/sql"
+	"fmt"
+	"time"
+
+	"go.sia.tech/renterd/stores/sql"
+	"go.sia.tech/renterd/stores/sql/mysql"
+	"go.sia.tech/renterd/stores/sql/postgresql"
+	"go.sia.tech/renterd/stores/sql/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ErrCopyDestinationNotEmpty is returned by CopyDatabases if the destination
+// was already initialised and the copy isn't resumed.
+var ErrCopyDestinationNotEmpty = sql.ErrCopyDestinationNotEmpty
+
+type (
+	// CopyConfig configures a copy of the main and metrics databases from one
+	// backend to another.
+	CopyConfig struct {
+		Src        gorm.Dialector
+		SrcMetrics gorm.Dialector
+		Dst        gorm.Dialector
+		DstMetrics gorm.Dialector
+
+		// Resume continues an interrupted copy instead of requiring the
+		// destination to be empty.
+		Resume bool
+
+		Logger *zap.SugaredLogger
+
+		// Progress is called after every batch of rows that was copied, db
+		// is either "main" or "metrics".
+		Progress func(db, table string, copied, total uint64)
+	}
+
+	copyEndpoint struct {
+		sql.CopyDB
+		name string
+	}
+)
+
+// CopyDatabases copies all rows of the main and metrics databases from the
+// source to the destination backend. The destination is migrated to the same
+// schema as the source, the primary keys are preserved so all relations
+// between rows remain intact. After copying, the number of rows and the
+// contents of every table are verified. Neither database may be in use while
+// copying.
+func CopyDatabases(ctx context.Context, cfg CopyConfig) error {
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop().Sugar()
+	}
+	for _, dbs := range []struct {
+		name     string
+		src, dst gorm.Dialector
+		metrics  bool
+	}{
+		{"main", cfg.Src, cfg.Dst, false},
+		{"metrics", cfg.SrcMetrics, cfg.DstMetrics, true},
+	} {
+		var progress sql.CopyProgressFn
+		if cfg.Progress != nil {
+			name := dbs.name
+			progress = func(table string, copied, total uint64) { cfg.Progress(name, table, copied, total) }
+		}
+		if err := copyDatabase(ctx, dbs.src, dbs.dst, dbs.metrics, cfg.Resume, cfg.Logger.Named(dbs.name), progress); err != nil {
+			return fmt.Errorf("failed to copy %s database: %w", dbs.name, err)
+		}
+	}
+	return nil
+}
+
+func copyDatabase(ctx context.Context, srcConn, dstConn gorm.Dialector, metrics, resume bool, l *zap.SugaredLogger, progress sql.CopyProgressFn) error {
+	src, err := openCopyEndpoint(srcConn)
+	if err != nil {
+		return fmt.Errorf("failed to open source: %w", err)
+	}
+	defer src.DB.Close()
+	dst, err := openCopyEndpoint(dstConn)
+	if err != nil {
+		return fmt.Errorf("failed to open destination: %w", err)
+	}
+	defer dst.DB.Close()
+
+	// a destination that was never migrated is considered empty, anything
+	// else requires resuming
+	tables, err := dst.Dialect.Tables(ctx, dst.DB)
+	if err != nil {
+		return fmt.Errorf("failed to fetch destination tables: %w", err)
+	}
+	fresh := true
+	for _, table := range tables {
+		if table == "migrations" {
+			fresh = false
+			break
+		}
+	}
+	if !fresh && !resume {
+		return ErrCopyDestinationNotEmpty
+	}
+
+	// mark a fresh destination before migrating it so the rows inserted by
+	// the migrations, e.g. the default bucket, are removed even if the copy
+	// is interrupted before they were cleared
+	if fresh {
+		if err := sql.PrepareCopyDestination(ctx, dst.CopyDB); err != nil {
+			return err
+		}
+	}
+	if err := migrateCopyDestination(ctx, dst, metrics, l); err != nil {
+		return fmt.Errorf("failed to migrate destination: %w", err)
+	} else if err := sql.ClearTables(ctx, dst.CopyDB); err != nil {
+		return err
+	}
+
+	l.Infof("copying from %s to %s", src.name, dst.name)
+	if err := sql.CopyTables(ctx, src.CopyDB, dst.CopyDB, resume || !fresh, progress); err != nil {
+		return err
+	}
+	l.Info("verifying copy")
+	return sql.VerifyTables(ctx, src.CopyDB, dst.CopyDB)
+}
+
+func openCopyEndpoint(conn gorm.Dialector) (copyEndpoint, error) {
+	db, err := gorm.Open(conn, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return copyEndpoint{}, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return copyEndpoint{}, err
+	}
+
+	var dialect sql.Dialect
+	switch conn.Name() {
+	case "sqlite":
+		dialect = sqlite.Dialect{}
+	case "mysql":
+		dialect = mysql.Dialect{}
+	case "postgres", "postgresql":
+		dialect = postgresql.Dialect{}
+	default:
+		return copyEndpoint{}, fmt.Errorf("unsupported database type: %v", conn.Name())
+	}
+	return copyEndpoint{
+		CopyDB: sql.CopyDB{DB: sqlDB, Dialect: dialect},
+		name:   conn.Name(),
+	}, nil
+}
+
+func migrateCopyDestination(ctx context.Context, dst copyEndpoint, metrics bool, l *zap.SugaredLogger) error {
+	type migrator interface {
+		Migrate(ctx context.Context) error
+	}
+	newDB := func(db *dsql.DB, metrics bool) (migrator, error) {
+		lqd, ltd := time.Minute, time.Minute
+		switch dst.Dialect.(type) {
+		case sqlite.Dialect:
+			if metrics {
+				return sqlite.NewMetricsDatabase(db, l, lqd, ltd)
+			}
+			return sqlite.NewMainDatabase(db, l, lqd, ltd)
+		case mysql.Dialect:
+			if metrics {
+				return mysql.NewMetricsDatabase(db, l, lqd, ltd)
+			}
+			return mysql.NewMainDatabase(db, l, lqd, ltd)
+		case postgresql.Dialect:
+			if metrics {
+				return postgresql.NewMetricsDatabase(db, l, lqd, ltd)
+			}
+			return postgresql.NewMainDatabase(db, l, lqd, ltd)
+		default:
+			return nil, fmt.Errorf("unsupported database type: %v", dst.name)
+		}
+	}
+	db, err := newDB(dst.DB, metrics)
+	if err != nil {
+		return err
+	}
+	return db.Migrate(ctx)
+}
@@ -0,0 +1,227 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/config"
+	"go.sia.tech/renterd/stores/sql"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCopyDatabases(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add some data
+	if err := ss.CreateBucket(context.Background(), "foo", api.BucketPolicy{PublicReadAccess: true}); err != nil {
+		t.Fatal(err)
+	} else if err := ss.UpdateSetting(context.Background(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	// copy the store's databases into a SQLite database on disk
+	src, srcMetrics, err := ss.cfg.dbConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cfg := CopyConfig{
+		Src:        src,
+		SrcMetrics: srcMetrics,
+		Dst:        NewSQLiteConnection(filepath.Join(dir, "db.sqlite")),
+		DstMetrics: NewSQLiteConnection(filepath.Join(dir, "metrics.sqlite")),
+	}
+	if err := CopyDatabases(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// copying again fails unless the copy is resumed
+	if err := CopyDatabases(context.Background(), cfg); !errors.Is(err, ErrCopyDestinationNotEmpty) {
+		t.Fatal("unexpected error", err)
+	}
+	cfg.Resume = true
+	if err := CopyDatabases(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the data is there
+	db, err := gorm.Open(cfg.Dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b dbBucket
+	if err := db.Where("name = ?", "foo").Take(&b).Error; err != nil {
+		t.Fatal(err)
+	} else if !b.Policy.PublicReadAccess {
+		t.Fatal("unexpected policy", b.Policy)
+	}
+	var s dbSetting
+	if err := db.Where("`key` = ?", "foo").Take(&s).Error; err != nil {
+		t.Fatal(err)
+	} else if s.Value != "bar" {
+		t.Fatal("unexpected setting", s.Value)
+	}
+	var defaultBucketID uint
+	if err := db.Model(&dbBucket{}).Select("id").Where("name = ?", api.DefaultBucketName).Scan(&defaultBucketID).Error; err != nil {
+		t.Fatal(err)
+	} else if defaultBucketID != ss.DefaultBucketID() {
+		t.Fatal("bucket ids differ", defaultBucketID, ss.DefaultBucketID())
+	}
+}
+
+func TestCopyDatabasesResumeAfterMigration(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// update the default bucket so it differs from the one inserted by the
+	// migrations
+	if err := ss.UpdateBucketPolicy(context.Background(), api.DefaultBucketName, api.BucketPolicy{PublicReadAccess: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	src, srcMetrics, err := ss.cfg.dbConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cfg := CopyConfig{
+		Src:        src,
+		SrcMetrics: srcMetrics,
+		Dst:        NewSQLiteConnection(filepath.Join(dir, "db.sqlite")),
+		DstMetrics: NewSQLiteConnection(filepath.Join(dir, "metrics.sqlite")),
+		Resume:     true,
+	}
+
+	// simulate a copy that was interrupted right after migrating the
+	// destination, before the rows inserted by the migrations were cleared
+	for _, db := range []struct {
+		conn    gorm.Dialector
+		metrics bool
+	}{{cfg.Dst, false}, {cfg.DstMetrics, true}} {
+		dst, err := openCopyEndpoint(db.conn)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := sql.PrepareCopyDestination(context.Background(), dst.CopyDB); err != nil {
+			t.Fatal(err)
+		} else if err := migrateCopyDestination(context.Background(), dst, db.metrics, zap.NewNop().Sugar()); err != nil {
+			t.Fatal(err)
+		}
+		dst.DB.Close()
+	}
+
+	// resuming the copy clears the seeded rows, otherwise the default bucket
+	// inserted by the migrations wouldn't be overwritten and the verification
+	// would fail
+	if err := CopyDatabases(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// resuming again is a no-op
+	if err := CopyDatabases(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyDatabasesMySQL(t *testing.T) {
+	if cfg := config.MySQLConfigFromEnv(); cfg.URI == "" {
+		t.Skip("requires a MySQL database")
+	} else if cfg.Database != "" || cfg.MetricsDatabase != "" {
+		t.Skip("requires random database names to create a fresh destination")
+	}
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add rows using bools, timestamps and composite primary keys
+	hk := types.PublicKey{1}
+	if err := ss.addTestHost(hk); err != nil {
+		t.Fatal(err)
+	} else if err := ss.addTestScan(hk, time.Now().Round(time.Second), nil, rhpv2.HostSettings{}); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.addTestContract(types.FileContractID{1}, hk); err != nil {
+		t.Fatal(err)
+	} else if err := ss.SetContractSet(context.Background(), testContractSet, []types.FileContractID{{1}}); err != nil {
+		t.Fatal(err)
+	} else if err := ss.CreateBucket(context.Background(), "foo", api.BucketPolicy{PublicReadAccess: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the dialect reports the keys and references of the schema
+	src, srcMetrics, err := ss.cfg.dbConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcEndpoint, err := openCopyEndpoint(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srcEndpoint.DB.Close()
+	if pk, err := srcEndpoint.Dialect.PrimaryKey(context.Background(), srcEndpoint.DB, "contract_set_contracts"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(pk, []string{"db_contract_set_id", "db_contract_id"}) {
+		t.Fatal("unexpected primary key", pk)
+	} else if refs, err := srcEndpoint.Dialect.References(context.Background(), srcEndpoint.DB, "contract_set_contracts"); err != nil {
+		t.Fatal(err)
+	} else if sort.Strings(refs); !reflect.DeepEqual(refs, []string{"contract_sets", "contracts"}) {
+		t.Fatal("unexpected references", refs)
+	}
+
+	// copy from MySQL to SQLite and back into a fresh MySQL database
+	dir := t.TempDir()
+	sqlite, sqliteMetrics := NewSQLiteConnection(filepath.Join(dir, "db.sqlite")), NewSQLiteConnection(filepath.Join(dir, "metrics.sqlite"))
+	if err := CopyDatabases(context.Background(), CopyConfig{
+		Src:        src,
+		SrcMetrics: srcMetrics,
+		Dst:        sqlite,
+		DstMetrics: sqliteMetrics,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	dstCfg := testSQLStoreConfig{dbName: randomDBName(), dbMetricsName: randomDBName()}
+	dst, dstMetrics, err := dstCfg.dbConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyDatabases(context.Background(), CopyConfig{
+		Src:        sqlite,
+		SrcMetrics: sqliteMetrics,
+		Dst:        dst,
+		DstMetrics: dstMetrics,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the data survived the round trip and new rows don't collide
+	// with the copied ones
+	db, err := gorm.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h dbHost
+	if err := db.Where("public_key = ?", publicKey(hk)).Take(&h).Error; err != nil {
+		t.Fatal(err)
+	} else if !h.LastScanSuccess || !h.Scanned {
+		t.Fatal("unexpected host", h.LastScanSuccess, h.Scanned)
+	}
+	var b dbBucket
+	if err := db.Where("name = ?", "foo").Take(&b).Error; err != nil {
+		t.Fatal(err)
+	} else if !b.Policy.PublicReadAccess {
+		t.Fatal("unexpected policy", b.Policy)
+	}
+	bucket := dbBucket{Name: "bar"}
+	if err := db.Create(&bucket).Error; err != nil {
+		t.Fatal(err)
+	} else if bucket.ID <= b.ID {
+		t.Fatal("sequence wasn't reset", bucket.ID, b.ID)
+	}
+}
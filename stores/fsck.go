@@ -0,0 +1,68 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/stores/sql"
+)
+
+// fsckBufferMinAge is the minimum age of a file in the partial slab directory
+// before it's considered orphaned, buffer files are created before the
+// buffered slab is added to the database.
+const fsckBufferMinAge = time.Hour
+
+// Fsck checks the metadata for inconsistencies and returns the result of every
+// check. Each check runs in its own read-only transaction. If repair is true,
+// the issues that can be repaired are fixed, the affected rows are looked up
+// again in the write transaction that repairs them.
+func (s *SQLStore) Fsck(ctx context.Context, repair bool) ([]api.FsckIssue, error) {
+	var issues []api.FsckIssue
+	for _, check := range sql.FsckChecks {
+		var issue api.FsckIssue
+		err := s.bMain.ReadOnlyTransaction(ctx, func(tx sql.DatabaseTx) (err error) {
+			issue, err = tx.Fsck(ctx, check, false)
+			return
+		})
+		if err != nil {
+			return nil, err
+		}
+		if repair && issue.Repairable && issue.Count > 0 {
+			err = s.bMain.Transaction(ctx, func(tx sql.DatabaseTx) (err error) {
+				issue, err = tx.Fsck(ctx, check, true)
+				return
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		issues = append(issues, issue)
+	}
+
+	// compare the buffered slabs with the files on disk
+	missing, orphaned, err := s.slabBufferMgr.Fsck(ctx, repair)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check slab buffers: %w", err)
+	}
+	return append(issues,
+		fileIssue("buffers_missing_file", "buffered slabs whose file is missing on disk, the data of the affected objects is lost", missing, false, false),
+		fileIssue("buffers_orphaned_file", "files in the partial slab directory that don't belong to any buffered slab", orphaned, true, repair),
+	), nil
+}
+
+func fileIssue(check, description string, files []string, repairable, repaired bool) api.FsckIssue {
+	issue := api.FsckIssue{
+		Check:       check,
+		Description: description,
+		Count:       len(files),
+		Repairable:  repairable,
+		Repaired:    repaired && len(files) > 0,
+	}
+	if len(files) > api.FsckMaxExamples {
+		files = files[:api.FsckMaxExamples]
+	}
+	issue.Examples = files
+	return issue
+}
@@ -0,0 +1,98 @@
+package stores
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/object"
+)
+
+func TestFsck(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// add an object with a slab that's stored on a contract
+	hks, err := ss.addTestHosts(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fcids, _, err := ss.addTestContracts(hks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ss.addTestObject("/foo/bar", object.Object{
+		Key: object.GenerateEncryptionKey(),
+		Slabs: []object.SlabSlice{{
+			Slab: object.Slab{
+				Key:       object.GenerateEncryptionKey(),
+				MinShards: 1,
+				Shards:    newTestShards(hks[0], fcids[0], types.Hash256{1}),
+			},
+			Length: 1,
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertIssues := func(repair bool, expected map[string]int) {
+		t.Helper()
+		issues, err := ss.Fsck(context.Background(), repair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := make(map[string]int)
+		for _, issue := range issues {
+			if issue.Count > 0 {
+				found[issue.Check] = issue.Count
+			}
+			if repair && issue.Count > 0 && issue.Repairable != issue.Repaired {
+				t.Fatalf("issue %v wasn't repaired", issue.Check)
+			}
+		}
+		if !reflect.DeepEqual(found, expected) {
+			t.Fatalf("unexpected issues, %v != %v", found, expected)
+		}
+	}
+
+	// a consistent store has no issues
+	assertIssues(false, map[string]int{})
+
+	// archive the contract without removing it from the active contracts, move
+	// the object to the wrong directory and add an orphaned buffer file
+	if err := ss.db.Exec("INSERT INTO archived_contracts (created_at, fcid, start_height, host) VALUES (?, ?, 0, ?)", time.Now(), fcids[0][:], hks[0][:]).Error; err != nil {
+		t.Fatal(err)
+	} else if err := ss.db.Exec("UPDATE objects SET db_directory_id = 1").Error; err != nil {
+		t.Fatal(err)
+	}
+	orphan := filepath.Join(ss.cfg.dir, "orphan")
+	if err := os.WriteFile(orphan, []byte("foo"), 0600); err != nil {
+		t.Fatal(err)
+	} else if err := os.Chtimes(orphan, time.Now().Add(-2*fsckBufferMinAge), time.Now().Add(-2*fsckBufferMinAge)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a dry run reports the issues without repairing them
+	expected := map[string]int{
+		"contracts_archived":      1,
+		"objects_wrong_directory": 1,
+		"directories_empty":       1,
+		"buffers_orphaned_file":   1,
+	}
+	assertIssues(false, expected)
+	assertIssues(false, expected)
+
+	// repairing them moves the object back into its directory and removes the
+	// contract, which leaves the slab unrecoverable
+	delete(expected, "directories_empty")
+	expected["slabs_unrecoverable"] = 1
+	assertIssues(true, expected)
+	assertIssues(false, map[string]int{"slabs_unrecoverable": 1})
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Fatal("orphaned file wasn't removed", err)
+	}
+}
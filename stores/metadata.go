@@ -713,6 +713,14 @@ func (s *SQLStore) Contract(ctx context.Context, id types.FileContractID) (api.C
 	return contract.convert(), nil
 }
 
+func (s *SQLStore) ContractHostKeys(ctx context.Context) (hostKeys map[types.FileContractID]types.PublicKey, err error) {
+	err = s.bMain.Transaction(ctx, func(tx sql.DatabaseTx) error {
+		hostKeys, err = tx.ContractHostKeys(ctx)
+		return err
+	})
+	return
+}
+
 func (s *SQLStore) ContractRoots(ctx context.Context, id types.FileContractID) (roots []types.Hash256, err error) {
 	if !s.isKnownContract(id) {
 		return nil, api.ErrContractNotFound
@@ -905,15 +913,22 @@ func (s *SQLStore) RenewedContract(ctx context.Context, renewedFrom types.FileCo
 	return contract.convert(), nil
 }
 
-func (s *SQLStore) SearchObjects(ctx context.Context, bucket, substring string, offset, limit int) ([]api.ObjectMetadata, error) {
+func (s *SQLStore) SearchObjects(ctx context.Context, bucket, substring string, offset, limit int) (objects []api.ObjectMetadata, err error) {
+	err = s.withReadReplica(ctx, func(db *gorm.DB) (err error) {
+		objects, err = s.searchObjects(db, bucket, substring, offset, limit)
+		return
+	})
+	return
+}
+
+func (s *SQLStore) searchObjects(db *gorm.DB, bucket, substring string, offset, limit int) ([]api.ObjectMetadata, error) {
 	// fetch one more to see if there are more entries
 	if limit <= -1 {
 		limit = math.MaxInt
 	}
 
 	var objects []api.ObjectMetadata
-	err := s.db.
-		WithContext(ctx).
+	err := db.
 		Select("o.object_id as Name, o.size as Size, o.health as Health, o.mime_type as MimeType, o.etag as ETag, o.created_at as ModTime").
 		Model(&dbObject{}).
 		Table("objects o").
@@ -931,6 +946,14 @@ func (s *SQLStore) SearchObjects(ctx context.Context, bucket, substring string,
 }
 
 func (s *SQLStore) ObjectEntries(ctx context.Context, bucket, path, prefix, sortBy, sortDir, marker string, offset, limit int) (metadata []api.ObjectMetadata, hasMore bool, err error) {
+	err = s.withReadReplica(ctx, func(db *gorm.DB) (err error) {
+		metadata, hasMore, err = s.objectEntries(db, bucket, path, prefix, sortBy, sortDir, marker, offset, limit)
+		return
+	})
+	return
+}
+
+func (s *SQLStore) objectEntries(db *gorm.DB, bucket, path, prefix, sortBy, sortDir, marker string, offset, limit int) (metadata []api.ObjectMetadata, hasMore bool, err error) {
 	// sanity check we are passing a directory
 	if !strings.HasSuffix(path, "/") {
 		panic("path must end in /")
@@ -981,7 +1004,7 @@ func (s *SQLStore) ObjectEntries(ctx context.Context, bucket, path, prefix, sort
 	}
 
 	// fetch id of directory to query
-	dirID, err := s.dirID(s.db, path)
+	dirID, err := s.dirID(db, path)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return []api.ObjectMetadata{}, false, nil
 	} else if err != nil {
@@ -990,7 +1013,7 @@ func (s *SQLStore) ObjectEntries(ctx context.Context, bucket, path, prefix, sort
 
 	// fetch bucket id
 	var dBucket dbBucket
-	if err := s.db.Select("id").
+	if err := db.Select("id").
 		Where("name", bucket).
 		Take(&dBucket).Error; err != nil {
 		return nil, false, fmt.Errorf("failed to fetch bucket id: %w", err)
@@ -1003,7 +1026,7 @@ func (s *SQLStore) ObjectEntries(ctx context.Context, bucket, path, prefix, sort
 	}
 
 	lengthFn := "CHAR_LENGTH"
-	if isSQLite(s.db) {
+	if isSQLite(db) {
 		lengthFn = "LENGTH"
 	}
 
@@ -1058,8 +1081,7 @@ GROUP BY d.id
 		switch sortBy {
 		case api.ObjectSortByHealth:
 			var markerHealth float64
-			if err = s.db.
-				WithContext(ctx).
+			if err = db.
 				Raw(fmt.Sprintf(`SELECT Health FROM (SELECT * FROM (%s) h WHERE ObjectName >= ? ORDER BY ObjectName LIMIT 1) as n`, objectsQuery), append(objectsQueryParams, marker)...).
 				Scan(&markerHealth).
 				Error; err != nil {
@@ -1075,8 +1097,7 @@ GROUP BY d.id
 			}
 		case api.ObjectSortBySize:
 			var markerSize float64
-			if err = s.db.
-				WithContext(ctx).
+			if err = db.
 				Raw(fmt.Sprintf(`SELECT Size FROM (SELECT * FROM (%s) s WHERE ObjectName >= ? ORDER BY ObjectName LIMIT 1) as n`, objectsQuery), append(objectsQueryParams, marker)...).
 				Scan(&markerSize).
 				Error; err != nil {
@@ -1119,8 +1140,7 @@ GROUP BY d.id
 	)
 	parameters := append(append(objectsQueryParams, markerParams...), limit, offset)
 
-	if err = s.db.
-		WithContext(ctx).
+	if err = db.
 		Raw(query, parameters...).
 		Scan(&rows).
 		Error; err != nil {
@@ -2068,7 +2088,15 @@ func (s *SQLStore) invalidateSlabHealthByFCID(ctx context.Context, fcids []types
 // TODO: we can use ObjectEntries instead of ListObject if we want to use '/' as
 // a delimiter for now (see backend.go) but it would be interesting to have
 // arbitrary 'delim' support in ListObjects.
-func (s *SQLStore) ListObjects(ctx context.Context, bucket, prefix, sortBy, sortDir, marker string, limit int) (api.ObjectsListResponse, error) {
+func (s *SQLStore) ListObjects(ctx context.Context, bucket, prefix, sortBy, sortDir, marker string, limit int) (resp api.ObjectsListResponse, err error) {
+	err = s.withReadReplica(ctx, func(db *gorm.DB) (err error) {
+		resp, err = s.listObjects(db, bucket, prefix, sortBy, sortDir, marker, limit)
+		return
+	})
+	return
+}
+
+func (s *SQLStore) listObjects(db *gorm.DB, bucket, prefix, sortBy, sortDir, marker string, limit int) (api.ObjectsListResponse, error) {
 	// fetch one more to see if there are more entries
 	if limit <= -1 {
 		limit = math.MaxInt
@@ -2086,12 +2114,12 @@ func (s *SQLStore) ListObjects(ctx context.Context, bucket, prefix, sortBy, sort
 	}
 
 	// build marker expr
-	markerExpr, markerOrderBy, err := buildMarkerExpr(s.db, bucket, prefix, marker, sortBy, sortDir)
+	markerExpr, markerOrderBy, err := buildMarkerExpr(db, bucket, prefix, marker, sortBy, sortDir)
 	if err != nil {
 		return api.ObjectsListResponse{}, err
 	}
 	var rows []rawObjectMetadata
-	if err := s.db.
+	if err := db.
 		Select("o.object_id as ObjectName, o.size as Size, o.health as Health, o.mime_type as MimeType, o.created_at as ModTime, o.etag as ETag").
 		Model(&dbObject{}).
 		Table("objects o").
@@ -829,6 +829,49 @@ func TestRenewedContract(t *testing.T) {
 	}
 }
 
+// TestContractHostKeys verifies that ContractHostKeys returns the host keys of
+// both active and archived contracts.
+func TestContractHostKeys(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	hk1, hk2 := types.PublicKey{1}, types.PublicKey{2}
+	if err := ss.addTestHost(hk1); err != nil {
+		t.Fatal(err)
+	} else if err := ss.addTestHost(hk2); err != nil {
+		t.Fatal(err)
+	}
+
+	// add a contract that is renewed, one that is archived without being
+	// renewed and one that stays active
+	fcid1, fcid2, fcid3, fcid4 := types.FileContractID{1}, types.FileContractID{2}, types.FileContractID{3}, types.FileContractID{4}
+	if _, err := ss.addTestContract(fcid1, hk1); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.addTestRenewedContract(fcid2, fcid1, hk1, 1); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.addTestContract(fcid3, hk2); err != nil {
+		t.Fatal(err)
+	} else if _, err := ss.addTestContract(fcid4, hk2); err != nil {
+		t.Fatal(err)
+	} else if err := ss.ArchiveContract(context.Background(), fcid3, api.ContractArchivalReasonRemoved); err != nil {
+		t.Fatal(err)
+	}
+
+	hostKeys, err := ss.ContractHostKeys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[types.FileContractID]types.PublicKey{
+		fcid1: hk1,
+		fcid2: hk1,
+		fcid3: hk2,
+		fcid4: hk2,
+	}
+	if !reflect.DeepEqual(hostKeys, expected) {
+		t.Fatal("unexpected host keys", hostKeys)
+	}
+}
+
 // TestAncestorsContracts verifies that AncestorContracts returns the right
 // ancestors in the correct order.
 func TestAncestorsContracts(t *testing.T) {
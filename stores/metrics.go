@@ -8,9 +8,27 @@ import (
 
 	"go.sia.tech/renterd/api"
 	sql "go.sia.tech/renterd/stores/sql"
+	"go.uber.org/zap"
+)
+
+const (
+	// metricsRetentionInterval is the interval at which metrics are
+	// downsampled and pruned.
+	metricsRetentionInterval = 10 * time.Minute
 )
 
 type (
+	// MetricsRetention configures how long the data points of a metric are
+	// kept, zero means forever. Contract and performance metrics are
+	// downsampled into hourly rollups once their raw data points expire and
+	// into daily rollups once the hourly ones expire. Other metrics are only
+	// pruned so Hourly and Daily don't apply to them.
+	MetricsRetention struct {
+		Raw    time.Duration
+		Hourly time.Duration
+		Daily  time.Duration
+	}
+
 	// dbContractMetric tracks information about a contract's funds.  It is
 	// supposed to be reported by a worker every time a contract is revised.
 	dbContractMetric struct {
@@ -228,6 +246,90 @@ func (s *SQLStore) PruneMetrics(ctx context.Context, metric string, cutoff time.
 		Error
 }
 
+func (s *SQLStore) initMetricsRetention() error {
+	for metric, retention := range s.metricsRetention {
+		switch metric {
+		case api.MetricContract, api.MetricPerformance:
+		case api.MetricContractPrune, api.MetricContractSet, api.MetricContractSetChurn, api.MetricWallet:
+			if retention.Hourly != 0 || retention.Daily != 0 {
+				return fmt.Errorf("metric '%s' isn't downsampled, only its raw retention can be configured", metric)
+			}
+		default:
+			return fmt.Errorf("unknown metric '%s'", metric)
+		}
+		if retention.Raw < 0 || retention.Hourly < 0 || retention.Daily < 0 {
+			return fmt.Errorf("retention of metric '%s' can't be negative", metric)
+		}
+	}
+	if len(s.metricsRetention) == 0 {
+		return nil
+	}
+
+	s.wg.Add(1)
+	go func() {
+		s.metricsRetentionLoop()
+		s.wg.Done()
+	}()
+	return nil
+}
+
+func (s *SQLStore) metricsRetentionLoop() {
+	t := time.NewTicker(metricsRetentionInterval)
+	defer t.Stop()
+
+	for {
+		if err := s.applyMetricsRetention(s.shutdownCtx, time.Now()); err != nil && !errors.Is(err, context.Canceled) {
+			s.logger.Errorw("failed to apply metrics retention", zap.Error(err))
+		}
+
+		select {
+		case <-s.shutdownCtx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// applyMetricsRetention downsamples the data points of every metric that
+// expired and prunes them afterwards. Every resolution is handled in a
+// separate transaction.
+func (s *SQLStore) applyMetricsRetention(ctx context.Context, now time.Time) error {
+	for metric, retention := range s.metricsRetention {
+		for _, r := range []struct {
+			resolution time.Duration
+			retention  time.Duration
+		}{
+			{0, retention.Raw},
+			{sql.MetricsResolutionHour, retention.Hourly},
+			{sql.MetricsResolutionDay, retention.Daily},
+		} {
+			if r.retention == 0 {
+				continue
+			}
+			cutoff := now.Add(-r.retention)
+			if err := s.bMetrics.Transaction(ctx, func(tx sql.MetricsDatabaseTx) error {
+				// downsample the expired data points into the next rollup
+				// before pruning them
+				if metric == api.MetricContract || metric == api.MetricPerformance {
+					if r.resolution == 0 {
+						if err := tx.DownsampleMetrics(ctx, metric, sql.MetricsResolutionHour, cutoff); err != nil {
+							return err
+						}
+					} else if r.resolution == sql.MetricsResolutionHour {
+						if err := tx.DownsampleMetrics(ctx, metric, sql.MetricsResolutionDay, cutoff); err != nil {
+							return err
+						}
+					}
+				}
+				return tx.PruneMetrics(ctx, metric, r.resolution, cutoff)
+			}); err != nil {
+				return fmt.Errorf("failed to apply retention of metric '%s': %w", metric, err)
+			}
+		}
+	}
+	return nil
+}
+
 func normaliseTimestamp(start time.Time, interval time.Duration, t unixTimeMS) unixTimeMS {
 	startMS := start.UnixMilli()
 	toNormaliseMS := time.Time(t).UnixMilli()
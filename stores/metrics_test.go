@@ -554,3 +554,152 @@ func TestWalletMetrics(t *testing.T) {
 		t.Fatalf("expected 1 metric, got %v", len(metrics))
 	}
 }
+
+func TestMetricsRetention(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// assert invalid retentions are rejected
+	ss.metricsRetention = map[string]MetricsRetention{api.MetricWallet: {Hourly: time.Hour}}
+	if err := ss.initMetricsRetention(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// record a performance and contract metric every 10 minutes for 2 days
+	day := time.Now().Add(-10 * 24 * time.Hour).Truncate(24 * time.Hour)
+	fcids := []types.FileContractID{{1}, {2}}
+	for ts := day; ts.Before(day.Add(48 * time.Hour)); ts = ts.Add(10 * time.Minute) {
+		if err := ss.RecordPerformanceMetric(context.Background(), api.PerformanceMetric{
+			Action:    "download",
+			Timestamp: api.TimeRFC3339(ts),
+			Duration:  time.Duration(frand.Intn(1000)),
+			HostKey:   types.PublicKey{1},
+			Origin:    "worker",
+		}); err != nil {
+			t.Fatal(err)
+		}
+		for _, fcid := range fcids {
+			if err := ss.RecordContractMetric(context.Background(), api.ContractMetric{
+				Timestamp:      api.TimeRFC3339(ts),
+				ContractID:     fcid,
+				HostKey:        types.PublicKey{1},
+				RemainingFunds: types.NewCurrency64(frand.Uint64n(1000)),
+				RevisionNumber: uint64(ts.Unix()),
+				UploadSpending: types.NewCurrency64(frand.Uint64n(1000)),
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// helper to count the data points in a table
+	count := func(table string) (n int64) {
+		t.Helper()
+		if err := ss.dbMetrics.Table(table).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	// helper to query the metrics at various intervals
+	type queryResult struct {
+		Performance []api.PerformanceMetric
+		Contracts   []api.ContractMetric
+		Contract    []api.ContractMetric
+	}
+	query := func() (res []queryResult) {
+		t.Helper()
+		for _, interval := range []time.Duration{time.Hour, 2 * time.Hour, 24 * time.Hour} {
+			var r queryResult
+			var err error
+			n := uint64(48 * time.Hour / interval)
+			if r.Performance, err = ss.PerformanceMetrics(context.Background(), day, n, interval, api.PerformanceMetricsQueryOpts{}); err != nil {
+				t.Fatal(err)
+			} else if r.Contracts, err = ss.ContractMetrics(context.Background(), day, n, interval, api.ContractMetricsQueryOpts{}); err != nil {
+				t.Fatal(err)
+			} else if r.Contract, err = ss.ContractMetrics(context.Background(), day, n, interval, api.ContractMetricsQueryOpts{ContractID: fcids[1]}); err != nil {
+				t.Fatal(err)
+			}
+			res = append(res, r)
+		}
+		return
+	}
+	expected := query()
+	for _, r := range expected {
+		if len(r.Performance) != len(r.Contracts) || len(r.Contracts) != len(r.Contract) || len(r.Contract) == 0 {
+			t.Fatalf("unexpected number of metrics %v %v %v", len(r.Performance), len(r.Contracts), len(r.Contract))
+		}
+	}
+
+	// downsample the first day, the queries should combine the rollups with
+	// the raw data points, the retention is applied directly rather than
+	// through initMetricsRetention to avoid racing its background loop
+	ss.metricsRetention = map[string]MetricsRetention{
+		api.MetricContract:    {Raw: 24 * time.Hour, Hourly: 48 * time.Hour},
+		api.MetricPerformance: {Raw: 24 * time.Hour},
+	}
+	if err := ss.applyMetricsRetention(context.Background(), day.Add(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if n := count("performance"); n != 24*6 {
+		t.Fatalf("expected %v raw performance metrics, got %v", 24*6, n)
+	} else if n := count("performance_hourly"); n != 24 {
+		t.Fatalf("expected 24 hourly performance metrics, got %v", n)
+	} else if n := count("contracts_hourly"); n != 48 {
+		t.Fatalf("expected 48 hourly contract metrics, got %v", n)
+	} else if n := count("contracts_daily"); n != 0 {
+		t.Fatalf("expected no daily contract metrics, got %v", n)
+	}
+	if diff := cmp.Diff(expected, query(), cmp.Comparer(api.CompareTimeRFC3339)); diff != "" {
+		t.Fatal("unexpected metrics", diff)
+	}
+
+	// downsample everything, contract metrics are only kept in the daily
+	// rollup so queries at a finer resolution are served by it
+	if err := ss.applyMetricsRetention(context.Background(), day.Add(4*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if n := count("performance"); n != 0 {
+		t.Fatalf("expected no raw performance metrics, got %v", n)
+	} else if n := count("performance_hourly"); n != 48 {
+		t.Fatalf("expected 48 hourly performance metrics, got %v", n)
+	} else if n := count("contracts"); n != 0 {
+		t.Fatalf("expected no raw contract metrics, got %v", n)
+	} else if n := count("contracts_hourly"); n != 0 {
+		t.Fatalf("expected no hourly contract metrics, got %v", n)
+	} else if n := count("contracts_daily"); n != 4 {
+		t.Fatalf("expected 4 daily contract metrics, got %v", n)
+	}
+	res := query()
+	if diff := cmp.Diff(expected[:2], res[:2], cmpopts.IgnoreFields(queryResult{}, "Contracts", "Contract"), cmp.Comparer(api.CompareTimeRFC3339)); diff != "" {
+		t.Fatal("unexpected metrics", diff)
+	} else if diff := cmp.Diff(expected[2], res[2], cmp.Comparer(api.CompareTimeRFC3339)); diff != "" {
+		t.Fatal("unexpected metrics", diff)
+	}
+	for _, r := range res[:2] {
+		if diff := cmp.Diff(expected[2].Contracts, r.Contracts, cmp.Comparer(api.CompareTimeRFC3339)); diff != "" {
+			t.Fatal("unexpected metrics", diff)
+		} else if diff := cmp.Diff(expected[2].Contract, r.Contract, cmp.Comparer(api.CompareTimeRFC3339)); diff != "" {
+			t.Fatal("unexpected metrics", diff)
+		}
+	}
+
+	// assert periods that don't align with the rollups' buckets are served by
+	// the rollups after the raw data points were pruned
+	start := day.Add(30 * time.Minute)
+	if performance, err := ss.PerformanceMetrics(context.Background(), start, 47, time.Hour, api.PerformanceMetricsQueryOpts{}); err != nil {
+		t.Fatal(err)
+	} else if len(performance) != 47 {
+		t.Fatalf("expected 47 performance metrics, got %v", len(performance))
+	} else if !time.Time(performance[0].Timestamp).Equal(start) {
+		t.Fatalf("expected first period to start at %v, got %v", start, time.Time(performance[0].Timestamp))
+	} else if diff := cmp.Diff(expected[0].Performance[1:], performance, cmpopts.IgnoreFields(api.PerformanceMetric{}, "Timestamp")); diff != "" {
+		t.Fatal("unexpected metrics", diff)
+	}
+	start = day.Add(12 * time.Hour)
+	if contracts, err := ss.ContractMetrics(context.Background(), start, 2, 24*time.Hour, api.ContractMetricsQueryOpts{ContractID: fcids[1]}); err != nil {
+		t.Fatal(err)
+	} else if len(contracts) != 1 {
+		t.Fatalf("expected 1 contract metric, got %v", len(contracts))
+	} else if diff := cmp.Diff(expected[2].Contract[1:], contracts, cmpopts.IgnoreFields(api.ContractMetric{}, "Timestamp")); diff != "" {
+		t.Fatal("unexpected metrics", diff)
+	}
+}
@@ -0,0 +1,183 @@
+package stores
+
+import (
+	"context"
+	dsql "database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.sia.tech/renterd/stores/sql/mysql"
+	"go.sia.tech/renterd/stores/sql/postgresql"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	glogger "gorm.io/gorm/logger"
+)
+
+const (
+	// defaultMaxReplicaLag is the replication lag after which a read replica
+	// is no longer queried if no other bound was configured.
+	defaultMaxReplicaLag = 5 * time.Second
+
+	// replicaLagCheckInterval is the interval at which the replication lag
+	// of the read replicas is checked. Since the lag isn't checked on every
+	// query, the effective bound on the lag of a queried replica is the
+	// configured bound plus this interval.
+	replicaLagCheckInterval = 5 * time.Second
+)
+
+type (
+	// readReplicas routes read-only queries to the replicas of the main
+	// database whose replication lag is within the configured bound.
+	readReplicas struct {
+		logger   *zap.SugaredLogger
+		maxLag   time.Duration
+		replicas []*readReplica
+
+		mu   sync.Mutex
+		next int
+	}
+
+	readReplica struct {
+		name  string
+		db    *gorm.DB
+		lagFn func(context.Context, *dsql.DB) (time.Duration, error)
+
+		mu      sync.Mutex
+		healthy bool
+	}
+)
+
+func newReadReplicas(conns []gorm.Dialector, primary string, maxLag time.Duration, gormLogger glogger.Interface, l *zap.SugaredLogger) (*readReplicas, error) {
+	if maxLag == 0 {
+		maxLag = defaultMaxReplicaLag
+	}
+	rr := &readReplicas{
+		logger: l.Named("replicas"),
+		maxLag: maxLag,
+	}
+	for i, conn := range conns {
+		var lagFn func(context.Context, *dsql.DB) (time.Duration, error)
+		switch conn.Name() {
+		case "mysql":
+			lagFn = mysql.ReplicationLag
+		case "postgres", "postgresql":
+			lagFn = postgresql.ReplicationLag
+		default:
+			rr.Close()
+			return nil, fmt.Errorf("read replicas aren't supported for database type: %v", conn.Name())
+		}
+		if conn.Name() != primary {
+			rr.Close()
+			return nil, fmt.Errorf("read replica of type %v doesn't match the main database of type %v", conn.Name(), primary)
+		}
+		db, err := gorm.Open(conn, &gorm.Config{Logger: gormLogger})
+		if err != nil {
+			rr.Close()
+			return nil, fmt.Errorf("failed to open read replica: %w", err)
+		}
+		rr.replicas = append(rr.replicas, &readReplica{
+			name:  fmt.Sprintf("replica-%d", i),
+			db:    db,
+			lagFn: lagFn,
+		})
+	}
+	return rr, nil
+}
+
+// Close closes the connections to all replicas.
+func (rr *readReplicas) Close() error {
+	var errs []error
+	for _, r := range rr.replicas {
+		if sqlDB, err := r.db.DB(); err != nil {
+			errs = append(errs, err)
+		} else if err := sqlDB.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkLag updates the health of every replica, replicas that can't report
+// their lag or lag behind by more than the configured bound are skipped
+// until the next check.
+func (rr *readReplicas) checkLag(ctx context.Context) {
+	for _, r := range rr.replicas {
+		var lag time.Duration
+		sqlDB, err := r.db.DB()
+		if err == nil {
+			lag, err = r.lagFn(ctx, sqlDB)
+		}
+		if err == nil && lag > rr.maxLag {
+			err = fmt.Errorf("replication lag of %v exceeds %v", lag, rr.maxLag)
+		}
+
+		r.mu.Lock()
+		if err != nil && r.healthy {
+			rr.logger.Warnw("routing reads away from replica", "replica", r.name, zap.Error(err))
+		} else if err == nil && !r.healthy {
+			rr.logger.Infow("routing reads to replica", "replica", r.name, "lag", lag)
+		}
+		r.healthy = err == nil
+		r.mu.Unlock()
+	}
+}
+
+// pick returns the next healthy replica in a round-robin fashion, nil is
+// returned if none of the replicas are healthy.
+func (rr *readReplicas) pick() *readReplica {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	for i := 0; i < len(rr.replicas); i++ {
+		r := rr.replicas[(rr.next+i)%len(rr.replicas)]
+		r.mu.Lock()
+		healthy := r.healthy
+		r.mu.Unlock()
+		if healthy {
+			rr.next = (rr.next + i + 1) % len(rr.replicas)
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) initReadReplicas() {
+	if s.replicas == nil {
+		return
+	}
+
+	// check the lag once so the replicas are used right away
+	s.replicas.checkLag(s.shutdownCtx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		t := time.NewTicker(replicaLagCheckInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-s.shutdownCtx.Done():
+				return
+			case <-t.C:
+			}
+			s.replicas.checkLag(s.shutdownCtx)
+		}
+	}()
+}
+
+// withReadReplica runs the given read-only queries against a healthy read
+// replica. If there is none or the queries fail on the replica they are run
+// against the main database instead.
+func (s *SQLStore) withReadReplica(ctx context.Context, fn func(db *gorm.DB) error) error {
+	if s.replicas != nil {
+		if r := s.replicas.pick(); r != nil {
+			err := fn(r.db.WithContext(ctx))
+			if err == nil || ctx.Err() != nil {
+				return err
+			}
+			s.logger.Debugw("read replica query failed, falling back to the main database", "replica", r.name, zap.Error(err))
+		}
+	}
+	return fn(s.db.WithContext(ctx))
+}
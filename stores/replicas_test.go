@@ -0,0 +1,84 @@
+package stores
+
+import (
+	"context"
+	dsql "database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/object"
+	"go.uber.org/zap"
+)
+
+func TestReadReplicas(t *testing.T) {
+	ss := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer ss.Close()
+
+	// use a second store as the replica, it contains a different object than
+	// the primary so we can tell which database served the query
+	replica := newTestSQLStore(t, defaultTestSQLStoreConfig)
+	defer replica.Close()
+	if _, err := ss.addTestObject("/primary", object.Object{Key: object.GenerateEncryptionKey()}); err != nil {
+		t.Fatal(err)
+	} else if _, err := replica.addTestObject("/replica", object.Object{Key: object.GenerateEncryptionKey()}); err != nil {
+		t.Fatal(err)
+	}
+
+	var lag time.Duration
+	var lagErr error
+	ss.replicas = &readReplicas{
+		logger: zap.NewNop().Sugar(),
+		maxLag: time.Second,
+		replicas: []*readReplica{{
+			name: "replica",
+			db:   replica.db,
+			lagFn: func(context.Context, *dsql.DB) (time.Duration, error) {
+				return lag, lagErr
+			},
+		}},
+	}
+
+	// assert the listing and searching queries are served by the expected db
+	assertServedBy := func(expected string) {
+		t.Helper()
+		ss.replicas.checkLag(context.Background())
+		if entries, _, err := ss.ObjectEntries(context.Background(), api.DefaultBucketName, "/", "", "", "", "", 0, -1); err != nil {
+			t.Fatal(err)
+		} else if len(entries) != 1 || entries[0].Name != expected {
+			t.Fatalf("expected entries to be served by %v, got %+v", expected, entries)
+		}
+		if objects, err := ss.SearchObjects(context.Background(), api.DefaultBucketName, "/", 0, -1); err != nil {
+			t.Fatal(err)
+		} else if len(objects) != 1 || objects[0].Name != expected {
+			t.Fatalf("expected search to be served by %v, got %+v", expected, objects)
+		}
+		if resp, err := ss.ListObjects(context.Background(), api.DefaultBucketName, "/", "", "", "", -1); err != nil {
+			t.Fatal(err)
+		} else if len(resp.Objects) != 1 || resp.Objects[0].Name != expected {
+			t.Fatalf("expected list to be served by %v, got %+v", expected, resp.Objects)
+		}
+	}
+
+	// replica is up-to-date
+	assertServedBy("/replica")
+
+	// replica lags behind too much
+	lag = 2 * time.Second
+	assertServedBy("/primary")
+
+	// replica can't report its lag
+	lag, lagErr = 0, errors.New("replication is not running")
+	assertServedBy("/primary")
+
+	// replica is healthy again
+	lagErr = nil
+	assertServedBy("/replica")
+
+	// queries that fail on the replica fall back to the primary
+	if err := replica.db.Exec("DROP TABLE objects").Error; err != nil {
+		t.Fatal(err)
+	}
+	assertServedBy("/primary")
+}
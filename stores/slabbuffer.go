@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -48,6 +49,11 @@ type SlabBufferManager struct {
 	completeBuffers   map[bufferGroupID][]*SlabBuffer
 	incompleteBuffers map[bufferGroupID][]*SlabBuffer
 	buffersByKey      map[string]*SlabBuffer
+
+	// backups is the number of backups in progress, while there are any the
+	// files of removed buffers are only deleted once the last backup is done
+	backups        int
+	pendingRemoval []string
 }
 
 func newSlabBufferManager(sqlStore *SQLStore, slabBufferCompletionThreshold int64, partialSlabDir string) (*SlabBufferManager, error) {
@@ -255,6 +261,137 @@ func (mgr *SlabBufferManager) BufferSize(gid bufferGroupID) (total int64) {
 	return
 }
 
+// Backup calls snapshot and copies the buffers to dir afterwards. No buffer
+// files are deleted until the copy is done, which makes sure that every buffer
+// that is referenced by the snapshot is part of the backup. Buffers are only
+// ever appended to so data added after the snapshot doesn't hurt. Since buffer
+// files are created before they are added to the database, all files in the
+// buffer dir are copied rather than the buffers the manager knows about.
+func (mgr *SlabBufferManager) Backup(dir string, snapshot func() error) error {
+	mgr.mu.Lock()
+	mgr.backups++
+	mgr.mu.Unlock()
+	defer mgr.finishBackup()
+
+	if err := snapshot(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create backup dir: %w", err)
+	}
+	entries, err := os.ReadDir(mgr.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read partial slab dir: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		err := copyFile(filepath.Join(mgr.dir, entry.Name()), filepath.Join(dir, entry.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			continue // orphaned file removed by fsck, not referenced by the snapshot
+		} else if err != nil {
+			return fmt.Errorf("failed to back up buffer %v: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// finishBackup marks a backup as done, once no backup is in progress anymore
+// the files of the buffers that were removed in the meantime are deleted.
+func (mgr *SlabBufferManager) finishBackup() {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	mgr.backups--
+	if mgr.backups > 0 {
+		return
+	}
+	for _, filename := range mgr.pendingRemoval {
+		if err := os.RemoveAll(filepath.Join(mgr.dir, filename)); err != nil {
+			mgr.s.logger.Errorf("failed to remove buffer %v: %v", filename, err)
+		}
+	}
+	mgr.pendingRemoval = nil
+}
+
+// Fsck compares the buffer files on disk with the buffered slabs in the
+// database. It returns the buffers that are missing on disk and the files that
+// don't belong to any buffer. Files that were modified recently are ignored
+// since they might belong to a buffer that is still being created. If repair is
+// true, the orphaned files are removed.
+func (mgr *SlabBufferManager) Fsck(ctx context.Context, repair bool) (missing, orphaned []string, _ error) {
+	// buffer files are created before they are added to the database and
+	// removed after they were deleted from it, so a buffer whose file is
+	// missing is only reported if it still exists after the file was checked
+	filenames, err := mgr.bufferedSlabFilenames(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	known := make(map[string]struct{})
+	var candidates []string
+	for _, filename := range filenames {
+		known[filename] = struct{}{}
+		if _, err := os.Stat(filepath.Join(mgr.dir, filename)); errors.Is(err, os.ErrNotExist) {
+			candidates = append(candidates, filename)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to stat buffer %v: %w", filename, err)
+		}
+	}
+	if len(candidates) > 0 {
+		filenames, err := mgr.bufferedSlabFilenames(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		exists := make(map[string]struct{})
+		for _, filename := range filenames {
+			exists[filename] = struct{}{}
+		}
+		for _, filename := range candidates {
+			if _, ok := exists[filename]; ok {
+				missing = append(missing, filename)
+			}
+		}
+	}
+
+	mgr.mu.Lock()
+	for _, buffer := range mgr.buffersByKey {
+		known[buffer.filename] = struct{}{}
+	}
+	mgr.mu.Unlock()
+
+	entries, err := os.ReadDir(mgr.dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read partial slab dir: %w", err)
+	}
+	for _, entry := range entries {
+		if _, ok := known[entry.Name()]; ok || entry.IsDir() {
+			continue
+		} else if fi, err := entry.Info(); err != nil {
+			return nil, nil, fmt.Errorf("failed to stat file %v: %w", entry.Name(), err)
+		} else if time.Since(fi.ModTime()) < fsckBufferMinAge {
+			continue
+		}
+		orphaned = append(orphaned, entry.Name())
+		if repair {
+			if err := os.Remove(filepath.Join(mgr.dir, entry.Name())); err != nil {
+				return nil, nil, fmt.Errorf("failed to remove orphaned file %v: %w", entry.Name(), err)
+			}
+		}
+	}
+	return missing, orphaned, nil
+}
+
+func (mgr *SlabBufferManager) bufferedSlabFilenames(ctx context.Context) (filenames []string, err error) {
+	err = mgr.s.bMain.ReadOnlyTransaction(ctx, func(tx sql.DatabaseTx) error {
+		filenames, err = tx.BufferedSlabFilenames(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch buffered slabs: %w", err)
+	}
+	return filenames, nil
+}
+
 func (mgr *SlabBufferManager) FetchPartialSlab(ctx context.Context, ec object.EncryptionKey, offset, length uint32) ([]byte, error) {
 	mgr.mu.Lock()
 	buffer, exists := mgr.buffersByKey[ec.String()]
@@ -362,6 +499,8 @@ func (mgr *SlabBufferManager) RemoveBuffers(fileNames ...string) {
 			// anyway.
 			if err := buffers[i].file.Close(); err != nil {
 				mgr.s.logger.Errorf("failed to close buffer %v: %v", buffers[i].filename, err)
+			} else if mgr.backups > 0 {
+				mgr.pendingRemoval = append(mgr.pendingRemoval, buffers[i].filename)
 			} else if err := os.RemoveAll(filepath.Join(mgr.dir, buffers[i].filename)); err != nil {
 				mgr.s.logger.Errorf("failed to remove buffer %v: %v", buffers[i].filename, err)
 			}
@@ -493,3 +632,23 @@ func createSlabBuffer(ctx context.Context, tx sql.DatabaseTx, contractSetID uint
 		file:     file,
 	}, err
 }
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	} else if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -17,6 +17,7 @@ import (
 	"go.sia.tech/renterd/stores/sql/mysql"
 	"go.sia.tech/renterd/stores/sql/postgresql"
 	"go.sia.tech/renterd/stores/sql/sqlite"
+	"go.sia.tech/renterd/wallet"
 	"go.sia.tech/siad/modules"
 	"go.uber.org/zap"
 	gmysql "gorm.io/driver/mysql"
@@ -48,12 +49,25 @@ type (
 		AnnouncementMaxAge            time.Duration
 		PersistInterval               time.Duration
 		WalletAddress                 types.Address
+		WalletAddresses               wallet.AddressTracker
 		SlabBufferCompletionThreshold int64
 		Logger                        *zap.SugaredLogger
 		GormLogger                    glogger.Interface
 		RetryTransactionIntervals     []time.Duration
 		LongQueryDuration             time.Duration
 		LongTxDuration                time.Duration
+
+		// MetricsRetention configures the retention of every metric, keyed
+		// by the metric's name, metrics that aren't listed are kept forever.
+		MetricsRetention map[string]MetricsRetention
+
+		// ReadReplicas are connections to read replicas of the main
+		// database. Listing and searching objects is routed to the replicas
+		// as long as they don't lag behind by more than MaxReplicaLag. The
+		// lag is checked periodically so a replica might lag behind by up
+		// to MaxReplicaLag plus the check interval before it's skipped.
+		ReadReplicas  []gorm.Dialector
+		MaxReplicaLag time.Duration
 	}
 
 	// SQLStore is a helper type for interacting with a SQL-based backend.
@@ -63,6 +77,7 @@ type (
 		dbMetrics *gorm.DB
 		bMain     sql.Database
 		bMetrics  sql.MetricsDatabase
+		replicas  *readReplicas
 		logger    *zap.SugaredLogger
 
 		slabBufferMgr *SlabBufferManager
@@ -85,12 +100,15 @@ type (
 		// HostDB related fields
 		announcementMaxAge time.Duration
 
+		// Metrics related fields
+		metricsRetention map[string]MetricsRetention
+
 		// SettingsDB related fields.
 		settingsMu sync.Mutex
 		settings   map[string]string
 
 		// WalletDB related fields.
-		walletAddress types.Address
+		walletAddresses wallet.AddressTracker
 
 		// Consensus related fields.
 		ccid       modules.ConsensusChangeID
@@ -207,7 +225,7 @@ func NewSQLStore(cfg Config) (*SQLStore, modules.ConsensusChangeID, error) {
 	case "mysql":
 		dbMain, mainErr = mysql.NewMainDatabase(sqlDB, l, cfg.LongQueryDuration, cfg.LongTxDuration)
 		bMetrics, metricsErr = mysql.NewMetricsDatabase(sqlDBMetrics, l, cfg.LongQueryDuration, cfg.LongTxDuration)
-	case "postgresql":
+	case "postgres", "postgresql":
 		dbMain, mainErr = postgresql.NewMainDatabase(sqlDB, l, cfg.LongQueryDuration, cfg.LongTxDuration)
 		bMetrics, metricsErr = postgresql.NewMetricsDatabase(sqlDBMetrics, l, cfg.LongQueryDuration, cfg.LongTxDuration)
 	default:
@@ -260,6 +278,21 @@ func NewSQLStore(cfg Config) (*SQLStore, modules.ConsensusChangeID, error) {
 		isOurContract[types.FileContractID(fcid)] = struct{}{}
 	}
 
+	// default to tracking a single wallet address
+	walletAddresses := cfg.WalletAddresses
+	if walletAddresses == nil {
+		walletAddresses = wallet.SingleAddressTracker(cfg.WalletAddress)
+	}
+
+	// open the read replicas
+	var replicas *readReplicas
+	if len(cfg.ReadReplicas) > 0 {
+		replicas, err = newReadReplicas(cfg.ReadReplicas, cfg.Conn.Name(), cfg.MaxReplicaLag, cfg.GormLogger, l)
+		if err != nil {
+			return nil, modules.ConsensusChangeID{}, err
+		}
+	}
+
 	shutdownCtx, shutdownCtxCancel := context.WithCancel(context.Background())
 	ss := &SQLStore{
 		alerts:                 cfg.Alerts,
@@ -268,6 +301,7 @@ func NewSQLStore(cfg Config) (*SQLStore, modules.ConsensusChangeID, error) {
 		dbMetrics:              dbMetrics,
 		bMain:                  dbMain,
 		bMetrics:               bMetrics,
+		replicas:               replicas,
 		logger:                 l,
 		knownContracts:         isOurContract,
 		lastSave:               time.Now(),
@@ -281,7 +315,9 @@ func NewSQLStore(cfg Config) (*SQLStore, modules.ConsensusChangeID, error) {
 
 		announcementMaxAge: cfg.AnnouncementMaxAge,
 
-		walletAddress: cfg.WalletAddress,
+		metricsRetention: cfg.MetricsRetention,
+
+		walletAddresses: walletAddresses,
 		chainIndex: types.ChainIndex{
 			Height: ci.Height,
 			ID:     types.BlockID(ci.BlockID),
@@ -301,6 +337,10 @@ func NewSQLStore(cfg Config) (*SQLStore, modules.ConsensusChangeID, error) {
 	if err := ss.initSlabPruning(); err != nil {
 		return nil, modules.ConsensusChangeID{}, err
 	}
+	if err := ss.initMetricsRetention(); err != nil {
+		return nil, modules.ConsensusChangeID{}, err
+	}
+	ss.initReadReplicas()
 	return ss, ccid, nil
 }
 
@@ -345,6 +385,11 @@ func (s *SQLStore) Close() error {
 	if err != nil {
 		return err
 	}
+	if s.replicas != nil {
+		if err := s.replicas.Close(); err != nil {
+			return err
+		}
+	}
 
 	err = s.slabBufferMgr.Close()
 	if err != nil {
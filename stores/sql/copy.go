@@ -0,0 +1,810 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	dsql "database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// copyBatchSize is the maximum number of rows that are inserted into the
+	// destination database within a single statement.
+	copyBatchSize = 500
+
+	// copyMaxArgs is the maximum number of placeholders used within a single
+	// insert statement, it's well below the limits of all supported backends.
+	copyMaxArgs = 30000
+
+	// migrationsTable is the name of the table that keeps track of the
+	// applied migrations, it is populated when the destination is migrated
+	// and therefore never copied.
+	migrationsTable = "migrations"
+
+	// clearPendingTable is created in a fresh destination before it is
+	// migrated and dropped once the rows inserted by the migrations were
+	// removed. As long as it exists, nothing was copied into the destination
+	// yet and the destination might still contain rows that don't belong to
+	// the source.
+	clearPendingTable = "copy_clear_pending"
+)
+
+var (
+	// ErrCopyDestinationNotEmpty is returned when a copy is started without
+	// resuming and the destination already contains data.
+	ErrCopyDestinationNotEmpty = errors.New("destination database is not empty, use resume to continue an interrupted copy")
+
+	// ErrCopyMismatch is returned when the verification of a copy fails.
+	ErrCopyMismatch = errors.New("copy verification failed")
+
+	// ErrCopySchemaMismatch is returned when the source and destination
+	// databases were not migrated to the same version.
+	ErrCopySchemaMismatch = errors.New("source and destination schemas differ")
+)
+
+type (
+	// Dialect abstracts the backend specific queries that are required to copy
+	// data between databases.
+	Dialect interface {
+		// DisableForeignKeys disables foreign key checks on the given
+		// connection. Backends that can't disable them may return nil, the
+		// tables are copied in dependency order regardless.
+		DisableForeignKeys(ctx context.Context, conn *dsql.Conn) error
+
+		// Placeholder returns the placeholder for the i-th (0-indexed)
+		// argument of a query.
+		Placeholder(i int) string
+
+		// PrimaryKey returns the columns that make up the primary key of the
+		// given table.
+		PrimaryKey(ctx context.Context, db *dsql.DB, table string) ([]string, error)
+
+		// QuoteIdentifier quotes a table or column name.
+		QuoteIdentifier(name string) string
+
+		// References returns the names of the tables the given table has
+		// foreign keys to.
+		References(ctx context.Context, db *dsql.DB, table string) ([]string, error)
+
+		// ResetSequence makes sure that the next auto-incremented value of
+		// the given column is greater than all values that were copied.
+		ResetSequence(ctx context.Context, conn *dsql.Conn, table, column string) error
+
+		// Tables returns the names of all tables in the database.
+		Tables(ctx context.Context, db *dsql.DB) ([]string, error)
+	}
+
+	// CopyDB is one side of a copy.
+	CopyDB struct {
+		DB      *dsql.DB
+		Dialect Dialect
+	}
+
+	// CopyProgressFn is called after every batch of rows that was copied.
+	CopyProgressFn func(table string, copied, total uint64)
+
+	columnKind int
+
+	copyColumn struct {
+		name    string
+		srcKind columnKind
+		dstKind columnKind
+	}
+
+	copyTable struct {
+		name    string
+		columns []copyColumn
+		pk      []int // indices into columns
+	}
+)
+
+const (
+	kindUnknown columnKind = iota
+	kindBool
+	kindBytes
+	kindFloat
+	kindInt
+	kindJSON
+	kindNumeric
+	kindText
+	kindTime
+)
+
+// CopyTables copies the rows of all tables, except for the migrations table,
+// from src to dst. Both databases are expected to be migrated to the same
+// version. Rows are copied in primary key order which allows for resuming an
+// interrupted copy. If resume is false, every table in dst has to be empty.
+func CopyTables(ctx context.Context, src, dst CopyDB, resume bool, progress CopyProgressFn) error {
+	if err := compareMigrations(ctx, src, dst); err != nil {
+		return err
+	}
+	tables, err := copyOrder(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	// use a single connection for all writes to make sure foreign key checks
+	// remain disabled
+	conn, err := dst.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer conn.Close()
+	if err := dst.Dialect.DisableForeignKeys(ctx, conn); err != nil {
+		return fmt.Errorf("failed to disable foreign keys: %w", err)
+	}
+
+	for _, name := range tables {
+		t, err := loadCopyTable(ctx, src, dst, name)
+		if err != nil {
+			return err
+		}
+		if err := t.copy(ctx, src, dst, conn, resume, progress); err != nil {
+			return fmt.Errorf("failed to copy table '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// VerifyTables compares the number of rows and a checksum over the contents of
+// all tables in src and dst. The checksum is independent of the order of rows
+// and of the backend the data is stored in.
+func VerifyTables(ctx context.Context, src, dst CopyDB) error {
+	tables, err := copyOrder(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	var mismatches []string
+	for _, name := range tables {
+		t, err := loadCopyTable(ctx, src, dst, name)
+		if err != nil {
+			return err
+		}
+		srcRows, srcSum, err := t.checksum(ctx, src, false)
+		if err != nil {
+			return fmt.Errorf("failed to compute checksum of source table '%s': %w", name, err)
+		}
+		dstRows, dstSum, err := t.checksum(ctx, dst, true)
+		if err != nil {
+			return fmt.Errorf("failed to compute checksum of destination table '%s': %w", name, err)
+		}
+		if srcRows != dstRows {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %d != %d rows", name, srcRows, dstRows))
+		} else if srcSum != dstSum {
+			mismatches = append(mismatches, fmt.Sprintf("%s: checksum mismatch", name))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%w: %s", ErrCopyMismatch, strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
+// PrepareCopyDestination marks a fresh destination as containing rows that
+// need to be cleared before copying. It has to be called before the
+// destination is migrated, that way an interrupted copy that is resumed still
+// clears the rows inserted by the migrations.
+func PrepareCopyDestination(ctx context.Context, db CopyDB) error {
+	if _, err := db.DB.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY)", db.Dialect.QuoteIdentifier(clearPendingTable))); err != nil {
+		return fmt.Errorf("failed to mark destination: %w", err)
+	}
+	return nil
+}
+
+// ClearTables deletes all rows from all tables, except for the migrations
+// table, in reverse dependency order if the destination was marked by
+// PrepareCopyDestination. It's used to get rid of the rows that are inserted
+// by the migrations before copying into a freshly migrated database. The mark
+// is removed once the tables are cleared.
+func ClearTables(ctx context.Context, db CopyDB) error {
+	tables, err := db.Dialect.Tables(ctx, db.DB)
+	if err != nil {
+		return fmt.Errorf("failed to fetch tables: %w", err)
+	}
+	var pending bool
+	for _, table := range tables {
+		if table == clearPendingTable {
+			pending = true
+			break
+		}
+	}
+	if !pending {
+		return nil
+	}
+
+	tables, err = copyOrder(ctx, db)
+	if err != nil {
+		return err
+	}
+	for i := len(tables) - 1; i >= 0; i-- {
+		if _, err := db.DB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", db.Dialect.QuoteIdentifier(tables[i]))); err != nil {
+			return fmt.Errorf("failed to clear table '%s': %w", tables[i], err)
+		}
+	}
+	if _, err := db.DB.ExecContext(ctx, fmt.Sprintf("DROP TABLE %s", db.Dialect.QuoteIdentifier(clearPendingTable))); err != nil {
+		return fmt.Errorf("failed to unmark destination: %w", err)
+	}
+	return nil
+}
+
+func compareMigrations(ctx context.Context, src, dst CopyDB) error {
+	srcIDs, err := migrationIDs(ctx, src)
+	if err != nil {
+		return fmt.Errorf("failed to fetch source migrations: %w", err)
+	}
+	dstIDs, err := migrationIDs(ctx, dst)
+	if err != nil {
+		return fmt.Errorf("failed to fetch destination migrations: %w", err)
+	}
+	if len(srcIDs) != len(dstIDs) {
+		return fmt.Errorf("%w: %d != %d migrations applied", ErrCopySchemaMismatch, len(srcIDs), len(dstIDs))
+	}
+	for id := range srcIDs {
+		if _, ok := dstIDs[id]; !ok {
+			return fmt.Errorf("%w: migration '%s' was not applied to the destination", ErrCopySchemaMismatch, id)
+		}
+	}
+	return nil
+}
+
+func migrationIDs(ctx context.Context, db CopyDB) (map[string]struct{}, error) {
+	rows, err := db.DB.QueryContext(ctx, fmt.Sprintf("SELECT id FROM %s", db.Dialect.QuoteIdentifier(migrationsTable)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]struct{})
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = struct{}{}
+	}
+	return ids, rows.Err()
+}
+
+// copyOrder returns the tables of the source database, ordered such that every
+// table comes after the tables it references.
+func copyOrder(ctx context.Context, src CopyDB) ([]string, error) {
+	tables, err := src.Dialect.Tables(ctx, src.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tables: %w", err)
+	}
+	sort.Strings(tables)
+
+	refs := make(map[string][]string)
+	for _, table := range tables {
+		if table == migrationsTable || table == clearPendingTable {
+			continue
+		}
+		refs[table], err = src.Dialect.References(ctx, src.DB, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch references of table '%s': %w", table, err)
+		}
+		sort.Strings(refs[table])
+	}
+
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(table string)
+	visit = func(table string) {
+		if _, ok := refs[table]; !ok || visited[table] {
+			return
+		}
+		visited[table] = true
+		for _, ref := range refs[table] {
+			visit(ref)
+		}
+		order = append(order, table)
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+	return order, nil
+}
+
+func loadCopyTable(ctx context.Context, src, dst CopyDB, name string) (copyTable, error) {
+	srcTypes, err := columnTypes(ctx, src, name)
+	if err != nil {
+		return copyTable{}, fmt.Errorf("failed to fetch columns of source table '%s': %w", name, err)
+	}
+	dstTypes, err := columnTypes(ctx, dst, name)
+	if err != nil {
+		return copyTable{}, fmt.Errorf("failed to fetch columns of destination table '%s': %w", name, err)
+	}
+	dstKinds := make(map[string]columnKind)
+	for _, ct := range dstTypes {
+		dstKinds[strings.ToLower(ct.Name())] = kindOf(ct.DatabaseTypeName())
+	}
+
+	t := copyTable{name: name}
+	for _, ct := range srcTypes {
+		dstKind, ok := dstKinds[strings.ToLower(ct.Name())]
+		if !ok {
+			return copyTable{}, fmt.Errorf("%w: column '%s.%s' is missing in the destination", ErrCopySchemaMismatch, name, ct.Name())
+		}
+		t.columns = append(t.columns, copyColumn{
+			name:    ct.Name(),
+			srcKind: kindOf(ct.DatabaseTypeName()),
+			dstKind: dstKind,
+		})
+	}
+
+	pk, err := src.Dialect.PrimaryKey(ctx, src.DB, name)
+	if err != nil {
+		return copyTable{}, fmt.Errorf("failed to fetch primary key of table '%s': %w", name, err)
+	} else if len(pk) == 0 {
+		return copyTable{}, fmt.Errorf("table '%s' has no primary key", name)
+	}
+	for _, col := range pk {
+		idx := -1
+		for i, c := range t.columns {
+			if strings.EqualFold(c.name, col) {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return copyTable{}, fmt.Errorf("primary key column '%s.%s' not found", name, col)
+		}
+		t.pk = append(t.pk, idx)
+	}
+	return t, nil
+}
+
+func columnTypes(ctx context.Context, db CopyDB, table string) ([]*dsql.ColumnType, error) {
+	rows, err := db.DB.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", db.Dialect.QuoteIdentifier(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.ColumnTypes()
+}
+
+func (t copyTable) copy(ctx context.Context, src, dst CopyDB, conn *dsql.Conn, resume bool, progress CopyProgressFn) error {
+	var total, copied uint64
+	if err := src.DB.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", src.Dialect.QuoteIdentifier(t.name))).Scan(&total); err != nil {
+		return fmt.Errorf("failed to count source rows: %w", err)
+	} else if err := dst.DB.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", dst.Dialect.QuoteIdentifier(t.name))).Scan(&copied); err != nil {
+		return fmt.Errorf("failed to count destination rows: %w", err)
+	} else if copied > 0 && !resume {
+		return ErrCopyDestinationNotEmpty
+	}
+
+	// continue after the greatest primary key in the destination
+	var last []any
+	if copied > 0 {
+		rows, err := t.query(ctx, dst, t.pk, true, nil, true, 1)
+		if err != nil {
+			return fmt.Errorf("failed to fetch last copied row: %w", err)
+		} else if len(rows) == 1 {
+			last = rows[0]
+		}
+	}
+	if progress != nil {
+		progress(t.name, copied, total)
+	}
+
+	limit := copyBatchSize
+	if n := copyMaxArgs / len(t.columns); n < limit {
+		limit = n
+	}
+	all := make([]int, len(t.columns))
+	for i := range all {
+		all[i] = i
+	}
+	for {
+		rows, err := t.query(ctx, src, all, false, last, false, limit)
+		if err != nil {
+			return fmt.Errorf("failed to fetch rows: %w", err)
+		} else if len(rows) == 0 {
+			break
+		}
+		if err := t.insert(ctx, dst, conn, rows); err != nil {
+			return fmt.Errorf("failed to insert rows: %w", err)
+		}
+
+		last = make([]any, len(t.pk))
+		for i, idx := range t.pk {
+			last[i] = rows[len(rows)-1][idx]
+		}
+		copied += uint64(len(rows))
+		if progress != nil {
+			progress(t.name, copied, total)
+		}
+		if len(rows) < limit {
+			break
+		}
+	}
+
+	if len(t.pk) == 1 && t.columns[t.pk[0]].dstKind == kindInt {
+		if err := dst.Dialect.ResetSequence(ctx, conn, t.name, t.columns[t.pk[0]].name); err != nil {
+			return fmt.Errorf("failed to reset sequence: %w", err)
+		}
+	}
+	return nil
+}
+
+func (t copyTable) columnList(db CopyDB, indices []int) string {
+	cols := make([]string, len(indices))
+	for i, idx := range indices {
+		cols[i] = db.Dialect.QuoteIdentifier(t.columns[idx].name)
+	}
+	return strings.Join(cols, ", ")
+}
+
+// query fetches the given columns of up to limit rows in primary key order,
+// starting after the given primary key. The returned values are normalized.
+func (t copyTable) query(ctx context.Context, db CopyDB, indices []int, isDst bool, after []any, desc bool, limit int) ([][]any, error) {
+	pk := t.columnList(db, t.pk)
+	order := pk
+	if desc {
+		order = strings.ReplaceAll(pk, ",", " DESC,") + " DESC"
+	}
+
+	var where string
+	if after != nil {
+		placeholders := make([]string, len(after))
+		for i := range after {
+			placeholders[i] = db.Dialect.Placeholder(i)
+		}
+		where = fmt.Sprintf("WHERE (%s) > (%s)", pk, strings.Join(placeholders, ", "))
+	}
+
+	rows, err := db.DB.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s %s ORDER BY %s LIMIT %d", t.columnList(db, indices), db.Dialect.QuoteIdentifier(t.name), where, order, limit), after...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	kinds := t.kinds(indices, isDst)
+
+	var res [][]any
+	for rows.Next() {
+		row, err := scanRow(rows, kinds)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+func (t copyTable) insert(ctx context.Context, dst CopyDB, conn *dsql.Conn, rows [][]any) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "INSERT INTO %s (", dst.Dialect.QuoteIdentifier(t.name))
+	for i, c := range t.columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(dst.Dialect.QuoteIdentifier(c.name))
+	}
+	sb.WriteString(") VALUES ")
+
+	args := make([]any, 0, len(rows)*len(t.columns))
+	for i, row := range rows {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(")
+		for j, v := range row {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(dst.Dialect.Placeholder(len(args)))
+			args = append(args, convertValue(v, t.columns[j].dstKind))
+		}
+		sb.WriteString(")")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, sb.String(), args...); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
+
+func (t copyTable) kinds(indices []int, isDst bool) []columnKind {
+	kinds := make([]columnKind, len(indices))
+	for i, idx := range indices {
+		kinds[i] = t.columns[idx].srcKind
+		if isDst {
+			kinds[i] = t.columns[idx].dstKind
+		}
+	}
+	return kinds
+}
+
+// checksum returns the number of rows in the table and the sum of the hashes
+// of all rows.
+func (t copyTable) checksum(ctx context.Context, db CopyDB, isDst bool) (n uint64, sum [4]uint64, err error) {
+	indices := make([]int, len(t.columns))
+	isJSON := make([]bool, len(t.columns))
+	for i, c := range t.columns {
+		indices[i] = i
+		isJSON[i] = c.srcKind == kindJSON || c.dstKind == kindJSON
+	}
+	kinds := t.kinds(indices, isDst)
+
+	rows, err := db.DB.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s", t.columnList(db, indices), db.Dialect.QuoteIdentifier(t.name)))
+	if err != nil {
+		return 0, sum, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row, err := scanRow(rows, kinds)
+		if err != nil {
+			return 0, sum, err
+		}
+		h := hashRow(row, isJSON)
+		for i := range sum {
+			sum[i] += binary.LittleEndian.Uint64(h[i*8:])
+		}
+		n++
+	}
+	return n, sum, rows.Err()
+}
+
+func scanRow(rows *dsql.Rows, kinds []columnKind) ([]any, error) {
+	row := make([]any, len(kinds))
+	ptrs := make([]any, len(kinds))
+	for i := range row {
+		ptrs[i] = &row[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	for i, v := range row {
+		nv, err := normalizeValue(v, kinds[i])
+		if err != nil {
+			return nil, err
+		}
+		row[i] = nv
+	}
+	return row, nil
+}
+
+func kindOf(typ string) columnKind {
+	typ = strings.ToUpper(typ)
+	switch {
+	case strings.Contains(typ, "BOOL"):
+		return kindBool
+	case strings.Contains(typ, "INT"):
+		return kindInt
+	case strings.Contains(typ, "JSON"):
+		return kindJSON
+	case strings.Contains(typ, "BLOB"), strings.Contains(typ, "BINARY"), strings.Contains(typ, "BYTEA"):
+		return kindBytes
+	case strings.Contains(typ, "CHAR"), strings.Contains(typ, "TEXT"), strings.Contains(typ, "CLOB"):
+		return kindText
+	case strings.Contains(typ, "DOUBLE"), strings.Contains(typ, "FLOAT"), strings.Contains(typ, "REAL"):
+		return kindFloat
+	case strings.Contains(typ, "DATE"), strings.Contains(typ, "TIME"):
+		return kindTime
+	case strings.Contains(typ, "NUMERIC"), strings.Contains(typ, "DECIMAL"):
+		return kindNumeric
+	default:
+		return kindUnknown
+	}
+}
+
+// normalizeValue converts a value returned by a driver into one of nil, int64,
+// uint64, float64, bool, string, []byte or time.Time depending on the column's
+// kind. Times are converted to UTC and truncated to milliseconds, which is the
+// lowest precision of all supported backends.
+func normalizeValue(v any, kind columnKind) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	// some drivers return numbers and times as text
+	var s string
+	switch v := v.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case float32:
+		return normalizeValue(float64(v), kind)
+	case time.Time:
+		return v.UTC().Truncate(time.Millisecond), nil
+	}
+
+	switch kind {
+	case kindInt, kindNumeric, kindBool:
+		switch v := v.(type) {
+		case bool:
+			if kind == kindBool {
+				return v, nil
+			} else if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case int64:
+			if kind == kindBool {
+				return v != 0, nil
+			}
+			return v, nil
+		case uint64:
+			if kind == kindBool {
+				return v != 0, nil
+			}
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+				return int64(v), nil
+			}
+			return v, nil
+		case []byte, string:
+			if kind == kindBool {
+				if b, err := strconv.ParseBool(s); err == nil {
+					return b, nil
+				}
+			}
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i, nil
+			} else if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+				return u, nil
+			} else if f, err := strconv.ParseFloat(s, 64); err == nil && kind == kindNumeric {
+				return f, nil
+			}
+			return nil, fmt.Errorf("failed to parse number '%s'", s)
+		}
+	case kindFloat:
+		switch v := v.(type) {
+		case int64:
+			return float64(v), nil
+		case []byte, string:
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse float '%s': %w", s, err)
+			}
+			return f, nil
+		}
+	case kindTime:
+		switch v.(type) {
+		case []byte, string:
+			for _, layout := range []string{
+				"2006-01-02 15:04:05.999999999-07:00",
+				"2006-01-02T15:04:05.999999999-07:00",
+				"2006-01-02 15:04:05.999999999",
+				"2006-01-02T15:04:05.999999999",
+				time.RFC3339Nano,
+			} {
+				if t, err := time.Parse(layout, s); err == nil {
+					return t.UTC().Truncate(time.Millisecond), nil
+				}
+			}
+			return nil, fmt.Errorf("failed to parse time '%s'", s)
+		}
+	case kindText, kindJSON:
+		if b, ok := v.([]byte); ok {
+			return string(b), nil
+		}
+	case kindBytes:
+		if s, ok := v.(string); ok {
+			return []byte(s), nil
+		}
+	}
+	if b, ok := v.([]byte); ok {
+		return bytes.Clone(b), nil
+	}
+	return v, nil
+}
+
+// convertValue converts a normalized value to a type the destination column
+// accepts.
+func convertValue(v any, kind columnKind) any {
+	switch kind {
+	case kindBool:
+		switch v := v.(type) {
+		case int64:
+			return v != 0
+		case uint64:
+			return v != 0
+		}
+	case kindInt:
+		if b, ok := v.(bool); ok {
+			if b {
+				return int64(1)
+			}
+			return int64(0)
+		}
+	case kindText, kindJSON:
+		if b, ok := v.([]byte); ok {
+			return string(b)
+		}
+	case kindBytes:
+		if s, ok := v.(string); ok {
+			return []byte(s)
+		}
+	}
+	return v
+}
+
+// hashRow hashes a normalized row in a way that doesn't depend on the backend
+// the row was read from.
+func hashRow(row []any, isJSON []bool) [32]byte {
+	h, _ := blake2b.New256(nil)
+	var buf [8]byte
+	write := func(prefix byte, b []byte) {
+		binary.LittleEndian.PutUint64(buf[:], uint64(len(b)))
+		h.Write([]byte{prefix})
+		h.Write(buf[:])
+		h.Write(b)
+	}
+	for i, v := range row {
+		switch v := v.(type) {
+		case nil:
+			write('n', nil)
+		case bool:
+			if v {
+				write('i', []byte("1"))
+			} else {
+				write('i', []byte("0"))
+			}
+		case int64:
+			write('i', []byte(strconv.FormatInt(v, 10)))
+		case uint64:
+			write('i', []byte(strconv.FormatUint(v, 10)))
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+				write('i', []byte(strconv.FormatInt(int64(v), 10)))
+			} else {
+				write('f', []byte(strconv.FormatFloat(v, 'g', -1, 64)))
+			}
+		case time.Time:
+			write('t', []byte(strconv.FormatInt(v.UnixMilli(), 10)))
+		case string:
+			if isJSON[i] {
+				write('b', canonicalJSON([]byte(v)))
+			} else {
+				write('b', []byte(v))
+			}
+		case []byte:
+			if isJSON[i] {
+				write('b', canonicalJSON(v))
+			} else {
+				write('b', v)
+			}
+		default:
+			write('?', []byte(fmt.Sprint(v)))
+		}
+	}
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// canonicalJSON re-encodes JSON to get rid of differences in whitespace and
+// key order introduced by the backends. Invalid JSON is returned as is.
+func canonicalJSON(b []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return b
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return b
+	}
+	return canonical
+}
@@ -17,9 +17,17 @@ type (
 	Database interface {
 		io.Closer
 
+		// Backup writes a consistent copy of the database to the given path
+		// without blocking writers.
+		Backup(ctx context.Context, path string) error
+
 		// Migrate runs all missing migrations on the database.
 		Migrate(ctx context.Context) error
 
+		// ReadOnlyTransaction starts a new transaction that only reads from
+		// the database.
+		ReadOnlyTransaction(ctx context.Context, fn func(DatabaseTx) error) error
+
 		// Transaction starts a new transaction.
 		Transaction(ctx context.Context, fn func(DatabaseTx) error) error
 
@@ -57,6 +65,9 @@ type (
 		// exist, it returns api.ErrBucketNotFound.
 		Bucket(ctx context.Context, bucket string) (api.Bucket, error)
 
+		// BufferedSlabFilenames returns the filenames of all buffered slabs.
+		BufferedSlabFilenames(ctx context.Context) ([]string, error)
+
 		// CompleteMultipartUpload completes a multipart upload by combining the
 		// provided parts into an object in bucket 'bucket' with key 'key'. The
 		// parts need to be provided in ascending partNumber order without
@@ -67,6 +78,10 @@ type (
 		// opts argument can be used to filter the result.
 		Contracts(ctx context.Context, opts api.ContractsOpts) ([]api.ContractMetadata, error)
 
+		// ContractHostKeys returns the host keys of all active and archived
+		// contracts.
+		ContractHostKeys(ctx context.Context) (map[types.FileContractID]types.PublicKey, error)
+
 		// ContractSize returns the size of the contract with the given ID as
 		// well as the estimated number of bytes that can be pruned from it.
 		ContractSize(ctx context.Context, id types.FileContractID) (api.ContractSize, error)
@@ -91,6 +106,10 @@ type (
 		// webhooks.ErrWebhookNotFound is returned.
 		DeleteWebhook(ctx context.Context, wh webhooks.Webhook) error
 
+		// Fsck runs the consistency check with the given name, see
+		// FsckChecks, and repairs the issues it finds if requested.
+		Fsck(ctx context.Context, check string, repair bool) (api.FsckIssue, error)
+
 		// InsertBufferedSlab inserts a buffered slab into the database. This
 		// includes the creation of a buffered slab as well as the corresponding
 		// regular slab it is linked to. It returns the ID of the buffered slab
@@ -248,6 +267,10 @@ type (
 	MetricsDatabase interface {
 		io.Closer
 
+		// Backup writes a consistent copy of the database to the given path
+		// without blocking writers.
+		Backup(ctx context.Context, path string) error
+
 		// Migrate runs all missing migrations on the database.
 		Migrate(ctx context.Context) error
 
@@ -275,9 +298,18 @@ type (
 		// time range and options.
 		ContractSetMetrics(ctx context.Context, start time.Time, n uint64, interval time.Duration, opts api.ContractSetMetricsQueryOpts) ([]api.ContractSetMetric, error)
 
+		// DownsampleMetrics updates the rollup of a metric with the given
+		// resolution with the data points recorded before the given time.
+		DownsampleMetrics(ctx context.Context, metric string, resolution time.Duration, until time.Time) error
+
 		// PerformanceMetrics returns performance metrics for the given time range
 		PerformanceMetrics(ctx context.Context, start time.Time, n uint64, interval time.Duration, opts api.PerformanceMetricsQueryOpts) ([]api.PerformanceMetric, error)
 
+		// PruneMetrics removes the data points of a metric at the given
+		// resolution that are older than the cutoff, 0 refers to the raw data
+		// points.
+		PruneMetrics(ctx context.Context, metric string, resolution time.Duration, cutoff time.Time) error
+
 		// RecordContractMetric records contract metrics.
 		RecordContractMetric(ctx context.Context, metrics ...api.ContractMetric) error
 
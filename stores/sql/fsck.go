@@ -0,0 +1,410 @@
+package sql
+
+import (
+	"context"
+	dsql "database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/sql"
+)
+
+// fsckBatchSize is the maximum number of ids passed to a single repair query.
+const fsckBatchSize = 1000
+
+// ErrUnknownFsckCheck is returned by Fsck if the check doesn't exist.
+var ErrUnknownFsckCheck = errors.New("unknown fsck check")
+
+// FsckChecks contains the names of all checks Fsck can run. They are ordered
+// such that repairing an issue never causes an issue that was already checked
+// for, e.g. unreferenced slabs are deleted before looking for dangling
+// sectors.
+var FsckChecks = []string{
+	"contracts_archived",
+	"contract_sectors_dangling",
+	"slices_dangling",
+	"slices_missing_slab",
+	"slabs_missing_buffer",
+	"slabs_unreferenced",
+	"sectors_dangling",
+	"buffered_slabs_unreferenced",
+	"objects_wrong_directory",
+	"directories_dangling",
+	"directories_empty",
+	"slabs_unrecoverable",
+	"slabs_health_overestimated",
+}
+
+type (
+	// MakeDirsFn creates all directories of the given object path and returns
+	// the id of the object's directory.
+	MakeDirsFn func(ctx context.Context, path string) (int64, error)
+
+	fsckCheck struct {
+		description string
+		example     string // format string for a single id
+
+		// find returns the ids of all affected rows
+		find func(ctx context.Context, tx sql.Tx) ([]int64, error)
+
+		// repair fixes the affected rows, it's nil if the issue can't be
+		// repaired automatically
+		repair func(ctx context.Context, tx sql.Tx, ids []int64, makeDirs MakeDirsFn) error
+	}
+)
+
+var fsckChecks = map[string]fsckCheck{
+	"contracts_archived": {
+		description: "active contracts that were also archived, their sectors are no longer stored",
+		example:     "contract %d",
+		find:        findIDs("SELECT c.id FROM contracts c INNER JOIN archived_contracts ac ON c.fcid = ac.fcid"),
+		repair: func(ctx context.Context, tx sql.Tx, ids []int64, _ MakeDirsFn) error {
+			// invalidate the health of all slabs with sectors on the contracts
+			// before deleting them
+			if err := execForIDs(ctx, tx, `
+				UPDATE slabs SET health_valid_until = 0 WHERE id IN (
+					SELECT s.db_slab_id
+					FROM sectors s
+					INNER JOIN contract_sectors cs ON cs.db_sector_id = s.id
+					WHERE cs.db_contract_id IN (%s)
+				)`, ids); err != nil {
+				return err
+			}
+			return execForIDs(ctx, tx, "DELETE FROM contracts WHERE id IN (%s)", ids)
+		},
+	},
+	"contract_sectors_dangling": {
+		description: "contract sectors referencing a sector or contract that doesn't exist",
+		example:     "sector %d",
+		find: findIDs(`
+			SELECT cs.db_sector_id
+			FROM contract_sectors cs
+			WHERE NOT EXISTS (SELECT 1 FROM sectors s WHERE s.id = cs.db_sector_id)
+			OR NOT EXISTS (SELECT 1 FROM contracts c WHERE c.id = cs.db_contract_id)`),
+		repair: func(ctx context.Context, tx sql.Tx, _ []int64, _ MakeDirsFn) error {
+			_, err := tx.Exec(ctx, `
+				DELETE FROM contract_sectors
+				WHERE NOT EXISTS (SELECT 1 FROM sectors s WHERE s.id = contract_sectors.db_sector_id)
+				OR NOT EXISTS (SELECT 1 FROM contracts c WHERE c.id = contract_sectors.db_contract_id)`)
+			return err
+		},
+	},
+	"slices_dangling": {
+		description: "slices that belong neither to an existing object nor to an existing multipart part",
+		example:     "slice %d",
+		find: findIDs(`
+			SELECT sli.id
+			FROM slices sli
+			WHERE (sli.db_object_id IS NULL AND sli.db_multipart_part_id IS NULL)
+			OR (sli.db_object_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM objects o WHERE o.id = sli.db_object_id))
+			OR (sli.db_multipart_part_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM multipart_parts mp WHERE mp.id = sli.db_multipart_part_id))`),
+		repair: deleteIDs("slices"),
+	},
+	"slices_missing_slab": {
+		description: "slices referencing a slab that doesn't exist, the data of the affected objects is lost",
+		example:     "slice %d",
+		find: findIDs(`
+			SELECT sli.id
+			FROM slices sli
+			WHERE NOT EXISTS (SELECT 1 FROM slabs sla WHERE sla.id = sli.db_slab_id)`),
+	},
+	"slabs_missing_buffer": {
+		description: "slabs referencing a buffered slab that doesn't exist, the data of the affected objects is lost",
+		example:     "slab %d",
+		find: findIDs(`
+			SELECT sla.id
+			FROM slabs sla
+			WHERE sla.db_buffered_slab_id IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM buffered_slabs bs WHERE bs.id = sla.db_buffered_slab_id)`),
+	},
+	"slabs_unreferenced": {
+		description: "uploaded slabs that aren't referenced by any slice",
+		example:     "slab %d",
+		find: findIDs(`
+			SELECT sla.id
+			FROM slabs sla
+			WHERE sla.db_buffered_slab_id IS NULL
+			AND NOT EXISTS (SELECT 1 FROM slices sli WHERE sli.db_slab_id = sla.id)`),
+		repair: deleteIDs("slabs"),
+	},
+	"sectors_dangling": {
+		description: "sectors referencing a slab that doesn't exist",
+		example:     "sector %d",
+		find: findIDs(`
+			SELECT s.id
+			FROM sectors s
+			WHERE NOT EXISTS (SELECT 1 FROM slabs sla WHERE sla.id = s.db_slab_id)`),
+		repair: deleteIDs("sectors"),
+	},
+	"buffered_slabs_unreferenced": {
+		description: "buffered slabs that aren't referenced by any slab",
+		example:     "buffered slab %d",
+		find: findIDs(`
+			SELECT bs.id
+			FROM buffered_slabs bs
+			WHERE NOT EXISTS (SELECT 1 FROM slabs sla WHERE sla.db_buffered_slab_id = bs.id)`),
+	},
+	"objects_wrong_directory": {
+		description: "objects that reference a directory that doesn't exist or doesn't match their path",
+		example:     "object %d",
+		find:        findObjectsWrongDirectory,
+		repair: func(ctx context.Context, tx sql.Tx, ids []int64, makeDirs MakeDirsFn) error {
+			for _, id := range ids {
+				var path string
+				if err := tx.QueryRow(ctx, "SELECT object_id FROM objects WHERE id = ?", id).Scan(&path); err != nil {
+					return fmt.Errorf("failed to fetch object %d: %w", id, err)
+				}
+				dirID, err := makeDirs(ctx, path)
+				if err != nil {
+					return fmt.Errorf("failed to create directories for object %d: %w", id, err)
+				} else if _, err := tx.Exec(ctx, "UPDATE objects SET db_directory_id = ? WHERE id = ?", dirID, id); err != nil {
+					return fmt.Errorf("failed to update directory of object %d: %w", id, err)
+				}
+			}
+			return nil
+		},
+	},
+	"directories_dangling": {
+		description: "directories referencing a parent directory that doesn't exist",
+		example:     "directory %d",
+		find: findIDs(`
+			SELECT d.id
+			FROM directories d
+			WHERE d.db_parent_id IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM directories p WHERE p.id = d.db_parent_id)`),
+		repair: func(ctx context.Context, tx sql.Tx, ids []int64, makeDirs MakeDirsFn) error {
+			for _, id := range ids {
+				var name string
+				if err := tx.QueryRow(ctx, "SELECT name FROM directories WHERE id = ?", id).Scan(&name); err != nil {
+					return fmt.Errorf("failed to fetch directory %d: %w", id, err)
+				}
+				// creating the directories for the directory's name returns
+				// the id of its parent
+				parentID, err := makeDirs(ctx, name)
+				if err != nil {
+					return fmt.Errorf("failed to create parent directories of directory %d: %w", id, err)
+				} else if _, err := tx.Exec(ctx, "UPDATE directories SET db_parent_id = ? WHERE id = ?", parentID, id); err != nil {
+					return fmt.Errorf("failed to update parent of directory %d: %w", id, err)
+				}
+			}
+			return nil
+		},
+	},
+	"directories_empty": {
+		description: "directories without objects or sub-directories",
+		example:     "directory %d",
+		find:        findIDs(emptyDirectoriesQuery),
+		repair: func(ctx context.Context, tx sql.Tx, ids []int64, _ MakeDirsFn) error {
+			// deleting a directory might leave its parent empty
+			find := findIDs(emptyDirectoriesQuery)
+			for len(ids) > 0 {
+				if err := execForIDs(ctx, tx, "DELETE FROM directories WHERE id IN (%s)", ids); err != nil {
+					return err
+				}
+				var err error
+				if ids, err = find(ctx, tx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	"slabs_unrecoverable": {
+		description: "slabs with fewer sectors on active contracts than required to recover them, the data of the affected objects is lost",
+		example:     "slab %d",
+		find: func(ctx context.Context, tx sql.Tx) ([]int64, error) {
+			return findSlabsByHealth(ctx, tx, func(h slabHealth) bool {
+				return h.activeHosts < h.minShards
+			})
+		},
+	},
+	"slabs_health_overestimated": {
+		description: "slabs whose cached health is greater than the health computed from their contracts",
+		example:     "slab %d",
+		find: func(ctx context.Context, tx sql.Tx) ([]int64, error) {
+			now := time.Now().Unix()
+			return findSlabsByHealth(ctx, tx, func(h slabHealth) bool {
+				return h.validUntil > now && h.health > h.computed()+1e-9
+			})
+		},
+		repair: func(ctx context.Context, tx sql.Tx, ids []int64, _ MakeDirsFn) error {
+			// the health is recomputed during the next health refresh
+			return execForIDs(ctx, tx, "UPDATE slabs SET health_valid_until = 0 WHERE id IN (%s)", ids)
+		},
+	},
+}
+
+const emptyDirectoriesQuery = `
+	SELECT d.id
+	FROM directories d
+	WHERE d.id != 1
+	AND NOT EXISTS (SELECT 1 FROM objects o WHERE o.db_directory_id = d.id)
+	AND NOT EXISTS (SELECT 1 FROM directories c WHERE c.db_parent_id = d.id)`
+
+// Fsck runs a single consistency check on the database. If repair is true and
+// the issue can be repaired, the affected rows are fixed within the same
+// transaction.
+func Fsck(ctx context.Context, tx sql.Tx, check string, repair bool, makeDirs MakeDirsFn) (api.FsckIssue, error) {
+	c, ok := fsckChecks[check]
+	if !ok {
+		return api.FsckIssue{}, fmt.Errorf("%w: %v", ErrUnknownFsckCheck, check)
+	}
+
+	ids, err := c.find(ctx, tx)
+	if err != nil {
+		return api.FsckIssue{}, fmt.Errorf("failed to run check '%s': %w", check, err)
+	}
+	issue := api.FsckIssue{
+		Check:       check,
+		Description: c.description,
+		Count:       len(ids),
+		Repairable:  c.repair != nil,
+	}
+	for i := 0; i < len(ids) && i < api.FsckMaxExamples; i++ {
+		issue.Examples = append(issue.Examples, fmt.Sprintf(c.example, ids[i]))
+	}
+
+	if repair && c.repair != nil && len(ids) > 0 {
+		if err := c.repair(ctx, tx, ids, makeDirs); err != nil {
+			return api.FsckIssue{}, fmt.Errorf("failed to repair '%s': %w", check, err)
+		}
+		issue.Repaired = true
+	}
+	return issue, nil
+}
+
+func findIDs(query string) func(ctx context.Context, tx sql.Tx) ([]int64, error) {
+	return func(ctx context.Context, tx sql.Tx) ([]int64, error) {
+		rows, err := tx.Query(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return nil, fmt.Errorf("failed to scan id: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		return ids, rows.Err()
+	}
+}
+
+func deleteIDs(table string) func(ctx context.Context, tx sql.Tx, ids []int64, _ MakeDirsFn) error {
+	return func(ctx context.Context, tx sql.Tx, ids []int64, _ MakeDirsFn) error {
+		return execForIDs(ctx, tx, fmt.Sprintf("DELETE FROM %s WHERE id IN (%%s)", table), ids)
+	}
+}
+
+// execForIDs executes the query for the given ids in batches, the query's %s
+// verb is replaced with the placeholders for a batch.
+func execForIDs(ctx context.Context, tx sql.Tx, query string, ids []int64) error {
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > fsckBatchSize {
+			n = fsckBatchSize
+		}
+		args := make([]any, n)
+		for i := range args {
+			args[i] = ids[i]
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf(query, strings.Repeat("?, ", n-1)+"?"), args...); err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
+
+func findObjectsWrongDirectory(ctx context.Context, tx sql.Tx) ([]int64, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT o.id, o.object_id, d.name
+		FROM objects o
+		LEFT JOIN directories d ON d.id = o.db_directory_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var path string
+		var dir dsql.NullString
+		if err := rows.Scan(&id, &path, &dir); err != nil {
+			return nil, fmt.Errorf("failed to scan object: %w", err)
+		} else if !dir.Valid || dir.String != objectDirectory(path) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+// objectDirectory returns the name of the directory an object with the given
+// path belongs to, it mirrors the directories created by MakeDirsForPath.
+func objectDirectory(path string) string {
+	dir := "/"
+	path = strings.TrimSuffix(path, "/")
+	for i := 0; i < utf8.RuneCountInString(path); i++ {
+		if path[i] == '/' && i > 0 {
+			dir = path[:i+1]
+		}
+	}
+	return dir
+}
+
+type slabHealth struct {
+	id          int64
+	health      float64
+	validUntil  int64
+	minShards   int64
+	totalShards int64
+	setHosts    int64 // hosts storing sectors on contracts in the slab's set
+	activeHosts int64 // hosts storing sectors on any active contract
+}
+
+// computed returns the slab's health the same way PrepareSlabHealth does.
+func (h slabHealth) computed() float64 {
+	if h.minShards == h.totalShards {
+		if h.setHosts < h.minShards {
+			return -1
+		}
+		return 1
+	}
+	return (float64(h.setHosts) - float64(h.minShards)) / float64(h.totalShards-h.minShards)
+}
+
+func findSlabsByHealth(ctx context.Context, tx sql.Tx, match func(slabHealth) bool) ([]int64, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT sla.id, sla.health, sla.health_valid_until, sla.min_shards, sla.total_shards,
+			COUNT(DISTINCT(CASE WHEN csc.db_contract_id IS NULL THEN NULL ELSE c.host_id END)),
+			COUNT(DISTINCT(c.host_id))
+		FROM slabs sla
+		INNER JOIN sectors s ON s.db_slab_id = sla.id
+		LEFT JOIN contract_sectors se ON se.db_sector_id = s.id
+		LEFT JOIN contracts c ON c.id = se.db_contract_id
+		LEFT JOIN contract_set_contracts csc ON csc.db_contract_id = c.id AND csc.db_contract_set_id = sla.db_contract_set_id
+		GROUP BY sla.id, sla.health, sla.health_valid_until, sla.min_shards, sla.total_shards`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var h slabHealth
+		if err := rows.Scan(&h.id, &h.health, &h.validUntil, &h.minShards, &h.totalShards, &h.setHosts, &h.activeHosts); err != nil {
+			return nil, fmt.Errorf("failed to scan slab health: %w", err)
+		} else if match(h) {
+			ids = append(ids, h.id)
+		}
+	}
+	return ids, rows.Err()
+}
@@ -140,6 +140,24 @@ func Bucket(ctx context.Context, tx sql.Tx, bucket string) (api.Bucket, error) {
 	return b, nil
 }
 
+func BufferedSlabFilenames(ctx context.Context, tx sql.Tx) ([]string, error) {
+	rows, err := tx.Query(ctx, "SELECT filename FROM buffered_slabs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch buffered slabs: %w", err)
+	}
+	defer rows.Close()
+
+	var filenames []string
+	for rows.Next() {
+		var filename string
+		if err := rows.Scan(&filename); err != nil {
+			return nil, fmt.Errorf("failed to scan filename: %w", err)
+		}
+		filenames = append(filenames, filename)
+	}
+	return filenames, nil
+}
+
 func Contracts(ctx context.Context, tx sql.Tx, opts api.ContractsOpts) ([]api.ContractMetadata, error) {
 	var rows *sql.LoggedRows
 	var err error
@@ -565,6 +583,31 @@ func InsertMetadata(ctx context.Context, tx sql.Tx, objID, muID *int64, md api.O
 	return nil
 }
 
+func ContractHostKeys(ctx context.Context, tx sql.Tx) (map[types.FileContractID]types.PublicKey, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT c.fcid, h.public_key
+		FROM contracts c
+		INNER JOIN hosts h ON h.id = c.host_id
+		UNION ALL
+		SELECT fcid, host FROM archived_contracts
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch contract host keys: %w", err)
+	}
+	defer rows.Close()
+
+	hostKeys := make(map[types.FileContractID]types.PublicKey)
+	for rows.Next() {
+		var fcid types.FileContractID
+		var hk types.PublicKey
+		if err := rows.Scan((*FileContractID)(&fcid), (*PublicKey)(&hk)); err != nil {
+			return nil, fmt.Errorf("failed to scan contract host key: %w", err)
+		}
+		hostKeys[fcid] = hk
+	}
+	return hostKeys, nil
+}
+
 func ContractSize(ctx context.Context, tx sql.Tx, id types.FileContractID) (api.ContractSize, error) {
 	var contractID, size uint64
 	if err := tx.QueryRow(ctx, "SELECT id, size FROM contracts WHERE fcid = ?", FileContractID(id)).
@@ -2,6 +2,7 @@ package sql
 
 import (
 	"context"
+	dsql "database/sql"
 	"fmt"
 	"math"
 	"time"
@@ -13,6 +14,16 @@ import (
 
 const (
 	contractMetricGranularity = 5 * time.Minute
+
+	// MetricsResolutionHour and MetricsResolutionDay are the resolutions of
+	// the rollups contract and performance metrics are downsampled into.
+	MetricsResolutionHour = time.Hour
+	MetricsResolutionDay  = 24 * time.Hour
+)
+
+const (
+	contractMetricColumns    = "created_at, timestamp, fcid, host, remaining_collateral_lo, remaining_collateral_hi, remaining_funds_lo, remaining_funds_hi, revision_number, upload_spending_lo, upload_spending_hi, download_spending_lo, download_spending_hi, fund_account_spending_lo, fund_account_spending_hi, delete_spending_lo, delete_spending_hi, list_spending_lo, list_spending_hi"
+	performanceMetricColumns = "created_at, timestamp, action, host, origin, duration"
 )
 
 type (
@@ -20,6 +31,19 @@ type (
 		api.ContractMetricsQueryOpts
 		IndexHint string
 	}
+
+	// metricsRollup is a table containing the first data point of every
+	// series within each bucket of the rollup's resolution. Since that's the
+	// data point the queries return for a period, querying a rollup yields
+	// the same result as querying the raw data points for periods that are
+	// multiples of its resolution.
+	metricsRollup struct {
+		table      string
+		source     string
+		columns    string
+		series     string
+		resolution time.Duration
+	}
 )
 
 func ContractMetrics(ctx context.Context, tx sql.Tx, start time.Time, n uint64, interval time.Duration, opts ContractMetricsQueryOpts) ([]api.ContractMetric, error) {
@@ -61,21 +85,28 @@ func ContractMetrics(ctx context.Context, tx sql.Tx, start time.Time, n uint64,
 		return
 	}
 
-	// if a host filter is set, query periods
-	if opts.ContractID != (types.FileContractID{}) || opts.HostKey != (types.PublicKey{}) {
-		return queryPeriods(ctx, tx, start, n, interval, opts.ContractMetricsQueryOpts, func(rows *sql.LoggedRows) (api.ContractMetric, error) {
-			return scanContractMetric(rows, false)
-		})
-	}
+	return queryRollups(ctx, tx, "contracts", start, n, interval, func(table string, start time.Time, n uint64) ([]api.ContractMetric, error) {
+		// if a host filter is set, query periods
+		if opts.ContractID != (types.FileContractID{}) || opts.HostKey != (types.PublicKey{}) {
+			return queryPeriods(ctx, tx, table, start, n, interval, opts.ContractMetricsQueryOpts, func(rows *sql.LoggedRows) (api.ContractMetric, error) {
+				return scanContractMetric(rows, false)
+			})
+		}
 
-	// otherwise we return the aggregated metrics for each period
-	return queryAggregatedPeriods(ctx, tx, start, n, interval, opts.IndexHint, func(rows *sql.LoggedRows) (api.ContractMetric, error) {
-		return scanContractMetric(rows, true)
+		// otherwise we return the aggregated metrics for each period, the
+		// index hint only applies to the raw data points
+		var indexHint string
+		if table == "contracts" {
+			indexHint = opts.IndexHint
+		}
+		return queryAggregatedPeriods(ctx, tx, table, start, n, interval, indexHint, func(rows *sql.LoggedRows) (api.ContractMetric, error) {
+			return scanContractMetric(rows, true)
+		})
 	})
 }
 
 func ContractPruneMetrics(ctx context.Context, tx sql.Tx, start time.Time, n uint64, interval time.Duration, opts api.ContractPruneMetricsQueryOpts) ([]api.ContractPruneMetric, error) {
-	return queryPeriods(ctx, tx, start, n, interval, opts, func(rows *sql.LoggedRows) (m api.ContractPruneMetric, err error) {
+	return queryPeriods(ctx, tx, "contract_prunes", start, n, interval, opts, func(rows *sql.LoggedRows) (m api.ContractPruneMetric, err error) {
 		var placeHolder int64
 		var placeHolderTime time.Time
 		var timestamp UnixTimeMS
@@ -100,7 +131,7 @@ func ContractPruneMetrics(ctx context.Context, tx sql.Tx, start time.Time, n uin
 }
 
 func ContractSetChurnMetrics(ctx context.Context, tx sql.Tx, start time.Time, n uint64, interval time.Duration, opts api.ContractSetChurnMetricsQueryOpts) ([]api.ContractSetChurnMetric, error) {
-	return queryPeriods(ctx, tx, start, n, interval, opts, func(rows *sql.LoggedRows) (m api.ContractSetChurnMetric, err error) {
+	return queryPeriods(ctx, tx, "contract_sets_churn", start, n, interval, opts, func(rows *sql.LoggedRows) (m api.ContractSetChurnMetric, err error) {
 		var placeHolder int64
 		var placeHolderTime time.Time
 		var timestamp UnixTimeMS
@@ -123,7 +154,7 @@ func ContractSetChurnMetrics(ctx context.Context, tx sql.Tx, start time.Time, n
 }
 
 func ContractSetMetrics(ctx context.Context, tx sql.Tx, start time.Time, n uint64, interval time.Duration, opts api.ContractSetMetricsQueryOpts) ([]api.ContractSetMetric, error) {
-	return queryPeriods(ctx, tx, start, n, interval, opts, func(rows *sql.LoggedRows) (m api.ContractSetMetric, err error) {
+	return queryPeriods(ctx, tx, "contract_sets", start, n, interval, opts, func(rows *sql.LoggedRows) (m api.ContractSetMetric, err error) {
 		var placeHolder int64
 		var placeHolderTime time.Time
 		var timestamp UnixTimeMS
@@ -144,25 +175,27 @@ func ContractSetMetrics(ctx context.Context, tx sql.Tx, start time.Time, n uint6
 }
 
 func PerformanceMetrics(ctx context.Context, tx sql.Tx, start time.Time, n uint64, interval time.Duration, opts api.PerformanceMetricsQueryOpts) ([]api.PerformanceMetric, error) {
-	return queryPeriods(ctx, tx, start, n, interval, opts, func(rows *sql.LoggedRows) (m api.PerformanceMetric, err error) {
-		var placeHolder int64
-		var placeHolderTime time.Time
-		var timestamp UnixTimeMS
-		err = rows.Scan(
-			&placeHolder,
-			&placeHolderTime,
-			&timestamp,
-			&m.Action,
-			(*PublicKey)(&m.HostKey),
-			&m.Origin,
-			&m.Duration,
-		)
-		if err != nil {
-			err = fmt.Errorf("failed to scan contract set metric: %w", err)
+	return queryRollups(ctx, tx, "performance", start, n, interval, func(table string, start time.Time, n uint64) ([]api.PerformanceMetric, error) {
+		return queryPeriods(ctx, tx, table, start, n, interval, opts, func(rows *sql.LoggedRows) (m api.PerformanceMetric, err error) {
+			var placeHolder int64
+			var placeHolderTime time.Time
+			var timestamp UnixTimeMS
+			err = rows.Scan(
+				&placeHolder,
+				&placeHolderTime,
+				&timestamp,
+				&m.Action,
+				(*PublicKey)(&m.HostKey),
+				&m.Origin,
+				&m.Duration,
+			)
+			if err != nil {
+				err = fmt.Errorf("failed to scan contract set metric: %w", err)
+				return
+			}
+			m.Timestamp = api.TimeRFC3339(normaliseTimestamp(start, interval, timestamp))
 			return
-		}
-		m.Timestamp = api.TimeRFC3339(normaliseTimestamp(start, interval, timestamp))
-		return
+		})
 	})
 }
 
@@ -366,7 +399,7 @@ func RecordWalletMetric(ctx context.Context, tx sql.Tx, metrics ...api.WalletMet
 }
 
 func WalletMetrics(ctx context.Context, tx sql.Tx, start time.Time, n uint64, interval time.Duration, opts api.WalletMetricsQueryOpts) ([]api.WalletMetric, error) {
-	return queryPeriods(ctx, tx, start, n, interval, opts, func(rows *sql.LoggedRows) (m api.WalletMetric, err error) {
+	return queryPeriods(ctx, tx, "wallets", start, n, interval, opts, func(rows *sql.LoggedRows) (m api.WalletMetric, err error) {
 		var placeHolder int64
 		var placeHolderTime time.Time
 		var timestamp UnixTimeMS
@@ -387,7 +420,270 @@ func WalletMetrics(ctx context.Context, tx sql.Tx, start time.Time, n uint64, in
 	})
 }
 
-func queryPeriods[T any](ctx context.Context, tx sql.Tx, start time.Time, n uint64, interval time.Duration, opts interface{}, scanRowFn func(*sql.LoggedRows) (T, error)) ([]T, error) {
+// DownsampleMetrics updates the rollup of the given metric with the given
+// resolution with all buckets that ended before 'until'. Rollups are only
+// updated up to the point their source was downsampled. Data points that are
+// recorded for a bucket after it was rolled up are not included.
+func DownsampleMetrics(ctx context.Context, tx sql.Tx, metric string, resolution time.Duration, until time.Time) error {
+	table, err := metricsTable(metric)
+	if err != nil {
+		return err
+	}
+	rollups := metricsRollups(table)
+
+	var r metricsRollup
+	for i := range rollups {
+		if rollups[i].resolution != resolution {
+			continue
+		}
+		r = rollups[i]
+		if i > 0 {
+			downsampled, err := rollupTimestamp(ctx, tx, r.source)
+			if err != nil {
+				return err
+			} else if downsampled.Before(until) {
+				until = downsampled
+			}
+		}
+		break
+	}
+	if r.table == "" {
+		return fmt.Errorf("metric '%s' has no rollup with resolution %v", metric, resolution)
+	}
+
+	from, err := rollupTimestamp(ctx, tx, r.table)
+	if err != nil {
+		return err
+	}
+	to := time.UnixMilli(until.UnixMilli() - until.UnixMilli()%r.resolution.Milliseconds())
+	if !to.After(from) {
+		return nil
+	}
+
+	// insert the first data point of every series in every bucket, ordered
+	// by id to preserve the order in which they were recorded
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		SELECT %s FROM %s
+		WHERE id IN (
+			SELECT MIN(id) FROM %s
+			WHERE timestamp >= ? AND timestamp < ?
+			GROUP BY timestamp - timestamp %% ?, %s
+		)
+		ORDER BY id ASC
+	`, r.table, r.columns, r.columns, r.source, r.source, r.series),
+		UnixTimeMS(from),
+		UnixTimeMS(to),
+		r.resolution.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to downsample %s into %s: %w", r.source, r.table, err)
+	} else if _, err := tx.Exec(ctx, "UPDATE rollups SET timestamp = ? WHERE name = ?", UnixTimeMS(to), r.table); err != nil {
+		return fmt.Errorf("failed to update rollup %s: %w", r.table, err)
+	}
+	return nil
+}
+
+// PruneMetrics removes the data points of the given metric that are older
+// than the cutoff. A resolution of 0 refers to the raw data points, otherwise
+// the rollup with the given resolution is pruned. Data points that weren't
+// downsampled into the next coarser rollup yet are never removed.
+func PruneMetrics(ctx context.Context, tx sql.Tx, metric string, resolution time.Duration, cutoff time.Time) error {
+	table, err := metricsTable(metric)
+	if err != nil {
+		return err
+	}
+	rollups := metricsRollups(table)
+
+	// find the table to prune and the rollup it's downsampled into
+	var next string
+	if resolution == 0 && len(rollups) > 0 {
+		next = rollups[0].table
+	} else if resolution != 0 {
+		found := false
+		for i, r := range rollups {
+			if r.resolution == resolution {
+				table, found = r.table, true
+				if i+1 < len(rollups) {
+					next = rollups[i+1].table
+				}
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("metric '%s' has no rollup with resolution %v", metric, resolution)
+		}
+	}
+	if next != "" {
+		downsampled, err := rollupTimestamp(ctx, tx, next)
+		if err != nil {
+			return err
+		} else if downsampled.Before(cutoff) {
+			cutoff = downsampled
+		}
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE timestamp < ?", table), UnixTimeMS(cutoff))
+	if err != nil {
+		return fmt.Errorf("failed to prune %s: %w", table, err)
+	}
+	return nil
+}
+
+func metricsTable(metric string) (string, error) {
+	switch metric {
+	case api.MetricContract:
+		return "contracts", nil
+	case api.MetricContractPrune:
+		return "contract_prunes", nil
+	case api.MetricContractSet:
+		return "contract_sets", nil
+	case api.MetricContractSetChurn:
+		return "contract_sets_churn", nil
+	case api.MetricPerformance:
+		return "performance", nil
+	case api.MetricWallet:
+		return "wallets", nil
+	default:
+		return "", fmt.Errorf("unknown metric '%s'", metric)
+	}
+}
+
+// metricsRollups returns the rollups of the given table ordered from the
+// finest to the coarsest resolution, every rollup is downsampled from the
+// previous one.
+func metricsRollups(table string) []metricsRollup {
+	var columns, series string
+	switch table {
+	case "contracts":
+		columns, series = contractMetricColumns, "fcid"
+	case "performance":
+		columns, series = performanceMetricColumns, "action, host, origin"
+	default:
+		return nil
+	}
+	return []metricsRollup{
+		{table: table + "_hourly", source: table, columns: columns, series: series, resolution: MetricsResolutionHour},
+		{table: table + "_daily", source: table + "_hourly", columns: columns, series: series, resolution: MetricsResolutionDay},
+	}
+}
+
+// rollupTimestamp returns the time up to which the given rollup contains all
+// data points.
+func rollupTimestamp(ctx context.Context, tx sql.Tx, rollup string) (time.Time, error) {
+	var ts UnixTimeMS
+	if err := tx.QueryRow(ctx, "SELECT timestamp FROM rollups WHERE name = ?", rollup).Scan(&ts); err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch timestamp of rollup %s: %w", rollup, err)
+	}
+	return time.Time(ts), nil
+}
+
+// firstTimestamp returns the timestamp of the oldest data point in the given
+// table. Since tables are pruned by timestamp, periods before it were either
+// pruned or never had any data points. If the table is empty, the zero time
+// and false are returned.
+func firstTimestamp(ctx context.Context, tx sql.Tx, table string) (time.Time, bool, error) {
+	var ts dsql.NullInt64
+	if err := tx.QueryRow(ctx, fmt.Sprintf("SELECT MIN(timestamp) FROM %s", table)).Scan(&ts); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to fetch first timestamp of %s: %w", table, err)
+	} else if !ts.Valid {
+		return time.Time{}, false, nil
+	}
+	return time.UnixMilli(ts.Int64), true, nil
+}
+
+// queryRollups queries the n periods starting at 'start' from the coarsest
+// rollup of the table whose resolution doesn't exceed the interval. Periods
+// that weren't downsampled into that rollup yet are queried from the finer
+// rollups or the raw data points, periods that were already pruned from it
+// are queried from the coarser rollups. The rollups contain the first data
+// point of every bucket, so periods that don't align with the buckets are
+// served by the data points of the buckets that start within them.
+func queryRollups[T any](ctx context.Context, tx sql.Tx, table string, start time.Time, n uint64, interval time.Duration, queryFn func(table string, start time.Time, n uint64) ([]T, error)) ([]T, error) {
+	if n > api.MetricMaxIntervals {
+		return nil, api.ErrMaxIntervalsExceeded
+	} else if n == 0 || interval <= 0 {
+		return queryFn(table, start, n)
+	}
+
+	// collect the tables ordered from the finest to the coarsest resolution,
+	// starting with the raw data points which are always complete
+	type source struct {
+		table       string
+		resolution  time.Duration
+		downsampled time.Time
+		complete    bool
+		first       time.Time
+		empty       bool
+	}
+	sources := []source{{table: table, complete: true}}
+	for _, r := range metricsRollups(table) {
+		downsampled, err := rollupTimestamp(ctx, tx, r.table)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source{table: r.table, resolution: r.resolution, downsampled: downsampled})
+	}
+	preferred := 0
+	for i := range sources {
+		if sources[i].resolution <= interval {
+			preferred = i
+		}
+	}
+	for i := range sources {
+		first, ok, err := firstTimestamp(ctx, tx, sources[i].table)
+		if err != nil {
+			return nil, err
+		}
+		sources[i].first, sources[i].empty = first, !ok
+	}
+
+	// pick the table for every period, periods are queried in batches of
+	// consecutive periods that are served by the same table
+	var result []T
+	var batchStart time.Time
+	var batchSize uint64
+	batchTable := -1
+	flush := func() error {
+		if batchSize == 0 {
+			return nil
+		}
+		metrics, err := queryFn(sources[batchTable].table, batchStart, batchSize)
+		if err != nil {
+			return err
+		}
+		result = append(result, metrics...)
+		return nil
+	}
+	for i := uint64(0); i < n; i++ {
+		periodStart := start.Add(time.Duration(i) * interval)
+		periodEnd := periodStart.Add(interval)
+
+		// use a finer table if the period wasn't fully downsampled yet
+		idx := preferred
+		for idx > 0 && !sources[idx].complete && periodEnd.After(sources[idx].downsampled) {
+			idx--
+		}
+		// use a coarser table if the period was pruned already
+		for idx < len(sources)-1 && (sources[idx].empty || periodStart.Before(sources[idx].first)) {
+			idx++
+		}
+
+		if idx != batchTable {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			batchTable, batchStart, batchSize = idx, periodStart, 0
+		}
+		batchSize++
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func queryPeriods[T any](ctx context.Context, tx sql.Tx, table string, start time.Time, n uint64, interval time.Duration, opts interface{}, scanRowFn func(*sql.LoggedRows) (T, error)) ([]T, error) {
 	if n > api.MetricMaxIntervals {
 		return nil, api.ErrMaxIntervalsExceeded
 	}
@@ -427,7 +723,7 @@ func queryPeriods[T any](ctx context.Context, tx sql.Tx, start time.Time, n uint
 		GROUP BY
 			p.period_start
 		) i ON %s.id = i.id ORDER BY Period ASC
-	`, where.table, where.table, where.table, where.query, where.table), params...)
+	`, table, table, table, where.query, table), params...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query periods: %w", err)
 	}
@@ -444,7 +740,7 @@ func queryPeriods[T any](ctx context.Context, tx sql.Tx, start time.Time, n uint
 	return result, nil
 }
 
-func queryAggregatedPeriods(ctx context.Context, tx sql.Tx, start time.Time, n uint64, interval time.Duration, indexHint string, scanRowFn func(int64 *sql.LoggedRows) (api.ContractMetric, error)) ([]api.ContractMetric, error) {
+func queryAggregatedPeriods(ctx context.Context, tx sql.Tx, table string, start time.Time, n uint64, interval time.Duration, indexHint string, scanRowFn func(int64 *sql.LoggedRows) (api.ContractMetric, error)) ([]api.ContractMetric, error) {
 	if n > api.MetricMaxIntervals {
 		return nil, api.ErrMaxIntervalsExceeded
 	}
@@ -452,7 +748,7 @@ func queryAggregatedPeriods(ctx context.Context, tx sql.Tx, start time.Time, n u
 
 	// fetch distinct contract ids
 	rows, err := tx.Query(ctx,
-		"SELECT DISTINCT fcid FROM contracts WHERE contracts.timestamp >= ? AND contracts.timestamp < ?",
+		fmt.Sprintf("SELECT DISTINCT fcid FROM %s WHERE %s.timestamp >= ? AND %s.timestamp < ?", table, table, table),
 		UnixTimeMS(start),
 		UnixTimeMS(end),
 	)
@@ -471,7 +767,7 @@ func queryAggregatedPeriods(ctx context.Context, tx sql.Tx, start time.Time, n u
 	}
 
 	// prepare statement to fetch contract metrics
-	queryStmt, err := tx.Prepare(ctx, fmt.Sprintf("SELECT * FROM contracts %s WHERE contracts.timestamp >= ? AND contracts.timestamp < ? AND contracts.fcid = ? LIMIT 1", indexHint))
+	queryStmt, err := tx.Prepare(ctx, fmt.Sprintf("SELECT * FROM %s %s WHERE %s.timestamp >= ? AND %s.timestamp < ? AND %s.fcid = ? LIMIT 1", table, indexHint, table, table, table))
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare statement to fetch contract metrics: %w", err)
 	}
@@ -509,7 +805,6 @@ func queryAggregatedPeriods(ctx context.Context, tx sql.Tx, start time.Time, n u
 }
 
 type whereClause struct {
-	table  string
 	query  string
 	params []interface{}
 }
@@ -519,7 +814,6 @@ func whereClauseFromQueryOpts(opts interface{}) (where whereClause, _ error) {
 
 	switch opts := opts.(type) {
 	case api.ContractMetricsQueryOpts:
-		where.table = "contracts"
 		if opts.ContractID != (types.FileContractID{}) {
 			where.query += " AND fcid = ?"
 			where.params = append(where.params, FileContractID(opts.ContractID))
@@ -529,7 +823,6 @@ func whereClauseFromQueryOpts(opts interface{}) (where whereClause, _ error) {
 			where.params = append(where.params, PublicKey(opts.HostKey))
 		}
 	case api.ContractPruneMetricsQueryOpts:
-		where.table = "contract_prunes"
 		if opts.ContractID != (types.FileContractID{}) {
 			where.query += " AND fcid = ?"
 			where.params = append(where.params, FileContractID(opts.ContractID))
@@ -543,7 +836,6 @@ func whereClauseFromQueryOpts(opts interface{}) (where whereClause, _ error) {
 			where.params = append(where.params, opts.HostVersion)
 		}
 	case api.ContractSetChurnMetricsQueryOpts:
-		where.table = "contract_sets_churn"
 		if opts.Name != "" {
 			where.query += " AND name = ?"
 			where.params = append(where.params, opts.Name)
@@ -557,13 +849,11 @@ func whereClauseFromQueryOpts(opts interface{}) (where whereClause, _ error) {
 			where.params = append(where.params, opts.Reason)
 		}
 	case api.ContractSetMetricsQueryOpts:
-		where.table = "contract_sets"
 		if opts.Name != "" {
 			where.query += " AND name = ?"
 			where.params = append(where.params, opts.Name)
 		}
 	case api.PerformanceMetricsQueryOpts:
-		where.table = "performance"
 		if opts.Action != "" {
 			where.query += " AND action = ?"
 			where.params = append(where.params, opts.Action)
@@ -577,7 +867,6 @@ func whereClauseFromQueryOpts(opts interface{}) (where whereClause, _ error) {
 			where.params = append(where.params, opts.Origin)
 		}
 	case api.WalletMetricsQueryOpts:
-		where.table = "wallets"
 	default:
 		return whereClause{}, fmt.Errorf("unknown query opts type: %T", opts)
 	}
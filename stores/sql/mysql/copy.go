@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"context"
+	dsql "database/sql"
+	"fmt"
+	"strings"
+
+	ssql "go.sia.tech/renterd/stores/sql"
+)
+
+// Dialect implements the backend specific queries required to copy data from
+// or to a MySQL database.
+type Dialect struct{}
+
+var _ ssql.Dialect = Dialect{}
+
+// DisableForeignKeys implements ssql.Dialect.
+func (Dialect) DisableForeignKeys(ctx context.Context, conn *dsql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0")
+	return err
+}
+
+// Placeholder implements ssql.Dialect.
+func (Dialect) Placeholder(int) string { return "?" }
+
+// PrimaryKey implements ssql.Dialect.
+func (Dialect) PrimaryKey(ctx context.Context, db *dsql.DB, table string) ([]string, error) {
+	return queryStrings(ctx, db, `
+		SELECT column_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = DATABASE() AND table_name = ? AND constraint_name = 'PRIMARY'
+		ORDER BY ordinal_position`, table)
+}
+
+// QuoteIdentifier implements ssql.Dialect.
+func (Dialect) QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// References implements ssql.Dialect.
+func (Dialect) References(ctx context.Context, db *dsql.DB, table string) ([]string, error) {
+	return queryStrings(ctx, db, `
+		SELECT DISTINCT referenced_table_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = DATABASE() AND table_name = ? AND referenced_table_name IS NOT NULL`, table)
+}
+
+// ResetSequence implements ssql.Dialect. InnoDB bumps the AUTO_INCREMENT
+// counter when a greater value is inserted explicitly.
+func (Dialect) ResetSequence(context.Context, *dsql.Conn, string, string) error {
+	return nil
+}
+
+// Tables implements ssql.Dialect.
+func (Dialect) Tables(ctx context.Context, db *dsql.DB) ([]string, error) {
+	return queryStrings(ctx, db, `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'`)
+}
+
+func queryStrings(ctx context.Context, db *dsql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
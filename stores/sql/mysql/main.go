@@ -48,6 +48,10 @@ func (b *MainDatabase) ApplyMigration(ctx context.Context, fn func(tx sql.Tx) (b
 	return applyMigration(ctx, b.db, fn)
 }
 
+func (b *MainDatabase) Backup(ctx context.Context, path string) error {
+	return backup(ctx, b.db, path)
+}
+
 func (b *MainDatabase) Close() error {
 	return b.db.Close()
 }
@@ -69,6 +73,12 @@ func (b *MainDatabase) Migrate(ctx context.Context) error {
 	return sql.PerformMigrations(ctx, b, migrationsFs, "main", sql.MainMigrations(ctx, b, migrationsFs, b.log))
 }
 
+func (b *MainDatabase) ReadOnlyTransaction(ctx context.Context, fn func(tx ssql.DatabaseTx) error) error {
+	return b.db.ReadOnlyTransaction(ctx, func(tx sql.Tx) error {
+		return fn(b.wrapTxn(tx))
+	})
+}
+
 func (b *MainDatabase) Transaction(ctx context.Context, fn func(tx ssql.DatabaseTx) error) error {
 	return b.db.Transaction(ctx, func(tx sql.Tx) error {
 		return fn(b.wrapTxn(tx))
@@ -168,6 +178,10 @@ func (tx *MainDatabaseTx) Bucket(ctx context.Context, bucket string) (api.Bucket
 	return ssql.Bucket(ctx, tx, bucket)
 }
 
+func (tx *MainDatabaseTx) BufferedSlabFilenames(ctx context.Context) ([]string, error) {
+	return ssql.BufferedSlabFilenames(ctx, tx)
+}
+
 func (tx *MainDatabaseTx) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []api.MultipartCompletedPart, opts api.CompleteMultipartOptions) (string, error) {
 	mpu, neededParts, size, eTag, err := ssql.MultipartUploadForCompletion(ctx, tx, bucket, key, uploadID, parts)
 	if err != nil {
@@ -235,6 +249,10 @@ func (tx *MainDatabaseTx) Contracts(ctx context.Context, opts api.ContractsOpts)
 	return ssql.Contracts(ctx, tx, opts)
 }
 
+func (tx *MainDatabaseTx) ContractHostKeys(ctx context.Context) (map[types.FileContractID]types.PublicKey, error) {
+	return ssql.ContractHostKeys(ctx, tx)
+}
+
 func (tx *MainDatabaseTx) ContractSize(ctx context.Context, id types.FileContractID) (api.ContractSize, error) {
 	return ssql.ContractSize(ctx, tx, id)
 }
@@ -264,6 +282,10 @@ func (tx *MainDatabaseTx) DeleteHostSector(ctx context.Context, hk types.PublicK
 	return ssql.DeleteHostSector(ctx, tx, hk, root)
 }
 
+func (tx *MainDatabaseTx) Fsck(ctx context.Context, check string, repair bool) (api.FsckIssue, error) {
+	return ssql.Fsck(ctx, tx, check, repair, tx.MakeDirsForPath)
+}
+
 func (tx *MainDatabaseTx) InsertBufferedSlab(ctx context.Context, fileName string, contractSetID int64, ec object.EncryptionKey, minShards, totalShards uint8) (int64, error) {
 	return ssql.InsertBufferedSlab(ctx, tx, fileName, contractSetID, ec, minShards, totalShards)
 }
@@ -42,6 +42,10 @@ func (b *MetricsDatabase) ApplyMigration(ctx context.Context, fn func(tx sql.Tx)
 	return applyMigration(ctx, b.db, fn)
 }
 
+func (b *MetricsDatabase) Backup(ctx context.Context, path string) error {
+	return backup(ctx, b.db, path)
+}
+
 func (b *MetricsDatabase) Close() error {
 	return b.db.Close()
 }
@@ -88,10 +92,18 @@ func (tx *MetricsDatabaseTx) ContractSetMetrics(ctx context.Context, start time.
 	return ssql.ContractSetMetrics(ctx, tx, start, n, interval, opts)
 }
 
+func (tx *MetricsDatabaseTx) DownsampleMetrics(ctx context.Context, metric string, resolution time.Duration, until time.Time) error {
+	return ssql.DownsampleMetrics(ctx, tx, metric, resolution, until)
+}
+
 func (tx *MetricsDatabaseTx) PerformanceMetrics(ctx context.Context, start time.Time, n uint64, interval time.Duration, opts api.PerformanceMetricsQueryOpts) ([]api.PerformanceMetric, error) {
 	return ssql.PerformanceMetrics(ctx, tx, start, n, interval, opts)
 }
 
+func (tx *MetricsDatabaseTx) PruneMetrics(ctx context.Context, metric string, resolution time.Duration, cutoff time.Time) error {
+	return ssql.PruneMetrics(ctx, tx, metric, resolution, cutoff)
+}
+
 func (tx *MetricsDatabaseTx) RecordContractMetric(ctx context.Context, metrics ...api.ContractMetric) error {
 	return ssql.RecordContractMetric(ctx, tx, metrics...)
 }
@@ -0,0 +1,96 @@
+-- dbContractMetric rollups
+CREATE TABLE `contracts_hourly` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `timestamp` bigint NOT NULL,
+  `fcid` varbinary(32) NOT NULL,
+  `host` varbinary(32) NOT NULL,
+  `remaining_collateral_lo` bigint NOT NULL,
+  `remaining_collateral_hi` bigint NOT NULL,
+  `remaining_funds_lo` bigint NOT NULL,
+  `remaining_funds_hi` bigint NOT NULL,
+  `revision_number` bigint NOT NULL,
+  `upload_spending_lo` bigint NOT NULL,
+  `upload_spending_hi` bigint NOT NULL,
+  `download_spending_lo` bigint NOT NULL,
+  `download_spending_hi` bigint NOT NULL,
+  `fund_account_spending_lo` bigint NOT NULL,
+  `fund_account_spending_hi` bigint NOT NULL,
+  `delete_spending_lo` bigint NOT NULL,
+  `delete_spending_hi` bigint NOT NULL,
+  `list_spending_lo` bigint NOT NULL,
+  `list_spending_hi` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_contracts_hourly_timestamp` (`timestamp`),
+  KEY `idx_contracts_hourly_host` (`host`),
+  KEY `idx_contracts_hourly_fcid_timestamp` (`fcid`,`timestamp`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `contracts_daily` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `timestamp` bigint NOT NULL,
+  `fcid` varbinary(32) NOT NULL,
+  `host` varbinary(32) NOT NULL,
+  `remaining_collateral_lo` bigint NOT NULL,
+  `remaining_collateral_hi` bigint NOT NULL,
+  `remaining_funds_lo` bigint NOT NULL,
+  `remaining_funds_hi` bigint NOT NULL,
+  `revision_number` bigint NOT NULL,
+  `upload_spending_lo` bigint NOT NULL,
+  `upload_spending_hi` bigint NOT NULL,
+  `download_spending_lo` bigint NOT NULL,
+  `download_spending_hi` bigint NOT NULL,
+  `fund_account_spending_lo` bigint NOT NULL,
+  `fund_account_spending_hi` bigint NOT NULL,
+  `delete_spending_lo` bigint NOT NULL,
+  `delete_spending_hi` bigint NOT NULL,
+  `list_spending_lo` bigint NOT NULL,
+  `list_spending_hi` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_contracts_daily_timestamp` (`timestamp`),
+  KEY `idx_contracts_daily_host` (`host`),
+  KEY `idx_contracts_daily_fcid_timestamp` (`fcid`,`timestamp`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- dbPerformanceMetric rollups
+CREATE TABLE `performance_hourly` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `timestamp` bigint NOT NULL,
+  `action` varchar(191) NOT NULL,
+  `host` varbinary(32) NOT NULL,
+  `origin` varchar(191) NOT NULL,
+  `duration` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_performance_hourly_timestamp` (`timestamp`),
+  KEY `idx_performance_hourly_action` (`action`),
+  KEY `idx_performance_hourly_host` (`host`),
+  KEY `idx_performance_hourly_origin` (`origin`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `performance_daily` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `timestamp` bigint NOT NULL,
+  `action` varchar(191) NOT NULL,
+  `host` varbinary(32) NOT NULL,
+  `origin` varchar(191) NOT NULL,
+  `duration` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_performance_daily_timestamp` (`timestamp`),
+  KEY `idx_performance_daily_action` (`action`),
+  KEY `idx_performance_daily_host` (`host`),
+  KEY `idx_performance_daily_origin` (`origin`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- dbMetricsRollup
+CREATE TABLE `rollups` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `name` varchar(191) NOT NULL,
+  `timestamp` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_rollups_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+INSERT INTO `rollups` (`created_at`, `name`, `timestamp`) VALUES (CURRENT_TIMESTAMP, 'contracts_hourly', 0), (CURRENT_TIMESTAMP, 'contracts_daily', 0), (CURRENT_TIMESTAMP, 'performance_hourly', 0), (CURRENT_TIMESTAMP, 'performance_daily', 0);
@@ -0,0 +1,66 @@
+package mysql
+
+import (
+	"context"
+	dsql "database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ReplicationLag returns how far the read replica behind the given connection
+// lags behind its source. A replica whose I/O thread isn't connected to its
+// source is considered unhealthy.
+func ReplicationLag(ctx context.Context, db *dsql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch replica status: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	} else if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errors.New("database is not a replica")
+	}
+	values := make([]dsql.RawBytes, len(columns))
+	dst := make([]interface{}, len(columns))
+	for i := range values {
+		dst[i] = &values[i]
+	}
+	if err := rows.Scan(dst...); err != nil {
+		return 0, fmt.Errorf("failed to scan replica status: %w", err)
+	}
+
+	// older versions of MySQL use 'Master' and 'Slave' in the column names,
+	// the replica is only considered up-to-date if it's connected to its
+	// source since the lag isn't updated otherwise
+	var lag dsql.RawBytes
+	var ioRunning string
+	var foundLag bool
+	for i, column := range columns {
+		switch column {
+		case "Replica_IO_Running", "Slave_IO_Running":
+			ioRunning = string(values[i])
+		case "Seconds_Behind_Source", "Seconds_Behind_Master":
+			lag, foundLag = values[i], true
+		}
+	}
+	if !foundLag {
+		return 0, errors.New("replica status is missing the replication lag")
+	} else if ioRunning != "Yes" {
+		return 0, fmt.Errorf("replica is not connected to its source, state: '%v'", ioRunning)
+	} else if lag == nil {
+		return 0, errors.New("replication is not running")
+	}
+	seconds, err := strconv.ParseUint(string(lag), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse replication lag: %w", err)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
@@ -5,6 +5,7 @@ import (
 	"embed"
 	"fmt"
 
+	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/internal/sql"
 )
 
@@ -40,3 +41,7 @@ func version(ctx context.Context, db *sql.DB) (string, string, error) {
 	}
 	return "PostgreSQL", version, nil
 }
+
+func backup(ctx context.Context, db *sql.DB, path string) error {
+	return fmt.Errorf("%w: use pg_dump to back up PostgreSQL databases", api.ErrBackupNotSupported)
+}
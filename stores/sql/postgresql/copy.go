@@ -0,0 +1,82 @@
+package postgresql
+
+import (
+	"context"
+	dsql "database/sql"
+	"fmt"
+	"strings"
+
+	ssql "go.sia.tech/renterd/stores/sql"
+)
+
+// Dialect implements the backend specific queries required to copy data from
+// or to a PostgreSQL database.
+type Dialect struct{}
+
+var _ ssql.Dialect = Dialect{}
+
+// DisableForeignKeys implements ssql.Dialect. Disabling foreign key checks
+// requires superuser privileges in PostgreSQL, instead we rely on tables being
+// copied in dependency order.
+func (Dialect) DisableForeignKeys(context.Context, *dsql.Conn) error {
+	return nil
+}
+
+// Placeholder implements ssql.Dialect.
+func (Dialect) Placeholder(i int) string { return fmt.Sprintf("$%d", i+1) }
+
+// PrimaryKey implements ssql.Dialect.
+func (Dialect) PrimaryKey(ctx context.Context, db *dsql.DB, table string) ([]string, error) {
+	return queryStrings(ctx, db, `
+		SELECT a.attname
+		FROM pg_index i
+		INNER JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = $1::regclass AND i.indisprimary
+		ORDER BY array_position(i.indkey::int2[], a.attnum)`, table)
+}
+
+// QuoteIdentifier implements ssql.Dialect.
+func (Dialect) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// References implements ssql.Dialect.
+func (Dialect) References(ctx context.Context, db *dsql.DB, table string) ([]string, error) {
+	return queryStrings(ctx, db, `
+		SELECT DISTINCT ccu.table_name
+		FROM information_schema.table_constraints tc
+		INNER JOIN information_schema.constraint_column_usage ccu ON tc.constraint_name = ccu.constraint_name AND tc.table_schema = ccu.table_schema
+		WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema() AND tc.table_name = $1`, table)
+}
+
+// ResetSequence implements ssql.Dialect. Sequences aren't updated when values
+// are inserted explicitly so they need to be moved past the copied values.
+func (d Dialect) ResetSequence(ctx context.Context, conn *dsql.Conn, table, column string) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(`
+		SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(%s), 0) + 1, false)
+		FROM %s`, d.QuoteIdentifier(column), d.QuoteIdentifier(table)), table, column)
+	return err
+}
+
+// Tables implements ssql.Dialect.
+func (Dialect) Tables(ctx context.Context, db *dsql.DB) ([]string, error) {
+	return queryStrings(ctx, db, "SELECT tablename FROM pg_tables WHERE schemaname = current_schema()")
+}
+
+func queryStrings(ctx context.Context, db *dsql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,90 @@
+-- dbContractMetric rollups
+CREATE TABLE contracts_hourly (
+  id SERIAL PRIMARY KEY,
+  created_at timestamp DEFAULT NULL,
+  timestamp int NOT NULL,
+  fcid int NOT NULL,
+  host int NOT NULL,
+  remaining_collateral_lo int NOT NULL,
+  remaining_collateral_hi int NOT NULL,
+  remaining_funds_lo int NOT NULL,
+  remaining_funds_hi int NOT NULL,
+  revision_number int NOT NULL,
+  upload_spending_lo int NOT NULL,
+  upload_spending_hi int NOT NULL,
+  download_spending_lo int NOT NULL,
+  download_spending_hi int NOT NULL,
+  fund_account_spending_lo int NOT NULL,
+  fund_account_spending_hi int NOT NULL,
+  delete_spending_lo int NOT NULL,
+  delete_spending_hi int NOT NULL,
+  list_spending_lo int NOT NULL,
+  list_spending_hi int NOT NULL
+);
+CREATE INDEX idx_contracts_hourly_timestamp ON contracts_hourly (timestamp);
+CREATE INDEX idx_contracts_hourly_host ON contracts_hourly (host);
+CREATE INDEX idx_contracts_hourly_fcid_timestamp ON contracts_hourly (fcid,timestamp);
+
+CREATE TABLE contracts_daily (
+  id SERIAL PRIMARY KEY,
+  created_at timestamp DEFAULT NULL,
+  timestamp int NOT NULL,
+  fcid int NOT NULL,
+  host int NOT NULL,
+  remaining_collateral_lo int NOT NULL,
+  remaining_collateral_hi int NOT NULL,
+  remaining_funds_lo int NOT NULL,
+  remaining_funds_hi int NOT NULL,
+  revision_number int NOT NULL,
+  upload_spending_lo int NOT NULL,
+  upload_spending_hi int NOT NULL,
+  download_spending_lo int NOT NULL,
+  download_spending_hi int NOT NULL,
+  fund_account_spending_lo int NOT NULL,
+  fund_account_spending_hi int NOT NULL,
+  delete_spending_lo int NOT NULL,
+  delete_spending_hi int NOT NULL,
+  list_spending_lo int NOT NULL,
+  list_spending_hi int NOT NULL
+);
+CREATE INDEX idx_contracts_daily_timestamp ON contracts_daily (timestamp);
+CREATE INDEX idx_contracts_daily_host ON contracts_daily (host);
+CREATE INDEX idx_contracts_daily_fcid_timestamp ON contracts_daily (fcid,timestamp);
+
+-- dbPerformanceMetric rollups
+CREATE TABLE performance_hourly (
+  id SERIAL PRIMARY KEY,
+  created_at timestamp DEFAULT NULL,
+  timestamp int NOT NULL,
+  action varchar(191) NOT NULL,
+  host int NOT NULL,
+  origin varchar(191) NOT NULL,
+  duration int NOT NULL
+);
+CREATE INDEX idx_performance_hourly_timestamp ON performance_hourly (timestamp);
+CREATE INDEX idx_performance_hourly_action ON performance_hourly (action);
+CREATE INDEX idx_performance_hourly_host ON performance_hourly (host);
+CREATE INDEX idx_performance_hourly_origin ON performance_hourly (origin);
+
+CREATE TABLE performance_daily (
+  id SERIAL PRIMARY KEY,
+  created_at timestamp DEFAULT NULL,
+  timestamp int NOT NULL,
+  action varchar(191) NOT NULL,
+  host int NOT NULL,
+  origin varchar(191) NOT NULL,
+  duration int NOT NULL
+);
+CREATE INDEX idx_performance_daily_timestamp ON performance_daily (timestamp);
+CREATE INDEX idx_performance_daily_action ON performance_daily (action);
+CREATE INDEX idx_performance_daily_host ON performance_daily (host);
+CREATE INDEX idx_performance_daily_origin ON performance_daily (origin);
+
+-- dbMetricsRollup
+CREATE TABLE rollups (
+  id SERIAL PRIMARY KEY,
+  created_at timestamp DEFAULT NULL,
+  name varchar(191) NOT NULL UNIQUE,
+  timestamp int NOT NULL
+);
+INSERT INTO rollups (created_at, name, timestamp) VALUES (CURRENT_TIMESTAMP, 'contracts_hourly', 0), (CURRENT_TIMESTAMP, 'contracts_daily', 0), (CURRENT_TIMESTAMP, 'performance_hourly', 0), (CURRENT_TIMESTAMP, 'performance_daily', 0);
@@ -0,0 +1,43 @@
+package postgresql
+
+import (
+	"context"
+	dsql "database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReplicationLag returns how far the read replica behind the given connection
+// lags behind its primary. A replica that is streaming from its primary and
+// replayed all of the WAL it received is considered up-to-date. Checking the
+// WAL receiver requires the user to have the privileges of the
+// pg_read_all_stats role.
+func ReplicationLag(ctx context.Context, db *dsql.DB) (time.Duration, error) {
+	var inRecovery bool
+	var receiverPID dsql.NullInt64
+	var receiverStatus dsql.NullString
+	var lag dsql.NullFloat64
+	if err := db.QueryRowContext(ctx, `
+		SELECT pg_is_in_recovery(),
+		(SELECT pid FROM pg_stat_wal_receiver),
+		(SELECT status FROM pg_stat_wal_receiver),
+		CASE
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+		END
+	`).Scan(&inRecovery, &receiverPID, &receiverStatus, &lag); err != nil {
+		return 0, fmt.Errorf("failed to fetch replication lag: %w", err)
+	} else if !inRecovery {
+		return 0, errors.New("database is not a replica")
+	} else if !receiverPID.Valid {
+		return 0, errors.New("replica is not connected to its primary")
+	} else if !receiverStatus.Valid {
+		return 0, errors.New("insufficient privileges to read the status of the WAL receiver, the user requires the pg_read_all_stats role")
+	} else if receiverStatus.String != "streaming" {
+		return 0, fmt.Errorf("replica is not streaming from its primary, status: %v", receiverStatus.String)
+	} else if !lag.Valid {
+		return 0, errors.New("replica hasn't replayed any transactions yet")
+	}
+	return time.Duration(lag.Float64 * float64(time.Second)), nil
+}
@@ -68,3 +68,13 @@ func version(ctx context.Context, db *sql.DB) (string, string, error) {
 	}
 	return "SQLite", version, nil
 }
+
+// backup uses VACUUM INTO to write a copy of the database to the given path.
+// Unlike copying the file it creates a consistent snapshot without stopping
+// writers and it includes the contents of the WAL.
+func backup(ctx context.Context, db *sql.DB, path string) error {
+	if _, err := db.Exec(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
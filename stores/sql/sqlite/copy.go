@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"context"
+	dsql "database/sql"
+	"fmt"
+	"strings"
+
+	ssql "go.sia.tech/renterd/stores/sql"
+)
+
+// Dialect implements the backend specific queries required to copy data from
+// or to a SQLite database.
+type Dialect struct{}
+
+var _ ssql.Dialect = Dialect{}
+
+// DisableForeignKeys implements ssql.Dialect.
+func (Dialect) DisableForeignKeys(ctx context.Context, conn *dsql.Conn) error {
+	_, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF")
+	return err
+}
+
+// Placeholder implements ssql.Dialect.
+func (Dialect) Placeholder(int) string { return "?" }
+
+// PrimaryKey implements ssql.Dialect.
+func (Dialect) PrimaryKey(ctx context.Context, db *dsql.DB, table string) ([]string, error) {
+	return queryStrings(ctx, db, "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", table)
+}
+
+// QuoteIdentifier implements ssql.Dialect.
+func (Dialect) QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// References implements ssql.Dialect.
+func (Dialect) References(ctx context.Context, db *dsql.DB, table string) ([]string, error) {
+	return queryStrings(ctx, db, `SELECT DISTINCT "table" FROM pragma_foreign_key_list(?)`, table)
+}
+
+// ResetSequence implements ssql.Dialect. SQLite keeps track of the greatest
+// inserted rowid by itself.
+func (Dialect) ResetSequence(context.Context, *dsql.Conn, string, string) error {
+	return nil
+}
+
+// Tables implements ssql.Dialect.
+func (Dialect) Tables(ctx context.Context, db *dsql.DB) ([]string, error) {
+	return queryStrings(ctx, db, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+}
+
+func queryStrings(ctx context.Context, db *dsql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
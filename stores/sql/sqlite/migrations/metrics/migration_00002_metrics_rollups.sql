@@ -0,0 +1,25 @@
+-- dbContractMetric rollups
+CREATE TABLE `contracts_hourly` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`timestamp` BIGINT NOT NULL,`fcid` blob NOT NULL,`host` blob NOT NULL,`remaining_collateral_lo` BIGINT NOT NULL,`remaining_collateral_hi` BIGINT NOT NULL,`remaining_funds_lo` BIGINT NOT NULL,`remaining_funds_hi` BIGINT NOT NULL,`revision_number` BIGINT NOT NULL,`upload_spending_lo` BIGINT NOT NULL,`upload_spending_hi` BIGINT NOT NULL,`download_spending_lo` BIGINT NOT NULL,`download_spending_hi` BIGINT NOT NULL,`fund_account_spending_lo` BIGINT NOT NULL,`fund_account_spending_hi` BIGINT NOT NULL,`delete_spending_lo` BIGINT NOT NULL,`delete_spending_hi` BIGINT NOT NULL,`list_spending_lo` BIGINT NOT NULL,`list_spending_hi` BIGINT NOT NULL);
+CREATE INDEX `idx_contracts_hourly_timestamp` ON `contracts_hourly`(`timestamp`);
+CREATE INDEX `idx_contracts_hourly_host` ON `contracts_hourly`(`host`);
+CREATE INDEX `idx_contracts_hourly_fcid_timestamp` ON `contracts_hourly`(`fcid`,`timestamp`);
+CREATE TABLE `contracts_daily` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`timestamp` BIGINT NOT NULL,`fcid` blob NOT NULL,`host` blob NOT NULL,`remaining_collateral_lo` BIGINT NOT NULL,`remaining_collateral_hi` BIGINT NOT NULL,`remaining_funds_lo` BIGINT NOT NULL,`remaining_funds_hi` BIGINT NOT NULL,`revision_number` BIGINT NOT NULL,`upload_spending_lo` BIGINT NOT NULL,`upload_spending_hi` BIGINT NOT NULL,`download_spending_lo` BIGINT NOT NULL,`download_spending_hi` BIGINT NOT NULL,`fund_account_spending_lo` BIGINT NOT NULL,`fund_account_spending_hi` BIGINT NOT NULL,`delete_spending_lo` BIGINT NOT NULL,`delete_spending_hi` BIGINT NOT NULL,`list_spending_lo` BIGINT NOT NULL,`list_spending_hi` BIGINT NOT NULL);
+CREATE INDEX `idx_contracts_daily_timestamp` ON `contracts_daily`(`timestamp`);
+CREATE INDEX `idx_contracts_daily_host` ON `contracts_daily`(`host`);
+CREATE INDEX `idx_contracts_daily_fcid_timestamp` ON `contracts_daily`(`fcid`,`timestamp`);
+
+-- dbPerformanceMetric rollups
+CREATE TABLE `performance_hourly` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`timestamp` BIGINT NOT NULL,`action` text NOT NULL,`host` blob NOT NULL,`origin` text NOT NULL,`duration` integer NOT NULL);
+CREATE INDEX `idx_performance_hourly_timestamp` ON `performance_hourly`(`timestamp`);
+CREATE INDEX `idx_performance_hourly_action` ON `performance_hourly`(`action`);
+CREATE INDEX `idx_performance_hourly_host` ON `performance_hourly`(`host`);
+CREATE INDEX `idx_performance_hourly_origin` ON `performance_hourly`(`origin`);
+CREATE TABLE `performance_daily` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`timestamp` BIGINT NOT NULL,`action` text NOT NULL,`host` blob NOT NULL,`origin` text NOT NULL,`duration` integer NOT NULL);
+CREATE INDEX `idx_performance_daily_timestamp` ON `performance_daily`(`timestamp`);
+CREATE INDEX `idx_performance_daily_action` ON `performance_daily`(`action`);
+CREATE INDEX `idx_performance_daily_host` ON `performance_daily`(`host`);
+CREATE INDEX `idx_performance_daily_origin` ON `performance_daily`(`origin`);
+
+-- dbMetricsRollup
+CREATE TABLE `rollups` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`name` text NOT NULL UNIQUE,`timestamp` BIGINT NOT NULL);
+INSERT INTO `rollups` (`created_at`, `name`, `timestamp`) VALUES (CURRENT_TIMESTAMP, 'contracts_hourly', 0), (CURRENT_TIMESTAMP, 'contracts_daily', 0), (CURRENT_TIMESTAMP, 'performance_hourly', 0), (CURRENT_TIMESTAMP, 'performance_daily', 0);
@@ -131,10 +131,14 @@ func (s *SQLStore) processConsensusChangeWallet(cc modules.ConsensusChange) {
 	for _, diff := range cc.SiacoinOutputDiffs {
 		var sco types.SiacoinOutput
 		convertToCore(diff.SiacoinOutput, (*types.V1SiacoinOutput)(&sco))
-		if sco.Address != s.walletAddress {
+		if !s.walletAddresses.OwnsAddress(sco.Address) {
 			continue
 		}
 		if diff.Direction == modules.DiffApply {
+			// mark the address as used, this derives more addresses if the
+			// wallet supports it
+			s.walletAddresses.MarkUsed(sco.Address)
+
 			// add new outputs
 			s.unappliedOutputChanges = append(s.unappliedOutputChanges, outputChange{
 				addition: true,
@@ -162,7 +166,7 @@ func (s *SQLStore) processConsensusChangeWallet(cc modules.ConsensusChange) {
 			// output has matured -- add a payout transaction.
 			if dsco.Direction != modules.DiffRevert {
 				continue
-			} else if types.Address(dsco.SiacoinOutput.UnlockHash) != s.walletAddress {
+			} else if !s.walletAddresses.OwnsAddress(types.Address(dsco.SiacoinOutput.UnlockHash)) {
 				continue
 			}
 			var sco types.SiacoinOutput
@@ -185,7 +189,7 @@ func (s *SQLStore) processConsensusChangeWallet(cc modules.ConsensusChange) {
 		for _, stxn := range block.Transactions {
 			var txn types.Transaction
 			convertToCore(stxn, &txn)
-			if transactionIsRelevant(txn, s.walletAddress) {
+			if transactionIsRelevant(txn, s.walletAddresses.OwnsAddress) {
 				// remove reverted txns
 				s.unappliedTxnChanges = append(s.unappliedTxnChanges, txnChange{
 					addition: false,
@@ -221,15 +225,15 @@ func (s *SQLStore) processConsensusChangeWallet(cc modules.ConsensusChange) {
 		for _, stxn := range block.Transactions {
 			var txn types.Transaction
 			convertToCore(stxn, &txn)
-			if transactionIsRelevant(txn, s.walletAddress) {
+			if transactionIsRelevant(txn, s.walletAddresses.OwnsAddress) {
 				var inflow, outflow types.Currency
 				for _, out := range txn.SiacoinOutputs {
-					if out.Address == s.walletAddress {
+					if s.walletAddresses.OwnsAddress(out.Address) {
 						inflow = inflow.Add(out.Value)
 					}
 				}
 				for _, in := range txn.SiacoinInputs {
-					if in.UnlockConditions.UnlockHash() == s.walletAddress {
+					if s.walletAddresses.OwnsAddress(in.UnlockConditions.UnlockHash()) {
 						so, ok := spentOutputs[in.ParentID]
 						if !ok {
 							panic("spent output not found")
@@ -257,50 +261,50 @@ func (s *SQLStore) processConsensusChangeWallet(cc modules.ConsensusChange) {
 	}
 }
 
-func transactionIsRelevant(txn types.Transaction, addr types.Address) bool {
+func transactionIsRelevant(txn types.Transaction, isWalletAddress func(types.Address) bool) bool {
 	for i := range txn.SiacoinInputs {
-		if txn.SiacoinInputs[i].UnlockConditions.UnlockHash() == addr {
+		if isWalletAddress(txn.SiacoinInputs[i].UnlockConditions.UnlockHash()) {
 			return true
 		}
 	}
 	for i := range txn.SiacoinOutputs {
-		if txn.SiacoinOutputs[i].Address == addr {
+		if isWalletAddress(txn.SiacoinOutputs[i].Address) {
 			return true
 		}
 	}
 	for i := range txn.SiafundInputs {
-		if txn.SiafundInputs[i].UnlockConditions.UnlockHash() == addr {
+		if isWalletAddress(txn.SiafundInputs[i].UnlockConditions.UnlockHash()) {
 			return true
 		}
-		if txn.SiafundInputs[i].ClaimAddress == addr {
+		if isWalletAddress(txn.SiafundInputs[i].ClaimAddress) {
 			return true
 		}
 	}
 	for i := range txn.SiafundOutputs {
-		if txn.SiafundOutputs[i].Address == addr {
+		if isWalletAddress(txn.SiafundOutputs[i].Address) {
 			return true
 		}
 	}
 	for i := range txn.FileContracts {
 		for _, sco := range txn.FileContracts[i].ValidProofOutputs {
-			if sco.Address == addr {
+			if isWalletAddress(sco.Address) {
 				return true
 			}
 		}
 		for _, sco := range txn.FileContracts[i].MissedProofOutputs {
-			if sco.Address == addr {
+			if isWalletAddress(sco.Address) {
 				return true
 			}
 		}
 	}
 	for i := range txn.FileContractRevisions {
 		for _, sco := range txn.FileContractRevisions[i].ValidProofOutputs {
-			if sco.Address == addr {
+			if isWalletAddress(sco.Address) {
 				return true
 			}
 		}
 		for _, sco := range txn.FileContractRevisions[i].MissedProofOutputs {
-			if sco.Address == addr {
+			if isWalletAddress(sco.Address) {
 				return true
 			}
 		}
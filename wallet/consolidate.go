@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"sort"
+	"time"
+
+	"gitlab.com/NebulousLabs/encoding"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/api"
+)
+
+// Consolidate returns a transaction that merges up to 'maxInputs' of the
+// smallest outputs with a value below 'maxValue' into a single output. Outputs
+// that aren't worth the fee it costs to spend them are left alone. If fewer
+// than two outputs qualify no transaction is returned.
+func (w *hotWallet) Consolidate(cs consensus.State, maxValue types.Currency, maxInputs int, feePerByte types.Currency, pool []types.Transaction) (types.Transaction, []types.Hash256, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// build map of inputs currently in the tx pool
+	inPool := make(map[types.Hash256]bool)
+	for _, ptxn := range pool {
+		for _, in := range ptxn.SiacoinInputs {
+			inPool[types.Hash256(in.ParentID)] = true
+		}
+	}
+
+	// fetch unspent transaction outputs
+	utxos, err := w.store.UnspentSiacoinElements(false)
+	if err != nil {
+		return types.Transaction{}, nil, err
+	}
+
+	// asc sort
+	sort.Slice(utxos, func(i, j int) bool {
+		return utxos[i].Value.Cmp(utxos[j].Value) < 0
+	})
+
+	// estimate the fees
+	outputs := []types.SiacoinOutput{{Value: types.MaxCurrency}}
+	outputFees := feePerByte.Mul64(uint64(len(encoding.Marshal(outputs))))
+	feePerInput := feePerByte.Mul64(BytesPerInput)
+
+	// collect the smallest outputs that are worth spending
+	var inputs []SiacoinElement
+	for _, sce := range utxos {
+		if len(inputs) >= maxInputs || sce.Value.Cmp(maxValue) >= 0 {
+			break
+		} else if w.isOutputUsed(sce.ID) || inPool[sce.ID] || cs.Index.Height < sce.MaturityHeight {
+			continue
+		} else if sce.Value.Cmp(feePerInput) <= 0 {
+			continue
+		}
+		inputs = append(inputs, sce)
+	}
+	fee := feePerInput.Mul64(uint64(len(inputs))).Add(outputFees)
+	if len(inputs) < 2 || SumOutputs(inputs).Cmp(fee) <= 0 {
+		return types.Transaction{}, nil, nil
+	}
+
+	// build the transaction
+	changeAddr, err := w.keys.ChangeAddress()
+	if err != nil {
+		return types.Transaction{}, nil, err
+	}
+	txn := types.Transaction{
+		SiacoinOutputs: []types.SiacoinOutput{{
+			Value:   SumOutputs(inputs).Sub(fee),
+			Address: changeAddr,
+		}},
+		MinerFees: []types.Currency{fee},
+	}
+	toSign := make([]types.Hash256, len(inputs))
+	for i, sce := range inputs {
+		uc, err := w.unlockConditions(sce.Address)
+		if err != nil {
+			return types.Transaction{}, nil, err
+		}
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
+			ParentID:         types.SiacoinOutputID(sce.ID),
+			UnlockConditions: uc,
+		})
+		toSign[i] = sce.ID
+	}
+	for _, sce := range inputs {
+		w.lastUsed[sce.ID] = time.Now()
+	}
+	return txn, toSign, nil
+}
+
+// Fragmentation returns statistics about the size of the given outputs. An
+// output is considered uneconomical if its value doesn't exceed the fee it
+// costs to spend it at the given fee per byte.
+func Fragmentation(utxos []SiacoinElement, feePerByte types.Currency) (f api.WalletFragmentation) {
+	f.Outputs = len(utxos)
+	if len(utxos) == 0 {
+		return
+	}
+
+	values := make([]types.Currency, len(utxos))
+	for i, sce := range utxos {
+		values[i] = sce.Value
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Cmp(values[j]) < 0
+	})
+	f.Smallest = values[0]
+	f.Largest = values[len(values)-1]
+	f.Median = values[len(values)/2]
+
+	feePerInput := feePerByte.Mul64(BytesPerInput)
+	for _, v := range values {
+		if v.Cmp(feePerInput) > 0 {
+			break
+		}
+		f.Uneconomical++
+		f.UneconomicalValue = f.UneconomicalValue.Add(v)
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.uber.org/zap"
+)
+
+func TestWalletConsolidate(t *testing.T) {
+	oneSC := types.Siacoins(1)
+	feePerByte := types.NewCurrency64(1)
+	dust := feePerByte.Mul64(BytesPerInput)
+
+	// create a wallet with a large output, a few small ones and one that isn't
+	// worth spending
+	priv := types.GeneratePrivateKey()
+	addr := StandardAddress(priv.PublicKey())
+	newOutput := func(v types.Currency) SiacoinElement {
+		return SiacoinElement{types.SiacoinOutput{Value: v, Address: addr}, randomOutputID(), 0}
+	}
+	s := &mockStore{utxos: []SiacoinElement{
+		newOutput(oneSC.Mul64(100)),
+		newOutput(oneSC),
+		newOutput(oneSC.Mul64(2)),
+		newOutput(oneSC.Mul64(3)),
+		newOutput(dust),
+	}}
+	w := NewSingleAddressWallet(priv, s, 0, zap.NewNop().Sugar())
+
+	// assert the fragmentation is reported
+	f := Fragmentation(s.utxos, feePerByte)
+	if f.Outputs != 5 || f.Uneconomical != 1 || !f.UneconomicalValue.Equals(dust) {
+		t.Fatal("unexpected fragmentation", f)
+	} else if !f.Smallest.Equals(dust) || !f.Median.Equals(oneSC.Mul64(2)) || !f.Largest.Equals(oneSC.Mul64(100)) {
+		t.Fatal("unexpected fragmentation", f)
+	}
+
+	// consolidate the two smallest outputs worth spending
+	txn, toSign, err := w.Consolidate(cs, oneSC.Mul64(10), 2, feePerByte, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(txn.SiacoinInputs) != 2 || len(toSign) != 2 {
+		t.Fatal("unexpected number of inputs", len(txn.SiacoinInputs))
+	} else if txn.SiacoinInputs[0].ParentID != types.SiacoinOutputID(s.utxos[1].ID) || txn.SiacoinInputs[1].ParentID != types.SiacoinOutputID(s.utxos[2].ID) {
+		t.Fatal("unexpected inputs")
+	} else if len(txn.SiacoinOutputs) != 1 || !txn.SiacoinOutputs[0].Value.Add(txn.MinerFees[0]).Equals(oneSC.Mul64(3)) {
+		t.Fatal("unexpected outputs", txn.SiacoinOutputs)
+	}
+
+	// the remaining small output can't be consolidated on its own
+	if txn, _, err := w.Consolidate(cs, oneSC.Mul64(10), 2, feePerByte, nil); err != nil {
+		t.Fatal(err)
+	} else if len(txn.SiacoinInputs) != 0 {
+		t.Fatal("unexpected consolidation", len(txn.SiacoinInputs))
+	}
+}
@@ -0,0 +1,390 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.sia.tech/core/types"
+	cwallet "go.sia.tech/coreutils/wallet"
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultGapLimit is the default number of consecutive unused addresses
+	// the HD wallet derives ahead of the last used address of each chain.
+	DefaultGapLimit = 20
+
+	// SettingHDWallet is the key of the setting the HD wallet uses to persist
+	// the number of addresses it handed out.
+	SettingHDWallet = "hdwallet"
+)
+
+const (
+	chainExternal = iota
+	chainChange
+	numChains
+)
+
+// An HDStore stores the state of an HD wallet.
+type HDStore interface {
+	SingleAddressStore
+	Setting(ctx context.Context, key string) (string, error)
+	UpdateSetting(ctx context.Context, key, value string) error
+}
+
+type (
+	// A Keychain derives the keys of an HD wallet from a seed. Addresses are
+	// split into an external chain, used to receive funds, and a change
+	// chain, used for the change outputs of the wallet's own transactions.
+	// Both chains are interleaved, external address i uses the key at index
+	// 2i and change address i uses the key at index 2i+1. That way the
+	// first external address matches the address of a single address wallet
+	// using the same seed and all addresses are found by wallets that scan
+	// sequential indices.
+	//
+	// The keychain derives up to 'gapLimit' addresses ahead of the last used
+	// or handed out address of each chain. Funds sent to addresses beyond the
+	// gap limit are not detected.
+	Keychain struct {
+		seed     [32]byte
+		gapLimit uint64
+
+		mu     sync.Mutex
+		keys   map[types.Address]derivedKey
+		addrs  [numChains][]types.Address
+		issued [numChains]uint64
+		used   [numChains]uint64
+		isUsed map[types.Address]bool
+	}
+
+	derivedKey struct {
+		priv  types.PrivateKey
+		chain int
+		index uint64
+	}
+
+	// keychainState is the state of the keychain that is persisted.
+	keychainState struct {
+		External uint64 `json:"external"`
+		Change   uint64 `json:"change"`
+	}
+)
+
+// NewKeychain returns a keychain that derives its keys from the given seed. A
+// gap limit of zero uses the DefaultGapLimit.
+func NewKeychain(seed [32]byte, gapLimit uint64) *Keychain {
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+	k := &Keychain{
+		seed:     seed,
+		gapLimit: gapLimit,
+		keys:     make(map[types.Address]derivedKey),
+		isUsed:   make(map[types.Address]bool),
+	}
+	k.issued[chainExternal] = 1 // the first address is always handed out
+	k.extendLocked()
+	return k
+}
+
+// Address returns the external address that was handed out last.
+func (k *Keychain) Address() types.Address {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.addrs[chainExternal][k.issued[chainExternal]-1]
+}
+
+// NextAddress hands out a new external address.
+func (k *Keychain) NextAddress() types.Address {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.nextLocked(chainExternal)
+}
+
+// ChangeAddress hands out a new change address.
+func (k *Keychain) ChangeAddress() types.Address {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.nextLocked(chainChange)
+}
+
+// PeekChangeAddress returns the change address that is handed out next
+// without handing it out.
+func (k *Keychain) PeekChangeAddress() types.Address {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	n := k.issued[chainChange]
+	if k.used[chainChange] > n {
+		n = k.used[chainChange]
+	}
+	return k.addrs[chainChange][n]
+}
+
+// OwnsAddress returns true if the given address was derived by the keychain.
+func (k *Keychain) OwnsAddress(addr types.Address) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	_, ok := k.keys[addr]
+	return ok
+}
+
+// MarkUsed marks the given address as used, if the address is close to the
+// end of the derived addresses, more addresses are derived to maintain the gap
+// limit.
+func (k *Keychain) MarkUsed(addr types.Address) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[addr]
+	if !ok {
+		return
+	}
+	k.isUsed[addr] = true
+	if key.index >= k.used[key.chain] {
+		k.used[key.chain] = key.index + 1
+		k.extendLocked()
+	}
+}
+
+// PublicKey returns the public key of the given address.
+func (k *Keychain) PublicKey(addr types.Address) (types.PublicKey, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[addr]
+	if !ok {
+		return types.PublicKey{}, false
+	}
+	return key.priv.PublicKey(), true
+}
+
+// PrivateKey returns the private key of the given address.
+func (k *Keychain) PrivateKey(addr types.Address) (types.PrivateKey, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[addr]
+	return key.priv, ok
+}
+
+// Addresses returns all addresses that were either handed out or used.
+func (k *Keychain) Addresses() []api.WalletAddress {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var addrs []api.WalletAddress
+	for chain := 0; chain < numChains; chain++ {
+		n := k.issued[chain]
+		if k.used[chain] > n {
+			n = k.used[chain]
+		}
+		for i := uint64(0); i < n; i++ {
+			addr := k.addrs[chain][i]
+			addrs = append(addrs, api.WalletAddress{
+				Address: addr,
+				Index:   i,
+				Change:  chain == chainChange,
+				Used:    k.isUsed[addr],
+			})
+		}
+	}
+	return addrs
+}
+
+func (k *Keychain) state() keychainState {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return keychainState{
+		External: k.issued[chainExternal],
+		Change:   k.issued[chainChange],
+	}
+}
+
+func (k *Keychain) setState(state keychainState) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if state.External > k.issued[chainExternal] {
+		k.issued[chainExternal] = state.External
+	}
+	if state.Change > k.issued[chainChange] {
+		k.issued[chainChange] = state.Change
+	}
+	k.extendLocked()
+}
+
+// nextLocked hands out the address that follows the last used or handed out
+// address of the given chain.
+func (k *Keychain) nextLocked(chain int) types.Address {
+	if k.used[chain] > k.issued[chain] {
+		k.issued[chain] = k.used[chain]
+	}
+	addr := k.addrs[chain][k.issued[chain]]
+	k.issued[chain]++
+	k.extendLocked()
+	return addr
+}
+
+// extendLocked derives addresses until every chain has 'gapLimit' addresses
+// beyond its last used or handed out address.
+func (k *Keychain) extendLocked() {
+	for chain := 0; chain < numChains; chain++ {
+		n := k.issued[chain]
+		if k.used[chain] > n {
+			n = k.used[chain]
+		}
+		for uint64(len(k.addrs[chain])) < n+k.gapLimit {
+			index := uint64(len(k.addrs[chain]))
+			priv := cwallet.KeyFromSeed(&k.seed, 2*index+uint64(chain))
+			addr := StandardAddress(priv.PublicKey())
+			k.keys[addr] = derivedKey{priv: priv, chain: chain, index: index}
+			k.addrs[chain] = append(k.addrs[chain], addr)
+		}
+	}
+}
+
+// An HDWallet is a hot wallet that manages the outputs controlled by the
+// addresses of a Keychain. Every transaction sends its change to a new change
+// address.
+type HDWallet struct {
+	*hotWallet
+	keychain *Keychain
+	store    HDStore
+}
+
+// hdKeys is the keyStore of an HD wallet, it persists the wallet's state
+// whenever a change address is handed out.
+type hdKeys struct {
+	*Keychain
+	w *HDWallet
+}
+
+// ChangeAddress hands out a new change address. The number of change
+// addresses handed out is persisted so change sent to them is detected after
+// a restart even if the transactions using them exceed the gap limit.
+func (k hdKeys) ChangeAddress() (types.Address, error) {
+	addr := k.Keychain.ChangeAddress()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := k.w.persist(ctx); err != nil {
+		return types.Address{}, fmt.Errorf("failed to persist wallet state: %w", err)
+	}
+	return addr, nil
+}
+
+// Address returns the external address that was handed out last.
+func (w *HDWallet) Address() types.Address {
+	return w.keychain.Address()
+}
+
+// NewAddress hands out a new external address. The number of addresses handed
+// out is persisted so funds sent to them are detected after a restart even if
+// they exceed the gap limit.
+func (w *HDWallet) NewAddress() (types.Address, error) {
+	addr := w.keychain.NextAddress()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := w.persist(ctx); err != nil {
+		return types.Address{}, fmt.Errorf("failed to persist wallet state: %w", err)
+	}
+	return addr, nil
+}
+
+// Addresses returns the addresses that were handed out or used along with
+// their balances.
+func (w *HDWallet) Addresses() ([]api.WalletAddress, error) {
+	confirmed, unconfirmed, err := w.addressBalances()
+	if err != nil {
+		return nil, err
+	}
+	addrs := w.keychain.Addresses()
+	for i := range addrs {
+		addrs[i].Confirmed = confirmed[addrs[i].Address]
+		addrs[i].Unconfirmed = unconfirmed[addrs[i].Address]
+	}
+	return addrs, nil
+}
+
+func (w *HDWallet) persist(ctx context.Context) error {
+	b, err := json.Marshal(w.keychain.state())
+	if err != nil {
+		return err
+	}
+	return w.store.UpdateSetting(ctx, SettingHDWallet, string(b))
+}
+
+// scan performs a gap limit scan over the wallet's transaction history and
+// its unspent outputs, every address that is found is marked as used which
+// derives more addresses. The scan is repeated until no new addresses are
+// found.
+func (w *HDWallet) scan() error {
+	txns, err := w.store.Transactions(time.Time{}, time.Time{}, 0, -1)
+	if err != nil {
+		return fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+	utxos, err := w.store.UnspentSiacoinElements(false)
+	if err != nil {
+		return fmt.Errorf("failed to fetch outputs: %w", err)
+	}
+
+	var addrs []types.Address
+	for _, txn := range txns {
+		for _, sci := range txn.Raw.SiacoinInputs {
+			addrs = append(addrs, sci.UnlockConditions.UnlockHash())
+		}
+		for _, sco := range txn.Raw.SiacoinOutputs {
+			addrs = append(addrs, sco.Address)
+		}
+	}
+	for _, sce := range utxos {
+		addrs = append(addrs, sce.Address)
+	}
+
+	found := make(map[types.Address]bool)
+	for {
+		var n int
+		for _, addr := range addrs {
+			if !found[addr] && w.keychain.OwnsAddress(addr) {
+				w.keychain.MarkUsed(addr)
+				found[addr] = true
+				n++
+			}
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return nil
+}
+
+// NewHDWallet returns a new HDWallet using the provided keychain and store. It
+// restores the number of handed out addresses and performs a gap limit scan
+// over the wallet's history. The keychain should be passed to the store
+// before it starts processing consensus changes so it knows which addresses
+// are relevant to the wallet.
+func NewHDWallet(keychain *Keychain, store HDStore, usedUTXOExpiry time.Duration, log *zap.SugaredLogger) (*HDWallet, error) {
+	w := &HDWallet{
+		keychain: keychain,
+		store:    store,
+	}
+	w.hotWallet = newHotWallet(hdKeys{Keychain: keychain, w: w}, store, usedUTXOExpiry, log)
+
+	// restore the state
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if value, err := store.Setting(ctx, SettingHDWallet); err != nil && !errors.Is(err, api.ErrSettingNotFound) {
+		return nil, fmt.Errorf("failed to fetch wallet state: %w", err)
+	} else if err == nil {
+		var state keychainState
+		if err := json.Unmarshal([]byte(value), &state); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal wallet state: %w", err)
+		}
+		keychain.setState(state)
+	}
+
+	// perform the gap limit scan
+	if err := w.scan(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
@@ -0,0 +1,244 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/chain"
+	cwallet "go.sia.tech/coreutils/wallet"
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+)
+
+type mockHDStore struct {
+	mockStore
+	settings map[string]string
+}
+
+func (s *mockHDStore) Setting(ctx context.Context, key string) (string, error) {
+	value, ok := s.settings[key]
+	if !ok {
+		return "", fmt.Errorf("key '%s' err: %w", key, api.ErrSettingNotFound)
+	}
+	return value, nil
+}
+
+func (s *mockHDStore) UpdateSetting(ctx context.Context, key, value string) error {
+	s.settings[key] = value
+	return nil
+}
+
+func TestKeychain(t *testing.T) {
+	var seed [32]byte
+	seed[0] = 1
+	k := NewKeychain(seed, 5)
+
+	// the first external address should match the single address wallet
+	if k.Address() != StandardAddress(cwallet.KeyFromSeed(&seed, 0).PublicKey()) {
+		t.Fatal("unexpected address")
+	} else if len(k.Addresses()) != 1 {
+		t.Fatal("unexpected number of addresses", len(k.Addresses()))
+	}
+
+	// change addresses use the odd indices
+	change := k.ChangeAddress()
+	if change != StandardAddress(cwallet.KeyFromSeed(&seed, 1).PublicKey()) {
+		t.Fatal("unexpected change address")
+	} else if !k.OwnsAddress(change) {
+		t.Fatal("keychain should own change address")
+	}
+
+	// addresses within the gap limit are known, the ones beyond it aren't
+	beyond := StandardAddress(cwallet.KeyFromSeed(&seed, 2*6).PublicKey())
+	if !k.OwnsAddress(StandardAddress(cwallet.KeyFromSeed(&seed, 2*5).PublicKey())) {
+		t.Fatal("keychain should own address within the gap limit")
+	} else if k.OwnsAddress(beyond) {
+		t.Fatal("keychain shouldn't own address beyond the gap limit")
+	}
+
+	// marking the last address as used extends the chain
+	k.MarkUsed(StandardAddress(cwallet.KeyFromSeed(&seed, 2*5).PublicKey()))
+	if !k.OwnsAddress(beyond) {
+		t.Fatal("keychain should own address after extending the chain")
+	} else if _, ok := k.PrivateKey(beyond); !ok {
+		t.Fatal("missing private key")
+	}
+
+	// handing out addresses skips the used ones
+	if addr := k.NextAddress(); addr != beyond {
+		t.Fatal("unexpected address")
+	} else if k.Address() != addr {
+		t.Fatal("unexpected current address")
+	}
+}
+
+func TestHDWalletRestore(t *testing.T) {
+	var seed [32]byte
+	seed[0] = 2
+	addr := func(chain, index uint64) types.Address {
+		return StandardAddress(cwallet.KeyFromSeed(&seed, 2*index+chain).PublicKey())
+	}
+
+	// the store contains outputs sent to addresses that are only found by
+	// repeatedly extending the chains
+	store := &mockHDStore{settings: make(map[string]string)}
+	for _, a := range []types.Address{addr(chainExternal, 4), addr(chainExternal, 8), addr(chainChange, 3)} {
+		store.utxos = append(store.utxos, SiacoinElement{
+			ID:            types.Hash256(a),
+			SiacoinOutput: types.SiacoinOutput{Address: a, Value: types.Siacoins(1)},
+		})
+	}
+
+	w, err := NewHDWallet(NewKeychain(seed, 5), store, time.Hour, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs, err := w.Addresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var external, change int
+	for _, a := range addrs {
+		if a.Change {
+			change++
+		} else {
+			external++
+		}
+	}
+	if external != 9 || change != 4 {
+		t.Fatal("unexpected number of addresses", external, change)
+	}
+
+	// hand out a new address and assert it's restored
+	next, err := w.NewAddress()
+	if err != nil {
+		t.Fatal(err)
+	} else if next != addr(chainExternal, 9) {
+		t.Fatal("unexpected address")
+	}
+	store.utxos = nil
+	w, err = NewHDWallet(NewKeychain(seed, 5), store, time.Hour, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	} else if w.Address() != next {
+		t.Fatal("address wasn't restored")
+	}
+}
+
+func TestHDWalletFundAndSign(t *testing.T) {
+	var seed [32]byte
+	seed[0] = 3
+	addr := func(chain, index uint64) types.Address {
+		return StandardAddress(cwallet.KeyFromSeed(&seed, 2*index+chain).PublicKey())
+	}
+
+	// fund the wallet through several external and change addresses
+	store := &mockHDStore{settings: make(map[string]string)}
+	for _, a := range []types.Address{addr(chainExternal, 0), addr(chainExternal, 2), addr(chainChange, 1)} {
+		store.utxos = append(store.utxos, SiacoinElement{
+			ID:            types.Hash256(a),
+			SiacoinOutput: types.SiacoinOutput{Address: a, Value: types.Siacoins(1)},
+		})
+	}
+	w, err := NewHDWallet(NewKeychain(seed, 5), store, time.Hour, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := chain.Mainnet()
+	cs := consensus.State{Network: n, Index: cs.Index}
+
+	// fund a transaction that spends all outputs and sign it
+	txn := types.Transaction{
+		SiacoinOutputs: []types.SiacoinOutput{{Value: types.Siacoins(2).Add(types.Siacoins(1).Div64(2))}},
+	}
+	toSign, err := w.FundTransaction(cs, &txn, txn.SiacoinOutputs[0].Value, false)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(toSign) != 3 {
+		t.Fatal("unexpected number of inputs", len(toSign))
+	} else if err := w.SignTransaction(cs, &txn, toSign, types.CoveredFields{WholeTransaction: true}); err != nil {
+		t.Fatal(err)
+	} else if err := VerifySignatures(cs, txn, toSign); err != nil {
+		t.Fatal(err)
+	}
+
+	// the change is sent to the change address following the used one
+	if len(txn.SiacoinOutputs) != 2 {
+		t.Fatal("missing change output")
+	}
+	change := txn.SiacoinOutputs[1].Address
+	if change != addr(chainChange, 2) {
+		t.Fatal("unexpected change address")
+	}
+
+	// restart the wallet without any outputs, the change address should
+	// still be owned and its successor is handed out next
+	store.utxos = nil
+	w, err = NewHDWallet(NewKeychain(seed, 5), store, time.Hour, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	} else if !w.keychain.OwnsAddress(change) {
+		t.Fatal("change address wasn't restored")
+	} else if next := w.keychain.ChangeAddress(); next != addr(chainChange, 3) {
+		t.Fatal("change index wasn't restored")
+	}
+
+	// spend the change and assert it's signed with the change address' key
+	store.utxos = []SiacoinElement{{
+		ID:            types.Hash256(change),
+		SiacoinOutput: types.SiacoinOutput{Address: change, Value: txn.SiacoinOutputs[1].Value},
+	}}
+	txn = types.Transaction{SiacoinOutputs: []types.SiacoinOutput{{Value: types.Siacoins(1).Div64(4)}}}
+	toSign, err = w.FundTransaction(cs, &txn, txn.SiacoinOutputs[0].Value, false)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := w.SignTransaction(cs, &txn, toSign, types.CoveredFields{WholeTransaction: true}); err != nil {
+		t.Fatal(err)
+	} else if err := VerifySignatures(cs, txn, toSign); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHDWalletSendDryRun(t *testing.T) {
+	var seed [32]byte
+	seed[0] = 4
+	a := StandardAddress(cwallet.KeyFromSeed(&seed, 0).PublicKey())
+
+	store := &mockHDStore{settings: make(map[string]string)}
+	store.utxos = []SiacoinElement{{
+		ID:            types.Hash256(a),
+		SiacoinOutput: types.SiacoinOutput{Address: a, Value: types.Siacoins(10)},
+	}}
+	w, err := NewHDWallet(NewKeychain(seed, 5), store, time.Hour, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := fmt.Sprint(store.settings)
+
+	// a dry run doesn't hand out a change address
+	dryRun, _, err := w.Send(cs, types.VoidAddress, types.Siacoins(1), types.ZeroCurrency, SendOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(dryRun.SiacoinOutputs) != 2 {
+		t.Fatal("missing change output")
+	} else if fmt.Sprint(store.settings) != settings {
+		t.Fatal("dry run updated the wallet state")
+	}
+	w.ReleaseInputs(dryRun)
+
+	// the actual transaction sends the change to the same address
+	txn, _, err := w.Send(cs, types.VoidAddress, types.Siacoins(1), types.ZeroCurrency, SendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	} else if txn.SiacoinOutputs[1].Address != dryRun.SiacoinOutputs[1].Address {
+		t.Fatal("unexpected change address")
+	} else if fmt.Sprint(store.settings) == settings {
+		t.Fatal("wallet state wasn't updated")
+	} else if next := w.keychain.PeekChangeAddress(); next == txn.SiacoinOutputs[1].Address {
+		t.Fatal("change address wasn't handed out")
+	}
+}
@@ -0,0 +1,186 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gitlab.com/NebulousLabs/encoding"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+)
+
+var (
+	// ErrInvalidInput is returned when an explicitly selected input is not
+	// spendable by the wallet.
+	ErrInvalidInput = errors.New("invalid input")
+
+	// ErrAmountTooSmall is returned when the amount that is sent doesn't
+	// cover the miner fee that is subtracted from it.
+	ErrAmountTooSmall = errors.New("amount doesn't cover the miner fee")
+)
+
+// SendOptions are the options for sending siacoins.
+type SendOptions struct {
+	// Inputs are the outputs to spend, if empty the wallet selects the
+	// inputs itself. Explicitly selected inputs are all spent, even if a
+	// subset of them would suffice.
+	Inputs []types.Hash256
+
+	// SubtractFee subtracts the miner fee from the amount that is sent
+	// instead of adding it on top.
+	SubtractFee bool
+
+	// UseUnconfirmed allows the wallet to spend outputs created by
+	// unconfirmed transactions.
+	UseUnconfirmed bool
+
+	// DryRun indicates the transaction won't be broadcast, the change is
+	// sent to the next change address without handing it out.
+	DryRun bool
+}
+
+// Send builds a transaction that sends the given amount to the given address.
+// The miner fee is estimated from the size of the transaction using the given
+// fee per byte. The inputs of the transaction are locked, they have to be
+// released if the transaction is not broadcast.
+func (w *hotWallet) Send(cs consensus.State, addr types.Address, amount, feePerByte types.Currency, opts SendOptions) (types.Transaction, []types.Hash256, error) {
+	if amount.IsZero() {
+		return types.Transaction{}, nil, errors.New("amount has to be greater than zero")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// fetch all unspent siacoin elements
+	utxos, err := w.store.UnspentSiacoinElements(false)
+	if err != nil {
+		return types.Transaction{}, nil, err
+	}
+	confirmed := len(utxos)
+	if opts.UseUnconfirmed || len(opts.Inputs) > 0 {
+		for _, sce := range w.tpoolUtxos {
+			utxos = append(utxos, sce)
+		}
+	}
+
+	// estimate the size of the outputs, assuming there is a change output
+	outputs := []types.SiacoinOutput{{Address: addr, Value: types.MaxCurrency}, {Address: addr, Value: types.MaxCurrency}}
+	outputFees := feePerByte.Mul64(uint64(len(encoding.Marshal(outputs))))
+	feePerInput := feePerByte.Mul64(BytesPerInput)
+	fee := func(inputs int) types.Currency {
+		return feePerInput.Mul64(uint64(inputs)).Add(outputFees)
+	}
+	needed := func(inputs int) types.Currency {
+		if opts.SubtractFee {
+			return amount
+		}
+		return amount.Add(fee(inputs))
+	}
+
+	var selected []SiacoinElement
+	if len(opts.Inputs) > 0 {
+		selected, err = w.selectInputs(cs, utxos, opts.Inputs)
+		if err != nil {
+			return types.Transaction{}, nil, err
+		}
+	} else {
+		// desc sort, unconfirmed outputs are only used as a last resort
+		sort.Slice(utxos[:confirmed], func(i, j int) bool {
+			return utxos[i].Value.Cmp(utxos[j].Value) > 0
+		})
+		unconfirmed := utxos[confirmed:]
+		sort.Slice(unconfirmed, func(i, j int) bool {
+			return unconfirmed[i].Value.Cmp(unconfirmed[j].Value) > 0
+		})
+
+		// fund the transaction using the largest utxos first
+		for _, sce := range utxos {
+			if SumOutputs(selected).Cmp(needed(len(selected))) >= 0 {
+				break
+			} else if w.isOutputUsed(sce.ID) || cs.Index.Height < sce.MaturityHeight {
+				continue
+			}
+			selected = append(selected, sce)
+		}
+	}
+
+	// if the transaction can't be funded, return an error
+	inputSum := SumOutputs(selected)
+	if inputSum.Cmp(needed(len(selected))) < 0 {
+		return types.Transaction{}, nil, fmt.Errorf("%w: inputs %v < needed %v", ErrInsufficientBalance, inputSum, needed(len(selected)))
+	}
+
+	// compute the amount that is sent and the change
+	minerFee := fee(len(selected))
+	sent := amount
+	if opts.SubtractFee {
+		if amount.Cmp(minerFee) <= 0 {
+			return types.Transaction{}, nil, fmt.Errorf("%w: amount %v, fee %v", ErrAmountTooSmall, amount, minerFee)
+		}
+		sent = amount.Sub(minerFee)
+	}
+	change := inputSum.Sub(sent.Add(minerFee))
+
+	// build the transaction
+	txn := types.Transaction{
+		SiacoinOutputs: []types.SiacoinOutput{{Address: addr, Value: sent}},
+		MinerFees:      []types.Currency{minerFee},
+	}
+	if !change.IsZero() {
+		changeAddr := w.keys.PeekChangeAddress()
+		if !opts.DryRun {
+			changeAddr, err = w.keys.ChangeAddress()
+			if err != nil {
+				return types.Transaction{}, nil, err
+			}
+		}
+		txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{
+			Value:   change,
+			Address: changeAddr,
+		})
+	}
+	toSign := make([]types.Hash256, len(selected))
+	for i, sce := range selected {
+		uc, err := w.unlockConditions(sce.Address)
+		if err != nil {
+			return types.Transaction{}, nil, err
+		}
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
+			ParentID:         types.SiacoinOutputID(sce.ID),
+			UnlockConditions: uc,
+		})
+		toSign[i] = sce.ID
+	}
+	for _, sce := range selected {
+		w.lastUsed[sce.ID] = time.Now()
+	}
+	return txn, toSign, nil
+}
+
+// selectInputs returns the outputs with the given ids, an error is returned if
+// any of them is not spendable.
+func (w *hotWallet) selectInputs(cs consensus.State, utxos []SiacoinElement, ids []types.Hash256) ([]SiacoinElement, error) {
+	byID := make(map[types.Hash256]SiacoinElement)
+	for _, sce := range utxos {
+		byID[sce.ID] = sce
+	}
+
+	var selected []SiacoinElement
+	seen := make(map[types.Hash256]bool)
+	for _, id := range ids {
+		sce, ok := byID[id]
+		if seen[id] {
+			return nil, fmt.Errorf("%w: output %v is selected more than once", ErrInvalidInput, id)
+		} else if !ok {
+			return nil, fmt.Errorf("%w: output %v not found", ErrInvalidInput, id)
+		} else if w.isOutputUsed(id) {
+			return nil, fmt.Errorf("%w: output %v is in use", ErrInvalidInput, id)
+		} else if cs.Index.Height < sce.MaturityHeight {
+			return nil, fmt.Errorf("%w: output %v matures at height %v", ErrInvalidInput, id, sce.MaturityHeight)
+		}
+		seen[id] = true
+		selected = append(selected, sce)
+	}
+	return selected, nil
+}
@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.uber.org/zap"
+)
+
+func TestWalletSend(t *testing.T) {
+	oneSC := types.Siacoins(1)
+
+	// create a wallet with three outputs
+	priv := types.GeneratePrivateKey()
+	addr := StandardAddress(priv.PublicKey())
+	var utxos []SiacoinElement
+	for _, v := range []uint64{10, 5, 1} {
+		utxos = append(utxos, SiacoinElement{
+			types.SiacoinOutput{Value: oneSC.Mul64(v), Address: addr},
+			randomOutputID(),
+			0,
+		})
+	}
+	s := &mockStore{utxos: utxos}
+	w := NewSingleAddressWallet(priv, s, 0, zap.NewNop().Sugar())
+	dest := types.Address{1}
+	feePerByte := types.NewCurrency64(1)
+
+	// send 12SC, the wallet should use the two largest outputs and add the fee
+	// on top of the amount
+	txn, toSign, err := w.Send(cs, dest, oneSC.Mul64(12), feePerByte, SendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(txn.SiacoinInputs) != 2 || len(toSign) != 2 {
+		t.Fatal("unexpected number of inputs", len(txn.SiacoinInputs))
+	} else if txn.SiacoinOutputs[0].Address != dest || !txn.SiacoinOutputs[0].Value.Equals(oneSC.Mul64(12)) {
+		t.Fatal("unexpected output", txn.SiacoinOutputs[0])
+	} else if len(txn.MinerFees) != 1 || txn.MinerFees[0].IsZero() {
+		t.Fatal("unexpected miner fees", txn.MinerFees)
+	} else if change := txn.SiacoinOutputs[1].Value; !change.Add(txn.MinerFees[0]).Equals(oneSC.Mul64(3)) {
+		t.Fatal("unexpected change", change)
+	}
+
+	// the inputs are locked
+	if _, _, err := w.Send(cs, dest, oneSC.Mul64(2), feePerByte, SendOptions{Inputs: []types.Hash256{utxos[0].ID}}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatal("unexpected error", err)
+	}
+	w.ReleaseInputs(txn)
+
+	// spend the smallest output explicitly and subtract the fee
+	txn, _, err = w.Send(cs, dest, oneSC, feePerByte, SendOptions{Inputs: []types.Hash256{utxos[2].ID}, SubtractFee: true})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(txn.SiacoinInputs) != 1 || txn.SiacoinInputs[0].ParentID != types.SiacoinOutputID(utxos[2].ID) {
+		t.Fatal("unexpected inputs", txn.SiacoinInputs)
+	} else if len(txn.SiacoinOutputs) != 1 || !txn.SiacoinOutputs[0].Value.Add(txn.MinerFees[0]).Equals(oneSC) {
+		t.Fatal("unexpected outputs", txn.SiacoinOutputs)
+	}
+	w.ReleaseInputs(txn)
+
+	// the amount can't be sent using the selected input
+	if _, _, err := w.Send(cs, dest, oneSC.Mul64(2), feePerByte, SendOptions{Inputs: []types.Hash256{utxos[2].ID}}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatal("unexpected error", err)
+	}
+
+	// the fee exceeds the amount
+	if _, _, err := w.Send(cs, dest, types.NewCurrency64(1), feePerByte, SendOptions{SubtractFee: true}); !errors.Is(err, ErrAmountTooSmall) {
+		t.Fatal("unexpected error", err)
+	}
+
+	// the wallet can't afford the fee on top of its balance
+	if _, _, err := w.Send(cs, dest, oneSC.Mul64(16), feePerByte, SendOptions{}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatal("unexpected error", err)
+	}
+}
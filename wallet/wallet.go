@@ -41,6 +41,10 @@ const (
 // cover the requested amount.
 var ErrInsufficientBalance = errors.New("insufficient balance")
 
+// ErrWatchOnly is returned when a watch-only wallet is asked to sign a
+// transaction.
+var ErrWatchOnly = errors.New("wallet is watch-only")
+
 // StandardUnlockConditions returns the standard unlock conditions for a single
 // Ed25519 key.
 func StandardUnlockConditions(pk types.PublicKey) types.UnlockConditions {
@@ -137,14 +141,66 @@ type TransactionPool interface {
 	ContainsElement(id types.Hash256) bool
 }
 
+// An AddressTracker keeps track of the addresses controlled by a wallet. The
+// store uses it to decide which outputs and transactions are relevant to the
+// wallet and marks addresses as used when they receive or spend outputs.
+type AddressTracker interface {
+	OwnsAddress(addr types.Address) bool
+	MarkUsed(addr types.Address)
+}
+
+// keyStore provides the keys of the addresses a hot wallet controls.
+type keyStore interface {
+	AddressTracker
+	ChangeAddress() (types.Address, error)
+	PeekChangeAddress() types.Address
+	PublicKey(addr types.Address) (types.PublicKey, bool)
+	PrivateKey(addr types.Address) (types.PrivateKey, bool)
+}
+
+// singleAddressKeys is the keyStore of a single address wallet, the private
+// key is nil if the wallet is watch-only.
+type singleAddressKeys struct {
+	priv types.PrivateKey
+	pub  types.PublicKey
+	addr types.Address
+}
+
+// SingleAddressTracker returns an AddressTracker for a wallet that controls a
+// single address.
+func SingleAddressTracker(addr types.Address) AddressTracker {
+	return singleAddressKeys{addr: addr}
+}
+
+func (k singleAddressKeys) OwnsAddress(addr types.Address) bool   { return addr == k.addr }
+func (k singleAddressKeys) MarkUsed(types.Address)                {}
+func (k singleAddressKeys) ChangeAddress() (types.Address, error) { return k.addr, nil }
+func (k singleAddressKeys) PeekChangeAddress() types.Address      { return k.addr }
+func (k singleAddressKeys) PublicKey(addr types.Address) (types.PublicKey, bool) {
+	return k.pub, addr == k.addr
+}
+func (k singleAddressKeys) PrivateKey(addr types.Address) (types.PrivateKey, bool) {
+	return k.priv, addr == k.addr && k.priv != nil
+}
+
 // A SingleAddressWallet is a hot wallet that manages the outputs controlled by
-// a single address.
+// a single address. A watch-only SingleAddressWallet only knows the public key
+// of the address, its transactions have to be signed externally.
 type SingleAddressWallet struct {
+	*hotWallet
+	priv types.PrivateKey
+	addr types.Address
+}
+
+// hotWallet contains the logic shared by the wallets that sign transactions
+// in process, it manages the outputs controlled by the addresses of its key
+// store.
+type hotWallet struct {
 	log            *zap.SugaredLogger
-	priv           types.PrivateKey
-	addr           types.Address
+	keys           keyStore
 	store          SingleAddressStore
 	usedUTXOExpiry time.Duration
+	watchOnly      bool
 
 	// for building transactions
 	mu       sync.Mutex
@@ -160,7 +216,8 @@ type SingleAddressWallet struct {
 	tpoolSpent map[types.SiacoinOutputID]bool
 }
 
-// PrivateKey returns the private key of the wallet.
+// PrivateKey returns the private key of the wallet, nil is returned if the
+// wallet is watch-only.
 func (w *SingleAddressWallet) PrivateKey() types.PrivateKey {
 	return w.priv
 }
@@ -170,8 +227,28 @@ func (w *SingleAddressWallet) Address() types.Address {
 	return w.addr
 }
 
+// NewAddress returns the address of the wallet, a single address wallet can't
+// generate new addresses.
+func (w *SingleAddressWallet) NewAddress() (types.Address, error) {
+	return w.addr, nil
+}
+
+// Addresses returns the address of the wallet and its balance.
+func (w *SingleAddressWallet) Addresses() ([]api.WalletAddress, error) {
+	confirmed, unconfirmed, err := w.addressBalances()
+	if err != nil {
+		return nil, err
+	}
+	return []api.WalletAddress{{
+		Address:     w.addr,
+		Used:        !confirmed[w.addr].IsZero() || !unconfirmed[w.addr].IsZero(),
+		Confirmed:   confirmed[w.addr],
+		Unconfirmed: unconfirmed[w.addr],
+	}}, nil
+}
+
 // Balance returns the balance of the wallet.
-func (w *SingleAddressWallet) Balance() (spendable, confirmed, unconfirmed types.Currency, _ error) {
+func (w *hotWallet) Balance() (spendable, confirmed, unconfirmed types.Currency, _ error) {
 	sces, err := w.store.UnspentSiacoinElements(true)
 	if err != nil {
 		return types.Currency{}, types.Currency{}, types.Currency{}, err
@@ -192,13 +269,13 @@ func (w *SingleAddressWallet) Balance() (spendable, confirmed, unconfirmed types
 	return
 }
 
-func (w *SingleAddressWallet) Height() uint64 {
+func (w *hotWallet) Height() uint64 {
 	return w.store.Height()
 }
 
 // UnspentOutputs returns the set of unspent Siacoin outputs controlled by the
 // wallet.
-func (w *SingleAddressWallet) UnspentOutputs() ([]SiacoinElement, error) {
+func (w *hotWallet) UnspentOutputs() ([]SiacoinElement, error) {
 	sces, err := w.store.UnspentSiacoinElements(false)
 	if err != nil {
 		return nil, err
@@ -216,7 +293,7 @@ func (w *SingleAddressWallet) UnspentOutputs() ([]SiacoinElement, error) {
 
 // Transactions returns up to max transactions relevant to the wallet that have
 // a timestamp later than since.
-func (w *SingleAddressWallet) Transactions(before, since time.Time, offset, limit int) ([]Transaction, error) {
+func (w *hotWallet) Transactions(before, since time.Time, offset, limit int) ([]Transaction, error) {
 	return w.store.Transactions(before, since, offset, limit)
 }
 
@@ -224,7 +301,7 @@ func (w *SingleAddressWallet) Transactions(before, since time.Time, offset, limi
 // the provided transaction. A change output is also added, if necessary. The
 // inputs will not be available to future calls to FundTransaction unless
 // ReleaseInputs is called or enough time has passed.
-func (w *SingleAddressWallet) FundTransaction(cs consensus.State, txn *types.Transaction, amount types.Currency, useUnconfirmedTxns bool) ([]types.Hash256, error) {
+func (w *hotWallet) FundTransaction(cs consensus.State, txn *types.Transaction, amount types.Currency, useUnconfirmedTxns bool) ([]types.Hash256, error) {
 	if amount.IsZero() {
 		return nil, nil
 	}
@@ -301,21 +378,35 @@ func (w *SingleAddressWallet) FundTransaction(cs consensus.State, txn *types.Tra
 		}
 	}
 
+	// add the inputs
+	toSign := make([]types.Hash256, len(selected))
+	inputs := make([]types.SiacoinInput, len(selected))
+	for i, sce := range selected {
+		uc, err := w.unlockConditions(sce.Address)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = types.SiacoinInput{
+			ParentID:         types.SiacoinOutputID(sce.ID),
+			UnlockConditions: uc,
+		}
+		toSign[i] = types.Hash256(sce.ID)
+	}
+
 	// add a change output if necessary
 	if inputSum.Cmp(amount) > 0 {
+		changeAddr, err := w.keys.ChangeAddress()
+		if err != nil {
+			return nil, err
+		}
 		txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{
 			Value:   inputSum.Sub(amount),
-			Address: w.addr,
+			Address: changeAddr,
 		})
 	}
 
-	toSign := make([]types.Hash256, len(selected))
-	for i, sce := range selected {
-		txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
-			ParentID:         types.SiacoinOutputID(sce.ID),
-			UnlockConditions: types.StandardUnlockConditions(w.priv.PublicKey()),
-		})
-		toSign[i] = types.Hash256(sce.ID)
+	txn.SiacoinInputs = append(txn.SiacoinInputs, inputs...)
+	for _, sce := range selected {
 		w.lastUsed[sce.ID] = time.Now()
 	}
 
@@ -325,13 +416,13 @@ func (w *SingleAddressWallet) FundTransaction(cs consensus.State, txn *types.Tra
 // ReleaseInputs is a helper function that releases the inputs of txn for use in
 // other transactions. It should only be called on transactions that are invalid
 // or will never be broadcast.
-func (w *SingleAddressWallet) ReleaseInputs(txns ...types.Transaction) {
+func (w *hotWallet) ReleaseInputs(txns ...types.Transaction) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	w.releaseInputs(txns...)
 }
 
-func (w *SingleAddressWallet) releaseInputs(txns ...types.Transaction) {
+func (w *hotWallet) releaseInputs(txns ...types.Transaction) {
 	for _, txn := range txns {
 		for _, in := range txn.SiacoinInputs {
 			delete(w.lastUsed, types.Hash256(in.ParentID))
@@ -339,31 +430,99 @@ func (w *SingleAddressWallet) releaseInputs(txns ...types.Transaction) {
 	}
 }
 
+// WatchOnly returns true if the wallet can't sign transactions.
+func (w *hotWallet) WatchOnly() bool {
+	return w.watchOnly
+}
+
 // SignTransaction adds a signature to each of the specified inputs.
-func (w *SingleAddressWallet) SignTransaction(cs consensus.State, txn *types.Transaction, toSign []types.Hash256, cf types.CoveredFields) error {
+func (w *hotWallet) SignTransaction(cs consensus.State, txn *types.Transaction, toSign []types.Hash256, cf types.CoveredFields) error {
+	if w.watchOnly {
+		return ErrWatchOnly
+	}
+	for _, id := range toSign {
+		priv, ok := w.keys.PrivateKey(inputAddress(*txn, id))
+		if !ok {
+			return fmt.Errorf("no key found to sign input %v", id)
+		}
+		signInput(cs, txn, id, cf, priv)
+	}
+	return nil
+}
+
+// SignTransactionWithKey adds a signature to each of the specified inputs
+// using the given key. It's used to sign the transactions of a watch-only
+// wallet.
+func SignTransactionWithKey(cs consensus.State, txn *types.Transaction, toSign []types.Hash256, cf types.CoveredFields, priv types.PrivateKey) error {
+	addr := StandardAddress(priv.PublicKey())
+	for _, id := range toSign {
+		if inputAddress(*txn, id) != addr {
+			return fmt.Errorf("input %v is not controlled by key of address %v", id, addr)
+		}
+		signInput(cs, txn, id, cf, priv)
+	}
+	return nil
+}
+
+// VerifySignatures verifies that every specified input is signed by the key in
+// its standard unlock conditions.
+func VerifySignatures(cs consensus.State, txn types.Transaction, toSign []types.Hash256) error {
 	for _, id := range toSign {
-		ts := types.TransactionSignature{
-			ParentID:       id,
-			CoveredFields:  cf,
-			PublicKeyIndex: 0,
+		var uc types.UnlockConditions
+		for _, sci := range txn.SiacoinInputs {
+			if types.Hash256(sci.ParentID) == id {
+				uc = sci.UnlockConditions
+			}
 		}
-		var h types.Hash256
-		if cf.WholeTransaction {
-			h = cs.WholeSigHash(*txn, ts.ParentID, ts.PublicKeyIndex, ts.Timelock, cf.Signatures)
-		} else {
-			h = cs.PartialSigHash(*txn, cf)
+		if len(uc.PublicKeys) != 1 || len(uc.PublicKeys[0].Key) != len(types.PublicKey{}) {
+			return fmt.Errorf("input %v doesn't use standard unlock conditions", id)
+		}
+		pk := *(*types.PublicKey)(uc.PublicKeys[0].Key)
+
+		var found bool
+		for _, ts := range txn.Signatures {
+			if ts.ParentID != id || ts.PublicKeyIndex != 0 || len(ts.Signature) != len(types.Signature{}) {
+				continue
+			}
+			var h types.Hash256
+			if ts.CoveredFields.WholeTransaction {
+				h = cs.WholeSigHash(txn, ts.ParentID, ts.PublicKeyIndex, ts.Timelock, ts.CoveredFields.Signatures)
+			} else {
+				h = cs.PartialSigHash(txn, ts.CoveredFields)
+			}
+			if pk.VerifyHash(h, *(*types.Signature)(ts.Signature)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("input %v is missing a valid signature", id)
 		}
-		sig := w.priv.SignHash(h)
-		ts.Signature = sig[:]
-		txn.Signatures = append(txn.Signatures, ts)
 	}
 	return nil
 }
 
+func signInput(cs consensus.State, txn *types.Transaction, id types.Hash256, cf types.CoveredFields, priv types.PrivateKey) {
+	ts := types.TransactionSignature{
+		ParentID:       id,
+		CoveredFields:  cf,
+		PublicKeyIndex: 0,
+	}
+	var h types.Hash256
+	if cf.WholeTransaction {
+		h = cs.WholeSigHash(*txn, ts.ParentID, ts.PublicKeyIndex, ts.Timelock, cf.Signatures)
+	} else {
+		h = cs.PartialSigHash(*txn, cf)
+	}
+	sig := priv.SignHash(h)
+	ts.Signature = sig[:]
+	txn.Signatures = append(txn.Signatures, ts)
+}
+
 // Redistribute returns a transaction that redistributes money in the wallet by
 // selecting a minimal set of inputs to cover the creation of the requested
 // outputs. It also returns a list of output IDs that need to be signed.
-func (w *SingleAddressWallet) Redistribute(cs consensus.State, outputs int, amount, feePerByte types.Currency, pool []types.Transaction) ([]types.Transaction, []types.Hash256, error) {
+func (w *hotWallet) Redistribute(cs consensus.State, outputs int, amount, feePerByte types.Currency, pool []types.Transaction) ([]types.Transaction, []types.Hash256, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -407,9 +566,14 @@ func (w *SingleAddressWallet) Redistribute(cs consensus.State, outputs int, amou
 	for outputs > 0 {
 		var txn types.Transaction
 		for i := 0; i < outputs && i < redistributeBatchSize; i++ {
+			addr, err := w.keys.ChangeAddress()
+			if err != nil {
+				w.releaseInputs(txns...)
+				return nil, nil, err
+			}
 			txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{
 				Value:   amount,
-				Address: w.Address(),
+				Address: addr,
 			})
 		}
 		outputs -= len(txn.SiacoinOutputs)
@@ -459,17 +623,27 @@ func (w *SingleAddressWallet) Redistribute(cs consensus.State, outputs int, amou
 		// add the change output
 		change := SumOutputs(inputs).Sub(want.Add(fee))
 		if !change.IsZero() {
+			changeAddr, err := w.keys.ChangeAddress()
+			if err != nil {
+				w.releaseInputs(txns...)
+				return nil, nil, err
+			}
 			txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{
 				Value:   change,
-				Address: w.addr,
+				Address: changeAddr,
 			})
 		}
 
 		// add the inputs
 		for _, sce := range inputs {
+			uc, err := w.unlockConditions(sce.Address)
+			if err != nil {
+				w.releaseInputs(txns...)
+				return nil, nil, err
+			}
 			txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
 				ParentID:         types.SiacoinOutputID(sce.ID),
-				UnlockConditions: StandardUnlockConditions(w.priv.PublicKey()),
+				UnlockConditions: uc,
 			})
 			toSign = append(toSign, sce.ID)
 			w.lastUsed[sce.ID] = time.Now()
@@ -481,7 +655,39 @@ func (w *SingleAddressWallet) Redistribute(cs consensus.State, outputs int, amou
 	return txns, toSign, nil
 }
 
-func (w *SingleAddressWallet) isOutputUsed(id types.Hash256) bool {
+// unlockConditions returns the unlock conditions of an output sent to the
+// given address.
+func (w *hotWallet) unlockConditions(addr types.Address) (types.UnlockConditions, error) {
+	pk, ok := w.keys.PublicKey(addr)
+	if !ok {
+		return types.UnlockConditions{}, fmt.Errorf("no key found for address %v", addr)
+	}
+	return StandardUnlockConditions(pk), nil
+}
+
+// addressBalances returns the confirmed and unconfirmed balance of every
+// address that holds unspent outputs.
+func (w *hotWallet) addressBalances() (confirmed, unconfirmed map[types.Address]types.Currency, _ error) {
+	sces, err := w.store.UnspentSiacoinElements(false)
+	if err != nil {
+		return nil, nil, err
+	}
+	confirmed = make(map[types.Address]types.Currency)
+	unconfirmed = make(map[types.Address]types.Currency)
+	for _, sce := range sces {
+		confirmed[sce.Address] = confirmed[sce.Address].Add(sce.Value)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, sce := range w.tpoolUtxos {
+		if !w.isOutputUsed(sce.ID) {
+			unconfirmed[sce.Address] = unconfirmed[sce.Address].Add(sce.Value)
+		}
+	}
+	return
+}
+
+func (w *hotWallet) isOutputUsed(id types.Hash256) bool {
 	inPool := w.tpoolSpent[types.SiacoinOutputID(id)]
 	lastUsed := w.lastUsed[id]
 	if w.usedUTXOExpiry == 0 {
@@ -491,7 +697,7 @@ func (w *SingleAddressWallet) isOutputUsed(id types.Hash256) bool {
 }
 
 // ProcessConsensusChange implements modules.ConsensusSetSubscriber.
-func (w *SingleAddressWallet) ProcessConsensusChange(cc modules.ConsensusChange) {
+func (w *hotWallet) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// only record when we are synced
 	if !cc.Synced {
 		return
@@ -521,7 +727,7 @@ func (w *SingleAddressWallet) ProcessConsensusChange(cc modules.ConsensusChange)
 }
 
 // ReceiveUpdatedUnconfirmedTransactions implements modules.TransactionPoolSubscriber.
-func (w *SingleAddressWallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.TransactionPoolDiff) {
+func (w *hotWallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.TransactionPoolDiff) {
 	siacoinOutputs := make(map[types.SiacoinOutputID]SiacoinElement)
 	utxos, err := w.store.UnspentSiacoinElements(false)
 	if err != nil {
@@ -573,7 +779,7 @@ func (w *SingleAddressWallet) ReceiveUpdatedUnconfirmedTransactions(diff *module
 				Timestamp: time.Now(),
 			}
 			for _, sci := range txn.SiacoinInputs {
-				if sci.UnlockConditions.UnlockHash() != w.addr {
+				if !w.keys.OwnsAddress(sci.UnlockConditions.UnlockHash()) {
 					continue
 				}
 				relevant = true
@@ -590,9 +796,10 @@ func (w *SingleAddressWallet) ReceiveUpdatedUnconfirmedTransactions(diff *module
 			}
 
 			for i, sco := range txn.SiacoinOutputs {
-				if sco.Address != w.addr {
+				if !w.keys.OwnsAddress(sco.Address) {
 					continue
 				}
+				w.keys.MarkUsed(sco.Address)
 				relevant = true
 				outputID := txn.SiacoinOutputID(i)
 				processed.Inflow = processed.Inflow.Add(sco.Value)
@@ -623,11 +830,26 @@ func SumOutputs(outputs []SiacoinElement) (sum types.Currency) {
 	return
 }
 
-// NewSingleAddressWallet returns a new SingleAddressWallet using the provided private key and store.
-func NewSingleAddressWallet(priv types.PrivateKey, store SingleAddressStore, usedUTXOExpiry time.Duration, log *zap.SugaredLogger) *SingleAddressWallet {
-	return &SingleAddressWallet{
-		priv:           priv,
-		addr:           StandardAddress(priv.PublicKey()),
+// inputAddress returns the address of the siacoin or siafund input with the
+// given parent id, the void address is returned if the transaction has no such
+// input.
+func inputAddress(txn types.Transaction, id types.Hash256) types.Address {
+	for _, sci := range txn.SiacoinInputs {
+		if types.Hash256(sci.ParentID) == id {
+			return sci.UnlockConditions.UnlockHash()
+		}
+	}
+	for _, sfi := range txn.SiafundInputs {
+		if types.Hash256(sfi.ParentID) == id {
+			return sfi.UnlockConditions.UnlockHash()
+		}
+	}
+	return types.VoidAddress
+}
+
+func newHotWallet(keys keyStore, store SingleAddressStore, usedUTXOExpiry time.Duration, log *zap.SugaredLogger) *hotWallet {
+	return &hotWallet{
+		keys:           keys,
 		store:          store,
 		lastUsed:       make(map[types.Hash256]time.Time),
 		usedUTXOExpiry: usedUTXOExpiry,
@@ -638,6 +860,29 @@ func NewSingleAddressWallet(priv types.PrivateKey, store SingleAddressStore, use
 	}
 }
 
+// NewSingleAddressWallet returns a new SingleAddressWallet using the provided private key and store.
+func NewSingleAddressWallet(priv types.PrivateKey, store SingleAddressStore, usedUTXOExpiry time.Duration, log *zap.SugaredLogger) *SingleAddressWallet {
+	addr := StandardAddress(priv.PublicKey())
+	return &SingleAddressWallet{
+		hotWallet: newHotWallet(singleAddressKeys{priv: priv, pub: priv.PublicKey(), addr: addr}, store, usedUTXOExpiry, log),
+		priv:      priv,
+		addr:      addr,
+	}
+}
+
+// NewWatchOnlyWallet returns a new watch-only SingleAddressWallet for the
+// address of the given public key. The wallet funds transactions but can't
+// sign them.
+func NewWatchOnlyWallet(pk types.PublicKey, store SingleAddressStore, usedUTXOExpiry time.Duration, log *zap.SugaredLogger) *SingleAddressWallet {
+	addr := StandardAddress(pk)
+	w := &SingleAddressWallet{
+		hotWallet: newHotWallet(singleAddressKeys{pub: pk, addr: addr}, store, usedUTXOExpiry, log),
+		addr:      addr,
+	}
+	w.watchOnly = true
+	return w
+}
+
 // convertToCore converts a siad type to an equivalent core type.
 func convertToCore(siad encoding.SiaMarshaler, core types.DecoderFrom) {
 	var buf bytes.Buffer
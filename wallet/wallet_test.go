@@ -2,12 +2,14 @@ package wallet
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 	"time"
 
 	"go.sia.tech/core/consensus"
 	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/chain"
 	"go.sia.tech/renterd/api"
 	"go.uber.org/zap"
 	"lukechampine.com/frand"
@@ -193,3 +195,53 @@ func randomOutputID() (t types.Hash256) {
 	frand.Read(t[:])
 	return
 }
+
+func TestWatchOnlyWallet(t *testing.T) {
+	oneSC := types.Siacoins(1)
+
+	// create a watch-only wallet for the address of a key it doesn't know
+	priv := types.GeneratePrivateKey()
+	addr := StandardAddress(priv.PublicKey())
+	s := &mockStore{utxos: []SiacoinElement{
+		{types.SiacoinOutput{Value: oneSC.Mul64(10), Address: addr}, randomOutputID(), 0},
+	}}
+	w := NewWatchOnlyWallet(priv.PublicKey(), s, 0, zap.NewNop().Sugar())
+	n, _ := chain.Mainnet()
+	cs := consensus.State{Network: n, Index: cs.Index}
+	if !w.WatchOnly() {
+		t.Fatal("wallet should be watch-only")
+	} else if w.Address() != addr {
+		t.Fatal("unexpected address", w.Address())
+	}
+
+	// the wallet funds the transaction but can't sign it
+	txn := types.Transaction{SiacoinOutputs: []types.SiacoinOutput{{Value: oneSC, Address: types.Address{1}}}}
+	toSign, err := w.FundTransaction(cs, &txn, oneSC, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf := types.CoveredFields{WholeTransaction: true}
+	if err := w.SignTransaction(cs, &txn, toSign, cf); !errors.Is(err, ErrWatchOnly) {
+		t.Fatal("unexpected error", err)
+	} else if err := VerifySignatures(cs, txn, toSign); err == nil {
+		t.Fatal("expected unsigned transaction to fail verification")
+	}
+
+	// sign it with a key that doesn't control the inputs
+	if err := SignTransactionWithKey(cs, &txn, toSign, cf, types.GeneratePrivateKey()); err == nil {
+		t.Fatal("expected error when signing with the wrong key")
+	}
+
+	// sign it with the wallet's key
+	if err := SignTransactionWithKey(cs, &txn, toSign, cf, priv); err != nil {
+		t.Fatal(err)
+	} else if err := VerifySignatures(cs, txn, toSign); err != nil {
+		t.Fatal(err)
+	}
+
+	// tamper with the transaction
+	txn.SiacoinOutputs[0].Value = oneSC.Mul64(2)
+	if err := VerifySignatures(cs, txn, toSign); err == nil {
+		t.Fatal("expected tampered transaction to fail verification")
+	}
+}
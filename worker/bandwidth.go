@@ -0,0 +1,165 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/renterd/api"
+	"golang.org/x/time/rate"
+)
+
+const (
+	keyBandwidthLimiters contextKey = "BandwidthLimiters"
+
+	// bandwidthLimiterBurst is the burst size of a bandwidth limiter, it
+	// equals the sector size since that's the unit in which data is
+	// transferred to and from hosts.
+	bandwidthLimiterBurst = rhpv2.SectorSize
+)
+
+type (
+	// bandwidthLimiter limits the rate at which data is transferred, a limit
+	// of 0 means unlimited.
+	bandwidthLimiter struct {
+		mu      sync.Mutex
+		limit   uint64
+		limiter *rate.Limiter
+	}
+
+	// bandwidthLimiters contains the bandwidth limiters that can be adjusted
+	// at runtime through the worker API.
+	bandwidthLimiters struct {
+		download    *bandwidthLimiter
+		upload      *bandwidthLimiter
+		migration   *bandwidthLimiter
+		packedSlabs *bandwidthLimiter
+	}
+)
+
+func newBandwidthLimiter(bytesPerSecond uint64) *bandwidthLimiter {
+	bl := &bandwidthLimiter{
+		limiter: rate.NewLimiter(rate.Inf, bandwidthLimiterBurst),
+	}
+	bl.SetLimit(bytesPerSecond)
+	return bl
+}
+
+func newBandwidthLimiters(limits api.BandwidthLimits) *bandwidthLimiters {
+	return &bandwidthLimiters{
+		download:    newBandwidthLimiter(limits.Download),
+		upload:      newBandwidthLimiter(limits.Upload),
+		migration:   newBandwidthLimiter(limits.Migration),
+		packedSlabs: newBandwidthLimiter(limits.PackedSlabs),
+	}
+}
+
+// Limit returns the current limit in bytes per second.
+func (bl *bandwidthLimiter) Limit() uint64 {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	return bl.limit
+}
+
+// SetLimit updates the limit, a limit of 0 removes it.
+func (bl *bandwidthLimiter) SetLimit(bytesPerSecond uint64) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	bl.limit = bytesPerSecond
+	if bytesPerSecond == 0 {
+		bl.limiter.SetLimit(rate.Inf)
+	} else {
+		bl.limiter.SetLimit(rate.Limit(bytesPerSecond))
+	}
+}
+
+// WaitN blocks until n bytes may be transferred or the context is done.
+func (bl *bandwidthLimiter) WaitN(ctx context.Context, n int) error {
+	for n > 0 {
+		chunk := n
+		if chunk > bandwidthLimiterBurst {
+			chunk = bandwidthLimiterBurst
+		}
+		if err := bl.limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+func (bls *bandwidthLimiters) Limits() api.BandwidthLimits {
+	return api.BandwidthLimits{
+		Download:    bls.download.Limit(),
+		Upload:      bls.upload.Limit(),
+		Migration:   bls.migration.Limit(),
+		PackedSlabs: bls.packedSlabs.Limit(),
+	}
+}
+
+func (bls *bandwidthLimiters) SetLimits(limits api.BandwidthLimits) {
+	bls.download.SetLimit(limits.Download)
+	bls.upload.SetLimit(limits.Upload)
+	bls.migration.SetLimit(limits.Migration)
+	bls.packedSlabs.SetLimit(limits.PackedSlabs)
+}
+
+// withBandwidthLimiter attaches a bandwidth limiter to the context, transfers
+// performed with this context are subject to all attached limiters.
+func withBandwidthLimiter(ctx context.Context, bl *bandwidthLimiter) context.Context {
+	limiters, _ := ctx.Value(keyBandwidthLimiters).([]*bandwidthLimiter)
+	limiters = append(limiters[:len(limiters):len(limiters)], bl) // copy on append
+	return context.WithValue(ctx, keyBandwidthLimiters, limiters)
+}
+
+// waitForBandwidth blocks until n bytes may be transferred according to the
+// given limiter and all limiters attached to the context.
+func waitForBandwidth(ctx context.Context, bl *bandwidthLimiter, n int) error {
+	if bl != nil {
+		if err := bl.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	limiters, _ := ctx.Value(keyBandwidthLimiters).([]*bandwidthLimiter)
+	for _, l := range limiters {
+		if err := l.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bandwidthLimited returns true if the given limiter or any of the limiters
+// attached to the context currently impose a limit.
+func bandwidthLimited(ctx context.Context, bl *bandwidthLimiter) bool {
+	if bl != nil && bl.Limit() > 0 {
+		return true
+	}
+	limiters, _ := ctx.Value(keyBandwidthLimiters).([]*bandwidthLimiter)
+	for _, l := range limiters {
+		if l.Limit() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueWithBandwidth calls enqueue once n bytes may be transferred. If the
+// transfer is throttled, the wait happens in a separate goroutine so it
+// neither blocks the caller nor the host's queue, which is shared with other
+// requests, and it doesn't count towards the host's measured speed. If the
+// wait fails, fail is called instead of enqueue.
+func enqueueWithBandwidth(ctx context.Context, bl *bandwidthLimiter, n int, enqueue func(), fail func(error)) {
+	if !bandwidthLimited(ctx, bl) {
+		enqueue()
+		return
+	}
+	go func() {
+		if err := waitForBandwidth(ctx, bl, n); err != nil {
+			fail(err)
+			return
+		}
+		enqueue()
+	}()
+}
@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.sia.tech/renterd/api"
+)
+
+func TestBandwidthLimiters(t *testing.T) {
+	bls := newBandwidthLimiters(api.BandwidthLimits{Download: 100})
+
+	// assert the limits are reported
+	if limits := bls.Limits(); limits.Download != 100 || limits.Upload != 0 {
+		t.Fatal("unexpected limits", limits)
+	}
+
+	// unlimited limiters never block
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := waitForBandwidth(ctx, bls.upload, 10*bandwidthLimiterBurst); err != nil {
+		t.Fatal(err)
+	}
+
+	// attach a per-request limiter to the context, exhaust its burst and
+	// assert the next wait can't be satisfied before the context expires
+	reqCtx := withBandwidthLimiter(ctx, newBandwidthLimiter(1))
+	if err := waitForBandwidth(reqCtx, bls.upload, bandwidthLimiterBurst); err != nil {
+		t.Fatal(err)
+	} else if err := waitForBandwidth(reqCtx, bls.upload, 1); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// the limiter attached to the request doesn't affect other requests
+	if err := waitForBandwidth(ctx, bls.upload, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// remove the download limit and assert we can download
+	bls.SetLimits(api.BandwidthLimits{})
+	if err := waitForBandwidth(ctx, bls.download, 10*bandwidthLimiterBurst); err != nil {
+		t.Fatal(err)
+	}
+
+	// a cancelled context returns an error once the burst is exhausted
+	bls.SetLimits(api.BandwidthLimits{Download: 1})
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := waitForBandwidth(cancelledCtx, bls.download, 2*bandwidthLimiterBurst); !errors.Is(err, context.Canceled) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestBandwidthLimitsDontBlockHost(t *testing.T) {
+	w := newTestWorker(t)
+	h := w.AddHost()
+
+	// add a sector to the host
+	sector, root := newTestSector()
+	h.AddSector(root, sector)
+
+	// create a downloader for the host
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := newDownloader(ctx, h, w.bandwidth.download)
+	go d.processQueue()
+	defer d.Stop()
+
+	// prepare a request that is throttled by exhausting its limiter's burst
+	limiter := newBandwidthLimiter(1)
+	if err := limiter.WaitN(ctx, bandwidthLimiterBurst); err != nil {
+		t.Fatal(err)
+	}
+	newReq := func(ctx context.Context) *sectorDownloadReq {
+		return &sectorDownloadReq{
+			ctx:    ctx,
+			length: 64,
+			root:   root,
+			host:   d,
+			resps:  &sectorResponses{c: make(chan struct{}, 1)},
+		}
+	}
+	throttled := newReq(withBandwidthLimiter(ctx, limiter))
+	unthrottled := newReq(ctx)
+
+	// launch the throttled request before the unthrottled one
+	s := &slabDownload{}
+	s.launch(throttled)
+	s.launch(unthrottled)
+
+	// assert the unthrottled request isn't held up by the throttled one
+	select {
+	case <-unthrottled.resps.c:
+	case <-time.After(time.Second):
+		t.Fatal("unthrottled request was delayed")
+	}
+	if resp := unthrottled.resps.Next(); resp == nil || resp.err != nil {
+		t.Fatal("unexpected response", resp)
+	} else if resp := throttled.resps.Next(); resp != nil {
+		t.Fatal("throttled request wasn't throttled")
+	}
+
+	// the throttled request fails once its context is cancelled
+	cancel()
+	select {
+	case <-throttled.resps.c:
+	case <-time.After(time.Second):
+		t.Fatal("throttled request didn't fail")
+	}
+	if resp := throttled.resps.Next(); resp == nil || !errors.Is(resp.err, context.Canceled) {
+		t.Fatal("unexpected response", resp)
+	}
+}
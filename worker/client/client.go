@@ -72,12 +72,29 @@ func (c *Client) DownloadObject(ctx context.Context, w io.Writer, bucket, path s
 	return err
 }
 
+// BandwidthLimits returns the worker's bandwidth limits.
+func (c *Client) BandwidthLimits(ctx context.Context) (resp api.BandwidthLimits, err error) {
+	err = c.c.WithContext(ctx).GET("/bandwidth", &resp)
+	return
+}
+
 // DownloadStats returns download statistics.
 func (c *Client) DownloadStats() (resp api.DownloadStatsResponse, err error) {
 	err = c.c.GET("/stats/downloads", &resp)
 	return
 }
 
+// HostStats returns per-host upload and download statistics, optionally
+// filtered to the hosts in the given contract set.
+func (c *Client) HostStats(ctx context.Context, set string) (resp api.HostStatsResponse, err error) {
+	values := make(url.Values)
+	if set != "" {
+		values.Set("contractset", set)
+	}
+	err = c.c.WithContext(ctx).GET("/stats/hosts?"+values.Encode(), &resp)
+	return
+}
+
 // HeadObject returns the metadata of the object at the given path.
 func (c *Client) HeadObject(ctx context.Context, bucket, path string, opts api.HeadObjectOptions) (*api.HeadObjectResponse, error) {
 	c.c.Custom("HEAD", fmt.Sprintf("/objects/%s", path), nil, nil)
@@ -263,6 +280,12 @@ func (c *Client) UploadObject(ctx context.Context, r io.Reader, bucket, path str
 	return &api.UploadObjectResponse{ETag: resp.Header.Get("ETag")}, nil
 }
 
+// UpdateBandwidthLimits updates the worker's bandwidth limits.
+func (c *Client) UpdateBandwidthLimits(ctx context.Context, limits api.BandwidthLimits) (err error) {
+	err = c.c.WithContext(ctx).PUT("/bandwidth", limits)
+	return
+}
+
 // UploadStats returns the upload stats.
 func (c *Client) UploadStats() (resp api.UploadStatsResponse, err error) {
 	err = c.c.GET("/stats/uploads", &resp)
@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	"go.sia.tech/core/types"
+)
+
+const (
+	keyCostTracker contextKey = "CostTracker"
+)
+
+// costTracker sums up the amount paid to hosts for the RPCs performed with a
+// context the tracker is attached to.
+type costTracker struct {
+	mu   sync.Mutex
+	cost types.Currency
+}
+
+// withCostTracker attaches a new cost tracker to the context.
+func withCostTracker(ctx context.Context) (context.Context, *costTracker) {
+	ct := &costTracker{}
+	return context.WithValue(ctx, keyCostTracker, ct), ct
+}
+
+// recordCost adds the cost to the tracker attached to the context, if any.
+func recordCost(ctx context.Context, cost types.Currency) {
+	if ct, ok := ctx.Value(keyCostTracker).(*costTracker); ok {
+		ct.mu.Lock()
+		ct.cost = ct.cost.Add(cost)
+		ct.mu.Unlock()
+	}
+}
+
+// Cost returns the total cost recorded by the tracker.
+func (ct *costTracker) Cost() types.Currency {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return ct.cost
+}
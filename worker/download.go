@@ -31,6 +31,7 @@ var (
 
 type (
 	downloadManager struct {
+		bl     *bandwidthLimiter
 		hm     HostManager
 		mm     MemoryManager
 		os     ObjectStore
@@ -50,9 +51,17 @@ type (
 	}
 
 	downloaderStats struct {
-		avgSpeedMBPS float64
-		healthy      bool
-		numDownloads uint64
+		avgSpeedMBPS        float64
+		consecutiveFailures uint64
+		healthy             bool
+		lastErr             string
+		lastErrTime         time.Time
+		latencyP50MS        float64
+		latencyP90MS        float64
+		latencyP99MS        float64
+		numDownloads        uint64
+		numFailures         uint64
+		queueDepth          uint64
 	}
 
 	slabDownload struct {
@@ -127,17 +136,22 @@ type (
 	}
 )
 
-func (w *worker) initDownloadManager(maxMemory, maxOverdrive uint64, overdriveTimeout time.Duration, logger *zap.SugaredLogger) {
+func (w *worker) initDownloadManager(bl *bandwidthLimiter, maxMemory uint64, reservedMemory map[api.MemoryClass]uint64, maxOverdrive uint64, overdriveTimeout time.Duration, logger *zap.SugaredLogger) error {
 	if w.downloadManager != nil {
 		panic("download manager already initialized") // developer error
 	}
 
-	mm := newMemoryManager(logger.Named("memorymanager"), maxMemory)
-	w.downloadManager = newDownloadManager(w.shutdownCtx, w, mm, w.bus, maxOverdrive, overdriveTimeout, logger)
+	mm, err := newMemoryManager(logger.Named("memorymanager"), maxMemory, reservedMemory)
+	if err != nil {
+		return fmt.Errorf("failed to create download memory manager: %w", err)
+	}
+	w.downloadManager = newDownloadManager(w.shutdownCtx, bl, w, mm, w.bus, maxOverdrive, overdriveTimeout, logger)
+	return nil
 }
 
-func newDownloadManager(ctx context.Context, hm HostManager, mm MemoryManager, os ObjectStore, maxOverdrive uint64, overdriveTimeout time.Duration, logger *zap.SugaredLogger) *downloadManager {
+func newDownloadManager(ctx context.Context, bl *bandwidthLimiter, hm HostManager, mm MemoryManager, os ObjectStore, maxOverdrive uint64, overdriveTimeout time.Duration, logger *zap.SugaredLogger) *downloadManager {
 	return &downloadManager{
+		bl:     bl,
 		hm:     hm,
 		mm:     mm,
 		os:     os,
@@ -155,7 +169,7 @@ func newDownloadManager(ctx context.Context, hm HostManager, mm MemoryManager, o
 	}
 }
 
-func (mgr *downloadManager) DownloadObject(ctx context.Context, w io.Writer, o object.Object, offset, length uint64, contracts []api.ContractMetadata) (err error) {
+func (mgr *downloadManager) DownloadObject(ctx context.Context, w io.Writer, o object.Object, offset, length uint64, contracts []api.ContractMetadata, class api.MemoryClass) (err error) {
 	// calculate what slabs we need
 	var ss []slabSlice
 	for _, s := range o.Slabs {
@@ -256,7 +270,7 @@ func (mgr *downloadManager) DownloadObject(ctx context.Context, w io.Writer, o o
 			}
 
 			// acquire memory
-			mem := mm.AcquireMemory(ctx, uint64(next.Length))
+			mem := mm.AcquireMemory(ctx, uint64(next.Length), class)
 			if mem == nil {
 				return // interrupted
 			}
@@ -493,7 +507,7 @@ func (mgr *downloadManager) refreshDownloaders(contracts []api.ContractMetadata)
 	for _, c := range want {
 		// create a host
 		host := mgr.hm.Host(c.HostKey, c.ID, c.SiamuxAddr)
-		downloader := newDownloader(mgr.shutdownCtx, host)
+		downloader := newDownloader(mgr.shutdownCtx, host, mgr.bl)
 		mgr.downloaders[c.HostKey] = downloader
 		go downloader.processQueue()
 	}
@@ -837,8 +851,8 @@ func (s *slabDownload) launch(req *sectorDownloadReq) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// queue the request
-	req.host.enqueue(req)
+	// queue the request once the bandwidth limits allow for it
+	enqueueWithBandwidth(req.ctx, req.host.bl, int(req.length), func() { req.host.enqueue(req) }, req.fail)
 
 	// update the state
 	s.numInflight++
@@ -24,10 +24,12 @@ var (
 
 type (
 	downloader struct {
+		bl   *bandwidthLimiter
 		host Host
 
 		statsDownloadSpeedBytesPerMS    *stats.DataPoints // keep track of this separately for stats (no decay is applied)
 		statsSectorDownloadEstimateInMS *stats.DataPoints
+		statsSectorDownloadLatencyInMS  *stats.DataPoints // only tracks successful downloads (no decay is applied)
 
 		signalWorkChan chan struct{}
 		shutdownCtx    context.Context
@@ -35,17 +37,22 @@ type (
 		mu                  sync.Mutex
 		consecutiveFailures uint64
 		numDownloads        uint64
+		numFailures         uint64
+		lastErr             error
+		lastErrTime         time.Time
 		queue               []*sectorDownloadReq
 		stopped             bool
 	}
 )
 
-func newDownloader(ctx context.Context, host Host) *downloader {
+func newDownloader(ctx context.Context, host Host, bl *bandwidthLimiter) *downloader {
 	return &downloader{
+		bl:   bl,
 		host: host,
 
 		statsSectorDownloadEstimateInMS: stats.Default(),
 		statsDownloadSpeedBytesPerMS:    stats.NoDecay(),
+		statsSectorDownloadLatencyInMS:  stats.NoDecay(),
 
 		signalWorkChan: make(chan struct{}, 1),
 		shutdownCtx:    ctx,
@@ -128,11 +135,13 @@ func (d *downloader) estimate() float64 {
 func (d *downloader) execute(req *sectorDownloadReq) (err error) {
 	// download the sector
 	buf := bytes.NewBuffer(make([]byte, 0, req.length))
+	start := time.Now()
 	err = d.host.DownloadSector(req.ctx, buf, req.root, req.offset, req.length, req.overpay)
 	if err != nil {
 		req.fail(err)
 		return err
 	}
+	d.statsSectorDownloadLatencyInMS.Track(float64(time.Since(start).Milliseconds()))
 
 	d.mu.Lock()
 	d.numDownloads++
@@ -279,10 +288,24 @@ outer:
 func (d *downloader) stats() downloaderStats {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	var lastErr string
+	if d.lastErr != nil {
+		lastErr = d.lastErr.Error()
+	}
+
+	latency := d.statsSectorDownloadLatencyInMS.Percentiles(50, 90, 99)
 	return downloaderStats{
-		avgSpeedMBPS: d.statsDownloadSpeedBytesPerMS.Average() * 0.008,
-		healthy:      d.consecutiveFailures == 0,
-		numDownloads: d.numDownloads,
+		avgSpeedMBPS:        d.statsDownloadSpeedBytesPerMS.Average() * 0.008,
+		consecutiveFailures: d.consecutiveFailures,
+		healthy:             d.consecutiveFailures == 0,
+		lastErr:             lastErr,
+		lastErrTime:         d.lastErrTime,
+		latencyP50MS:        latency[0],
+		latencyP90MS:        latency[1],
+		latencyP99MS:        latency[2],
+		numDownloads:        d.numDownloads,
+		numFailures:         d.numFailures,
+		queueDepth:          uint64(len(d.queue)),
 	}
 }
 
@@ -303,5 +326,8 @@ func (d *downloader) trackFailure(err error) {
 	}
 
 	d.consecutiveFailures++
+	d.numFailures++
+	d.lastErr = err
+	d.lastErrTime = time.Now()
 	d.statsSectorDownloadEstimateInMS.Track(float64(time.Hour.Milliseconds()))
 }
@@ -34,3 +34,29 @@ func TestDownloaderStopped(t *testing.T) {
 		t.Fatal("no response")
 	}
 }
+
+func TestDownloaderStats(t *testing.T) {
+	w := newTestWorker(t)
+	hosts := w.AddHosts(1)
+
+	dm := w.downloadManager
+	dm.refreshDownloaders(w.Contracts())
+	dl := dm.downloaders[hosts[0].PublicKey()]
+
+	// track a host failure
+	dl.trackFailure(errors.New("host error"))
+	if stats := dl.stats(); stats.healthy || stats.consecutiveFailures != 1 || stats.numFailures != 1 {
+		t.Fatal("unexpected stats", stats)
+	} else if stats.lastErr != "host error" || stats.lastErrTime.IsZero() {
+		t.Fatal("unexpected last error", stats.lastErr, stats.lastErrTime)
+	}
+
+	// track a success, consecutive failures should reset but the last error
+	// should be retained
+	dl.trackFailure(nil)
+	if stats := dl.stats(); !stats.healthy || stats.consecutiveFailures != 0 || stats.numFailures != 1 {
+		t.Fatal("unexpected stats", stats)
+	} else if stats.lastErr != "host error" {
+		t.Fatal("unexpected last error", stats.lastErr)
+	}
+}
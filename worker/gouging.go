@@ -398,13 +398,9 @@ func checkDownloadGougingRHPv3(gs api.GougingSettings, pt *rhpv3.HostPriceTable)
 	if pt == nil {
 		return nil
 	}
-	sectorDownloadPrice, overflow := sectorReadCostRHPv3(*pt)
-	if overflow {
-		return fmt.Errorf("%w: overflow detected when computing sector download price", errPriceTableGouging)
-	}
-	dpptb, overflow := sectorDownloadPrice.Mul64WithOverflow(1 << 40 / rhpv2.SectorSize) // sectors per TiB
-	if overflow {
-		return fmt.Errorf("%w: overflow detected when computing download price per TiB", errPriceTableGouging)
+	dpptb, err := DownloadPricePerTiB(*pt)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPriceTableGouging, err)
 	}
 	if !gs.MaxDownloadPrice.IsZero() && dpptb.Cmp(gs.MaxDownloadPrice) > 0 {
 		return fmt.Errorf("%w: cost per TiB exceeds max dl price: %v > %v", errPriceTableGouging, dpptb, gs.MaxDownloadPrice)
@@ -416,13 +412,9 @@ func checkUploadGougingRHPv3(gs api.GougingSettings, pt *rhpv3.HostPriceTable) e
 	if pt == nil {
 		return nil
 	}
-	sectorUploadPricePerMonth, overflow := sectorUploadCostRHPv3(*pt)
-	if overflow {
-		return fmt.Errorf("%w: overflow detected when computing sector price", errPriceTableGouging)
-	}
-	uploadPrice, overflow := sectorUploadPricePerMonth.Mul64WithOverflow(1 << 40 / rhpv2.SectorSize) // sectors per TiB
-	if overflow {
-		return fmt.Errorf("%w: overflow detected when computing upload price per TiB", errPriceTableGouging)
+	uploadPrice, err := UploadPricePerTiB(*pt)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPriceTableGouging, err)
 	}
 	if !gs.MaxUploadPrice.IsZero() && uploadPrice.Cmp(gs.MaxUploadPrice) > 0 {
 		return fmt.Errorf("%w: cost per TiB exceeds max ul price: %v > %v", errPriceTableGouging, uploadPrice, gs.MaxUploadPrice)
@@ -430,6 +422,36 @@ func checkUploadGougingRHPv3(gs api.GougingSettings, pt *rhpv3.HostPriceTable) e
 	return nil
 }
 
+// DownloadPricePerTiB returns the price of downloading 1TiB of data from the
+// host with the given price table, it's the price that is compared to the
+// 'MaxDownloadPrice' gouging setting.
+func DownloadPricePerTiB(pt rhpv3.HostPriceTable) (types.Currency, error) {
+	sectorDownloadPrice, overflow := sectorReadCostRHPv3(pt)
+	if overflow {
+		return types.ZeroCurrency, errors.New("overflow detected when computing sector download price")
+	}
+	dpptb, overflow := sectorDownloadPrice.Mul64WithOverflow(1 << 40 / rhpv2.SectorSize) // sectors per TiB
+	if overflow {
+		return types.ZeroCurrency, errors.New("overflow detected when computing download price per TiB")
+	}
+	return dpptb, nil
+}
+
+// UploadPricePerTiB returns the price of uploading 1TiB of data to the host
+// with the given price table, it's the price that is compared to the
+// 'MaxUploadPrice' gouging setting.
+func UploadPricePerTiB(pt rhpv3.HostPriceTable) (types.Currency, error) {
+	sectorUploadPricePerMonth, overflow := sectorUploadCostRHPv3(pt)
+	if overflow {
+		return types.ZeroCurrency, errors.New("overflow detected when computing sector price")
+	}
+	uploadPrice, overflow := sectorUploadPricePerMonth.Mul64WithOverflow(1 << 40 / rhpv2.SectorSize) // sectors per TiB
+	if overflow {
+		return types.ZeroCurrency, errors.New("overflow detected when computing upload price per TiB")
+	}
+	return uploadPrice, nil
+}
+
 func sectorReadCostRHPv3(pt rhpv3.HostPriceTable) (types.Currency, bool) {
 	return sectorReadCost(
 		pt.ReadLengthCost,
@@ -114,6 +114,9 @@ func (h *host) DownloadSector(ctx context.Context, w io.Writer, root types.Hash2
 			payment := rhpv3.PayByEphemeralAccount(h.acc.id, cost, pt.HostBlockHeight+defaultWithdrawalExpiryBlocks, h.accountKey)
 			cost, refund, err = RPCReadSector(ctx, t, w, hpt, &payment, offset, length, root)
 			amount = cost.Sub(refund)
+			if err == nil {
+				recordCost(ctx, amount)
+			}
 			return err
 		})
 		return
@@ -154,6 +157,7 @@ func (h *host) UploadSector(ctx context.Context, sectorRoot types.Hash256, secto
 
 	// record spending
 	h.contractSpendingRecorder.Record(rev, api.ContractSpending{Uploads: cost})
+	recordCost(ctx, cost)
 	return nil
 }
 
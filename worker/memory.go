@@ -14,7 +14,7 @@ type (
 	// uploads and downloads.
 	MemoryManager interface {
 		Status() api.MemoryStatus
-		AcquireMemory(ctx context.Context, amt uint64) Memory
+		AcquireMemory(ctx context.Context, amt uint64, class api.MemoryClass) Memory
 		Limit(amt uint64) (MemoryManager, error)
 	}
 
@@ -25,15 +25,18 @@ type (
 
 	memoryManager struct {
 		totalAvailable uint64
+		reserved       map[api.MemoryClass]uint64
 		logger         *zap.SugaredLogger
 
 		mu        sync.Mutex
 		sigNewMem sync.Cond
 		available uint64
+		used      map[api.MemoryClass]uint64
 	}
 
 	acquiredMemory struct {
-		mm *memoryManager
+		mm    *memoryManager
+		class api.MemoryClass
 
 		remaining uint64
 	}
@@ -41,45 +44,72 @@ type (
 
 var _ MemoryManager = (*memoryManager)(nil)
 
-func newMemoryManager(logger *zap.SugaredLogger, maxMemory uint64) MemoryManager {
+// newMemoryManager creates a memory manager that hands out at most maxMemory.
+// The reserved map specifies the minimum amount of memory that is kept
+// available for each memory class, the sum of all reservations can't exceed
+// maxMemory.
+func newMemoryManager(logger *zap.SugaredLogger, maxMemory uint64, reserved map[api.MemoryClass]uint64) (MemoryManager, error) {
+	var totalReserved uint64
+	for _, r := range reserved {
+		totalReserved += r
+	}
+	if totalReserved > maxMemory {
+		return nil, fmt.Errorf("cannot reserve %v memory when only %v is available", totalReserved, maxMemory)
+	}
+
 	mm := &memoryManager{
 		logger:         logger,
+		reserved:       reserved,
 		totalAvailable: maxMemory,
+		used:           make(map[api.MemoryClass]uint64),
 	}
 	mm.available = mm.totalAvailable
 	mm.sigNewMem = *sync.NewCond(&mm.mu)
-	return mm
+	return mm, nil
 }
 
 func (mm *memoryManager) Limit(amt uint64) (MemoryManager, error) {
 	if amt > mm.totalAvailable {
 		return nil, fmt.Errorf("cannot limit memory to %v when only %v is available", amt, mm.available)
 	}
+	child, err := newMemoryManager(mm.logger, amt, nil)
+	if err != nil {
+		return nil, err
+	}
 	return &limitMemoryManager{
 		parent: mm,
-		child:  newMemoryManager(mm.logger, amt),
+		child:  child,
 	}, nil
 }
 
 func (mm *memoryManager) Status() api.MemoryStatus {
 	mm.mu.Lock()
 	defer mm.mu.Unlock()
+
+	classes := make(map[api.MemoryClass]api.MemoryClassStatus)
+	for _, class := range api.MemoryClasses {
+		classes[class] = api.MemoryClassStatus{
+			Reserved: mm.reserved[class],
+			Used:     mm.used[class],
+		}
+	}
 	return api.MemoryStatus{
 		Available: mm.available,
 		Total:     mm.totalAvailable,
+		Classes:   classes,
 	}
 }
 
-func (mm *memoryManager) AcquireMemory(ctx context.Context, amt uint64) Memory {
+func (mm *memoryManager) AcquireMemory(ctx context.Context, amt uint64, class api.MemoryClass) Memory {
 	if amt == 0 {
 		mm.logger.Fatal("cannot acquire 0 memory")
-	} else if mm.totalAvailable < amt {
-		mm.logger.Errorf("cannot acquire %v memory with only %v available", amt, mm.totalAvailable)
+	} else if maxAmt := mm.maxAvailableFor(class); maxAmt < amt {
+		mm.logger.Errorf("cannot acquire %v memory with only %v available to class '%v'", amt, maxAmt, class)
 		return nil
 	}
 	// block until enough memory is available
 	mm.sigNewMem.L.Lock()
-	for mm.available < amt {
+	for mm.availableFor(class) < amt {
 		mm.sigNewMem.Wait()
 
 		// check if the context was canceled in the meantime
@@ -92,22 +122,56 @@ func (mm *memoryManager) AcquireMemory(ctx context.Context, amt uint64) Memory {
 		}
 	}
 	mm.available -= amt
-	mm.sigNewMem.Signal() // wake next goroutine
+	mm.used[class] += amt
+	mm.sigNewMem.Broadcast() // wake other goroutines, they might be of a different class
 	mm.sigNewMem.L.Unlock()
 
 	return &acquiredMemory{
 		mm:        mm,
+		class:     class,
 		remaining: amt,
 	}
 }
 
+// availableFor returns the amount of memory that can currently be acquired by
+// the given class, memory reserved for other classes that is not in use by
+// those classes is not considered available. Must be called with the lock held.
+func (mm *memoryManager) availableFor(class api.MemoryClass) uint64 {
+	available := mm.available
+	for c, reserved := range mm.reserved {
+		if c == class || mm.used[c] >= reserved {
+			continue
+		}
+		shortfall := reserved - mm.used[c]
+		if shortfall >= available {
+			return 0
+		}
+		available -= shortfall
+	}
+	return available
+}
+
+// maxAvailableFor returns the maximum amount of memory the given class can
+// ever acquire at once, which is the total amount of memory minus the memory
+// reserved for other classes.
+func (mm *memoryManager) maxAvailableFor(class api.MemoryClass) uint64 {
+	maxAvailable := mm.totalAvailable
+	for c, reserved := range mm.reserved {
+		if c != class {
+			maxAvailable -= reserved
+		}
+	}
+	return maxAvailable
+}
+
 // release returns all the remaining memory to the memory manager. Should always
 // be called on every acquiredMemory when done using it.
 func (am *acquiredMemory) Release() {
 	am.mm.sigNewMem.L.Lock()
 	am.mm.available += am.remaining
+	am.mm.used[am.class] -= am.remaining
 	am.remaining = 0
-	am.mm.sigNewMem.Signal() // wake next goroutine
+	am.mm.sigNewMem.Broadcast() // wake other goroutines
 	am.mm.sigNewMem.L.Unlock()
 }
 
@@ -120,8 +184,9 @@ func (am *acquiredMemory) ReleaseSome(amt uint64) {
 		panic("releasing more memory than remaining")
 	}
 	am.mm.available += amt
+	am.mm.used[am.class] -= amt
 	am.remaining -= amt
-	am.mm.sigNewMem.Signal() // wake next goroutine
+	am.mm.sigNewMem.Broadcast() // wake other goroutines
 	am.mm.sigNewMem.L.Unlock()
 }
 
@@ -141,12 +206,12 @@ func (lmm *limitMemoryManager) Status() api.MemoryStatus {
 	return lmm.child.Status()
 }
 
-func (lmm *limitMemoryManager) AcquireMemory(ctx context.Context, amt uint64) Memory {
-	childMem := lmm.child.AcquireMemory(ctx, amt)
+func (lmm *limitMemoryManager) AcquireMemory(ctx context.Context, amt uint64, class api.MemoryClass) Memory {
+	childMem := lmm.child.AcquireMemory(ctx, amt, class)
 	if childMem == nil {
 		return nil
 	}
-	parentMem := lmm.parent.AcquireMemory(ctx, amt)
+	parentMem := lmm.parent.AcquireMemory(ctx, amt, class)
 	if parentMem == nil {
 		childMem.Release()
 		return nil
@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+)
+
+func TestMemoryManagerReservations(t *testing.T) {
+	// reserving more memory than available should fail
+	_, err := newMemoryManager(zap.NewNop().Sugar(), 100, map[api.MemoryClass]uint64{
+		api.MemoryClassInteractive: 60,
+		api.MemoryClassMigration:   60,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	// create a memory manager that reserves 40 bytes for interactive use
+	mm, err := newMemoryManager(zap.NewNop().Sugar(), 100, map[api.MemoryClass]uint64{
+		api.MemoryClassInteractive: 40,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// background work can never acquire more than the unreserved memory
+	if mem := mm.AcquireMemory(context.Background(), 61, api.MemoryClassBackground); mem != nil {
+		t.Fatal("expected nil memory")
+	}
+
+	// acquire all unreserved memory for background work
+	bg := mm.AcquireMemory(context.Background(), 60, api.MemoryClassBackground)
+	if bg == nil {
+		t.Fatal("expected memory")
+	}
+
+	// acquiring more background memory should block
+	acquired := make(chan Memory)
+	go func() {
+		acquired <- mm.AcquireMemory(context.Background(), 10, api.MemoryClassBackground)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("background work acquired reserved memory")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// interactive work can still use its reservation
+	interactive := mm.AcquireMemory(context.Background(), 40, api.MemoryClassInteractive)
+	if interactive == nil {
+		t.Fatal("expected memory")
+	}
+
+	// assert the status reports usage per class
+	status := mm.Status()
+	if status.Available != 0 {
+		t.Fatal("unexpected available memory", status.Available)
+	} else if cs := status.Classes[api.MemoryClassBackground]; cs.Used != 60 || cs.Reserved != 0 {
+		t.Fatal("unexpected background status", cs)
+	} else if cs := status.Classes[api.MemoryClassInteractive]; cs.Used != 40 || cs.Reserved != 40 {
+		t.Fatal("unexpected interactive status", cs)
+	}
+
+	// releasing the background memory unblocks the pending acquisition since
+	// the interactive reservation is in use
+	bg.Release()
+	select {
+	case mem := <-acquired:
+		if mem == nil {
+			t.Fatal("expected memory")
+		}
+		mem.Release()
+	case <-time.After(time.Second):
+		t.Fatal("background work didn't acquire memory")
+	}
+	interactive.Release()
+
+	// assert all memory was returned
+	status = mm.Status()
+	if status.Available != 100 {
+		t.Fatal("unexpected available memory", status.Available)
+	}
+	for class, cs := range status.Classes {
+		if cs.Used != 0 {
+			t.Fatal("unexpected usage", class, cs.Used)
+		}
+	}
+}
@@ -79,7 +79,7 @@ SHARDS:
 	}
 
 	// acquire memory for the migration
-	mem := w.uploadManager.mm.AcquireMemory(ctx, uint64(len(shardIndices))*rhpv2.SectorSize)
+	mem := w.uploadManager.mm.AcquireMemory(ctx, uint64(len(shardIndices))*rhpv2.SectorSize, api.MemoryClassMigration)
 	if mem == nil {
 		return 0, false, fmt.Errorf("failed to acquire memory for migration")
 	}
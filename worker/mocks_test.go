@@ -342,7 +342,7 @@ func (mm *memoryManagerMock) Limit(amt uint64) (MemoryManager, error) {
 
 func (mm *memoryManagerMock) Status() api.MemoryStatus { return api.MemoryStatus{} }
 
-func (mm *memoryManagerMock) AcquireMemory(ctx context.Context, amt uint64) Memory {
+func (mm *memoryManagerMock) AcquireMemory(ctx context.Context, amt uint64, class api.MemoryClass) Memory {
 	<-mm.memBlockChan
 	return &memoryMock{}
 }
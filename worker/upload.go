@@ -39,6 +39,7 @@ var (
 
 type (
 	uploadManager struct {
+		bl     *bandwidthLimiter
 		hm     HostManager
 		mm     MemoryManager
 		os     ObjectStore
@@ -66,7 +67,7 @@ type (
 		avgOverdrivePct        float64
 		healthyUploaders       uint64
 		numUploaders           uint64
-		uploadSpeedsMBPS       map[types.PublicKey]float64
+		uploaders              map[types.PublicKey]uploaderStats
 	}
 
 	upload struct {
@@ -146,13 +147,17 @@ type (
 	}
 )
 
-func (w *worker) initUploadManager(maxMemory, maxOverdrive uint64, overdriveTimeout time.Duration, logger *zap.SugaredLogger) {
+func (w *worker) initUploadManager(bl *bandwidthLimiter, maxMemory uint64, reservedMemory map[api.MemoryClass]uint64, maxOverdrive uint64, overdriveTimeout time.Duration, logger *zap.SugaredLogger) error {
 	if w.uploadManager != nil {
 		panic("upload manager already initialized") // developer error
 	}
 
-	mm := newMemoryManager(logger.Named("memorymanager"), maxMemory)
-	w.uploadManager = newUploadManager(w.shutdownCtx, w, mm, w.bus, w.bus, w.bus, maxOverdrive, overdriveTimeout, w.contractLockingDuration, logger)
+	mm, err := newMemoryManager(logger.Named("memorymanager"), maxMemory, reservedMemory)
+	if err != nil {
+		return fmt.Errorf("failed to create upload memory manager: %w", err)
+	}
+	w.uploadManager = newUploadManager(w.shutdownCtx, bl, w, mm, w.bus, w.bus, w.bus, maxOverdrive, overdriveTimeout, w.contractLockingDuration, logger)
+	return nil
 }
 
 func (w *worker) upload(ctx context.Context, bucket, path string, r io.Reader, contracts []api.ContractMetadata, opts ...UploadOption) (_ string, err error) {
@@ -189,7 +194,7 @@ func (w *worker) upload(ctx context.Context, bucket, path string, r io.Reader, c
 
 	// try and upload one slab synchronously
 	if bufferSizeLimitReached {
-		mem := w.uploadManager.mm.AcquireMemory(ctx, up.rs.SlabSize())
+		mem := w.uploadManager.mm.AcquireMemory(ctx, up.rs.SlabSize(), api.MemoryClassInteractive)
 		if mem != nil {
 			defer mem.Release()
 
@@ -236,7 +241,7 @@ func (w *worker) threadedUploadPackedSlabs(rs api.RedundancySettings, contractSe
 	var wg sync.WaitGroup
 	for {
 		// block until we have memory
-		mem := w.uploadManager.mm.AcquireMemory(interruptCtx, rs.SlabSize())
+		mem := w.uploadManager.mm.AcquireMemory(interruptCtx, rs.SlabSize(), api.MemoryClassBackground)
 		if mem == nil {
 			break // interrupted
 		}
@@ -294,6 +299,9 @@ func (w *worker) tryUploadPackedSlab(ctx context.Context, mem Memory, ps api.Pac
 	// attach gouging checker to the context
 	ctx = WithGougingChecker(ctx, w.bus, up.GougingParams)
 
+	// packed slab uploads are subject to the packed slabs bandwidth limit
+	ctx = withBandwidthLimiter(ctx, w.bandwidth.packedSlabs)
+
 	// upload packed slab
 	err = w.uploadManager.UploadPackedSlab(ctx, rs, ps, mem, contracts, up.CurrentHeight, lockPriority)
 	if err != nil {
@@ -303,8 +311,9 @@ func (w *worker) tryUploadPackedSlab(ctx context.Context, mem Memory, ps api.Pac
 	return nil
 }
 
-func newUploadManager(ctx context.Context, hm HostManager, mm MemoryManager, os ObjectStore, cl ContractLocker, cs ContractStore, maxOverdrive uint64, overdriveTimeout time.Duration, contractLockDuration time.Duration, logger *zap.SugaredLogger) *uploadManager {
+func newUploadManager(ctx context.Context, bl *bandwidthLimiter, hm HostManager, mm MemoryManager, os ObjectStore, cl ContractLocker, cs ContractStore, maxOverdrive uint64, overdriveTimeout time.Duration, contractLockDuration time.Duration, logger *zap.SugaredLogger) *uploadManager {
 	return &uploadManager{
+		bl:     bl,
 		hm:     hm,
 		mm:     mm,
 		os:     os,
@@ -328,6 +337,7 @@ func newUploadManager(ctx context.Context, hm HostManager, mm MemoryManager, os
 
 func (mgr *uploadManager) newUploader(os ObjectStore, cl ContractLocker, cs ContractStore, hm HostManager, c api.ContractMetadata) *uploader {
 	return &uploader{
+		bl:     mgr.bl,
 		os:     os,
 		cl:     cl,
 		cs:     cs,
@@ -342,6 +352,7 @@ func (mgr *uploadManager) newUploader(os ObjectStore, cl ContractLocker, cs Cont
 
 		// stats
 		statsSectorUploadEstimateInMS:    stats.Default(),
+		statsSectorUploadLatencyInMS:     stats.NoDecay(),
 		statsSectorUploadSpeedBytesPerMS: stats.NoDecay(),
 
 		// covered by mutex
@@ -357,10 +368,11 @@ func (mgr *uploadManager) Stats() uploadManagerStats {
 	defer mgr.mu.Unlock()
 
 	var numHealthy uint64
-	speeds := make(map[types.PublicKey]float64)
+	uploaders := make(map[types.PublicKey]uploaderStats)
 	for _, u := range mgr.uploaders {
-		speeds[u.hk] = u.statsSectorUploadSpeedBytesPerMS.Average() * 0.008
-		if u.Healthy() {
+		stats := u.stats()
+		uploaders[u.hk] = stats
+		if stats.healthy {
 			numHealthy++
 		}
 	}
@@ -370,8 +382,8 @@ func (mgr *uploadManager) Stats() uploadManagerStats {
 		avgSlabUploadSpeedMBPS: mgr.statsSlabUploadSpeedBytesPerMS.Average() * 0.008, // convert bytes per ms to mbps,
 		avgOverdrivePct:        mgr.statsOverdrivePct.Average(),
 		healthyUploaders:       numHealthy,
-		numUploaders:           uint64(len(speeds)),
-		uploadSpeedsMBPS:       speeds,
+		numUploaders:           uint64(len(uploaders)),
+		uploaders:              uploaders,
 	}
 }
 
@@ -445,7 +457,7 @@ func (mgr *uploadManager) Upload(ctx context.Context, r io.Reader, contracts []a
 			default:
 			}
 			// acquire memory
-			mem := mgr.mm.AcquireMemory(ctx, slabSize)
+			mem := mgr.mm.AcquireMemory(ctx, slabSize, api.MemoryClassInteractive)
 			if mem == nil {
 				return // interrupted
 			}
@@ -1000,8 +1012,12 @@ func (s *slabUpload) launch(req *sectorUploadReq) error {
 	s.numInflight++
 	s.numLaunched++
 
-	// enqueue the req
-	candidate.uploader.enqueue(req)
+	// enqueue the req once the bandwidth limits allow for it
+	uploader := candidate.uploader
+	enqueueWithBandwidth(req.sector.ctx, uploader.bl, rhpv2.SectorSize, func() { uploader.enqueue(req) }, func(err error) {
+		req.hk = uploader.hk
+		req.finish(err)
+	})
 	return nil
 }
 
@@ -28,6 +28,7 @@ var (
 
 type (
 	uploader struct {
+		bl     *bandwidthLimiter
 		os     ObjectStore
 		cs     ContractStore
 		cl     ContractLocker
@@ -48,11 +49,30 @@ type (
 
 		// stats related field
 		consecutiveFailures uint64
+		lastErr             error
+		lastErrTime         time.Time
 		lastRecompute       time.Time
+		numFailures         uint64
+		numUploads          uint64
 
 		statsSectorUploadEstimateInMS    *stats.DataPoints
+		statsSectorUploadLatencyInMS     *stats.DataPoints // only tracks successful uploads (no decay is applied)
 		statsSectorUploadSpeedBytesPerMS *stats.DataPoints
 	}
+
+	uploaderStats struct {
+		avgSpeedMBPS        float64
+		consecutiveFailures uint64
+		healthy             bool
+		lastErr             string
+		lastErrTime         time.Time
+		latencyP50MS        float64
+		latencyP90MS        float64
+		latencyP99MS        float64
+		numFailures         uint64
+		numUploads          uint64
+		queueDepth          uint64
+	}
 )
 
 func (u *uploader) ContractID() types.FileContractID {
@@ -132,9 +152,13 @@ outer:
 			success, failure, uploadEstimateMS, uploadSpeedBytesPerMS := handleSectorUpload(err, duration, elapsed, req.overdrive)
 			u.trackSectorUploadStats(uploadEstimateMS, uploadSpeedBytesPerMS)
 			u.trackConsecutiveFailures(success, failure)
+			if success {
+				u.statsSectorUploadLatencyInMS.Track(float64(duration.Milliseconds()))
+			}
 
 			// debug log
 			if uploadEstimateMS > 0 && !success {
+				u.trackError(err)
 				u.logger.Debugw("sector upload failure was penalised", "uploadError", err, "uploadDuration", duration, "totalDuration", elapsed, "overdrive", req.overdrive, "penalty", uploadEstimateMS, "hk", u.hk)
 			} else if uploadEstimateMS == 0 && err != nil && !utils.IsErr(err, errSectorUploadFinished) {
 				u.logger.Debugw("sector upload failure was ignored", "uploadError", err, "uploadDuration", duration, "totalDuration", elapsed, "overdrive", req.overdrive, "hk", u.hk)
@@ -333,11 +357,20 @@ func (u *uploader) trackConsecutiveFailures(success, failure bool) {
 
 	if success {
 		u.consecutiveFailures = 0
+		u.numUploads++
 	} else if failure {
 		u.consecutiveFailures++
+		u.numFailures++
 	}
 }
 
+func (u *uploader) trackError(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.lastErr = err
+	u.lastErrTime = time.Now()
+}
+
 func (u *uploader) trackSectorUploadStats(uploadEstimateMS, uploadSpeedBytesPerMS float64) {
 	u.mu.Lock()
 	defer u.mu.Unlock()
@@ -350,6 +383,31 @@ func (u *uploader) trackSectorUploadStats(uploadEstimateMS, uploadSpeedBytesPerM
 	}
 }
 
+func (u *uploader) stats() uploaderStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var lastErr string
+	if u.lastErr != nil {
+		lastErr = u.lastErr.Error()
+	}
+
+	latency := u.statsSectorUploadLatencyInMS.Percentiles(50, 90, 99)
+	return uploaderStats{
+		avgSpeedMBPS:        u.statsSectorUploadSpeedBytesPerMS.Average() * 0.008,
+		consecutiveFailures: u.consecutiveFailures,
+		healthy:             u.consecutiveFailures == 0,
+		lastErr:             lastErr,
+		lastErrTime:         u.lastErrTime,
+		latencyP50MS:        latency[0],
+		latencyP90MS:        latency[1],
+		latencyP99MS:        latency[2],
+		numFailures:         u.numFailures,
+		numUploads:          u.numUploads,
+		queueDepth:          uint64(len(u.queue)),
+	}
+}
+
 func (u *uploader) tryRecomputeStats() {
 	u.mu.Lock()
 	defer u.mu.Unlock()
@@ -0,0 +1,507 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/utils"
+	"golang.org/x/net/webdav"
+)
+
+const (
+	// listLimit is the number of entries fetched per request when listing
+	// the contents of a directory.
+	listLimit = 1000
+)
+
+var (
+	// errUnsupportedRename is returned when a client tries to rename a bucket
+	// or move a resource between buckets, the bus only supports renaming
+	// objects within a bucket.
+	errUnsupportedRename = errors.New("renaming buckets or moving between buckets is not supported")
+
+	_ webdav.FileSystem   = (*fileSystem)(nil)
+	_ webdav.File         = (*dirFile)(nil)
+	_ webdav.File         = (*objectFile)(nil)
+	_ webdav.File         = (*uploadFile)(nil)
+	_ webdav.ContentTyper = (*fileInfo)(nil)
+	_ webdav.ETager       = (*fileInfo)(nil)
+)
+
+type (
+	// fileSystem maps the WebDAV namespace onto buckets and objects. Names
+	// are of the form "/<bucket>/<path>" where the path refers to either an
+	// object or a directory within the bucket.
+	fileSystem struct {
+		b Bus
+		w Worker
+	}
+
+	fileInfo struct {
+		name     string
+		size     int64
+		modTime  time.Time
+		dir      bool
+		eTag     string
+		mimeType string
+	}
+
+	// dirFile is returned when opening the root, a bucket or a directory.
+	dirFile struct {
+		ctx    context.Context
+		fs     *fileSystem
+		bucket string
+		path   string
+		fi     *fileInfo
+
+		entries []fs.FileInfo
+		listed  bool
+	}
+
+	// objectFile is returned when opening an object for reading, it lazily
+	// downloads the object starting at the current offset which allows for
+	// serving range requests.
+	objectFile struct {
+		ctx    context.Context
+		fs     *fileSystem
+		bucket string
+		path   string
+		fi     *fileInfo
+
+		offset int64
+		rc     io.ReadCloser
+	}
+
+	// uploadFile is returned when opening an object for writing, the data
+	// written to it is streamed to the worker and the upload completes when
+	// the file is closed.
+	uploadFile struct {
+		ctx    context.Context
+		fs     *fileSystem
+		bucket string
+		path   string
+
+		closed  bool
+		copied  bool
+		done    chan error
+		modTime time.Time
+		pw      *io.PipeWriter
+		size    int64
+	}
+)
+
+// Mkdir creates a bucket when called on the root, otherwise it creates an
+// empty directory object.
+func (fsys *fileSystem) Mkdir(ctx context.Context, name string, _ os.FileMode) error {
+	bucket, p := splitName(name)
+	if bucket == "" {
+		return os.ErrExist
+	} else if p == "" {
+		err := fsys.b.CreateBucket(ctx, bucket, api.CreateBucketOptions{})
+		if utils.IsErr(err, api.ErrBucketExists) {
+			return os.ErrExist
+		}
+		return err
+	}
+
+	// the directory must not exist yet
+	if _, err := fsys.stat(ctx, bucket, p); err == nil {
+		return os.ErrExist
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// the parent must exist
+	if parent := path.Dir(p); parent != "." {
+		if fi, err := fsys.stat(ctx, bucket, parent); err != nil {
+			return err
+		} else if !fi.dir {
+			return os.ErrNotExist
+		}
+	} else if _, err := fsys.stat(ctx, bucket, ""); err != nil {
+		return err
+	}
+
+	_, err := fsys.w.UploadObject(ctx, bytes.NewReader(nil), bucket, p+"/", api.UploadObjectOptions{})
+	return err
+}
+
+// OpenFile opens the named resource. Opening an object with any of the write
+// flags set starts a new upload which replaces the object when the file is
+// closed.
+func (fsys *fileSystem) OpenFile(ctx context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	bucket, p := splitName(name)
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) != 0 {
+		if bucket == "" || p == "" {
+			return nil, os.ErrPermission
+		} else if _, err := fsys.stat(ctx, bucket, ""); err != nil {
+			return nil, err
+		}
+		return &uploadFile{
+			ctx:     ctx,
+			fs:      fsys,
+			bucket:  bucket,
+			path:    p,
+			modTime: time.Now(),
+		}, nil
+	}
+
+	fi, err := fsys.stat(ctx, bucket, p)
+	if err != nil {
+		return nil, err
+	} else if fi.dir {
+		return &dirFile{ctx: ctx, fs: fsys, bucket: bucket, path: p, fi: fi}, nil
+	}
+	return &objectFile{ctx: ctx, fs: fsys, bucket: bucket, path: p, fi: fi}, nil
+}
+
+// RemoveAll removes the named resource, directories are removed including
+// all of their contents. Buckets can only be removed if they are empty.
+func (fsys *fileSystem) RemoveAll(ctx context.Context, name string) error {
+	bucket, p := splitName(name)
+	if bucket == "" {
+		return os.ErrPermission
+	} else if p == "" {
+		err := fsys.b.DeleteBucket(ctx, bucket)
+		if utils.IsErr(err, api.ErrBucketNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	fi, err := fsys.stat(ctx, bucket, p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if fi.dir {
+		return fsys.b.DeleteObject(ctx, bucket, p+"/", api.DeleteObjectOptions{Batch: true})
+	}
+
+	err = fsys.b.DeleteObject(ctx, bucket, p, api.DeleteObjectOptions{})
+	if utils.IsErr(err, api.ErrObjectNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Rename renames an object or a directory through the bus, renaming buckets
+// or moving resources between buckets is not supported.
+func (fsys *fileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	srcBucket, src := splitName(oldName)
+	dstBucket, dst := splitName(newName)
+	if srcBucket != dstBucket || src == "" || dst == "" {
+		return errUnsupportedRename
+	}
+
+	fi, err := fsys.stat(ctx, srcBucket, src)
+	if err != nil {
+		return err
+	} else if fi.dir {
+		err = fsys.b.RenameObjects(ctx, srcBucket, "/"+src+"/", "/"+dst+"/", false)
+	} else {
+		err = fsys.b.RenameObject(ctx, srcBucket, "/"+src, "/"+dst, false)
+	}
+	if utils.IsErr(err, api.ErrObjectNotFound) {
+		return os.ErrNotExist
+	} else if utils.IsErr(err, api.ErrObjectExists) {
+		return os.ErrExist
+	}
+	return err
+}
+
+// Stat returns information about the named resource.
+func (fsys *fileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	bucket, p := splitName(name)
+	return fsys.stat(ctx, bucket, p)
+}
+
+func (fsys *fileSystem) stat(ctx context.Context, bucket, p string) (*fileInfo, error) {
+	// the root
+	if bucket == "" {
+		return &fileInfo{name: "/", dir: true}, nil
+	}
+
+	// a bucket
+	if p == "" {
+		b, err := fsys.b.Bucket(ctx, bucket)
+		if utils.IsErr(err, api.ErrBucketNotFound) {
+			return nil, os.ErrNotExist
+		} else if err != nil {
+			return nil, err
+		}
+		return &fileInfo{name: bucket, modTime: b.CreatedAt.Std(), dir: true}, nil
+	}
+
+	// an object or a directory, since directories are implicit we look for
+	// the entry in the listing of its parent
+	dir, base := path.Split(p)
+	opts := api.GetObjectOptions{Prefix: base, Limit: listLimit}
+	for {
+		res, err := fsys.b.Object(ctx, bucket, dir, opts)
+		if utils.IsErr(err, api.ErrBucketNotFound) {
+			return nil, os.ErrNotExist
+		} else if err != nil {
+			return nil, err
+		}
+		for _, entry := range res.Entries {
+			if name := strings.TrimPrefix(entry.Name, "/"+dir); name == base || name == base+"/" {
+				return newFileInfo(entry), nil
+			}
+		}
+		if !res.HasMore || len(res.Entries) == 0 {
+			return nil, os.ErrNotExist
+		}
+		opts.Marker = res.Entries[len(res.Entries)-1].Name
+	}
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() any           { return nil }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// ContentType implements webdav.ContentTyper, it prevents the WebDAV handler
+// from downloading the object to sniff its content type.
+func (fi *fileInfo) ContentType(_ context.Context) (string, error) {
+	if fi.mimeType != "" {
+		return fi.mimeType, nil
+	} else if mimeType := mime.TypeByExtension(path.Ext(fi.name)); mimeType != "" {
+		return mimeType, nil
+	}
+	return "application/octet-stream", nil
+}
+
+// ETag implements webdav.ETager.
+func (fi *fileInfo) ETag(_ context.Context) (string, error) {
+	if fi.eTag == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return fmt.Sprintf("%q", fi.eTag), nil
+}
+
+func (f *dirFile) Close() error                       { return nil }
+func (f *dirFile) Read(_ []byte) (int, error)         { return 0, os.ErrInvalid }
+func (f *dirFile) Seek(_ int64, _ int) (int64, error) { return 0, os.ErrInvalid }
+func (f *dirFile) Stat() (fs.FileInfo, error)         { return f.fi, nil }
+func (f *dirFile) Write(_ []byte) (int, error)        { return 0, os.ErrInvalid }
+
+// Readdir returns the buckets when called on the root and the entries of the
+// directory otherwise.
+func (f *dirFile) Readdir(count int) ([]fs.FileInfo, error) {
+	if !f.listed {
+		entries, err := f.list()
+		if err != nil {
+			return nil, err
+		}
+		f.entries = entries
+		f.listed = true
+	}
+
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	} else if len(f.entries) == 0 {
+		return nil, io.EOF
+	} else if count > len(f.entries) {
+		count = len(f.entries)
+	}
+	entries := f.entries[:count]
+	f.entries = f.entries[count:]
+	return entries, nil
+}
+
+func (f *dirFile) list() (entries []fs.FileInfo, _ error) {
+	if f.bucket == "" {
+		buckets, err := f.fs.b.ListBuckets(f.ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range buckets {
+			entries = append(entries, &fileInfo{name: b.Name, modTime: b.CreatedAt.Std(), dir: true})
+		}
+		return entries, nil
+	}
+
+	var dir string
+	if f.path != "" {
+		dir = f.path + "/"
+	}
+	opts := api.GetObjectOptions{Limit: listLimit}
+	for {
+		res, err := f.fs.b.Object(f.ctx, f.bucket, dir, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range res.Entries {
+			entries = append(entries, newFileInfo(entry))
+		}
+		if !res.HasMore || len(res.Entries) == 0 {
+			return entries, nil
+		}
+		opts.Marker = res.Entries[len(res.Entries)-1].Name
+	}
+}
+
+func (f *objectFile) Readdir(_ int) ([]fs.FileInfo, error) { return nil, os.ErrInvalid }
+func (f *objectFile) Stat() (fs.FileInfo, error)           { return f.fi, nil }
+func (f *objectFile) Write(_ []byte) (int, error)          { return 0, os.ErrInvalid }
+
+func (f *objectFile) Close() error {
+	if f.rc == nil {
+		return nil
+	}
+	err := f.rc.Close()
+	f.rc = nil
+	return err
+}
+
+func (f *objectFile) Read(p []byte) (int, error) {
+	if f.offset >= f.fi.size {
+		return 0, io.EOF
+	}
+
+	// start a download at the current offset
+	if f.rc == nil {
+		var opts api.DownloadObjectOptions
+		if f.offset > 0 {
+			opts.Range = &api.DownloadRange{Offset: f.offset, Length: -1}
+		}
+		res, err := f.fs.w.GetObject(f.ctx, f.bucket, f.path, opts)
+		if err != nil {
+			return 0, err
+		}
+		f.rc = res.Content
+	}
+
+	n, err := f.rc.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+// Seek updates the offset at which the object is read, if the offset changes
+// the ongoing download is aborted and a new one is started on the next read.
+func (f *objectFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.fi.size
+	default:
+		return 0, os.ErrInvalid
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+
+	if offset != f.offset {
+		if err := f.Close(); err != nil {
+			return 0, err
+		}
+		f.offset = offset
+	}
+	return offset, nil
+}
+
+func (f *uploadFile) Read(_ []byte) (int, error)           { return 0, os.ErrInvalid }
+func (f *uploadFile) Readdir(_ int) ([]fs.FileInfo, error) { return nil, os.ErrInvalid }
+func (f *uploadFile) Seek(_ int64, _ int) (int64, error)   { return 0, os.ErrInvalid }
+
+func (f *uploadFile) Stat() (fs.FileInfo, error) {
+	return &fileInfo{name: path.Base(f.path), size: f.size, modTime: f.modTime}, nil
+}
+
+// Close finishes the upload and returns its error, if nothing was written an
+// empty object is uploaded.
+func (f *uploadFile) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	if f.copied {
+		return nil
+	} else if f.pw == nil {
+		f.startUpload()
+	}
+	f.pw.Close()
+	return <-f.done
+}
+
+// ReadFrom implements io.ReaderFrom, the WebDAV handler copies resources by
+// opening both files and copying the data between them, when the source is
+// an object we have the bus copy it instead of downloading and uploading its
+// contents.
+func (f *uploadFile) ReadFrom(r io.Reader) (int64, error) {
+	if src, ok := r.(*objectFile); ok && f.pw == nil && !f.copied && src.offset == 0 {
+		if _, err := f.fs.b.CopyObject(f.ctx, src.bucket, f.bucket, "/"+src.path, "/"+f.path, api.CopyObjectOptions{
+			MimeType: src.fi.mimeType,
+		}); err != nil {
+			return 0, err
+		}
+		f.copied = true
+		f.size = src.fi.size
+		return f.size, nil
+	}
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+func (f *uploadFile) Write(p []byte) (int, error) {
+	if f.closed || f.copied {
+		return 0, os.ErrClosed
+	} else if f.pw == nil {
+		f.startUpload()
+	}
+	n, err := f.pw.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *uploadFile) startUpload() {
+	pr, pw := io.Pipe()
+	f.pw = pw
+	f.done = make(chan error, 1)
+	go func() {
+		_, err := f.fs.w.UploadObject(f.ctx, pr, f.bucket, f.path, api.UploadObjectOptions{})
+		pr.CloseWithError(err)
+		f.done <- err
+	}()
+}
+
+func newFileInfo(entry api.ObjectMetadata) *fileInfo {
+	return &fileInfo{
+		name:     path.Base(entry.Name),
+		size:     entry.Size,
+		modTime:  entry.ModTime.Std(),
+		dir:      strings.HasSuffix(entry.Name, "/"),
+		eTag:     entry.ETag,
+		mimeType: entry.MimeType,
+	}
+}
+
+// splitName splits a WebDAV name into a bucket and a path within that bucket,
+// the path has neither a leading nor a trailing slash.
+func splitName(name string) (bucket, p string) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	bucket, p, _ = strings.Cut(name, "/")
+	return
+}
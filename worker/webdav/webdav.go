@@ -0,0 +1,46 @@
+package webdav
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+type Bus interface {
+	Bucket(ctx context.Context, bucketName string) (api.Bucket, error)
+	CreateBucket(ctx context.Context, bucketName string, opts api.CreateBucketOptions) error
+	DeleteBucket(ctx context.Context, bucketName string) error
+	ListBuckets(ctx context.Context) (buckets []api.Bucket, err error)
+
+	CopyObject(ctx context.Context, srcBucket, dstBucket, srcPath, dstPath string, opts api.CopyObjectOptions) (om api.ObjectMetadata, err error)
+	DeleteObject(ctx context.Context, bucket, path string, opts api.DeleteObjectOptions) (err error)
+	Object(ctx context.Context, bucket, path string, opts api.GetObjectOptions) (res api.ObjectsResponse, err error)
+	RenameObject(ctx context.Context, bucket, from, to string, force bool) (err error)
+	RenameObjects(ctx context.Context, bucket, from, to string, force bool) (err error)
+}
+
+type Worker interface {
+	GetObject(ctx context.Context, bucket, path string, opts api.DownloadObjectOptions) (*api.GetObjectResponse, error)
+	UploadObject(ctx context.Context, r io.Reader, bucket, path string, opts api.UploadObjectOptions) (*api.UploadObjectResponse, error)
+}
+
+// New returns a WebDAV handler that serves the objects stored in the bus. The
+// root collection contains all buckets, every bucket is a collection that
+// contains the bucket's directories and objects. The handler doesn't perform
+// any authentication, that's up to the caller.
+func New(b Bus, w Worker, logger *zap.SugaredLogger) http.Handler {
+	logger = logger.Named("webdav")
+	return &webdav.Handler{
+		FileSystem: &fileSystem{b: b, w: w},
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(req *http.Request, err error) {
+			if err != nil {
+				logger.Debugw("request failed", "method", req.Method, "path", req.URL.Path, "error", err)
+			}
+		},
+	}
+}
@@ -0,0 +1,288 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.sia.tech/renterd/api"
+	"go.uber.org/zap"
+)
+
+// memStore is an in-memory implementation of the Bus and Worker interfaces,
+// object paths are stored with a leading slash just like in the bus.
+type memStore struct {
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+}
+
+func newMemStore(buckets ...string) *memStore {
+	s := &memStore{buckets: make(map[string]map[string][]byte)}
+	for _, b := range buckets {
+		s.buckets[b] = make(map[string][]byte)
+	}
+	return s
+}
+
+func (s *memStore) Bucket(_ context.Context, bucket string) (api.Bucket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.buckets[bucket]; !ok {
+		return api.Bucket{}, api.ErrBucketNotFound
+	}
+	return api.Bucket{Name: bucket}, nil
+}
+
+func (s *memStore) CreateBucket(_ context.Context, bucket string, _ api.CreateBucketOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.buckets[bucket]; ok {
+		return api.ErrBucketExists
+	}
+	s.buckets[bucket] = make(map[string][]byte)
+	return nil
+}
+
+func (s *memStore) DeleteBucket(_ context.Context, bucket string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if objects, ok := s.buckets[bucket]; !ok {
+		return api.ErrBucketNotFound
+	} else if len(objects) > 0 {
+		return api.ErrBucketNotEmpty
+	}
+	delete(s.buckets, bucket)
+	return nil
+}
+
+func (s *memStore) ListBuckets(_ context.Context) (buckets []api.Bucket, _ error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for b := range s.buckets {
+		buckets = append(buckets, api.Bucket{Name: b})
+	}
+	return
+}
+
+func (s *memStore) CopyObject(_ context.Context, srcBucket, dstBucket, srcPath, dstPath string, _ api.CopyObjectOptions) (api.ObjectMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.buckets[srcBucket][srcPath]
+	if !ok {
+		return api.ObjectMetadata{}, api.ErrObjectNotFound
+	}
+	s.buckets[dstBucket][dstPath] = data
+	return api.ObjectMetadata{Name: dstPath, Size: int64(len(data))}, nil
+}
+
+func (s *memStore) DeleteObject(_ context.Context, bucket, path string, opts api.DeleteObjectOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path = "/" + strings.TrimPrefix(path, "/")
+	for key := range s.buckets[bucket] {
+		if key == path || (opts.Batch && strings.HasPrefix(key, path)) {
+			delete(s.buckets[bucket], key)
+		}
+	}
+	return nil
+}
+
+func (s *memStore) Object(_ context.Context, bucket, path string, opts api.GetObjectOptions) (api.ObjectsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objects, ok := s.buckets[bucket]
+	if !ok {
+		return api.ObjectsResponse{}, api.ErrBucketNotFound
+	}
+
+	path = "/" + strings.TrimPrefix(path, "/")
+	if !strings.HasSuffix(path, "/") {
+		data, ok := objects[path]
+		if !ok {
+			return api.ObjectsResponse{}, api.ErrObjectNotFound
+		}
+		return api.ObjectsResponse{Object: &api.Object{ObjectMetadata: api.ObjectMetadata{Name: path, Size: int64(len(data))}}}, nil
+	}
+
+	entries := make(map[string]api.ObjectMetadata)
+	for key, data := range objects {
+		rest, ok := strings.CutPrefix(key, path)
+		if !ok || rest == "" || !strings.HasPrefix(rest, opts.Prefix) {
+			continue
+		}
+		if dir, _, isDir := strings.Cut(rest, "/"); isDir {
+			entries[path+dir+"/"] = api.ObjectMetadata{Name: path + dir + "/"}
+		} else {
+			entries[key] = api.ObjectMetadata{Name: key, Size: int64(len(data)), ModTime: api.TimeRFC3339(time.Now())}
+		}
+	}
+
+	var res api.ObjectsResponse
+	for name, entry := range entries {
+		if name > opts.Marker {
+			res.Entries = append(res.Entries, entry)
+		}
+	}
+	sort.Slice(res.Entries, func(i, j int) bool { return res.Entries[i].Name < res.Entries[j].Name })
+	if opts.Limit > 0 && len(res.Entries) > opts.Limit {
+		res.Entries = res.Entries[:opts.Limit]
+		res.HasMore = true
+	}
+	return res, nil
+}
+
+func (s *memStore) RenameObject(_ context.Context, bucket, from, to string, force bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.buckets[bucket][from]
+	if !ok {
+		return api.ErrObjectNotFound
+	} else if _, exists := s.buckets[bucket][to]; exists && !force {
+		return api.ErrObjectExists
+	}
+	delete(s.buckets[bucket], from)
+	s.buckets[bucket][to] = data
+	return nil
+}
+
+func (s *memStore) RenameObjects(_ context.Context, bucket, from, to string, _ bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, data := range s.buckets[bucket] {
+		if rest, ok := strings.CutPrefix(key, from); ok {
+			delete(s.buckets[bucket], key)
+			s.buckets[bucket][to+rest] = data
+		}
+	}
+	return nil
+}
+
+func (s *memStore) GetObject(_ context.Context, bucket, path string, opts api.DownloadObjectOptions) (*api.GetObjectResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.buckets[bucket]["/"+path]
+	if !ok {
+		return nil, api.ErrObjectNotFound
+	}
+	if opts.Range != nil {
+		data = data[opts.Range.Offset:]
+	}
+	return &api.GetObjectResponse{Content: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (s *memStore) UploadObject(_ context.Context, r io.Reader, bucket, path string, _ api.UploadObjectOptions) (*api.UploadObjectResponse, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets[bucket]["/"+path] = data
+	return &api.UploadObjectResponse{}, nil
+}
+
+func (s *memStore) objects(bucket string) (keys []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func TestWebDAV(t *testing.T) {
+	store := newMemStore("default")
+	srv := httptest.NewServer(New(store, store, zap.NewNop().Sugar()))
+	defer srv.Close()
+
+	do := func(method, path string, body []byte, headers map[string]string, expected int) []byte {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		} else if resp.StatusCode != expected {
+			t.Fatalf("%s %s: unexpected status %d, expected %d: %s", method, path, resp.StatusCode, expected, b)
+		}
+		return b
+	}
+	assertObjects := func(bucket string, expected ...string) {
+		t.Helper()
+		if objects := store.objects(bucket); strings.Join(objects, ",") != strings.Join(expected, ",") {
+			t.Fatalf("unexpected objects %v, expected %v", objects, expected)
+		}
+	}
+
+	// create a directory, creating one in a missing parent should fail
+	do("MKCOL", "/default/dir", nil, nil, http.StatusCreated)
+	do("MKCOL", "/default/missing/dir", nil, nil, http.StatusConflict)
+	do("MKCOL", "/default/dir", nil, nil, http.StatusMethodNotAllowed)
+	assertObjects("default", "/dir/")
+
+	// create a bucket
+	do("MKCOL", "/other", nil, nil, http.StatusCreated)
+
+	// upload a file and download a range of it
+	do("PUT", "/default/dir/file.txt", []byte("hello world"), nil, http.StatusCreated)
+	if b := do("GET", "/default/dir/file.txt", nil, nil, http.StatusOK); string(b) != "hello world" {
+		t.Fatal("unexpected content", string(b))
+	} else if b := do("GET", "/default/dir/file.txt", nil, map[string]string{"Range": "bytes=6-"}, http.StatusPartialContent); string(b) != "world" {
+		t.Fatal("unexpected content", string(b))
+	}
+
+	// list the directory
+	if b := do("PROPFIND", "/default/dir/", nil, map[string]string{"Depth": "1"}, http.StatusMultiStatus); !strings.Contains(string(b), "/default/dir/file.txt") {
+		t.Fatal("file missing from listing", string(b))
+	}
+
+	// list the buckets
+	if b := do("PROPFIND", "/", nil, map[string]string{"Depth": "1"}, http.StatusMultiStatus); !strings.Contains(string(b), "/other/") {
+		t.Fatal("bucket missing from listing", string(b))
+	}
+
+	// copy the file, within the bucket and to the other bucket
+	do("COPY", "/default/dir/file.txt", nil, map[string]string{"Destination": srv.URL + "/default/copy.txt"}, http.StatusCreated)
+	do("COPY", "/default/dir/file.txt", nil, map[string]string{"Destination": srv.URL + "/other/copy.txt"}, http.StatusCreated)
+	assertObjects("default", "/copy.txt", "/dir/", "/dir/file.txt")
+	assertObjects("other", "/copy.txt")
+
+	// move the file and the directory
+	do("MOVE", "/default/copy.txt", nil, map[string]string{"Destination": srv.URL + "/default/dir/moved.txt"}, http.StatusCreated)
+	do("MOVE", "/default/dir/", nil, map[string]string{"Destination": srv.URL + "/default/dir2/"}, http.StatusCreated)
+	assertObjects("default", "/dir2/", "/dir2/file.txt", "/dir2/moved.txt")
+
+	// moving between buckets is not supported
+	do("MOVE", "/default/dir2/moved.txt", nil, map[string]string{"Destination": srv.URL + "/other/moved.txt"}, http.StatusForbidden)
+
+	// delete a file and a directory
+	do("DELETE", "/other/copy.txt", nil, nil, http.StatusNoContent)
+	do("DELETE", "/default/dir2/", nil, nil, http.StatusNoContent)
+	do("GET", "/default/dir2/file.txt", nil, nil, http.StatusNotFound)
+	assertObjects("default")
+	assertObjects("other")
+
+	// delete the empty bucket
+	do("DELETE", "/other", nil, nil, http.StatusNoContent)
+	if _, err := store.Bucket(context.Background(), "other"); err != api.ErrBucketNotFound {
+		t.Fatal("expected bucket to be deleted", err)
+	}
+}
@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gotd/contrib/http_range"
+	"go.sia.tech/jape"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/internal/utils"
+)
+
+var errBucketNotWebsite = errors.New("bucket is not configured as a website")
+
+// websiteHandlerGET serves the contents of a public bucket as a static
+// website. Directory paths are served using the bucket's index document,
+// missing objects are served using its error document and the bucket's
+// redirect rules are applied before and after looking up the object.
+func (w *worker) websiteHandlerGET(jc jape.Context) {
+	ctx := jc.Request.Context()
+
+	// fetch the bucket and assert it's configured as a website
+	bucket, err := w.bus.Bucket(ctx, jc.PathParam("bucket"))
+	if utils.IsErr(err, api.ErrBucketNotFound) {
+		jc.Error(api.ErrBucketNotFound, http.StatusNotFound)
+		return
+	} else if jc.Check("couldn't fetch bucket", err) != nil {
+		return
+	}
+	website := bucket.Policy.Website
+	if !bucket.Policy.PublicReadAccess || website == nil {
+		jc.Error(errBucketNotWebsite, http.StatusNotFound)
+		return
+	}
+
+	// apply the redirect rules that don't depend on the object
+	key := strings.TrimPrefix(jc.PathParam("path"), "/")
+	if rule, ok := website.RedirectRule(key, 0); ok {
+		websiteRedirect(jc, key, rule)
+		return
+	}
+
+	dr, err := api.ParseDownloadRange(jc.Request)
+	if errors.Is(err, http_range.ErrInvalid) || errors.Is(err, api.ErrMultiRangeNotSupported) {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	} else if errors.Is(err, http_range.ErrNoOverlap) {
+		jc.Error(err, http.StatusRequestedRangeNotSatisfiable)
+		return
+	} else if err != nil {
+		jc.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	// serve the object, directories are served using the index document
+	objectKey := key
+	if objectKey == "" || strings.HasSuffix(objectKey, "/") {
+		objectKey += website.IndexDocument
+	}
+	gor, err := w.GetObject(ctx, bucket.Name, objectKey, api.DownloadObjectOptions{Range: &dr})
+	if err == nil {
+		defer gor.Content.Close()
+		serveContent(jc.ResponseWriter, jc.Request, objectKey, gor.Content, gor.HeadObjectResponse)
+		return
+	} else if errors.Is(err, http_range.ErrInvalid) {
+		jc.Error(err, http.StatusBadRequest)
+		return
+	} else if !utils.IsErr(err, api.ErrObjectNotFound) {
+		jc.Check("couldn't get object", err)
+		return
+	}
+
+	// if the key refers to a directory with an index document, redirect to
+	// the directory
+	if objectKey == key {
+		if _, err := w.HeadObject(ctx, bucket.Name, key+"/"+website.IndexDocument, api.HeadObjectOptions{}); err == nil {
+			jc.ResponseWriter.Header().Set("Location", websiteLocation(key, key+"/"))
+			jc.ResponseWriter.WriteHeader(http.StatusFound)
+			return
+		}
+	}
+
+	// apply the redirect rules for missing objects
+	if rule, ok := website.RedirectRule(key, http.StatusNotFound); ok {
+		websiteRedirect(jc, key, rule)
+		return
+	}
+
+	// serve the error document
+	if website.ErrorDocument != "" {
+		gor, err := w.GetObject(ctx, bucket.Name, website.ErrorDocument, api.DownloadObjectOptions{})
+		if err == nil {
+			defer gor.Content.Close()
+			jc.ResponseWriter.Header().Set("Content-Type", gor.ContentType)
+			jc.ResponseWriter.WriteHeader(http.StatusNotFound)
+			if jc.Request.Method != http.MethodHead {
+				io.Copy(jc.ResponseWriter, gor.Content)
+			}
+			return
+		} else if !utils.IsErr(err, api.ErrObjectNotFound) {
+			w.logger.Warnw("failed to serve error document", "bucket", bucket.Name, "key", website.ErrorDocument, "error", err)
+		}
+	}
+	jc.Error(api.ErrObjectNotFound, http.StatusNotFound)
+}
+
+// websiteRedirect redirects the request for the given key according to the
+// redirect rule.
+func websiteRedirect(jc jape.Context, key string, rule api.BucketRedirectRule) {
+	location := websiteLocation(key, rule.RedirectKey(key))
+	if rule.HostName != "" {
+		u := url.URL{
+			Scheme: rule.Protocol,
+			Host:   rule.HostName,
+			Path:   "/" + strings.TrimPrefix(rule.RedirectKey(key), "/"),
+		}
+		if u.Scheme == "" && jc.Request.TLS != nil {
+			u.Scheme = "https"
+		} else if u.Scheme == "" {
+			u.Scheme = "http"
+		}
+		location = u.String()
+	}
+	jc.ResponseWriter.Header().Set("Location", location)
+	jc.ResponseWriter.WriteHeader(rule.RedirectCode())
+}
+
+// websiteLocation returns the location of the target key relative to the key
+// that was requested. Using a relative reference keeps redirects working
+// regardless of the path prefix the worker API is served under.
+func websiteLocation(requested, target string) string {
+	u := url.URL{Path: "./" + strings.Repeat("../", strings.Count(requested, "/")) + target}
+	return u.String()
+}
@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.sia.tech/renterd/api"
+)
+
+func TestWebsiteRedirects(t *testing.T) {
+	website := api.BucketWebsite{
+		IndexDocument: "index.html",
+		RedirectRules: []api.BucketRedirectRule{
+			{KeyPrefixEquals: "docs/", ReplaceKeyPrefixWith: "documents/"},
+			{KeyPrefixEquals: "images/", HTTPErrorCodeReturnedEquals: http.StatusNotFound, ReplaceKeyWith: "missing.png", HTTPRedirectCode: http.StatusFound},
+		},
+	}
+	if err := (api.BucketPolicy{Website: &website}).Validate(); err == nil {
+		t.Fatal("expected error, website requires public read access")
+	} else if err := (api.BucketPolicy{PublicReadAccess: true, Website: &website}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert the prefix rule applies before the object is looked up
+	rule, ok := website.RedirectRule("docs/a/b.html", 0)
+	if !ok {
+		t.Fatal("expected rule")
+	} else if key := rule.RedirectKey("docs/a/b.html"); key != "documents/a/b.html" {
+		t.Fatal("unexpected key", key)
+	} else if code := rule.RedirectCode(); code != http.StatusMovedPermanently {
+		t.Fatal("unexpected code", code)
+	} else if loc := websiteLocation("docs/a/b.html", key); loc != "./../../documents/a/b.html" {
+		t.Fatal("unexpected location", loc)
+	}
+
+	// assert the error code rule only applies to missing objects
+	if _, ok := website.RedirectRule("images/a.png", 0); ok {
+		t.Fatal("unexpected rule")
+	} else if rule, ok := website.RedirectRule("images/a.png", http.StatusNotFound); !ok {
+		t.Fatal("expected rule")
+	} else if key := rule.RedirectKey("images/a.png"); key != "missing.png" {
+		t.Fatal("unexpected key", key)
+	} else if loc := websiteLocation("images/a.png", key); loc != "./../missing.png" {
+		t.Fatal("unexpected location", loc)
+	}
+
+	// assert a directory redirect from the bucket root is relative to it
+	if loc := websiteLocation("blog", "blog/"); loc != "./blog/" {
+		t.Fatal("unexpected location", loc)
+	}
+}
+
+// websiteBusMock returns the configured bucket when a bucket is fetched.
+type websiteBusMock struct {
+	Bus
+	bucket api.Bucket
+}
+
+func (b *websiteBusMock) Bucket(_ context.Context, bucket string) (api.Bucket, error) {
+	if bucket != b.bucket.Name {
+		return api.Bucket{}, api.ErrBucketNotFound
+	}
+	return b.bucket, nil
+}
+
+func TestWebsiteHandler(t *testing.T) {
+	w := newTestWorker(t)
+	w.AddHosts(testRedundancySettings.TotalShards)
+
+	// upload the website
+	for path, content := range map[string]string{
+		"index.html":      "index",
+		"blog/index.html": "blog",
+		"404.html":        "not found",
+	} {
+		if _, err := w.upload(context.Background(), testBucket, path, strings.NewReader(content), w.Contracts(), testOpts()...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bus := &websiteBusMock{Bus: w.bus, bucket: api.Bucket{Name: testBucket}}
+	w.bus = bus
+	srv := httptest.NewServer(w.Handler())
+	defer srv.Close()
+
+	// prepare a client that doesn't follow redirects
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	request := func(method, path string) (int, string, http.Header) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+"/website/"+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body), resp.Header
+	}
+
+	// assert buckets that aren't public websites are rejected
+	for _, policy := range []api.BucketPolicy{
+		{},
+		{PublicReadAccess: true},
+		{Website: &api.BucketWebsite{IndexDocument: "index.html"}},
+	} {
+		bus.bucket.Policy = policy
+		if code, body, _ := request(http.MethodGet, testBucket+"/index.html"); code != http.StatusNotFound || !strings.Contains(body, errBucketNotWebsite.Error()) {
+			t.Fatalf("unexpected response for policy %+v: %d %s", policy, code, body)
+		}
+	}
+	if code, _, _ := request(http.MethodGet, "unknown/index.html"); code != http.StatusNotFound {
+		t.Fatal("unexpected status code", code)
+	}
+
+	bus.bucket.Policy = api.BucketPolicy{
+		PublicReadAccess: true,
+		Website: &api.BucketWebsite{
+			IndexDocument: "index.html",
+			ErrorDocument: "404.html",
+			RedirectRules: []api.BucketRedirectRule{
+				{KeyPrefixEquals: "docs/", ReplaceKeyPrefixWith: "documents/"},
+				{KeyPrefixEquals: "images/", HTTPErrorCodeReturnedEquals: http.StatusNotFound, ReplaceKeyWith: "missing.png", HTTPRedirectCode: http.StatusFound},
+			},
+		},
+	}
+
+	// assert directories are served using the index document
+	for path, want := range map[string]string{
+		testBucket + "/":                "index",
+		testBucket + "/index.html":      "index",
+		testBucket + "/blog/":           "blog",
+		testBucket + "/blog/index.html": "blog",
+	} {
+		if code, body, _ := request(http.MethodGet, path); code != http.StatusOK || body != want {
+			t.Fatalf("unexpected response for %s: %d %s", path, code, body)
+		}
+	}
+
+	// assert directories without a trailing slash are redirected
+	if code, _, header := request(http.MethodGet, testBucket+"/blog"); code != http.StatusFound || header.Get("Location") != "./blog/" {
+		t.Fatal("unexpected response", code, header.Get("Location"))
+	}
+
+	// assert missing objects are served using the error document
+	if code, body, _ := request(http.MethodGet, testBucket+"/missing.html"); code != http.StatusNotFound || body != "not found" {
+		t.Fatal("unexpected response", code, body)
+	} else if code, body, _ := request(http.MethodHead, testBucket+"/missing.html"); code != http.StatusNotFound || body != "" {
+		t.Fatal("unexpected response", code, body)
+	}
+
+	// assert the redirect rules are applied
+	if code, _, header := request(http.MethodGet, testBucket+"/docs/a.html"); code != http.StatusMovedPermanently || header.Get("Location") != "./../documents/a.html" {
+		t.Fatal("unexpected response", code, header.Get("Location"))
+	} else if code, _, header := request(http.MethodGet, testBucket+"/images/a.png"); code != http.StatusFound || header.Get("Location") != "./../missing.png" {
+		t.Fatal("unexpected response", code, header.Get("Location"))
+	}
+
+	// assert a plain 404 is returned without an error document
+	bus.bucket.Policy.Website.ErrorDocument = ""
+	if code, body, _ := request(http.MethodGet, testBucket+"/missing.html"); code != http.StatusNotFound || !strings.Contains(body, api.ErrObjectNotFound.Error()) {
+		t.Fatal("unexpected response", code, body)
+	}
+}
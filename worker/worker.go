@@ -210,6 +210,7 @@ type worker struct {
 	masterKey       [32]byte
 	startTime       time.Time
 
+	bandwidth       *bandwidthLimiters
 	downloadManager *downloadManager
 	uploadManager   *uploadManager
 
@@ -779,6 +780,12 @@ func (w *worker) slabMigrateHandler(jc jape.Context) {
 	// attach gouging checker to the context
 	ctx = WithGougingChecker(ctx, w.bus, up.GougingParams)
 
+	// migrations are subject to the migration bandwidth limit
+	ctx = withBandwidthLimiter(ctx, w.bandwidth.migration)
+
+	// keep track of the cost of the migration
+	ctx, ct := withCostTracker(ctx)
+
 	// fetch all contracts
 	dlContracts, err := w.bus.Contracts(ctx, api.ContractsOpts{})
 	if jc.Check("couldn't fetch contracts from bus", err) != nil {
@@ -797,6 +804,7 @@ func (w *worker) slabMigrateHandler(jc jape.Context) {
 		jc.Encode(api.MigrateSlabResponse{
 			NumShardsMigrated: numShardsMigrated,
 			SurchargeApplied:  surchargeApplied,
+			Cost:              ct.Cost(),
 			Error:             err.Error(),
 		})
 		return
@@ -805,6 +813,7 @@ func (w *worker) slabMigrateHandler(jc jape.Context) {
 	jc.Encode(api.MigrateSlabResponse{
 		NumShardsMigrated: numShardsMigrated,
 		SurchargeApplied:  surchargeApplied,
+		Cost:              ct.Cost(),
 	})
 }
 
@@ -843,10 +852,10 @@ func (w *worker) uploadsStatsHandlerGET(jc jape.Context) {
 
 	// prepare upload stats
 	var uss []api.UploaderStats
-	for hk, mbps := range stats.uploadSpeedsMBPS {
+	for hk, stat := range stats.uploaders {
 		uss = append(uss, api.UploaderStats{
 			HostKey:                  hk,
-			AvgSectorUploadSpeedMBPS: mbps,
+			AvgSectorUploadSpeedMBPS: stat.avgSpeedMBPS,
 		})
 	}
 	sort.SliceStable(uss, func(i, j int) bool {
@@ -863,6 +872,86 @@ func (w *worker) uploadsStatsHandlerGET(jc jape.Context) {
 	})
 }
 
+func (w *worker) hostsStatsHandlerGET(jc jape.Context) {
+	var contractset string
+	if jc.DecodeForm("contractset", &contractset) != nil {
+		return
+	}
+
+	// fetch the hosts in the contract set if a filter was specified
+	var inSet map[types.PublicKey]struct{}
+	if contractset != "" {
+		contracts, err := w.bus.Contracts(jc.Request.Context(), api.ContractsOpts{ContractSet: contractset})
+		if jc.Check("couldn't fetch contracts from bus", err) != nil {
+			return
+		}
+		inSet = make(map[types.PublicKey]struct{})
+		for _, c := range contracts {
+			inSet[c.HostKey] = struct{}{}
+		}
+	}
+	included := func(hk types.PublicKey) bool {
+		if inSet == nil {
+			return true
+		}
+		_, ok := inSet[hk]
+		return ok
+	}
+
+	// collect stats per host
+	hosts := make(map[types.PublicKey]*api.HostStats)
+	hostStats := func(hk types.PublicKey) *api.HostStats {
+		if _, ok := hosts[hk]; !ok {
+			hosts[hk] = &api.HostStats{HostKey: hk}
+		}
+		return hosts[hk]
+	}
+	for hk, stat := range w.downloadManager.Stats().downloaders {
+		if included(hk) {
+			hostStats(hk).Downloads = &api.HostTransferStats{
+				AvgSpeedMBPS:        stat.avgSpeedMBPS,
+				ConsecutiveFailures: stat.consecutiveFailures,
+				Healthy:             stat.healthy,
+				LastError:           stat.lastErr,
+				LastErrorTime:       api.TimeRFC3339(stat.lastErrTime),
+				LatencyP50MS:        stat.latencyP50MS,
+				LatencyP90MS:        stat.latencyP90MS,
+				LatencyP99MS:        stat.latencyP99MS,
+				NumFailures:         stat.numFailures,
+				NumSuccesses:        stat.numDownloads,
+				QueueDepth:          stat.queueDepth,
+			}
+		}
+	}
+	for hk, stat := range w.uploadManager.Stats().uploaders {
+		if included(hk) {
+			hostStats(hk).Uploads = &api.HostTransferStats{
+				AvgSpeedMBPS:        stat.avgSpeedMBPS,
+				ConsecutiveFailures: stat.consecutiveFailures,
+				Healthy:             stat.healthy,
+				LastError:           stat.lastErr,
+				LastErrorTime:       api.TimeRFC3339(stat.lastErrTime),
+				LatencyP50MS:        stat.latencyP50MS,
+				LatencyP90MS:        stat.latencyP90MS,
+				LatencyP99MS:        stat.latencyP99MS,
+				NumFailures:         stat.numFailures,
+				NumSuccesses:        stat.numUploads,
+				QueueDepth:          stat.queueDepth,
+			}
+		}
+	}
+
+	// sort by host key for a stable response
+	resp := api.HostStatsResponse{Hosts: make([]api.HostStats, 0, len(hosts))}
+	for _, hs := range hosts {
+		resp.Hosts = append(resp.Hosts, *hs)
+	}
+	sort.Slice(resp.Hosts, func(i, j int) bool {
+		return resp.Hosts[i].HostKey.String() < resp.Hosts[j].HostKey.String()
+	})
+	jc.Encode(resp)
+}
+
 func (w *worker) objectsHandlerHEAD(jc jape.Context) {
 	// parse bucket
 	bucket := api.DefaultBucketName
@@ -959,6 +1048,14 @@ func (w *worker) objectsHandlerGET(jc jape.Context) {
 	if jc.DecodeForm("ignoreDelim", &ignoreDelim) != nil {
 		return
 	}
+	memoryClass := api.MemoryClassInteractive
+	if jc.DecodeForm("memoryclass", &memoryClass) != nil {
+		return
+	}
+	var bandwidthLimit uint64
+	if jc.DecodeForm("bandwidthlimit", &bandwidthLimit) != nil {
+		return
+	}
 
 	opts := api.GetObjectOptions{
 		Prefix:      prefix,
@@ -998,6 +1095,8 @@ func (w *worker) objectsHandlerGET(jc jape.Context) {
 
 	gor, err := w.GetObject(ctx, bucket, path, api.DownloadObjectOptions{
 		GetObjectOptions: opts,
+		BandwidthLimit:   bandwidthLimit,
+		MemoryClass:      memoryClass,
 		Range:            &dr,
 	})
 	if utils.IsErr(err, api.ErrObjectNotFound) {
@@ -1049,6 +1148,12 @@ func (w *worker) objectsHandlerPUT(jc jape.Context) {
 		return
 	}
 
+	// decode the bandwidth limit from the query string
+	var bandwidthLimit uint64
+	if jc.DecodeForm("bandwidthlimit", &bandwidthLimit) != nil {
+		return
+	}
+
 	// parse headers and extract object meta
 	metadata := make(api.ObjectUserMetadata)
 	for k, v := range jc.Request.Header {
@@ -1059,12 +1164,13 @@ func (w *worker) objectsHandlerPUT(jc jape.Context) {
 
 	// upload the object
 	resp, err := w.UploadObject(ctx, jc.Request.Body, bucket, path, api.UploadObjectOptions{
-		MinShards:     minShards,
-		TotalShards:   totalShards,
-		ContractSet:   contractset,
-		ContentLength: jc.Request.ContentLength,
-		MimeType:      mimeType,
-		Metadata:      metadata,
+		MinShards:      minShards,
+		TotalShards:    totalShards,
+		ContractSet:    contractset,
+		ContentLength:  jc.Request.ContentLength,
+		MimeType:       mimeType,
+		Metadata:       metadata,
+		BandwidthLimit: bandwidthLimit,
 	})
 	if utils.IsErr(err, api.ErrInvalidRedundancySettings) {
 		jc.Error(err, http.StatusBadRequest)
@@ -1129,6 +1235,12 @@ func (w *worker) multipartUploadHandlerPUT(jc jape.Context) {
 		return
 	}
 
+	// decode the bandwidth limit from the query string
+	var bandwidthLimit uint64
+	if jc.DecodeForm("bandwidthlimit", &bandwidthLimit) != nil {
+		return
+	}
+
 	// prepare options
 	opts := api.UploadMultipartUploadPartOptions{
 		ContractSet:      contractset,
@@ -1136,6 +1248,7 @@ func (w *worker) multipartUploadHandlerPUT(jc jape.Context) {
 		TotalShards:      totalShards,
 		EncryptionOffset: nil,
 		ContentLength:    jc.Request.ContentLength,
+		BandwidthLimit:   bandwidthLimit,
 	}
 
 	// get the offset
@@ -1250,6 +1363,18 @@ func (w *worker) eventsHandler(jc jape.Context) {
 	}
 }
 
+func (w *worker) bandwidthHandlerGET(jc jape.Context) {
+	jc.Encode(w.bandwidth.Limits())
+}
+
+func (w *worker) bandwidthHandlerPUT(jc jape.Context) {
+	var limits api.BandwidthLimits
+	if jc.Decode(&limits) != nil {
+		return
+	}
+	w.bandwidth.SetLimits(limits)
+}
+
 func (w *worker) memoryGET(jc jape.Context) {
 	jc.Encode(api.MemoryResponse{
 		Download: w.downloadManager.mm.Status(),
@@ -1281,7 +1406,7 @@ func (w *worker) stateHandlerGET(jc jape.Context) {
 }
 
 // New returns an HTTP handler that serves the worker API.
-func New(masterKey [32]byte, id string, b Bus, contractLockingDuration, busFlushInterval, downloadOverdriveTimeout, uploadOverdriveTimeout time.Duration, downloadMaxOverdrive, uploadMaxOverdrive, downloadMaxMemory, uploadMaxMemory uint64, allowPrivateIPs bool, l *zap.Logger) (*worker, error) {
+func New(masterKey [32]byte, id string, b Bus, contractLockingDuration, busFlushInterval, downloadOverdriveTimeout, uploadOverdriveTimeout time.Duration, downloadMaxOverdrive, uploadMaxOverdrive, downloadMaxMemory, uploadMaxMemory uint64, downloadReservedMemory, uploadReservedMemory map[api.MemoryClass]uint64, bandwidthLimits api.BandwidthLimits, allowPrivateIPs bool, l *zap.Logger) (*worker, error) {
 	if contractLockingDuration == 0 {
 		return nil, errors.New("contract lock duration must be positive")
 	}
@@ -1308,6 +1433,7 @@ func New(masterKey [32]byte, id string, b Bus, contractLockingDuration, busFlush
 	w := &worker{
 		alerts:                  alerts.WithOrigin(b, fmt.Sprintf("worker.%s", id)),
 		allowPrivateIPs:         allowPrivateIPs,
+		bandwidth:               newBandwidthLimiters(bandwidthLimits),
 		contractLockingDuration: contractLockingDuration,
 		cache:                   cache,
 		id:                      id,
@@ -1324,8 +1450,14 @@ func New(masterKey [32]byte, id string, b Bus, contractLockingDuration, busFlush
 	w.initPriceTables()
 	w.initTransportPool()
 
-	w.initDownloadManager(downloadMaxMemory, downloadMaxOverdrive, downloadOverdriveTimeout, l.Named("downloadmanager").Sugar())
-	w.initUploadManager(uploadMaxMemory, uploadMaxOverdrive, uploadOverdriveTimeout, l.Named("uploadmanager").Sugar())
+	if err := w.initDownloadManager(w.bandwidth.download, downloadMaxMemory, downloadReservedMemory, downloadMaxOverdrive, downloadOverdriveTimeout, l.Named("downloadmanager").Sugar()); err != nil {
+		shutdownCancel()
+		return nil, err
+	}
+	if err := w.initUploadManager(w.bandwidth.upload, uploadMaxMemory, uploadReservedMemory, uploadMaxOverdrive, uploadOverdriveTimeout, l.Named("uploadmanager").Sugar()); err != nil {
+		shutdownCancel()
+		return nil, err
+	}
 
 	w.initContractSpendingRecorder(busFlushInterval)
 	return w, nil
@@ -1337,6 +1469,9 @@ func (w *worker) Handler() http.Handler {
 		"GET    /account/:hostkey": w.accountHandlerGET,
 		"GET    /id":               w.idHandlerGET,
 
+		"GET    /bandwidth": w.bandwidthHandlerGET,
+		"PUT    /bandwidth": w.bandwidthHandlerPUT,
+
 		"POST   /events": w.eventsHandler,
 
 		"GET /memory": w.memoryGET,
@@ -1353,6 +1488,7 @@ func (w *worker) Handler() http.Handler {
 		"POST   /rhp/pricetable":             w.rhpPriceTableHandler,
 
 		"GET    /stats/downloads": w.downloadsStatsHandlerGET,
+		"GET    /stats/hosts":     w.hostsStatsHandlerGET,
 		"GET    /stats/uploads":   w.uploadsStatsHandlerGET,
 		"POST   /slab/migrate":    w.slabMigrateHandler,
 
@@ -1363,6 +1499,9 @@ func (w *worker) Handler() http.Handler {
 
 		"PUT    /multipart/*path": w.multipartUploadHandlerPUT,
 
+		"HEAD   /website/:bucket/*path": w.websiteHandlerGET,
+		"GET    /website/:bucket/*path": w.websiteHandlerGET,
+
 		"GET    /state": w.stateHandlerGET,
 	})
 }
@@ -1603,6 +1742,17 @@ func (w *worker) GetObject(ctx context.Context, bucket, path string, opts api.Do
 		return nil, fmt.Errorf("couldn't fetch contracts from bus: %w", err)
 	}
 
+	// default to the interactive memory class
+	memoryClass := opts.MemoryClass
+	if memoryClass == "" {
+		memoryClass = api.MemoryClassInteractive
+	}
+
+	// apply the per-request bandwidth limit
+	if opts.BandwidthLimit > 0 {
+		ctx = withBandwidthLimiter(ctx, newBandwidthLimiter(opts.BandwidthLimit))
+	}
+
 	// prepare the content
 	var content io.ReadCloser
 	if opts.Range.Length == 0 || obj.TotalSize() == 0 {
@@ -1613,7 +1763,7 @@ func (w *worker) GetObject(ctx context.Context, bucket, path string, opts api.Do
 		// otherwise return a pipe reader
 		downloadFn := func(wr io.Writer, offset, length int64) error {
 			ctx = WithGougingChecker(ctx, w.bus, gp)
-			err = w.downloadManager.DownloadObject(ctx, wr, obj, uint64(offset), uint64(length), contracts)
+			err = w.downloadManager.DownloadObject(ctx, wr, obj, uint64(offset), uint64(length), contracts, memoryClass)
 			if err != nil {
 				w.logger.Error(err)
 				if !errors.Is(err, ErrShuttingDown) &&
@@ -1654,6 +1804,11 @@ func (w *worker) UploadObject(ctx context.Context, r io.Reader, bucket, path str
 	// attach gouging checker to the context
 	ctx = WithGougingChecker(ctx, w.bus, up.GougingParams)
 
+	// apply the per-request bandwidth limit
+	if opts.BandwidthLimit > 0 {
+		ctx = withBandwidthLimiter(ctx, newBandwidthLimiter(opts.BandwidthLimit))
+	}
+
 	// fetch contracts
 	contracts, err := w.bus.Contracts(ctx, api.ContractsOpts{ContractSet: up.ContractSet})
 	if err != nil {
@@ -1697,6 +1852,11 @@ func (w *worker) UploadMultipartUploadPart(ctx context.Context, r io.Reader, buc
 	// attach gouging checker to the context
 	ctx = WithGougingChecker(ctx, w.bus, up.GougingParams)
 
+	// apply the per-request bandwidth limit
+	if opts.BandwidthLimit > 0 {
+		ctx = withBandwidthLimiter(ctx, newBandwidthLimiter(opts.BandwidthLimit))
+	}
+
 	// prepare opts
 	uploadOpts := []UploadOption{
 		WithBlockHeight(up.CurrentHeight),
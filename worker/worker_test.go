@@ -2,13 +2,17 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http/httptest"
+	"testing"
 	"time"
 
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/api"
 	"go.sia.tech/renterd/internal/test"
+	"go.sia.tech/renterd/worker/client"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/blake2b"
 	"lukechampine.com/frand"
@@ -42,7 +46,7 @@ func newTestWorker(t test.TestingCommon) *testWorker {
 	ulmm := newMemoryManagerMock()
 
 	// create worker
-	w, err := New(blake2b.Sum256([]byte("testwork")), "test", b, time.Second, time.Second, time.Second, time.Second, 0, 0, 1, 1, false, zap.NewNop())
+	w, err := New(blake2b.Sum256([]byte("testwork")), "test", b, time.Second, time.Second, time.Second, time.Second, 0, 0, 1, 1, nil, nil, api.BandwidthLimits{}, false, zap.NewNop())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -134,3 +138,91 @@ func newTestSector() (*[rhpv2.SectorSize]byte, types.Hash256) {
 	frand.Read(sector[:])
 	return &sector, rhpv2.SectorRoot(&sector)
 }
+
+// contractSetBusMock returns the contracts of the given hosts when contracts
+// are fetched for a contract set.
+type contractSetBusMock struct {
+	Bus
+	set map[types.PublicKey]struct{}
+}
+
+func (b *contractSetBusMock) Contracts(ctx context.Context, opts api.ContractsOpts) ([]api.ContractMetadata, error) {
+	contracts, err := b.Bus.Contracts(ctx, opts)
+	if err != nil || opts.ContractSet == "" {
+		return contracts, err
+	}
+	var filtered []api.ContractMetadata
+	for _, c := range contracts {
+		if _, ok := b.set[c.HostKey]; ok {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, nil
+}
+
+func TestHostStats(t *testing.T) {
+	w := newTestWorker(t)
+	hosts := w.AddHosts(3)
+	w.bus = &contractSetBusMock{Bus: w.bus, set: map[types.PublicKey]struct{}{hosts[2].PublicKey(): {}}}
+
+	srv := httptest.NewServer(w.Handler())
+	defer srv.Close()
+	c := client.New(srv.URL, "")
+
+	// create downloaders and uploaders for all hosts
+	w.downloadManager.refreshDownloaders(w.Contracts())
+	w.uploadManager.refreshUploaders(w.Contracts(), 1)
+
+	// track a failed download on the first host
+	w.downloadManager.downloaders[hosts[0].PublicKey()].trackFailure(errors.New("download error"))
+
+	// track two successful uploads and a failed one on the second host
+	for _, u := range w.uploadManager.uploaders {
+		if u.hk == hosts[1].PublicKey() {
+			u.trackConsecutiveFailures(true, false)
+			u.trackConsecutiveFailures(true, false)
+			u.trackConsecutiveFailures(false, true)
+			u.trackError(errors.New("upload error"))
+		}
+	}
+
+	// assert the stats of every host are returned, sorted by host key
+	resp, err := c.HostStats(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(resp.Hosts) != len(hosts) {
+		t.Fatal("unexpected number of hosts", len(resp.Hosts))
+	}
+	stats := make(map[types.PublicKey]api.HostStats)
+	for i, hs := range resp.Hosts {
+		if i > 0 && resp.Hosts[i-1].HostKey.String() >= hs.HostKey.String() {
+			t.Fatal("hosts aren't sorted")
+		} else if hs.Downloads == nil || hs.Uploads == nil {
+			t.Fatalf("missing stats for host %v", hs.HostKey)
+		}
+		stats[hs.HostKey] = hs
+	}
+
+	// assert the aggregates
+	if dl := stats[hosts[0].PublicKey()].Downloads; dl.Healthy || dl.ConsecutiveFailures != 1 || dl.NumFailures != 1 || dl.NumSuccesses != 0 {
+		t.Fatalf("unexpected download stats %+v", dl)
+	} else if dl.LastError != "download error" || time.Time(dl.LastErrorTime).IsZero() {
+		t.Fatalf("unexpected download error %+v", dl)
+	}
+	if ul := stats[hosts[1].PublicKey()].Uploads; ul.ConsecutiveFailures != 1 || ul.NumFailures != 1 || ul.NumSuccesses != 2 {
+		t.Fatalf("unexpected upload stats %+v", ul)
+	} else if ul.LastError != "upload error" || time.Time(ul.LastErrorTime).IsZero() {
+		t.Fatalf("unexpected upload error %+v", ul)
+	}
+	if hs := stats[hosts[2].PublicKey()]; !hs.Downloads.Healthy || !hs.Uploads.Healthy || hs.Downloads.NumFailures != 0 || hs.Uploads.NumSuccesses != 0 || hs.Uploads.LastError != "" {
+		t.Fatalf("unexpected stats %+v %+v", hs.Downloads, hs.Uploads)
+	}
+
+	// assert filtering by contract set only returns the hosts in the set
+	resp, err = c.HostStats(context.Background(), "set")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(resp.Hosts) != 1 || resp.Hosts[0].HostKey != hosts[2].PublicKey() {
+		t.Fatalf("unexpected hosts %+v", resp.Hosts)
+	}
+}